@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/routes"
+)
+
+// `gitus hook` handler. called by the hook dispatchers gitus installs
+// into the repositories (see `InstallGitusHooks` in pkg/gitlib). the
+// environment variables are set by `routes.PreparePushEnvironment`.
+// see docs/hooks.org.
+//
+// unlike `gitus ssh`, the output here goes to the stderr of the hook,
+// which git relays to the client as "remote: ..." lines.
+
+type refUpdate struct {
+	OldRev string
+	NewRev string
+	RefName string
+}
+
+func isZeroObjectId(s string) bool {
+	return len(strings.Trim(s, "0")) <= 0
+}
+
+// each line is "{oldrev} {newrev} {refname}". see githooks(5).
+func parseRefUpdateList(s string) []refUpdate {
+	res := make([]refUpdate, 0)
+	for _, line := range strings.Split(s, "\n") {
+		k := strings.Fields(line)
+		if len(k) < 3 { continue }
+		res = append(res, refUpdate{ OldRev: k[0], NewRev: k[1], RefName: k[2] })
+	}
+	return res
+}
+
+func HandleHook(ctx *routes.RouterContext, hookName string) {
+	ctx.Config.RecalculateProperPath()
+	// the ref update list is read by us first & then passed to the
+	// user-defined hook, so we have to keep it.
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read ref updates: %s\n", err)
+		os.Exit(1)
+	}
+	updateList := parseRefUpdateList(string(input))
+	pusher := os.Getenv("GITUS_PUSHER")
+	if len(pusher) > 0 {
+		ns := os.Getenv("GITUS_REPO_NAMESPACE")
+		name := os.Getenv("GITUS_REPO_NAME")
+		repo, err := ctx.DatabaseInterface.GetRepositoryByName(ns, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get repository: %s\n", err)
+			os.Exit(1)
+		}
+		switch hookName {
+		case "pre-receive":
+			if !checkPreReceive(ctx, repo, pusher, updateList) { os.Exit(1) }
+		case "post-receive":
+			handlePostReceive(ctx, repo, pusher, updateList)
+		}
+	}
+	os.Exit(runUserHook(hookName, input))
+}
+
+// returns false if the push should be rejected. the reasons are
+// printed to stderr.
+func checkPreReceive(ctx *routes.RouterContext, repo *model.Repository, pusher string, updateList []refUpdate) bool {
+	// this is also checked by the ssh/http handler but it doesn't
+	// hurt to check again since the status could've changed.
+	if repo.Status == model.REPO_ARCHIVED {
+		fmt.Fprintf(os.Stderr, "The repository %s is ARCHIVED; no push to remote is allowed.\n", repo.FullName())
+		return false
+	}
+	lgr, ok := repo.Repository.(*gitlib.LocalGitRepository)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Repository %s is not a Git repository.\n", repo.FullName())
+		return false
+	}
+	if ctx.Config.GitConfig.MaxPushSize > 0 {
+		size, err := quarantineSize()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to check push size: %s\n", err)
+			return false
+		}
+		if size > ctx.Config.GitConfig.MaxPushSize {
+			fmt.Fprintf(os.Stderr, "Push too large: %d bytes (limit %d bytes).\n", size, ctx.Config.GitConfig.MaxPushSize)
+			return false
+		}
+	}
+	ruleList, err := ctx.DatabaseInterface.GetAllBranchProtectionRule(repo.Namespace, repo.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get branch protection rules: %s\n", err)
+		return false
+	}
+	if len(ruleList) <= 0 { return true }
+	res := true
+	for _, k := range updateList {
+		if !strings.HasPrefix(k.RefName, "refs/heads/") { continue }
+		branchName := strings.TrimPrefix(k.RefName, "refs/heads/")
+		var updateType int
+		if isZeroObjectId(k.NewRev) {
+			updateType = model.BRANCH_UPDATE_DELETE
+		} else if isZeroObjectId(k.OldRev) {
+			updateType = model.BRANCH_UPDATE_CREATE
+		} else {
+			// the new objects are still in quarantine at this
+			// point, but git makes them visible to the commands we
+			// run thru the environment it gives to the hook.
+			ff, err := lgr.IsAncestor(k.OldRev, k.NewRev)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to check update of %s: %s\n", k.RefName, err)
+				return false
+			}
+			if ff {
+				updateType = model.BRANCH_UPDATE_PUSH
+			} else {
+				updateType = model.BRANCH_UPDATE_FORCE_PUSH
+			}
+		}
+		err = model.CheckBranchUpdate(ruleList, pusher, branchName, updateType)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			res = false
+		}
+	}
+	return res
+}
+
+// the total size of the objects brought in by the current push. git
+// (since 2.11) puts them in a quarantine directory until pre-receive
+// accepts them; the size is 0 if that's not available.
+func quarantineSize() (int64, error) {
+	p := os.Getenv("GIT_QUARANTINE_PATH")
+	if len(p) <= 0 { return 0, nil }
+	var res int64 = 0
+	err := filepath.WalkDir(p, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil { return err }
+		if d.IsDir() { return nil }
+		info, err := d.Info()
+		if err != nil { return err }
+		res += info.Size()
+		return nil
+	})
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+// the result of the push is already decided at this point, so errors
+// are only reported & don't affect the exit code.
+func handlePostReceive(ctx *routes.RouterContext, repo *model.Repository, pusher string, updateList []refUpdate) {
+	// for the explore page. see docs/explore.org.
+	if len(updateList) > 0 {
+		err := ctx.DatabaseInterface.RecordRepositoryPush(repo.Namespace, repo.Name, time.Now().Unix())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record push activity: %s\n", err)
+		}
+	}
+	for _, k := range updateList {
+		if strings.HasPrefix(k.RefName, "refs/heads/") && !isZeroObjectId(k.NewRev) {
+			// pull requests from the updated branches need to know.
+			// see docs/pull-request-review.org.
+			branchName := strings.TrimPrefix(k.RefName, "refs/heads/")
+			prList, err := ctx.DatabaseInterface.UpdatePullRequestProviderBranch(repo.Namespace, repo.Name, branchName, pusher, k.NewRev)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to update pull requests from %s: %s\n", k.RefName, err)
+			}
+			routes.TriggerPullRequestUpdatedWebhook(ctx, prList, pusher, k.NewRev)
+			routes.EnqueuePullRequestCIRun(ctx, prList, pusher)
+			err = processPushedIssueReference(ctx, repo, pusher, k)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to process issue references in %s: %s\n", k.RefName, err)
+			}
+		}
+		err := routes.EnqueueRefUpdateWebhook(ctx, repo, k.RefName, k.OldRev, k.NewRev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to enqueue webhook for %s: %s\n", k.RefName, err)
+		}
+		// see docs/ci-runner.org.
+		err = routes.EnqueueCIRun(ctx, repo, k.RefName, k.NewRev, pusher)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to queue ci runs for %s: %s\n", k.RefName, err)
+		}
+	}
+}
+
+// records the issue references in the pushed commits. closing
+// references only close issues when pushed to the default branch.
+// see docs/issue.org.
+func processPushedIssueReference(ctx *routes.RouterContext, repo *model.Repository, pusher string, k refUpdate) error {
+	lgr, ok := repo.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return nil }
+	cobjList, err := getPushedCommitList(repo, k.RefName, k.OldRev, k.NewRev)
+	if err != nil { return err }
+	if len(cobjList) <= 0 { return nil }
+	err = lgr.SyncAllBranchList()
+	if err != nil { return err }
+	br := lgr.GetDefaultBranch()
+	allowClose := br != nil && "refs/heads/" + br.Name == k.RefName
+	var lastErr error = nil
+	// oldest first, so that the events are in the same order as
+	// the commits.
+	for i := len(cobjList)-1; i >= 0; i-- {
+		cobj := cobjList[i]
+		err = routes.ProcessIssueReference(ctx, repo, routes.CommitReferenceSource(repo, cobj), cobj.CommitMessage, pusher, allowClose)
+		if err != nil { lastErr = err }
+	}
+	return lastErr
+}
+
+// runs the user-defined version of `hookName` (if there's any) w/ the
+// same input & environment and returns its exit code.
+func runUserHook(hookName string, input []byte) int {
+	gitDir := os.Getenv("GIT_DIR")
+	if len(gitDir) <= 0 { gitDir = "." }
+	p := path.Join(gitDir, gitlib.USER_HOOK_DIRECTORY, hookName)
+	st, err := os.Stat(p)
+	if err != nil {
+		if os.IsNotExist(err) { return 0 }
+		fmt.Fprintf(os.Stderr, "Failed to check %s hook: %s\n", hookName, err)
+		return 1
+	}
+	// git ignores hooks that are not executable; we do the same.
+	if st.Mode() & 0111 == 0 { return 0 }
+	cmd := exec.Command(p)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) { return exitErr.ExitCode() }
+		fmt.Fprintf(os.Stderr, "Failed to run %s hook: %s\n", hookName, err)
+		return 1
+	}
+	return 0
+}
@@ -363,15 +363,13 @@ func InstallGitus(ctx routes.RouterContext) {
 		fmt.Print("Cannot infer database interface since database type empty in config. Please fix it and try again.")
 		os.Exit(1)
 	}
-	s, err := dbif.IsDatabaseUsable()
-	if err != nil { log.Panic(err) }
-	if !s {
-		fmt.Println("Setting up tables...")
-		err = dbif.InstallTables()
-		if err != nil {
-			log.Panic(err)
-		}
-	}	
+	// always run: everything in `InstallTables` is idempotent & it
+	// also adds what's missing in the tables of older installs.
+	fmt.Println("Setting up tables...")
+	err = dbif.InstallTables()
+	if err != nil {
+		log.Panic(err)
+	}
 
 	// setting up session store
 	fmt.Println("Setting up session store...")
@@ -379,7 +377,7 @@ func InstallGitus(ctx routes.RouterContext) {
 		fmt.Print("Cannot infer session interface since session type empty in config. Please fix it and try again.")
 		os.Exit(1)
 	}
-	s, err = ssif.IsSessionStoreUsable()
+	s, err := ssif.IsSessionStoreUsable()
 	if err != nil { log.Panic(err) }
 	if !s {
 		fmt.Println("Setting up session store...")
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/jobqueue"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/mail"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/routes"
+)
+
+// handlers of the background jobs. see docs/job-queue.org.
+
+// `mailer` is the mailer that actually sends the mails (as opposed
+// to `ctx.Mailer`, which enqueues them); mail jobs are not handled if
+// it's nil.
+func registerJobHandler(ctx *routes.RouterContext, mailer mail.GitusMailerInterface) {
+	if mailer != nil {
+		ctx.JobQueue.RegisterHandler(model.JOB_TYPE_MAIL, jobqueue.MailJobHandler(mailer))
+	}
+	ctx.JobQueue.RegisterHandler(model.JOB_TYPE_WEBHOOK, func(job *model.Job) error {
+		return handleWebHookJob(ctx, job)
+	})
+	ctx.JobQueue.RegisterHandler(model.JOB_TYPE_WEBHOOK_DELIVERY, func(job *model.Job) error {
+		return handleWebHookDeliveryJob(ctx, job)
+	})
+	ctx.JobQueue.RegisterHandler(model.JOB_TYPE_MERGE_CHECK, func(job *model.Job) error {
+		return handleMergeCheckJob(ctx, job)
+	})
+	ctx.JobQueue.RegisterHandler(model.JOB_TYPE_PULL_REQUEST_CI, func(job *model.Job) error {
+		return handlePullRequestCIJob(ctx, job)
+	})
+}
+
+func handleWebHookJob(ctx *routes.RouterContext, job *model.Job) error {
+	var p model.WebhookJobPayload
+	err := jobqueue.UnmarshalPayload(job, &p)
+	if err != nil { return err }
+	repo, err := ctx.DatabaseInterface.GetRepositoryByName(p.RepoNamespace, p.RepoName)
+	if err == db.ErrEntityNotFound {
+		return &jobqueue.PermanentError{Err: fmt.Errorf("Repository %s:%s no longer exists", p.RepoNamespace, p.RepoName)}
+	}
+	if err != nil { return err }
+	dList, err := newWebHookDeliveryList(ctx, repo, p.RefName, p.OldRev, p.NewRev)
+	if err != nil { return err }
+	for _, d := range dList {
+		err = routes.EnqueueWebhookDelivery(ctx, d)
+		if err != nil { return err }
+	}
+	return nil
+}
+
+func handleWebHookDeliveryJob(ctx *routes.RouterContext, job *model.Job) error {
+	var p model.WebhookDeliveryJobPayload
+	err := jobqueue.UnmarshalPayload(job, &p)
+	if err != nil { return err }
+	d, err := ctx.DatabaseInterface.GetWebhookDelivery(p.UUID)
+	if err == db.ErrEntityNotFound { return nil }
+	if err != nil { return err }
+	if d.Status != model.WEBHOOK_DELIVERY_PENDING { return nil }
+	return routes.DeliverWebhook(ctx, d, job.Attempt >= job.MaxAttempt)
+}
+
+// the result is stored w/ the pull request.
+func handleMergeCheckJob(ctx *routes.RouterContext, job *model.Job) error {
+	var p model.MergeCheckJobPayload
+	err := jobqueue.UnmarshalPayload(job, &p)
+	if err != nil { return err }
+	pr, err := ctx.DatabaseInterface.GetPullRequestByAbsId(p.PRAbsId)
+	if err == db.ErrEntityNotFound { return nil }
+	if err != nil { return err }
+	if pr.Status != model.PULL_REQUEST_OPEN { return nil }
+	_, err = ctx.DatabaseInterface.CheckPullRequestMergeConflict(p.PRAbsId)
+	return err
+}
+
+// see docs/ci-runner.org.
+func handlePullRequestCIJob(ctx *routes.RouterContext, job *model.Job) error {
+	var p model.PullRequestCIJobPayload
+	err := jobqueue.UnmarshalPayload(job, &p)
+	if err != nil { return err }
+	pr, err := ctx.DatabaseInterface.GetPullRequestByAbsId(p.PRAbsId)
+	if err == db.ErrEntityNotFound { return nil }
+	if err != nil { return err }
+	if pr.Status != model.PULL_REQUEST_OPEN { return nil }
+	return routes.QueuePullRequestCIRun(ctx, pr, p.Trigger)
+}
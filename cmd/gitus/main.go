@@ -16,6 +16,7 @@ import (
 	"github.com/GitusCodeForge/Gitus/pkg/gitus"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/confirm_code"
 	dbinit "github.com/GitusCodeForge/Gitus/pkg/gitus/db/init"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/jobqueue"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/mail"
 	rsinit "github.com/GitusCodeForge/Gitus/pkg/gitus/receipt/init"
 	ssinit "github.com/GitusCodeForge/Gitus/pkg/gitus/session/init"
@@ -79,11 +80,15 @@ func main() {
 	isWebHooks := containsCommand && mainCall[0] == "web-hooks"
 	isUpdateTrigger := containsCommand && mainCall[0] == "update-trigger"
 	isResetAdmin := containsCommand && mainCall[0] == "reset-admin"
-	dbifNeeded := isWebServer || (containsCommand && (isSsh || isWebHooks || isUpdateTrigger || isResetAdmin))
+	isHook := containsCommand && mainCall[0] == "hook"
+	isRunner := containsCommand && mainCall[0] == "runner"
+	dbifNeeded := isWebServer || (containsCommand && (isSsh || isWebHooks || isUpdateTrigger || isResetAdmin || isHook || isRunner))
 	ssifNeeded := isWebServer
 	keyctxNeeded := isWebServer || (containsCommand && isSsh)
 	rsifNeeded := isWebServer
-	mailerNeeded := isWebServer
+	// the hook dispatcher enqueues the mails of the notifications
+	// it sends; see docs/job-queue.org.
+	mailerNeeded := isWebServer || isHook
 	ccmNeeded := isWebServer
 
 	config, err := gitus.LoadConfigFile(configPath)
@@ -104,6 +109,7 @@ func main() {
 	}
 	
 	masterTemplate := templates.LoadTemplate()
+	var directMailer mail.GitusMailerInterface = nil
 	context := routes.RouterContext{
 		Config: config,
 		MasterTemplate: masterTemplate,
@@ -118,6 +124,7 @@ func main() {
 				os.Exit(1)
 			}
 			context.DatabaseInterface = dbif
+			context.JobQueue = jobqueue.NewJobQueue(dbif)
 		}
 
 		if ssifNeeded {
@@ -158,6 +165,13 @@ func main() {
 			context.Mailer = ml
 		}
 
+		// mails are sent by the job workers, which use the actual
+		// mailer; everything else only enqueues them.
+		if context.Mailer != nil && context.JobQueue != nil {
+			directMailer = context.Mailer
+			context.Mailer = jobqueue.NewQueuedMailer(context.JobQueue)
+		}
+
 		if ccmNeeded {
 			ccm, err := confirm_code.InitializeConfirmCodeManager(config)
 			if err != nil {
@@ -228,6 +242,23 @@ func main() {
 				fmt.Print(gitlib.ToPktLine(fmt.Sprintf("Error command for `gitus web-hooks`: %s.", mainCall[1])))
 			}
 			return
+		case "hook":
+			if len(mainCall) < 2 {
+				fmt.Fprintf(os.Stderr, "Error format for `gitus hook`.\n")
+				os.Exit(1)
+			}
+			switch mainCall[1] {
+			case "pre-receive": fallthrough
+			case "post-receive":
+				HandleHook(&context, mainCall[1])
+			default:
+				fmt.Fprintf(os.Stderr, "Error command for `gitus hook`: %s.\n", mainCall[1])
+				os.Exit(1)
+			}
+			return
+		case "runner":
+			HandleRunner(&context)
+			return
 		case "update-trigger":
 			if len(mainCall) < 6 {
 				fmt.Print(gitlib.ToPktLine("Error format for `gitus-update-trigger`."))
@@ -251,6 +282,15 @@ func main() {
 	
 	controller.InitializeRoute(&context)
 
+	if context.JobQueue != nil {
+		registerJobHandler(&context, directMailer)
+		err = context.JobQueue.Start(jobqueue.DEFAULT_WORKER_COUNT)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start job workers: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
 	go func() {
 		log.Printf("Start serving at %s:%d\n", config.BindAddress, config.BindPort)
 		err := server.ListenAndServe()
@@ -284,6 +324,14 @@ func main() {
 		log.Fatalf("HTTP shutdown err: %v", err.Error())
 	}
 
+	// running jobs are left to finish; the ones that can't make it
+	// in time are run again the next time.
+	if context.JobQueue != nil {
+		if err = context.JobQueue.Stop(shutdownCtx); err != nil {
+			log.Printf("Failed to wait for running jobs: %s\n", err.Error())
+		}
+	}
+
 	if context.DatabaseInterface != nil {
 		if err = context.DatabaseInterface.Dispose(); err != nil {
 			log.Printf("Failed to dispose database interface: %s\n", err.Error())
@@ -0,0 +1,53 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/runner"
+	"github.com/GitusCodeForge/Gitus/routes"
+)
+
+// `gitus runner` handler. runs the jobs queued by pushes until
+// SIGINT/SIGTERM. see docs/ci-runner.org.
+
+func HandleRunner(ctx *routes.RouterContext) {
+	if ctx.Config.OperationMode != gitus.OP_MODE_NORMAL {
+		fmt.Fprintf(os.Stderr, "The ci runner is only available in normal mode.\n")
+		os.Exit(1)
+	}
+	if !ctx.Config.Runner.Enable {
+		fmt.Fprintf(os.Stderr, "The ci runner is not enabled in the config file.\n")
+		os.Exit(1)
+	}
+	ctx.Config.RecalculateProperPath()
+	rn, err := runner.NewRunner(ctx.DatabaseInterface, ctx.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create runner: %s\n", err)
+		os.Exit(1)
+	}
+	err = rn.Start(ctx.Config.Runner.Concurrency)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start runner: %s\n", err)
+		os.Exit(1)
+	}
+	log.Println("Runner started.")
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+	shutdownCtx, shutdownRelease := gocontext.WithTimeout(gocontext.Background(), 30*time.Second)
+	defer shutdownRelease()
+	if err = rn.Stop(shutdownCtx); err != nil {
+		log.Printf("Failed to wait for running jobs: %s\n", err.Error())
+	}
+	if err = ctx.DatabaseInterface.Dispose(); err != nil {
+		log.Printf("Failed to dispose database interface: %s\n", err.Error())
+	}
+	log.Println("Runner stopped.")
+}
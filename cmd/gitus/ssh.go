@@ -8,11 +8,13 @@ import (
 	"strings"
 
 	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/ssh"
 	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
 	"github.com/GitusCodeForge/Gitus/pkg/shellparse"
 	"github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
 )
 
 // `gitus ssh` handler.
@@ -48,9 +50,68 @@ func parseTargetRepositoryName(ctx *routes.RouterContext, relPath string) (strin
 		namespaceName = ""
 		repositoryName = relPathSegment[0]
 	}
+	// follow the redirect if the repository has been moved so that
+	// the remotes w/ the old name keep working. see
+	// docs/repo-move.org.
+	if ctx.Config.OperationMode == gitus.OP_MODE_NORMAL && ctx.DatabaseInterface != nil {
+		_, err := ctx.DatabaseInterface.GetRepositoryByName(namespaceName, repositoryName)
+		if err == db.ErrEntityNotFound {
+			newNs, newName, err := ctx.DatabaseInterface.GetRepositoryRedirect(namespaceName, repositoryName)
+			if err == nil { return newNs, newName }
+		}
+	}
 	return namespaceName, repositoryName
 }
 
+// snippets are addressed as `snippet/{username}/{name}`. repository
+// names never contain slashes so this doesn't clash w/ repositories.
+func parseTargetSnippetName(relPath string) (string, string, bool) {
+	if relPath[0] == '~' || relPath[0] == '/' {
+		relPath = relPath[1:]
+	}
+	relPathSegment := strings.Split(relPath, "/")
+	if len(relPathSegment) != 3 || relPathSegment[0] != "snippet" { return "", "", false }
+	return relPathSegment[1], relPathSegment[2], true
+}
+
+// snippets are read-only over ssh; see docs/snippets.org.
+func handleSSHSnippet(ctx *routes.RouterContext, username string, parsedOrigCmd []string, snippetUser string, snippetName string) {
+	if parsedOrigCmd[0] != "git-upload-pack" && parsedOrigCmd[0] != "git-upload-archive" {
+		printGitError("Snippets cannot be pushed to.")
+		os.Exit(1)
+	}
+	u, err := ctx.DatabaseInterface.GetUserByName(username)
+	if err != nil {
+		printGitError(fmt.Sprintf("Failed while reading user: %s.", err.Error()))
+		os.Exit(1)
+	}
+	loginInfo := &templates.LoginInfoModel{
+		LoggedIn: true,
+		UserName: username,
+		IsAdmin: (u.Status == model.ADMIN || u.Status == model.SUPER_ADMIN) && !routes.AdminSecondFactorRequired(ctx, u),
+	}
+	sn, err := ctx.DatabaseInterface.GetSnippet(snippetUser, snippetName)
+	if err != nil || !routes.CheckSnippetVisibleToUser(loginInfo, sn) {
+		printGitError("Snippet not found.")
+		os.Exit(1)
+	}
+	lgr, err := sn.OpenRepository(ctx.Config.SnippetRoot)
+	if err != nil {
+		printGitError(fmt.Sprintf("Failed to open snippet: %s.", err.Error()))
+		os.Exit(1)
+	}
+	parsedOrigCmd[len(parsedOrigCmd)-1] = lgr.GitDirectoryPath
+	cmdobj := exec.Command(parsedOrigCmd[0], parsedOrigCmd[1:]...)
+	cmdobj.Stdout = os.Stdout
+	cmdobj.Stdin = os.Stdin
+	cmdobj.Stderr = os.Stderr
+	err = cmdobj.Run()
+	if err != nil {
+		printGitError(err.Error())
+	}
+	os.Exit(0)
+}
+
 func handleSSHSimpleMode(ctx *routes.RouterContext, username string, keyname string) {
 	if ctx.SSHKeyManagingContext == nil {
 		sshCtx, err := ssh.ToContext(ctx.Config)
@@ -149,6 +210,10 @@ func HandleSSHLogin(ctx *routes.RouterContext, username string, keyname string)
 	parsedOrigCmd := shellparse.ParseShellCommand(origCmd)
 	isPushingToRemote := parsedOrigCmd[0] == "git-receive-pack"
 	relPath := parsedOrigCmd[len(parsedOrigCmd)-1]
+	if snippetUser, snippetName, ok := parseTargetSnippetName(relPath); ok {
+		handleSSHSnippet(ctx, username, parsedOrigCmd, snippetUser, snippetName)
+		return
+	}
 	namespaceName, repositoryName := parseTargetRepositoryName(ctx, relPath)
 
 	// check acl.
@@ -190,6 +255,16 @@ func HandleSSHLogin(ctx *routes.RouterContext, username string, keyname string)
 	realGitPath := path.Join(ctx.Config.GitRoot, r.Namespace, r.Name)
 	parsedOrigCmd[len(parsedOrigCmd)-1] = realGitPath
 	cmdobj := exec.Command(parsedOrigCmd[0], parsedOrigCmd[1:]...)
+	if isPushingToRemote {
+		// the gitus hook dispatchers (see docs/hooks.org) need to
+		// know who is pushing.
+		env, err := routes.PreparePushEnvironment(ctx, username, r)
+		if err != nil {
+			printGitError(fmt.Sprintf("Failed while preparing push: %s", err.Error()))
+			os.Exit(1)
+		}
+		cmdobj.Env = append(os.Environ(), env...)
+	}
 
 	cmdobj.Stdout = os.Stdout
 	cmdobj.Stdin = os.Stdin
@@ -2,20 +2,13 @@ package main
 
 import (
 	"bytes"
-	"crypto/rand"
-	"encoding/json"
 	"fmt"
-	"math/big"
-	"net/http"
 	"os/exec"
 	"strings"
-	"time"
 
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
 	"github.com/GitusCodeForge/Gitus/routes"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 )
 
 type WebHookEntityInfo struct{
@@ -33,37 +26,17 @@ type WebHookCommitInfo struct{
 	Timestamp int64 `json:"timestamp"`
 }
 
-type WebHookRepositoryOwnerInfo struct{
-	Id int64 `json:"id"`
-	Login string `json:"login"`
-	FullName string `json:"full_name"`
-	Email string `json:"email"`
-	UserName string `json:"username"`
-}
-
-type WebHookRepositoryInfo struct {
-	Id int64 `json:"id"`
-	Owner WebHookRepositoryOwnerInfo `json:"owner"`
-	Name string `json:"name"`
-	Namespace string `json:"namespace"`
-	FullName string `json:"full_name"`
-	Description string `json:"description"`
-	Fork bool `json:"fork"`
-	HTMLURL string `json:"html_url"`
-	SSHURL string `json:"ssh_url"`
-	CloneURL string `json:"clone_url"`
-}
-
 type WebHookPayload struct {
 	Id string `json:"id"`
 	ResultReport string `json:"result_report"`
 	ResultReportId string `json:"result_report_id"`
+	Event string `json:"event"`
 	Reference string `json:"ref"`
 	BeforeCommitId string `json:"before"`
 	AfterCommitId string `json:"after"`
 	CompareURL string `json:"compare_url"`
 	Commits []*WebHookCommitInfo `json:"commits"`
-	Repository *WebHookRepositoryInfo `json:"repository"`
+	Repository *routes.WebHookRepositoryInfo `json:"repository"`
 }
 
 func resolveURL(ctx *routes.RouterContext, repo *model.Repository, cobj *gitlib.CommitObject) string {
@@ -76,24 +49,6 @@ func resolveUsername(ctx *routes.RouterContext, email string) (string, error) {
 	return s, nil
 }
 
-func resolveRepoHTMLURL(ctx *routes.RouterContext, repo *model.Repository) string {
-	httpHostName := ctx.Config.ProperHTTPHostName()
-	rfn := repo.FullName()
-	return fmt.Sprintf("%s/repo/%s", httpHostName, rfn)
-}
-
-func resolveRepoCloneURL(ctx *routes.RouterContext, repo *model.Repository) string {
-	httpHostName := ctx.Config.ProperHTTPHostName()
-	rfn := repo.FullName()
-	return fmt.Sprintf("%s/repo/%s", httpHostName, rfn)
-}
-
-func resolveRepoSSHURL(ctx *routes.RouterContext, repo *model.Repository) string {
-	gitSshHostName := ctx.Config.GitSSHHostName()
-	sshfn := fmt.Sprintf("%s/%s", repo.Namespace, repo.Name)
-	return fmt.Sprintf("%s%s", gitSshHostName, sshfn)
-}
-
 // NOTE THAT even if any error happens at this part we still need to
 // let the whole program return a success exit code. we can retrigger
 // failed cicd later, but whatever pushed to the depot should be accepted
@@ -114,48 +69,70 @@ func HandleWebHook(ctx *routes.RouterContext, repoFullName string, refFullName s
 		printGitError(fmt.Sprintf("Failed to get repository: %s", err))
 		return
 	}
-	if !repo.WebHookConfig.Enable { return }
-	nonce, err := rand.Int(rand.Reader, big.NewInt(1<<31))
+	// sent right away w/o retrying; the result can be seen in the
+	// delivery list in the repository setting.
+	dList, err := newWebHookDeliveryList(ctx, repo, refFullName, oldRev, newRev)
 	if err != nil {
-		printGitError(fmt.Sprintf("Failed to get repository: %s", err))
+		printGitError(err.Error())
 		return
 	}
-	reqUuid := uuid.New()
-	reportUuid := uuid.New()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
-		"iss": time.Now().Unix(),
-		"nonce": nonce.Int64(),
-	})
-	tokenStr, err := token.SignedString([]byte(repo.WebHookConfig.Secret))
-	if err != nil {
-		printGitError(fmt.Sprintf("Failed to get repository: %s", err))
-		return
+	for _, d := range dList {
+		err = routes.DeliverWebhook(ctx, d, true)
+		if err != nil { printGitError(err.Error()) }
 	}
-	cmd := exec.Command("git", "rev-list", newRev, "^"+oldRev)
-	cmd.Dir = repo.Repository.(*gitlib.LocalGitRepository).GitDirectoryPath
+}
+
+// the commits brought in by the update of `refFullName` from `oldRev`
+// to `newRev`, newest first. deleting a ref brings in no commits;
+// creating a ref brings in the commits that are not reachable from
+// any other branch.
+func getPushedCommitList(repo *model.Repository, refFullName string, oldRev string, newRev string) ([]*gitlib.CommitObject, error) {
+	res := make([]*gitlib.CommitObject, 0)
+	if isZeroObjectId(newRev) { return res, nil }
+	localgr := repo.Repository.(*gitlib.LocalGitRepository)
+	var cmd *exec.Cmd
+	if isZeroObjectId(oldRev) {
+		cmd = exec.Command("git", "rev-list", newRev, "--not", "--exclude="+refFullName, "--branches")
+	} else {
+		cmd = exec.Command("git", "rev-list", newRev, "^"+oldRev)
+	}
+	cmd.Dir = localgr.GitDirectoryPath
 	stdoutBuf := new(bytes.Buffer)
 	cmd.Stdout = stdoutBuf
-	err = cmd.Run()
+	err := cmd.Run()
 	if err != nil {
-		printGitError(fmt.Sprintf("Failed to get rev list: %s", err))
-		return
+		return nil, fmt.Errorf("Failed to get rev list: %s", err)
 	}
-	commitIdList := strings.Split(stdoutBuf.String(), "\n")
-	commits := make([]*WebHookCommitInfo, 0)
-	localgr := repo.Repository.(*gitlib.LocalGitRepository)
-	for _, k := range commitIdList {
+	for _, k := range strings.Split(stdoutBuf.String(), "\n") {
 		id := strings.TrimSpace(k)
 		if len(id) <= 0 { break }
 		gobj, err := localgr.ReadObject(id)
 		if err != nil {
-			printGitError(fmt.Sprintf("Failed to retrieve rev %s: %s", k, err))
-			return
+			return nil, fmt.Errorf("Failed to retrieve rev %s: %s", k, err)
 		}
 		cobj, ok := gobj.(*gitlib.CommitObject)
 		if !ok {
-			printGitError(fmt.Sprintf("Failed to retrieve rev %s: %s", k, err))
-			return
+			return nil, fmt.Errorf("Failed to retrieve rev %s: %s", k, err)
 		}
+		res = append(res, cobj)
+	}
+	return res, nil
+}
+
+// builds & stores the webhook deliveries of `repo` for the update
+// of `refFullName` from `oldRev` to `newRev`, one for each webhook
+// that wants it. the deliveries are sent by `routes.DeliverWebhook`.
+// see docs/webhooks.org.
+func newWebHookDeliveryList(ctx *routes.RouterContext, repo *model.Repository, refFullName string, oldRev string, newRev string) ([]*model.WebhookDelivery, error) {
+	event, branch := model.WebhookRefEvent(refFullName, newRev)
+	if len(event) <= 0 { return nil, nil }
+	hookList, err := routes.GetMatchingWebhook(ctx, repo, event, branch)
+	if err != nil { return nil, err }
+	if len(hookList) <= 0 { return nil, nil }
+	cobjList, err := getPushedCommitList(repo, refFullName, oldRev, newRev)
+	if err != nil { return nil, err }
+	commits := make([]*WebHookCommitInfo, 0)
+	for _, cobj := range cobjList {
 		authorUsername, _ := resolveUsername(ctx, cobj.AuthorInfo.AuthorEmail)
 		committerUsername, _ := resolveUsername(ctx, cobj.CommitterInfo.AuthorEmail)
 		commits = append(commits, &WebHookCommitInfo{
@@ -175,68 +152,15 @@ func HandleWebHook(ctx *routes.RouterContext, repoFullName string, refFullName s
 			Timestamp: cobj.CommitTime.Unix(),
 		})
 	}
-	owner, err := ctx.DatabaseInterface.GetUserByName(repo.Owner)
-	if err != nil {
-		printGitError(fmt.Sprintf("Failed to get user: %s", err))
-		return
-	}
-	
-	err = ctx.DatabaseInterface.RegisterWebhookRequest(reqUuid.String(), reportUuid.String(), repo.Namespace, repo.Name, newRev)
-	if err != nil {
-		printGitError(fmt.Sprintf("Failed to register webhook in database: %s", err))
-		return
-	}
+	repoInfo, err := routes.NewWebHookRepositoryInfo(ctx, repo)
+	if err != nil { return nil, err }
 	payload := WebHookPayload{
-		Id: reqUuid.String(),
-		ResultReport: fmt.Sprintf("%s/%s", ctx.Config.ProperHTTPHostName(), "webhook-result-report"),
-		ResultReportId: reportUuid.String(),
+		Event: event,
 		Reference: refFullName,
 		BeforeCommitId: oldRev,
 		AfterCommitId: newRev,
 		Commits: commits,
-		Repository: &WebHookRepositoryInfo{
-			Id: repo.AbsId,
-			Owner: WebHookRepositoryOwnerInfo{
-				Id: 0,
-				Login: owner.Name,
-				FullName: owner.Title,
-				Email: owner.Email,
-				UserName: owner.Name,
-			},
-			Description: repo.Description,
-			Name: repo.Name,
-			Namespace: repo.Namespace,
-			FullName: repo.FullName(),
-			Fork: repo.ForkOriginName != "" || repo.ForkOriginNamespace != "",
-			HTMLURL: resolveRepoHTMLURL(ctx, repo),
-			SSHURL: resolveRepoSSHURL(ctx, repo),
-			CloneURL: resolveRepoCloneURL(ctx, repo),
-		},
-	}
-	var req *http.Request
-	switch repo.WebHookConfig.PayloadType {
-	case "json":
-		payloadJson, err := json.Marshal(payload)
-		if err != nil {
-			printGitError(fmt.Sprintf("Failed to serialize webhook to json: %s", err))
-			return
-		}
-		rd := bytes.NewReader(payloadJson)
-		req, err = http.NewRequest("POST", repo.WebHookConfig.TargetURL, rd)
-	default:
-		printGitError(fmt.Sprintf("Unsupported webhook payload type: %s", repo.WebHookConfig.PayloadType))
-		return
-	}
-	req.Header.Add("Authentication", fmt.Sprintf("Bearer webhook-jwt-%s", tokenStr))
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		printGitError(fmt.Sprintf("Failed while sending HTTP POST request: %s", err))
-		return
-	}
-	if !strings.HasPrefix(resp.Status, "2") {
-		printGitError(fmt.Sprintf("Errorneous HTTP response: %s", resp.Status))
-		return
+		Repository: repoInfo,
 	}
+	return routes.NewWebhookDeliveryList(ctx, repo, hookList, event, refFullName, newRev, payload)
 }
-
@@ -260,6 +260,7 @@ func bindAllWebInstallerRoutes(ctx *WebInstallerRoutingContext) {
 		ctx.Config.SnippetRoot = strings.TrimSpace(r.Form.Get("snippet-root"))
 		ctx.Config.GitConfig.HTTPCloneProtocol.V1Dumb = len(strings.TrimSpace(r.Form.Get("git-http-clone-enable-v1-dumb"))) > 0
 		ctx.Config.GitConfig.HTTPCloneProtocol.V2 = len(strings.TrimSpace(r.Form.Get("git-http-clone-enable-v2"))) > 0
+		ctx.Config.GitConfig.HTTPPush = len(strings.TrimSpace(r.Form.Get("git-http-enable-push"))) > 0
 		next := ""
 		if ctx.Config.IsInPlainMode() {
 			next = "/step7"
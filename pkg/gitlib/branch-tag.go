@@ -1,5 +1,7 @@
 package gitlib
 
+import "sort"
+
 type Branch struct {
 	Name string
 	HeadId string
@@ -10,3 +12,17 @@ type Tag struct {
 	HeadId string
 }
 
+
+// the branch shown by default: "master", then "main", then the first
+// branch in alphabetical order. nil if there's no branch. uses
+// `BranchIndex`, so the branch list should be synced first.
+func (gr *LocalGitRepository) GetDefaultBranch() *Branch {
+	br, ok := gr.BranchIndex["master"]
+	if !ok { br, ok = gr.BranchIndex["main"] }
+	if ok { return br }
+	if len(gr.BranchIndex) <= 0 { return nil }
+	k := make([]string, 0, len(gr.BranchIndex))
+	for name := range gr.BranchIndex { k = append(k, name) }
+	sort.Strings(k)
+	return gr.BranchIndex[k[0]]
+}
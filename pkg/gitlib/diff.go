@@ -3,6 +3,7 @@ package gitlib
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os/exec"
 	"regexp"
@@ -67,13 +68,29 @@ type DiffItem struct {
 	PatchList []*DiffItemPatch `json:"patchList"`
 }
 
+// the path of the file before the change, w/o the "a/" prefix. empty
+// if the file is newly created.
+func (d *DiffItem) OldPath() string {
+	if d.File1 == "/dev/null" { return "" }
+	return strings.TrimPrefix(d.File1, "a/")
+}
+
+// the path of the file after the change, w/o the "b/" prefix. empty
+// if the file is deleted.
+func (d *DiffItem) NewPath() string {
+	if d.File2 == "/dev/null" { return "" }
+	return strings.TrimPrefix(d.File2, "b/")
+}
+
 type Diff struct {
 	CommitHash string `json:"commit"`
 	ItemList []*DiffItem `json:"item"`
 }
 
 // shhhh....... (finger across lips)
-var reGitDiffHeader = regexp.MustCompile(`(((?:(?:old|new|deleted file|new file) mode)|(?:copy (?:from|to))|(?:rename (?:from|to))|(?:dis)?similarity index) (.*))|(?:index ([^.]+)\.\.([^.]+) (.*))`)
+// NOTE: the mode is omitted from the "index" line for new & deleted
+// files.
+var reGitDiffHeader = regexp.MustCompile(`(((?:(?:old|new|deleted file|new file) mode)|(?:copy (?:from|to))|(?:rename (?:from|to))|(?:dis)?similarity index) (.*))|(?:index ([^.]+)\.\.([^.\s]+)(?: (.*))?)`)
 func parseGitDiffHeaderItem(s string) *DiffItemHeaderItem {
 	matchres := reGitDiffHeader.FindStringSubmatch(s)
 	if len(matchres) <= 0 { return nil }
@@ -106,7 +123,10 @@ func parseGitDiffHeaderItem(s string) *DiffItemHeaderItem {
 
 var reGitDiffItemFile1Header = regexp.MustCompile("--- (.*)")
 var reGitDiffItemFile2Header = regexp.MustCompile(`\+\+\+ (.*)`)
-var reGitDiffItemLineHeader = regexp.MustCompile(`@@ -(\d+),(\d+) \+(\d+),(\d+) @@(.*)`)
+var reGitDiffItemGitHeader = regexp.MustCompile(`diff --git (a/.*) (b/.*)`)
+var reGitDiffItemBinaryHeader = regexp.MustCompile(`Binary files (.*) and (.*) differ`)
+// NOTE: the line count is omitted by git when it's 1.
+var reGitDiffItemLineHeader = regexp.MustCompile(`@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)`)
 
 func parseGitDiff(br *bytes.Buffer) (*Diff, error) {
 	tlr := newTrueLineReader(br)
@@ -121,7 +141,15 @@ func parseGitDiff(br *bytes.Buffer) (*Diff, error) {
 		l, err = tlr.readLine()
 		if errors.Is(err, io.EOF) { break }
 		if err != nil { return nil, err }
-		// NOTE: we skip the "diff --git" line.
+		// NOTE: the file names in the "diff --git" line are only
+		// used when there's no "---"/"+++" lines, e.g. binary files,
+		// mode changes & pure renames.
+		file1, file2 := "", ""
+		matchres := reGitDiffItemGitHeader.FindStringSubmatch(strings.TrimSpace(l))
+		if len(matchres) > 0 {
+			file1 = matchres[1]
+			file2 = matchres[2]
+		}
 		// parse header
 		headerItemList := make([]*DiffItemHeaderItem, 0)
 		for {
@@ -138,17 +166,40 @@ func parseGitDiff(br *bytes.Buffer) (*Diff, error) {
 
 		// parse file headers
 		l, err = tlr.readLine()
-		if errors.Is(err, io.EOF) { break }
+		if errors.Is(err, io.EOF) {
+			itemList = append(itemList, &DiffItem{
+				File1: file1,
+				File2: file2,
+				Header: headerItemList,
+				PatchList: make([]*DiffItemPatch, 0),
+			})
+			break
+		}
 		if err != nil { return nil, err }
-		matchres := reGitDiffItemFile1Header.FindStringSubmatch(l)
+		if strings.HasPrefix(l, "diff --git ") || strings.HasPrefix(l, "Binary files ") {
+			if strings.HasPrefix(l, "diff --git ") { tlr.unreadLine(l) }
+			matchres = reGitDiffItemBinaryHeader.FindStringSubmatch(strings.TrimSpace(l))
+			if len(matchres) > 0 {
+				file1 = matchres[1]
+				file2 = matchres[2]
+			}
+			itemList = append(itemList, &DiffItem{
+				File1: file1,
+				File2: file2,
+				Header: headerItemList,
+				PatchList: make([]*DiffItemPatch, 0),
+			})
+			continue
+		}
+		matchres = reGitDiffItemFile1Header.FindStringSubmatch(l)
 		if len(matchres) <= 0 { return nil, ErrInvalidFormat }
-		file1 := matchres[1]
+		file1 = matchres[1]
 		l, err = tlr.readLine()
 		if errors.Is(err, io.EOF) { break }
 		if err != nil { return nil, err }
 		matchres = reGitDiffItemFile2Header.FindStringSubmatch(l)
 		if len(matchres) <= 0 { return nil, ErrInvalidFormat }
-		file2 := matchres[1]
+		file2 = matchres[1]
 
 		// parse patch
 		patchList := make([]*DiffItemPatch, 0)
@@ -166,9 +217,11 @@ func parseGitDiff(br *bytes.Buffer) (*Diff, error) {
 			rStartStr := matchres[3]
 			rLineCountStr := matchres[4]
 			lStart, _ := strconv.ParseInt(lStartStr, 10, 64)
-			lLineCount, _ := strconv.ParseInt(lLineCountStr, 10, 64)
+			var lLineCount int64 = 1
+			if len(lLineCountStr) > 0 { lLineCount, _ = strconv.ParseInt(lLineCountStr, 10, 64) }
 			rStart, _ := strconv.ParseInt(rStartStr, 10, 64)
-			rLineCount, _ := strconv.ParseInt(rLineCountStr, 10, 64)
+			var rLineCount int64 = 1
+			if len(rLineCountStr) > 0 { rLineCount, _ = strconv.ParseInt(rLineCountStr, 10, 64) }
 			context := matchres[5]
 			pLines := make([]AnnotatedLine, 0)
 			f1LineNumberCounter := lStart
@@ -177,6 +230,8 @@ func parseGitDiff(br *bytes.Buffer) (*Diff, error) {
 				l, err = tlr.readLine()
 				if errors.Is(err, io.EOF) { break }
 				if err != nil { return nil, err }
+				// "\ No newline at end of file"
+				if l[0] == '\\' { continue }
 				if l[0] != ' ' && l[0] != '-' && l[0] != '+' {
 					tlr.unreadLine(l)
 					break
@@ -184,23 +239,28 @@ func parseGitDiff(br *bytes.Buffer) (*Diff, error) {
 				lineContent := l[1:]
 				var lineType uint8
 				switch l[0] {
-				case ' ':
-					lineType = SAME
-					f1LineNumberCounter += 1
-					f2LineNumberCounter += 1
-				case '+':
-					lineType = APPEND
-					f2LineNumberCounter += 1
-				case '-':
-					lineType = DELETE
-					f1LineNumberCounter += 1
+				case ' ': lineType = SAME
+				case '+': lineType = APPEND
+				case '-': lineType = DELETE
 				}
+				// NOTE: the counters are the line numbers of the
+				// current line; they're advanced after the line is
+				// recorded.
 				pLines = append(pLines, AnnotatedLine{
 					Type: lineType,
 					F1LineNum: f1LineNumberCounter,
 					F2LineNum: f2LineNumberCounter,
 					Line: lineContent,
 				})
+				switch lineType {
+				case SAME:
+					f1LineNumberCounter += 1
+					f2LineNumberCounter += 1
+				case APPEND:
+					f2LineNumberCounter += 1
+				case DELETE:
+					f1LineNumberCounter += 1
+				}
 			}
 			patchList = append(patchList, &DiffItemPatch{
 				LStart: lStart,
@@ -247,3 +307,35 @@ func (gr LocalGitRepository) GetDiff(commitId string) (*Diff, error) {
 }
 
 
+
+// the id of the empty tree, which git always knows about even if it
+// isn't stored in the repository. diffing against this gives the
+// changes introduced by a root commit.
+func (gr LocalGitRepository) EmptyTreeId() string {
+	if gr.isSHA256 { return "6ef19b41225c5369f1c104d45d8d85efa9b057b53b14b4b9b939dd74decc5321" }
+	return "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+}
+
+// the diff between two commits, e.g. the merge base of a pull
+// request & the head of its provider branch. `.CommitHash` of the
+// result would be "{baseId}..{headId}".
+func (gr LocalGitRepository) GetRangeDiff(baseId string, headId string) (*Diff, error) {
+	cmd := exec.Command("git", "diff-tree", "-p", "-M", baseId, headId)
+	cmd.Dir = gr.GitDirectoryPath
+	stderrBuf := new(bytes.Buffer)
+	cmd.Stderr = stderrBuf
+	stdoutBuf := new(bytes.Buffer)
+	// NOTE: unlike diffing a single commit, diff-tree doesn't output
+	// the commit id when given two trees; `parseGitDiff` expects it,
+	// so we put one there.
+	stdoutBuf.WriteString(fmt.Sprintf("%s..%s\n", baseId, headId))
+	cmd.Stdout = stdoutBuf
+	err := cmd.Run()
+	if err != nil {
+		if strings.Contains(stderrBuf.String(), "dubious ownership") {
+			return nil, ErrDubiousOwnership
+		}
+		return nil, fmt.Errorf("Failed to git-diff-tree: %s; %s", err, stderrBuf.String())
+	}
+	return parseGitDiff(stdoutBuf)
+}
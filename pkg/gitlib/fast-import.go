@@ -210,3 +210,51 @@ data %d
 	return newestCommitId, nil
 }
 
+
+// removes `filePath` from the tip of `branchName` w/ a new commit.
+// the branch must already exist.
+func (gr *LocalGitRepository) RemoveFileFromRepo(
+	branchName string, filePath string,
+	authorName string, authorEmail string,
+	committerName string, committerEmail string,
+	commitMessage string,
+) (string, error) {
+	err := gr.SyncBranch(branchName)
+	if err != nil { return "", err }
+	k, ok := gr.BranchIndex[branchName]
+	if !ok { return "", fmt.Errorf("Branch %s does not exist", branchName) }
+	cmd := exec.Command("git", "fast-import", "--date-format=now", "--quiet")
+	cmd.Dir = gr.GitDirectoryPath
+	stdoutBuff := new(bytes.Buffer)
+	cmd.Stdout = stdoutBuff
+	stderrBuf := new(bytes.Buffer)
+	cmd.Stderr = stderrBuf
+	stdinPipe, err := cmd.StdinPipe()
+	if err != nil { return "", err }
+	payload := fmt.Sprintf(`commit refs/heads/%s
+mark :1
+author %s <%s> now
+committer %s <%s> now
+data %d
+%s
+from refs/heads/%s^0
+D %s
+get-mark :1`,
+		branchName, authorName, authorEmail,
+		committerName, committerEmail,
+		len(commitMessage), commitMessage,
+		branchName,
+		filePath,
+	)
+	err = cmd.Start()
+	if err != nil { return "", err }
+	_, err = stdinPipe.Write([]byte(payload))
+	if err != nil { return "", err }
+	err = stdinPipe.Close()
+	if err != nil { return "", err }
+	err = cmd.Wait()
+	if err != nil { return "", fmt.Errorf("%s; %s", err, stderrBuf.String()) }
+	newestCommitId := strings.TrimSpace(stdoutBuff.String())
+	k.HeadId = newestCommitId
+	return newestCommitId, nil
+}
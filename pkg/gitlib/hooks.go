@@ -1,8 +1,12 @@
 package gitlib
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path"
+	"slices"
+	"strings"
 )
 
 var HookList = []string{
@@ -37,10 +41,29 @@ var HookList = []string{
 	"post-index-change",
 }
 
+// hooks that gitus installs its own dispatcher for. the user-defined
+// versions of these hooks are stored under `USER_HOOK_DIRECTORY`
+// instead & are called by the dispatcher after gitus is done with its
+// own things. see docs/hooks.org.
+var GitusManagedHookList = []string{
+	"pre-receive",
+	"post-receive",
+}
+
+const USER_HOOK_DIRECTORY = "gitus-hooks"
+
+// returns the path where the (user-defined) hook `hookName` is stored.
+func (lgr LocalGitRepository) HookPath(hookName string) string {
+	if slices.Contains(GitusManagedHookList, hookName) {
+		return path.Join(lgr.GitDirectoryPath, USER_HOOK_DIRECTORY, hookName)
+	}
+	return path.Join(lgr.GitDirectoryPath, "hooks", hookName)
+}
+
 func (lgr LocalGitRepository) GetAllSetHooksName() ([]string, error) {
 	res := make([]string, 0)
 	for _, item := range HookList {
-		p := path.Join(lgr.GitDirectoryPath, "hooks", item)
+		p := lgr.HookPath(item)
 		f, err := os.Open(p)
 		if err != nil { continue }
 		res = append(res, item)
@@ -50,7 +73,7 @@ func (lgr LocalGitRepository) GetAllSetHooksName() ([]string, error) {
 }
 
 func (lgr LocalGitRepository) GetHook(hookName string) (string, error) {
-	p := path.Join(lgr.GitDirectoryPath, "hooks", hookName)
+	p := lgr.HookPath(hookName)
 	s, err := os.ReadFile(p)
 	if err != nil {
 		if os.IsNotExist(err) { return "", nil }
@@ -60,7 +83,9 @@ func (lgr LocalGitRepository) GetHook(hookName string) (string, error) {
 }
 
 func (lgr LocalGitRepository) SaveHook(hookName string, hookContent string) error {
-	p := path.Join(lgr.GitDirectoryPath, "hooks", hookName)
+	p := lgr.HookPath(hookName)
+	err := os.MkdirAll(path.Dir(p), 0755)
+	if err != nil { return err }
 	f, err := os.OpenFile(p, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0764)
 	if err != nil { return err }
 	defer f.Close()
@@ -72,7 +97,73 @@ func (lgr LocalGitRepository) SaveHook(hookName string, hookContent string) erro
 func (lgr LocalGitRepository) DeleteHook(hookName string) error {
 	if lgr.Hooks == nil { lgr.Hooks = make(map[string]string, 0) }
 	delete(lgr.Hooks, hookName)
-	p := path.Join(lgr.GitDirectoryPath, "hooks", hookName)
+	p := lgr.HookPath(hookName)
 	return os.Remove(p)
 }
 
+
+// the hooks installed by gitus itself contain this line; we never
+// overwrite hooks that don't.
+const GITUS_MANAGED_HOOK_MARKER = "# this hook is managed by gitus; do not edit."
+
+var ErrForeignHook = errors.New("Hook not managed by Gitus")
+
+// the dispatcher calls back into gitus w/ the environment variables
+// set by the ssh/http handler. pushes that don't come thru gitus
+// (e.g. pushes made by the server admin on the server) don't have
+// `GITUS_CONFIG` and go straight to the user-defined hook.
+func gitusHookScript(hookName string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s
+if [ -z "$GITUS_CONFIG" ]; then
+	h="${GIT_DIR:-.}/%s/%s"
+	[ -x "$h" ] || exit 0
+	exec "$h" "$@"
+fi
+exec "${GITUS_EXECUTABLE:-gitus}" -config "$GITUS_CONFIG" hook %s
+`, GITUS_MANAGED_HOOK_MARKER, USER_HOOK_DIRECTORY, hookName, hookName)
+}
+
+// the `update` hook written by older versions of gitus (which calls
+// `aegis web-hooks send` for every updated ref). webhooks are now
+// sent by the post-receive dispatcher so it has to go.
+func isLegacyWebHookUpdateHook(s string) bool {
+	return strings.Contains(s, "web-hooks send") && strings.Contains(s, "# --- Command line")
+}
+
+// installs the gitus pre-receive & post-receive dispatchers if they're
+// not already installed. hooks that are already there but not
+// installed by gitus are moved to `USER_HOOK_DIRECTORY` so that they
+// are still called by the dispatcher; returns `ErrForeignHook` if
+// that place is taken as well.
+func (lgr LocalGitRepository) InstallGitusHooks() error {
+	hookDir := path.Join(lgr.GitDirectoryPath, "hooks")
+	err := os.MkdirAll(hookDir, 0755)
+	if err != nil { return err }
+	for _, hookName := range GitusManagedHookList {
+		p := path.Join(hookDir, hookName)
+		script := gitusHookScript(hookName)
+		s, err := os.ReadFile(p)
+		if err != nil && !os.IsNotExist(err) { return err }
+		if string(s) == script { continue }
+		if len(s) > 0 && !strings.Contains(string(s), GITUS_MANAGED_HOOK_MARKER) {
+			userHookPath := lgr.HookPath(hookName)
+			_, err = os.Stat(userHookPath)
+			if err == nil { return ErrForeignHook }
+			if !os.IsNotExist(err) { return err }
+			err = os.MkdirAll(path.Dir(userHookPath), 0755)
+			if err != nil { return err }
+			err = os.Rename(p, userHookPath)
+			if err != nil { return err }
+		}
+		err = os.WriteFile(p, []byte(script), 0755)
+		if err != nil { return err }
+	}
+	updateHookPath := path.Join(hookDir, "update")
+	s, err := os.ReadFile(updateHookPath)
+	if err == nil && isLegacyWebHookUpdateHook(string(s)) {
+		err = os.Remove(updateHookPath)
+		if err != nil { return err }
+	}
+	return nil
+}
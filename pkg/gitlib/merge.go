@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -150,41 +152,265 @@ func (gr LocalGitRepository) CheckBranchMergeConflict(localBranch string, remote
 	return preres, nil
 }
 
-func (gr LocalGitRepository) Merge(remote string, remoteBranch string, localBranch string, author string, email string) error {
+// merge strategies. see docs/pull-request.org.
+const (
+	// a two-parent merge commit.
+	MERGE_STRATEGY_MERGE = 1
+	// a single commit w/ the combined changes on top of the local
+	// branch.
+	MERGE_STRATEGY_SQUASH = 2
+	// replay each commit on top of the local branch.
+	MERGE_STRATEGY_REBASE = 3
+	// only move the local branch forward; fails if the local branch
+	// is not an ancestor of the remote branch.
+	MERGE_STRATEGY_FAST_FORWARD = 4
+)
+
+var ErrCannotFastForward = errors.New("Cannot fast-forward: the target branch has commits that are not in the source branch")
+var ErrCannotRebaseMergeCommit = errors.New("Cannot rebase: the source branch contains merge commits")
+
+func ValidMergeStrategy(s int) bool {
+	return s >= MERGE_STRATEGY_MERGE && s <= MERGE_STRATEGY_FAST_FORWARD
+}
+
+func MergeStrategyName(s int) string {
+	switch s {
+	case MERGE_STRATEGY_MERGE: return "merge"
+	case MERGE_STRATEGY_SQUASH: return "squash"
+	case MERGE_STRATEGY_REBASE: return "rebase"
+	case MERGE_STRATEGY_FAST_FORWARD: return "fast-forward"
+	}
+	return ""
+}
+
+type MergeOption struct {
+	Strategy int
+	// the message of the resulting commit when merging or squashing;
+	// a default one is generated when empty. not used when rebasing
+	// or fast-forwarding.
+	Message string
+	// the user who performs the merge. used as the committer of all
+	// new commits & the author of merge/squash commits.
+	UserName string
+	UserEmail string
+}
+
+type mergeCommitInfo struct {
+	Id string
+	ParentList []string
+	AuthorName string
+	AuthorEmail string
+	AuthorTime string
+	Message string
+}
+
+// commits that are in `to` but not in `from`, oldest first.
+func (gr LocalGitRepository) mergeCommitList(from string, to string) ([]*mergeCommitInfo, error) {
+	cmd := exec.Command("git", "log", "--reverse", "-z", "--format=%H%x1f%P%x1f%an%x1f%ae%x1f%aI%x1f%B", fmt.Sprintf("%s..%s", from, to))
+	cmd.Dir = gr.GitDirectoryPath
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	cmd.Stdout = buf
+	cmd.Stderr = errBuf
+	err := cmd.Run()
+	if err != nil { return nil, fmt.Errorf("Failed while log: %s; %s", err.Error(), errBuf.String()) }
+	res := make([]*mergeCommitInfo, 0)
+	for _, k := range strings.Split(buf.String(), "\x00") {
+		if len(strings.TrimSpace(k)) <= 0 { continue }
+		f := strings.SplitN(strings.TrimPrefix(k, "\n"), "\x1f", 6)
+		if len(f) < 6 { return nil, fmt.Errorf("Invalid log output: %s", k) }
+		res = append(res, &mergeCommitInfo{
+			Id: f[0],
+			ParentList: strings.Fields(f[1]),
+			AuthorName: f[2],
+			AuthorEmail: f[3],
+			AuthorTime: f[4],
+			Message: strings.TrimSpace(f[5]),
+		})
+	}
+	return res, nil
+}
+
+func (gr LocalGitRepository) resolveCommitId(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", rev + "^{commit}")
+	cmd.Dir = gr.GitDirectoryPath
+	buf := new(bytes.Buffer)
+	cmd.Stdout = buf
+	err := cmd.Run()
+	if err != nil { return "", fmt.Errorf("Failed to resolve %s: %s", rev, err.Error()) }
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// `env` is appended to the environment of the command; it's used to
+// set the author & committer.
+func (gr LocalGitRepository) commitTree(treeId string, message string, parentList []string, env []string) (string, error) {
+	args := []string{"commit-tree", treeId, "-m", message}
+	for _, k := range parentList {
+		args = append(args, "-p", k)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = gr.GitDirectoryPath
+	cmd.Env = append(os.Environ(), env...)
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	cmd.Stdout = buf
+	cmd.Stderr = errBuf
+	err := cmd.Run()
+	if err != nil { return "", fmt.Errorf("Failed while commit-tree: %s; %s", err.Error(), errBuf.String()) }
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// adds a `Co-Authored-By` trailer for each author of `commitList`
+// except the user who performs the merge. trailers that are already
+// in the message are not added again.
+func squashMessageWithTrailer(message string, commitList []*mergeCommitInfo, opt *MergeOption) string {
+	trailerList := make([]string, 0)
+	for _, k := range commitList {
+		if k.AuthorName == opt.UserName && k.AuthorEmail == opt.UserEmail { continue }
+		t := fmt.Sprintf("Co-Authored-By: %s <%s>", k.AuthorName, k.AuthorEmail)
+		if slices.Contains(trailerList, t) { continue }
+		if strings.Contains(message, t) { continue }
+		trailerList = append(trailerList, t)
+	}
+	if len(trailerList) <= 0 { return message }
+	return strings.TrimSpace(message) + "\n\n" + strings.Join(trailerList, "\n")
+}
+
+// merges `remote/remoteBranch` into `localBranch` w/ the strategy
+// specified in `opt`. returns the new head of `localBranch`. the
+// caller should've checked for conflicts beforehand, but conflicts
+// are still reported as errors.
+func (gr LocalGitRepository) Merge(remote string, remoteBranch string, localBranch string, opt *MergeOption) (string, error) {
 	buf := new(bytes.Buffer)
 	cmd1 := exec.Command("git", "fetch", remote, remoteBranch)
 	cmd1.Dir = gr.GitDirectoryPath
 	cmd1.Stderr = buf
 	err := cmd1.Run()
-	if err != nil { return fmt.Errorf("%s: %s", err.Error(), buf.String()) }
-	buf.Reset()
+	if err != nil { return "", fmt.Errorf("%s: %s", err.Error(), buf.String()) }
 	providerFullName := fmt.Sprintf("%s/%s", remote, remoteBranch)
-	cmd2 := exec.Command("git", "merge-tree", "--write-tree", localBranch, providerFullName)
-	cmd2.Dir = gr.GitDirectoryPath
-	cmd2.Stdout = buf
-	err = cmd2.Run()
-	if err != nil { return fmt.Errorf("Failed while merge-tree: %s", err.Error()) }
-	treeId := strings.TrimSpace(buf.String())
-	mergeMessage := fmt.Sprintf("merge: from %s/%s to %s", remote, remoteBranch, localBranch)
-	buf.Reset()
-	cmd3 := exec.Command("git", "commit-tree", treeId, "-m", mergeMessage, "-p", localBranch, "-p", providerFullName)
-	cmd3.Dir = gr.GitDirectoryPath
-	cmd3.Env = os.Environ()
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_AUTHOR_NAME=%s", author))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", email))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_COMMITTER_NAME=%s", author))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", email))
-	err = cmd3.Run()
-	if err != nil { return fmt.Errorf("Failed while commit-tree: %s", err.Error()) }
-	commitId := strings.TrimSpace(buf.String())
+	localHead, err := gr.resolveCommitId(localBranch)
+	if err != nil { return "", err }
+	providerHead, err := gr.resolveCommitId(providerFullName)
+	if err != nil { return "", err }
+	userEnv := []string{
+		fmt.Sprintf("GIT_COMMITTER_NAME=%s", opt.UserName),
+		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", opt.UserEmail),
+	}
+	var newHead string
+	switch opt.Strategy {
+	case MERGE_STRATEGY_FAST_FORWARD:
+		cmd := exec.Command("git", "merge-base", "--is-ancestor", localHead, providerHead)
+		cmd.Dir = gr.GitDirectoryPath
+		err = cmd.Run()
+		if err != nil { return "", ErrCannotFastForward }
+		newHead = providerHead
+	case MERGE_STRATEGY_REBASE:
+		newHead, err = gr.rebase(localHead, providerHead, userEnv)
+		if err != nil { return "", err }
+	case MERGE_STRATEGY_MERGE: fallthrough
+	case MERGE_STRATEGY_SQUASH:
+		buf.Reset()
+		cmd2 := exec.Command("git", "merge-tree", "--write-tree", localHead, providerHead)
+		cmd2.Dir = gr.GitDirectoryPath
+		cmd2.Stdout = buf
+		err = cmd2.Run()
+		if err != nil { return "", fmt.Errorf("Failed while merge-tree: %s", err.Error()) }
+		treeId := strings.TrimSpace(buf.String())
+		authorEnv := append(userEnv,
+			fmt.Sprintf("GIT_AUTHOR_NAME=%s", opt.UserName),
+			fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", opt.UserEmail),
+		)
+		message := strings.TrimSpace(opt.Message)
+		if opt.Strategy == MERGE_STRATEGY_MERGE {
+			if len(message) <= 0 {
+				message = fmt.Sprintf("merge: from %s/%s to %s", remote, remoteBranch, localBranch)
+			}
+			newHead, err = gr.commitTree(treeId, message, []string{localHead, providerHead}, authorEnv)
+			if err != nil { return "", err }
+		} else {
+			commitList, err := gr.mergeCommitList(localHead, providerHead)
+			if err != nil { return "", err }
+			if len(message) <= 0 {
+				l := make([]string, 0)
+				l = append(l, fmt.Sprintf("squash: from %s/%s to %s", remote, remoteBranch, localBranch))
+				for _, k := range commitList {
+					l = append(l, "* " + k.Message)
+				}
+				message = strings.Join(l, "\n\n")
+			}
+			message = squashMessageWithTrailer(message, commitList, opt)
+			newHead, err = gr.commitTree(treeId, message, []string{localHead}, authorEnv)
+			if err != nil { return "", err }
+		}
+	default:
+		return "", fmt.Errorf("Unknown merge strategy: %d", opt.Strategy)
+	}
 	buf.Reset()
 	localBranchFullName := fmt.Sprintf("refs/heads/%s", localBranch)
-	cmd4 := exec.Command("git", "update-ref", localBranchFullName, commitId)
+	cmd4 := exec.Command("git", "update-ref", localBranchFullName, newHead, localHead)
 	cmd4.Dir = gr.GitDirectoryPath
 	cmd4.Stderr = buf
-	err = cmd3.Run()
-	if err != nil { return fmt.Errorf("Failed while update-ref: %s; %s", err.Error(), buf.String()) }
-	return nil
+	err = cmd4.Run()
+	if err != nil { return "", fmt.Errorf("Failed while update-ref: %s; %s", err.Error(), buf.String()) }
+	return newHead, nil
 }
 
-
+// replays the commits in `providerHead` but not in `localHead` on
+// top of `localHead`. this is done w/ a temporary index file & `git
+// apply --cached --3way` so that it works in bare repositories.
+func (gr LocalGitRepository) rebase(localHead string, providerHead string, userEnv []string) (string, error) {
+	commitList, err := gr.mergeCommitList(localHead, providerHead)
+	if err != nil { return "", err }
+	for _, k := range commitList {
+		if len(k.ParentList) > 1 { return "", ErrCannotRebaseMergeCommit }
+	}
+	tmpDir, err := os.MkdirTemp("", "gitus-rebase-")
+	if err != nil { return "", err }
+	defer os.RemoveAll(tmpDir)
+	indexEnv := append(os.Environ(), fmt.Sprintf("GIT_INDEX_FILE=%s", path.Join(tmpDir, "index")))
+	head := localHead
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	for _, k := range commitList {
+		cmd1 := exec.Command("git", "read-tree", head)
+		cmd1.Dir = gr.GitDirectoryPath
+		cmd1.Env = indexEnv
+		err = cmd1.Run()
+		if err != nil { return "", fmt.Errorf("Failed while read-tree: %s", err.Error()) }
+		// root commits can't be rebased.
+		if len(k.ParentList) <= 0 { return "", fmt.Errorf("Cannot rebase root commit %s", k.Id) }
+		buf.Reset()
+		cmd2 := exec.Command("git", "diff-tree", "-p", "--binary", "--full-index", k.ParentList[0], k.Id)
+		cmd2.Dir = gr.GitDirectoryPath
+		cmd2.Stdout = buf
+		err = cmd2.Run()
+		if err != nil { return "", fmt.Errorf("Failed while diff-tree: %s", err.Error()) }
+		// empty commits are kept as-is.
+		if buf.Len() > 0 {
+			errBuf.Reset()
+			cmd3 := exec.Command("git", "apply", "--cached", "--3way")
+			cmd3.Dir = gr.GitDirectoryPath
+			cmd3.Env = indexEnv
+			cmd3.Stdin = buf
+			cmd3.Stderr = errBuf
+			err = cmd3.Run()
+			if err != nil { return "", fmt.Errorf("Failed to rebase commit %s: %s", k.Id, errBuf.String()) }
+		}
+		buf.Reset()
+		cmd4 := exec.Command("git", "write-tree")
+		cmd4.Dir = gr.GitDirectoryPath
+		cmd4.Env = indexEnv
+		cmd4.Stdout = buf
+		err = cmd4.Run()
+		if err != nil { return "", fmt.Errorf("Failed while write-tree: %s", err.Error()) }
+		treeId := strings.TrimSpace(buf.String())
+		env := append(userEnv,
+			fmt.Sprintf("GIT_AUTHOR_NAME=%s", k.AuthorName),
+			fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", k.AuthorEmail),
+			fmt.Sprintf("GIT_AUTHOR_DATE=%s", k.AuthorTime),
+		)
+		head, err = gr.commitTree(treeId, k.Message, []string{head}, env)
+		if err != nil { return "", err }
+	}
+	return head, nil
+}
@@ -497,3 +497,136 @@ func (gr LocalGitRepository) GetBranchCommitHistory(b Branch) ([]CommitObject, e
 func (gr LocalGitRepository) GetBranchCommitHistoryN(b Branch, n int) ([]CommitObject, error) {
 	return gr.GetCommitHistoryN(b.HeadId, n)
 }
+
+// checks whether `ancestor` is an ancestor of `descendant` (both are
+// commit ids). used to tell fast-forwards from force pushes.
+func (gr LocalGitRepository) IsAncestor(ancestor string, descendant string) (bool, error) {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", ancestor, descendant)
+	cmd.Dir = gr.GitDirectoryPath
+	stderrBuf := new(bytes.Buffer)
+	cmd.Stderr = stderrBuf
+	err := cmd.Run()
+	if err == nil { return true, nil }
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("Failed to git-merge-base: %s; %s", err, stderrBuf.String())
+}
+
+// fetches `remoteBranch` from `remote` & returns the merge base of
+// `localBranch` & the fetched branch along w/ the head of the
+// latter, i.e. the range of commits that merging the remote branch
+// would bring in.
+func (gr LocalGitRepository) GetBranchRange(localBranch string, remote string, remoteBranch string) (string, string, error) {
+	cmd1 := exec.Command("git", "fetch", remote, remoteBranch)
+	cmd1.Dir = gr.GitDirectoryPath
+	stderrBuf := new(bytes.Buffer)
+	cmd1.Stderr = stderrBuf
+	err := cmd1.Run()
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to git-fetch: %s; %s", err, stderrBuf.String())
+	}
+	cmd2 := exec.Command("git", "rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s^{commit}", remote, remoteBranch))
+	cmd2.Dir = gr.GitDirectoryPath
+	stdoutBuf := new(bytes.Buffer)
+	cmd2.Stdout = stdoutBuf
+	stderrBuf.Reset()
+	cmd2.Stderr = stderrBuf
+	err = cmd2.Run()
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to git-rev-parse: %s; %s", err, stderrBuf.String())
+	}
+	headId := strings.TrimSpace(stdoutBuf.String())
+	cmd3 := exec.Command("git", "merge-base", fmt.Sprintf("refs/heads/%s", localBranch), headId)
+	cmd3.Dir = gr.GitDirectoryPath
+	stdoutBuf.Reset()
+	cmd3.Stdout = stdoutBuf
+	stderrBuf.Reset()
+	cmd3.Stderr = stderrBuf
+	err = cmd3.Run()
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to git-merge-base: %s; %s", err, stderrBuf.String())
+	}
+	return strings.TrimSpace(stdoutBuf.String()), headId, nil
+}
+
+// the commits reachable from `headId` but not from `baseId`, newest
+// first (same as the commit history).
+func (gr LocalGitRepository) GetCommitRange(baseId string, headId string) ([]CommitObject, error) {
+	cmd := exec.Command("git", "rev-list", fmt.Sprintf("%s..%s", baseId, headId))
+	cmd.Dir = gr.GitDirectoryPath
+	stdoutBuf := new(bytes.Buffer)
+	cmd.Stdout = stdoutBuf
+	stderrBuf := new(bytes.Buffer)
+	cmd.Stderr = stderrBuf
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to git-rev-list: %s; %s", err, stderrBuf.String())
+	}
+	res := make([]CommitObject, 0)
+	for _, k := range strings.Fields(stdoutBuf.String()) {
+		obj, err := gr.ReadObject(k)
+		if err != nil { return nil, err }
+		c, ok := obj.(*CommitObject)
+		if !ok { return nil, fmt.Errorf("%s is not a commit", k) }
+		res = append(res, *c)
+	}
+	return res, nil
+}
+
+// the lines of the file at `p` in commit `commitId`.
+func (gr LocalGitRepository) ReadFileLineAtCommit(commitId string, p string) ([]string, error) {
+	gobj, err := gr.ReadObject(commitId)
+	if err != nil { return nil, err }
+	cobj, ok := gobj.(*CommitObject)
+	if !ok { return nil, fmt.Errorf("%s is not a commit", commitId) }
+	gobj, err = gr.ReadObject(cobj.TreeObjId)
+	if err != nil { return nil, err }
+	tobj, ok := gobj.(*TreeObject)
+	if !ok { return nil, fmt.Errorf("%s is not a tree", cobj.TreeObjId) }
+	gobj, err = gr.ResolveTreePath(tobj, p)
+	if err != nil { return nil, err }
+	bobj, ok := gobj.(*BlobObject)
+	if !ok { return nil, fmt.Errorf("%s is not a file", p) }
+	return strings.Split(strings.TrimSuffix(string(bobj.Data), "\n"), "\n"), nil
+}
+
+// points the remotes of `gr` that refer to a repository that has
+// been moved from `oldPath` to `newPath` to the new location. the
+// remote named `oldName` (gitus names the remotes of forks & pull
+// requests after the repositories they refer to) is renamed to
+// `newName` as well.
+func (gr LocalGitRepository) RelocateRemote(oldName string, oldPath string, newName string, newPath string) error {
+	cfg, err := gr.readConfig()
+	if err != nil { return err }
+	l, ok := cfg.GetSectionList("remote")
+	if !ok { return nil }
+	for k, v := range l {
+		if k != oldName && path.Clean(v.Value["url"]) != path.Clean(oldPath) { continue }
+		var cmd *exec.Cmd
+		if k == oldName && oldName != newName {
+			// the remote-tracking refs are fetched again when
+			// they're needed, so they're not worth keeping.
+			cmd = exec.Command("git", "remote", "remove", k)
+			cmd.Dir = gr.GitDirectoryPath
+			stderrBuf := new(bytes.Buffer)
+			cmd.Stderr = stderrBuf
+			err = cmd.Run()
+			if err != nil { return errors.New(err.Error() + ": " + stderrBuf.String()) }
+			_, exists := l[newName]
+			if exists {
+				cmd = exec.Command("git", "remote", "set-url", newName, newPath)
+			} else {
+				cmd = exec.Command("git", "remote", "add", newName, newPath)
+			}
+		} else {
+			cmd = exec.Command("git", "remote", "set-url", k, newPath)
+		}
+		cmd.Dir = gr.GitDirectoryPath
+		stderrBuf := new(bytes.Buffer)
+		cmd.Stderr = stderrBuf
+		err = cmd.Run()
+		if err != nil { return errors.New(err.Error() + ": " + stderrBuf.String()) }
+	}
+	return nil
+}
@@ -208,8 +208,11 @@ func (gr LocalGitRepository) ResolveTreePath(t *TreeObject, p string) (GitObject
 	var err error = nil
 	var tobj *TreeObject = t
 	for item := range strings.SplitSeq(p, "/") {
-		tobj = gobj.(*TreeObject)
 		if len(item) <= 0 { continue }
+		// e.g. "some-file/something".
+		t, ok := gobj.(*TreeObject)
+		if !ok { return nil, ErrObjectNotFound }
+		tobj = t
 		found := false
 		if item == "." { continue }
 		for _, sub := range tobj.ObjectList {
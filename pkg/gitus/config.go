@@ -149,6 +149,11 @@ type GitusConfig struct {
 	// 0 turns off the rate limiter.
 	MaxRequestInSecond float64 `json:"maxRequestInSecond"`
 
+	// when set to true, admins (& super admins) that haven't enrolled
+	// a second factor don't have admin privileges until they do.
+	// see docs/two-factor-auth.org.
+	RequireAdminSecondFactor bool `json:"requireAdminSecondFactor"`
+
 	// confirm code manager.
 	// used in email 2fa.
 	ConfirmCodeManager GitusConfirmCodeManagerConfig `json:"confirmCode"`
@@ -156,6 +161,10 @@ type GitusConfig struct {
 	// root directory for storing snippets.
 	SnippetRoot string `json:"snippetRoot"`
 
+	// the built-in ci runner (`gitus runner`). see
+	// docs/ci-runner.org.
+	Runner GitusRunnerConfig `json:"runner"`
+
 	DefaultNewUserStatus model.GitusUserStatus `json:"defaultNewUserStatus"`
 	DefaultNewUserNamespace string `json:"defaultNewUserNamespace"`
 
@@ -207,6 +216,14 @@ type GitusGitHTTPTransferProtocolDescriptor struct {
 }
 type GitusGitConfig struct {
 	HTTPCloneProtocol GitusGitHTTPTransferProtocolDescriptor `json:"httpCloneProtocol"`
+	// when set to true, pushing thru http (git-receive-pack) is
+	// allowed for authenticated users. requires the v2 protocol to
+	// be enabled as well, since v1-dumb is read-only.
+	HTTPPush bool `json:"httpPush"`
+	// max total size (in bytes) of the objects a single push can
+	// bring into a repository. 0 means no limit. enforced by the
+	// pre-receive hook dispatcher; see docs/hooks.org.
+	MaxPushSize int64 `json:"maxPushSize"`
 }
 
 type GitusSessionConfig struct {
@@ -294,6 +311,8 @@ type GitusConfirmCodeManagerConfig struct {
 type GitusFrontPageConfig struct {
 	// + "all/namespace"
 	// + "all/repository"
+	// + "explore": see docs/explore.org. falls back to
+	//   "all/repository" when not in normal mode.
 	// + "repository"
 	// + "namespace"
 	// + "static/markdown", "static/org", "static/text", "static/html"
@@ -311,6 +330,29 @@ type GitusFrontPageConfig struct {
 	FileContent string `json:"fileContent"`
 }
 
+type GitusRunnerConfig struct {
+	// when set to false, pushes don't queue any ci run.
+	Enable bool `json:"enable"`
+	// where the commits are checked out for the runs. each run
+	// gets its own directory inside, which is removed when the run
+	// is done. empty means `gitus-runner` in the system temporary
+	// directory.
+	WorkDirectory string `json:"workDirectory"`
+	// the user the steps are run as. must be an unprivileged user
+	// that can't read the repositories or the config file, & must
+	// not be the user that runs `gitus runner`; the runner refuses
+	// to start otherwise.
+	User string `json:"user"`
+	// the timeout (in seconds) of a job that doesn't specify one.
+	// 0 means 3600.
+	DefaultTimeout int `json:"defaultTimeout"`
+	// the longest timeout (in seconds) a job can specify. 0 means
+	// no limit.
+	MaxTimeout int `json:"maxTimeout"`
+	// the number of jobs run at the same time. 0 means 1.
+	Concurrency int `json:"concurrency"`
+}
+
 type GitusThemeConfig struct {
 	ForegroundColor string `json:"foregroundColor"`
 	BackgroundColor string `json:"backgroundColor"`
@@ -397,6 +439,8 @@ func CreateConfigFile(p string) error {
 				V1Dumb: true,
 				V2: true,
 			},
+			HTTPPush: false,
+			MaxPushSize: 0,
 		},
 		Database: GitusDatabaseConfig{
 			Type: "sqlite",
@@ -438,6 +482,14 @@ func CreateConfigFile(p string) error {
 			DefaultTimeoutMinute: 5,
 		},
 		SnippetRoot: "",
+		Runner: GitusRunnerConfig{
+			Enable: false,
+			WorkDirectory: "",
+			User: "",
+			DefaultTimeout: 3600,
+			MaxTimeout: 0,
+			Concurrency: 1,
+		},
 		DefaultNewUserStatus: model.GitusUserStatus(model.NORMAL_USER),
 		DefaultNewUserNamespace: "",
 		FrontPage: GitusFrontPageConfig{
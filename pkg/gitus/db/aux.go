@@ -1,8 +1,17 @@
 package db
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 )
 
 func ToSqlSearchPattern(s string) string {
@@ -20,3 +29,123 @@ func IsSubDir(r string, d string) bool {
 	return true
 }
 
+
+// the statuses of the head of the provider branch of `pr`: the ones
+// reported to the receiver repository & the ones reported to the
+// provider repository (nil if it's the receiver itself). only the
+// former counts for required status checks, since the author of a
+// pull request from a fork can report anything to their fork. see
+// docs/commit-status.org.
+func GetPullRequestCommitStatus(dbif GitusDatabaseInterface, pr *model.PullRequest) ([]*model.CommitStatus, []*model.CommitStatus, error) {
+	provider, err := dbif.GetRepositoryByName(pr.ProviderNamespace, pr.ProviderName)
+	if err != nil { return nil, nil, err }
+	lgr, ok := provider.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return nil, nil, errors.New("Pull requests are only supported for Git repositories.") }
+	err = lgr.SyncBranch(pr.ProviderBranch)
+	if err != nil { return nil, nil, err }
+	br, ok := lgr.BranchIndex[pr.ProviderBranch]
+	if !ok { return nil, nil, fmt.Errorf("Branch %s not found in %s.", pr.ProviderBranch, provider.FullName()) }
+	res, err := dbif.GetAllCommitStatus(pr.ReceiverNamespace, pr.ReceiverName, br.HeadId)
+	if err != nil { return nil, nil, err }
+	if pr.ProviderNamespace == pr.ReceiverNamespace && pr.ProviderName == pr.ReceiverName { return res, nil, nil }
+	providerList, err := dbif.GetAllCommitStatus(pr.ProviderNamespace, pr.ProviderName, br.HeadId)
+	if err != nil { return nil, nil, err }
+	return res, providerList, nil
+}
+
+// checks if a repository can be moved from `oldNs:oldName` to
+// `newNs:newName` & moves its directory under `gitRoot`. the
+// directory is moved before the database is updated; the caller
+// should move it back w/ `UndoMoveRepositoryDirectory` if updating
+// the database fails.
+func MoveRepositoryDirectory(gitRoot string, oldNs string, oldName string, newNs string, newName string) error {
+	if len(newName) <= 0 || !model.ValidNamespaceName(newNs) || !model.ValidStrictRepositoryName(newName) {
+		return ErrInvalidLocation
+	}
+	oldP := path.Join(gitRoot, oldNs, oldName)
+	newP := path.Join(gitRoot, newNs, newName)
+	if !IsSubDir(gitRoot, newP) { return ErrInvalidLocation }
+	_, err := os.Stat(newP)
+	if err == nil { return ErrEntityAlreadyExists }
+	if !errors.Is(err, os.ErrNotExist) { return err }
+	err = os.MkdirAll(path.Dir(newP), os.ModeDir|0755)
+	if err != nil { return err }
+	return os.Rename(oldP, newP)
+}
+
+func UndoMoveRepositoryDirectory(gitRoot string, oldNs string, oldName string, newNs string, newName string) {
+	err := os.Rename(path.Join(gitRoot, newNs, newName), path.Join(gitRoot, oldNs, oldName))
+	if err != nil { log.Printf("Failed to move repository %s:%s back: %s\n", newNs, newName, err) }
+}
+
+// like `MoveRepositoryDirectory` but for renaming a namespace. a
+// namespace w/o a directory (i.e. one w/o any repository that's never
+// had one created) is fine.
+func MoveNamespaceDirectory(gitRoot string, oldNs string, newNs string) error {
+	if len(newNs) <= 0 || !model.ValidNamespaceName(newNs) { return ErrInvalidLocation }
+	oldP := path.Join(gitRoot, oldNs)
+	newP := path.Join(gitRoot, newNs)
+	if !IsSubDir(gitRoot, newP) { return ErrInvalidLocation }
+	_, err := os.Stat(newP)
+	if err == nil { return ErrEntityAlreadyExists }
+	if !errors.Is(err, os.ErrNotExist) { return err }
+	_, err = os.Stat(oldP)
+	if errors.Is(err, os.ErrNotExist) { return nil }
+	if err != nil { return err }
+	return os.Rename(oldP, newP)
+}
+
+func UndoMoveNamespaceDirectory(gitRoot string, oldNs string, newNs string) {
+	newP := path.Join(gitRoot, newNs)
+	_, err := os.Stat(newP)
+	if errors.Is(err, os.ErrNotExist) { return }
+	err = os.Rename(newP, path.Join(gitRoot, oldNs))
+	if err != nil { log.Printf("Failed to move namespace %s back: %s\n", newNs, err) }
+}
+
+// the payload of a webhook job queued for a repository that's been
+// moved; returns false if the job isn't for it.
+func MoveWebhookJobPayload(payload string, oldNs string, oldName string, newNs string, newName string) (string, bool) {
+	var p model.WebhookJobPayload
+	err := json.Unmarshal([]byte(payload), &p)
+	if err != nil { return "", false }
+	if p.RepoNamespace != oldNs || p.RepoName != oldName { return "", false }
+	p.RepoNamespace = newNs
+	p.RepoName = newName
+	b, err := json.Marshal(&p)
+	if err != nil { return "", false }
+	return string(b), true
+}
+
+// like `MoveWebhookJobPayload` but for all the repositories in a
+// namespace that's been renamed.
+func RenameNamespaceWebhookJobPayload(payload string, oldNs string, newNs string) (string, bool) {
+	var p model.WebhookJobPayload
+	err := json.Unmarshal([]byte(payload), &p)
+	if err != nil { return "", false }
+	if p.RepoNamespace != oldNs { return "", false }
+	return MoveWebhookJobPayload(payload, oldNs, p.RepoName, newNs, p.RepoName)
+}
+
+// fixes the remotes that refer to a repository that's been moved.
+// `repoList` is the repositories that could have such remotes (in
+// `{namespace, name}`, w/ the moved repository under its new name):
+// the fork origin of the moved repository (which has a remote for
+// each fork), its forks (whose `origin` is the moved repository) &
+// the receivers of the pull requests from it. failing to fix them
+// only breaks the branch comparison & pull requests until they're
+// set up again, so errors are logged instead of returned.
+func RelocateMovedRepositoryRemote(gitRoot string, repoList [][2]string, oldNs string, oldName string, newNs string, newName string) {
+	oldP := path.Join(gitRoot, oldNs, oldName)
+	newP := path.Join(gitRoot, newNs, newName)
+	oldRemote := fmt.Sprintf("%s/%s", oldNs, oldName)
+	newRemote := fmt.Sprintf("%s/%s", newNs, newName)
+	done := make(map[[2]string]bool, 0)
+	for _, k := range repoList {
+		if len(k[1]) <= 0 || done[k] { continue }
+		done[k] = true
+		lgr := gitlib.NewLocalGitRepository(path.Join(gitRoot, k[0], k[1]))
+		err := lgr.RelocateRemote(oldRemote, oldP, newRemote, newP)
+		if err != nil { log.Printf("Failed to relocate remote %s in %s:%s: %s\n", oldRemote, k[0], k[1], err) }
+	}
+}
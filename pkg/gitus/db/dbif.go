@@ -22,6 +22,15 @@ type GitusDatabaseInterface interface {
 	UpdateSignKey(username string, keyname string, keytext string) error
 	RegisterSignKey(username string, keyname string, keytext string) error
 	RemoveSignKey(username string, keyname string) error
+	// personal access tokens. `tokenHash` is the result of
+	// `model.HashAccessToken`; the token text itself is never
+	// stored. `expireTime` being 0 means the token never expires.
+	// implementers should return `ErrEntityAlreadyExists` when a
+	// token of the same name already exists for the user.
+	RegisterAccessToken(username string, name string, tokenHash string, scope []string, expireTime int64) error
+	GetAllAccessTokenByUsername(username string) ([]*model.GitusAccessToken, error)
+	GetAccessTokenByHash(tokenHash string) (*model.GitusAccessToken, error)
+	RevokeAccessToken(username string, name string) error
 	GetNamespaceByName(name string) (*model.Namespace, error)
 	GetRepositoryByName(nsName string, repoName string) (*model.Repository, error)
 	GetAllNamespace() (map[string]*model.Namespace, error)
@@ -57,9 +66,21 @@ type GitusDatabaseInterface interface {
 	UpdateRepositoryInfo(ns string, name string, robj *model.Repository) error
 	UpdateRepositoryStatus(ns string, name string, status model.GitusRepositoryStatus) error
 	HardDeleteRepository(ns string, name string) error
-	// TODO: gitus currently doesn't support moving.
-	// we'll reconsider this when the appropriate time comes.
-	// MoveRepository(oldNs string, oldName string, newNs string, newName string) error
+	// renames a repository and/or moves it to another namespace:
+	// its directory under the git root & everything keyed by its
+	// name are moved, and a redirect from the old name is recorded.
+	// returns ErrEntityAlreadyExists if the new name is taken. see
+	// docs/repo-move.org.
+	MoveRepository(oldNs string, oldName string, newNs string, newName string) error
+	// the current name of the repository that used to be
+	// `ns:name`; returns ErrEntityNotFound if there's no redirect.
+	GetRepositoryRedirect(ns string, name string) (string, string, error)
+	// renames a namespace: its directory under the git root &
+	// everything keyed by its name are moved, and a redirect is
+	// recorded for every repository in it. returns
+	// ErrEntityAlreadyExists if the new name is taken. see
+	// docs/repo-move.org.
+	RenameNamespace(oldName string, newName string) error
 
 	GetAllUsers(pageNum int64, pageSize int64) ([]*model.GitusUser, error)
 	GetAllNamespaces(pageNum int64, pageSize int64) (map[string]*model.Namespace, error)
@@ -89,6 +110,14 @@ type GitusDatabaseInterface interface {
 	SetNamespaceACL(nsName string, targetUserName string, acl *model.ACLTuple) error
 	SetRepositoryACL(nsName string, repoName string, targetUserName string, acl *model.ACLTuple) error
 
+	// branch protection rules of a repository. see
+	// docs/protected-branch.org. a repository has at most one rule
+	// per pattern; `SetBranchProtectionRule` replaces the existing
+	// rule of the same pattern.
+	GetAllBranchProtectionRule(ns string, name string) ([]*model.BranchProtectionRule, error)
+	SetBranchProtectionRule(ns string, name string, rule *model.BranchProtectionRule) error
+	DeleteBranchProtectionRule(ns string, name string, pattern string) error
+
 	GetAllComprisingNamespace(username string) (map[string]*model.Namespace, error)
 	
 	CountAllVisibleNamespaceSearchResult(username string, pattern string) (int64, error)
@@ -98,9 +127,9 @@ type GitusDatabaseInterface interface {
 	GetRepositoryIssue(ns string, name string, iid int) (*model.Issue, error)
 	CountAllRepositoryIssue(ns string, name string) (int, error)
 	// filterType: 0 - all, 1 - open, 2 - closed, 3 - solved, 4 - discarded
-	// when query = "" it looks for all issue.
-	CountIssue(query string, namespace string, name string, filterType int) (int64, error)
-	SearchIssuePaginated(query string, namespace string, name string, filterType int, pageNum int64, pageSize int64) ([]*model.Issue, error)
+	// when query = "" it looks for all issue. `filter` can be nil.
+	CountIssue(query string, namespace string, name string, filterType int, filter *model.IssueFilter) (int64, error)
+	SearchIssuePaginated(query string, namespace string, name string, filterType int, filter *model.IssueFilter, pageNum int64, pageSize int64) ([]*model.Issue, error)
 	// returns the issue_id of the new issue.
 	NewRepositoryIssue(ns string, name string, author string, title string, content string) (int64, error)
 	HardDeleteRepositoryIssue(ns string, name string, issueId int) error
@@ -109,6 +138,30 @@ type GitusDatabaseInterface interface {
 	NewRepositoryIssueEvent(ns string, name string, issueId int64, eType int, author string, content string) error
 	HardDeleteRepositoryIssueEvent(eventAbsId int64) error
 
+	// issue labels & milestones of a repository. see docs/issue.org.
+	// `Set*` replaces the existing one w/ the same name/title.
+	GetAllRepositoryIssueLabel(ns string, name string) ([]*model.IssueLabel, error)
+	SetRepositoryIssueLabel(ns string, name string, label *model.IssueLabel) error
+	// also removes the label from all issues.
+	DeleteRepositoryIssueLabel(ns string, name string, labelName string) error
+	GetAllRepositoryIssueMilestone(ns string, name string) ([]*model.IssueMilestone, error)
+	SetRepositoryIssueMilestone(ns string, name string, milestone *model.IssueMilestone) error
+	// also removes all issues from the milestone.
+	DeleteRepositoryIssueMilestone(ns string, name string, title string) error
+	// the following records an issue event as well; they do nothing
+	// (and record nothing) if nothing has changed. callers should
+	// check whether the label/milestone exists first.
+	AddIssueLabel(issueAbsId int64, labelName string, author string) error
+	RemoveIssueLabel(issueAbsId int64, labelName string, author string) error
+	AddIssueAssignee(issueAbsId int64, username string, author string) error
+	RemoveIssueAssignee(issueAbsId int64, username string, author string) error
+	// empty title removes the issue from its milestone.
+	SetIssueMilestone(issueAbsId int64, title string, author string) error
+	// records an `EVENT_REFERENCED_BY_*` event unless there's
+	// already one of the same type w/ the same content; the same
+	// commit could be pushed to multiple branches.
+	AddIssueReferenceEvent(issueAbsId int64, eType int, author string, content string) error
+
 	// return all namespace that `viewingUser` is a member of
 	GetAllBelongingNamespace(viewingUser string, user string) ([]*model.Namespace, error)
 	// return all repository that `viewingUser` is a member of
@@ -127,7 +180,9 @@ type GitusDatabaseInterface interface {
 	CheckPullRequestMergeConflict(absId int64) (*gitlib.MergeCheckResult, error)
 	DeletePullRequest(absId int64) error
 	GetAllPullRequestEventPaginated(absId int64, pageNum int64, pageSize int64) ([]*model.PullRequestEvent, error)
-	CheckAndMergePullRequest(absId int64, username string) error
+	// `strategy` is one of `gitlib.MERGE_STRATEGY_*`; `message` is
+	// the message of the merge/squash commit, empty for the default.
+	CheckAndMergePullRequest(absId int64, username string, strategy int, message string) error
 	CommentOnPullRequest(absId int64, author string, content string) (*model.PullRequestEvent, error)
 	CommentOnPullRequestCode(absId int64, comment *model.PullRequestCommentOnCode) (*model.PullRequestEvent, error)
 	ClosePullRequestAsNotMerged(absid int64, author string) error
@@ -136,6 +191,23 @@ type GitusDatabaseInterface interface {
 	// when query = "" it looks for all pull request.
 	CountPullRequest(query string, namespace string, name string, filterType int) (int64, error)
 	SearchPullRequestPaginated(query string, namespace string, name string, filterType int, pageNum int64, pageSize int64) ([]*model.PullRequest, error)
+	// returns a setting w/ no requirement if the repository
+	// doesn't have one.
+	GetPullRequestSetting(ns string, name string) (*model.PullRequestSetting, error)
+	SetPullRequestSetting(ns string, name string, setting *model.PullRequestSetting) error
+	// records both the review itself and a review event.
+	ReviewPullRequest(review *model.PullRequestReview) (*model.PullRequestEvent, error)
+	// all reviews of a pull request (including dismissed ones),
+	// sorted by time.
+	GetAllPullRequestReview(absId int64) ([]*model.PullRequestReview, error)
+	// records an update-on-branch event on every open pull request
+	// that has the specified branch as its provider & dismisses the
+	// approvals of these pull requests. returns the abs ids of these
+	// pull requests.
+	UpdatePullRequestProviderBranch(providerNamespace string, providerName string, providerBranch string, author string, commitId string) ([]int64, error)
+	// records a `PULL_REQUEST_EVENT_REFERENCED` event unless there's
+	// already one w/ the same content.
+	AddPullRequestReferenceEvent(absId int64, author string, content string) error
 
 	GetAllRegisteredEmailOfUser(username string) ([]struct{Email string;Verified bool}, error)
 	AddEmail(username string, email string) error
@@ -175,9 +247,129 @@ type GitusDatabaseInterface interface {
 	SaveSnippetInfo(m *model.Snippet) error
 	GetSnippet(username string, name string) (*model.Snippet, error)
 
-	RegisterWebhookRequest(uuid string, reportUuid string, repoNs string, repoName string, commitId string) error
+	// comments, stars & forks of snippets. see docs/snippets.org.
+	// oldest first.
+	GetAllSnippetEvent(username string, name string) ([]*model.SnippetEvent, error)
+	NewSnippetEvent(username string, name string, eType int, author string, content string) error
+	HardDeleteSnippetEvent(username string, name string, eventAbsId int64) error
+	// starring an already starred snippet is not an error.
+	StarSnippet(username string, name string, starUser string) error
+	UnstarSnippet(username string, name string, starUser string) error
+	IsStarringSnippet(username string, name string, starUser string) (bool, error)
+	CountSnippetStar(username string, name string) (int64, error)
+	// creates the snippet `{username}:{name}` as a copy of
+	// `{originUser}:{originName}`, incl. all its revisions. returns
+	// `ErrEntityAlreadyExists` if the target already exists.
+	ForkSnippet(originUser string, originName string, username string, name string, status uint8) (*model.Snippet, error)
+	// returns `ErrEntityNotFound` if the snippet isn't a fork.
+	GetSnippetForkOrigin(username string, name string) (string, string, error)
+	CountSnippetFork(username string, name string) (int64, error)
+
+	// notifications & repository watching. see
+	// docs/notification.org.
+	NewNotification(n *model.Notification) error
+	CountNotification(username string, unreadOnly bool) (int64, error)
+	// newest first.
+	GetNotificationPaginated(username string, unreadOnly bool, pageNum int64, pageSize int64) ([]*model.Notification, error)
+	MarkNotificationRead(username string, id int64) error
+	MarkAllNotificationRead(username string) error
+	DeleteAllReadNotification(username string) error
+	// returns `model.DefaultNotificationSetting()` if the user
+	// hasn't saved one.
+	GetNotificationSetting(username string) (*model.NotificationSetting, error)
+	SetNotificationSetting(username string, setting *model.NotificationSetting) error
+	// watching an already watched repository is not an error.
+	WatchRepository(ns string, name string, username string) error
+	UnwatchRepository(ns string, name string, username string) error
+	IsWatchingRepository(ns string, name string, username string) (bool, error)
+	GetAllRepositoryWatcher(ns string, name string) ([]string, error)
+	// starring an already starred repository is not an error.
+	StarRepository(ns string, name string, username string) error
+	UnstarRepository(ns string, name string, username string) error
+	IsStarringRepository(ns string, name string, username string) (bool, error)
+	// called after every push. see docs/explore.org.
+	RecordRepositoryPush(ns string, name string, pushTime int64) error
+	// same visibility rules as `GetAllVisibleRepositoryPaginated`;
+	// `sortBy` is one of `model.REPO_EXPLORE_SORT_*`. fills in
+	// `StarCount`, `WatcherCount` & `LastPushTime`.
+	GetAllVisibleRepositoryExplorePaginated(username string, sortBy string, pageNum int64, pageSize int64) ([]*model.Repository, error)
+	// names of all users w/ the status `ADMIN` or `SUPER_ADMIN`.
+	GetAllAdminUsername() ([]string, error)
+
+	// background jobs. see docs/job-queue.org.
+	// sets `job.Id`, `job.Status` & `job.CreateTime`; the job runs
+	// right away if `job.NextRunTime` is 0.
+	EnqueueJob(job *model.Job) error
+	// marks the pending job w/ the earliest `NextRunTime` that's due
+	// as running & returns it. returns `ErrEntityNotFound` if there's
+	// none.
+	ClaimJob() (*model.Job, error)
+	// deletes the job.
+	CompleteJob(id int64) error
+	// marks the running job as pending again, to be run at
+	// `nextRunTime`.
+	RescheduleJob(id int64, lastError string, nextRunTime int64) error
+	// marks the running job as failed.
+	FailJob(id int64, lastError string) error
+	// marks the failed job as pending w/ its attempt count reset.
+	RetryJob(id int64) error
+	DeleteJob(id int64) error
+	// marks all running jobs as pending. called before the workers
+	// start, since jobs left running are from a previous run that
+	// didn't shut down properly.
+	ResetRunningJob() error
+	// `status` being 0 means all.
+	CountJob(status int) (int64, error)
+	// oldest first.
+	GetJobPaginated(status int, pageNum int64, pageSize int64) ([]*model.Job, error)
+
 	UpdateWebhookResult(uuid string, result *model.WebhookResult) error
 	GetWebhookResultByUUID(uuid string) (*model.WebhookResult, error)
+	// webhook deliveries. see docs/webhooks.org. a new delivery
+	// comes w/ an undefined webhook result.
+	NewWebhookDelivery(d *model.WebhookDelivery) error
+	GetWebhookDelivery(uuid string) (*model.WebhookDelivery, error)
+	// only updates the things that could change between attempts,
+	// i.e. the request header, the response, the status & the
+	// attempt count.
+	UpdateWebhookDelivery(d *model.WebhookDelivery) error
+	CountWebhookDelivery(repoNs string, repoName string) (int64, error)
+	// newest first.
+	GetWebhookDeliveryPaginated(repoNs string, repoName string, pageNum int64, pageSize int64) ([]*model.WebhookDelivery, error)
+
+	// webhooks other than the default one of a repository. `repoName`
+	// being empty means the webhooks of the namespace. see
+	// model.Webhook.
+	NewWebhook(w *model.Webhook) (int64, error)
+	GetWebhook(id int64) (*model.Webhook, error)
+	UpdateWebhook(w *model.Webhook) error
+	DeleteWebhook(id int64) error
+	// oldest first.
+	GetAllWebhook(ns string, repoName string) ([]*model.Webhook, error)
+
+	// runs of the built-in ci runner. see docs/ci-runner.org.
+	// sets `r.Id`, `r.Status` & `r.CreateTime`.
+	NewCIRun(r *model.CIRun) error
+	GetCIRun(id int64) (*model.CIRun, error)
+	// marks the oldest queued run as running & returns it. returns
+	// `ErrEntityNotFound` if there's none.
+	ClaimCIRun() (*model.CIRun, error)
+	UpdateCIRunLog(id int64, log string) error
+	// sets the final status & log of a running run.
+	FinishCIRun(id int64, status int, log string) error
+	// marks all running runs as queued. called before the runner
+	// starts, since runs left running are from a previous runner
+	// that didn't shut down properly.
+	ResetRunningCIRun() error
+	CountCIRun(ns string, name string) (int64, error)
+	// newest first. the logs are not filled.
+	GetCIRunPaginated(ns string, name string, pageNum int64, pageSize int64) ([]*model.CIRun, error)
+
+	// commit statuses. see docs/commit-status.org.
+	// replaces the status of the same context if there's one.
+	SetCommitStatus(s *model.CommitStatus) error
+	// sorted by context.
+	GetAllCommitStatus(ns string, name string, commitId string) ([]*model.CommitStatus, error)
 }
 
 
@@ -46,9 +46,30 @@ var requiredTableList = []string{
 	"repository",
 	"issue",
 	"issue_event",
+	"issue_label",
+	"issue_milestone",
+	"issue_label_assoc",
+	"issue_assignee",
+	"issue_milestone_assoc",
 	"pull_request",
 	"pull_request_event",
 	"webhook_log",
+	"user_access_token",
+	"branch_protection",
+	"pull_request_setting",
+	"pull_request_review",
+	"notification",
+	"user_notification_setting",
+	"repo_watch",
+	"job",
+	"webhook",
+	"commit_status",
+	"ci_run",
+	"snippet_event",
+	"snippet_star",
+	"snippet_fork",
+	"repo_star",
+	"repo_activity",
 }
 
 func (dbif *PostgresGitusDatabaseInterface) IsDatabaseUsable() (bool, error) {
@@ -85,6 +85,20 @@ CREATE TABLE IF NOT EXISTS %s_repository (
 )`, pfx, pfx))
 	if err != nil { return err }
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_redirect (
+    old_ns VARCHAR(64),
+    old_name VARCHAR(64),
+    new_ns VARCHAR(64),
+    new_name VARCHAR(64),
+    redirect_timestamp TIMESTAMP
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_repo_redirect_old
+ON %s_repo_redirect (old_ns, old_name)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s_issue (
     issue_absid BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     repo_namespace VARCHAR(64),
@@ -110,6 +124,49 @@ CREATE TABLE IF NOT EXISTS %s_issue_event (
 )`, pfx, pfx))
 	if err != nil { return err }
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_label (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    label_name VARCHAR(64),
+    -- "#rrggbb".
+    label_color VARCHAR(7),
+    label_description VARCHAR(1024),
+    UNIQUE (repo_namespace, repo_name, label_name)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_milestone (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    milestone_title VARCHAR(256),
+    milestone_description TEXT,
+    -- null means no due date.
+    milestone_due TIMESTAMP,
+    milestone_closed BOOLEAN,
+    UNIQUE (repo_namespace, repo_name, milestone_title)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_label_assoc (
+    issue_absid BIGINT REFERENCES %s_issue(issue_absid),
+    label_name VARCHAR(64),
+    UNIQUE (issue_absid, label_name)
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_assignee (
+    issue_absid BIGINT REFERENCES %s_issue(issue_absid),
+    username VARCHAR(64),
+    UNIQUE (issue_absid, username)
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_milestone_assoc (
+    issue_absid BIGINT UNIQUE REFERENCES %s_issue(issue_absid),
+    milestone_title VARCHAR(256)
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s_pull_request(
     pull_request_absid BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     author_username VARCHAR(64),
@@ -168,14 +225,280 @@ CREATE TABLE IF NOT EXISTS %s_snippet (
     status SMALLINT,
     shared_user JSONB
 )`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_snippet_event (
+    event_absid BIGINT GENERATED ALWAYS AS IDENTITY,
+    username VARCHAR(64),
+    name VARCHAR(64),
+	-- see model.SNIPPET_EVENT_*.
+	event_type SMALLINT,
+	event_time TIMESTAMP,
+	event_author VARCHAR(64),
+	event_content TEXT
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_snippet_event_snippet
+ON %s_snippet_event (username, name)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_snippet_star (
+    username VARCHAR(64),
+    name VARCHAR(64),
+    star_user VARCHAR(64),
+    UNIQUE (username, name, star_user)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_snippet_fork (
+    username VARCHAR(64),
+    name VARCHAR(64),
+    origin_username VARCHAR(64),
+    origin_name VARCHAR(64),
+    UNIQUE (username, name)
+)`, pfx))
+	if err != nil { return err }
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s_webhook_log (
     uuid VARCHAR(48) UNIQUE,
 	repo_namespace VARCHAR(64),
 	repo_name VARCHAR(64),
 	commit_id VARCHAR(96),
-    webhook_result JSONB
+    webhook_result JSONB,
+    ref_name VARCHAR(256),
+    target_url TEXT,
+    request_header TEXT,
+    request_payload TEXT,
+    response_status INTEGER,
+    response_body TEXT,
+    delivery_error TEXT,
+    -- in milliseconds.
+    duration BIGINT,
+    -- see model.WEBHOOK_DELIVERY_*.
+    delivery_status SMALLINT,
+    attempt INTEGER,
+    create_time TIMESTAMP,
+    delivery_time TIMESTAMP,
+    redelivery_of VARCHAR(48),
+    webhook_id BIGINT,
+    event VARCHAR(32)
 )`, pfx))
+	if err != nil { return err }
+	// the columns added for webhook deliveries; older installs only
+	// have the ones up to `webhook_result`. the rows from before
+	// have no delivery info.
+	for _, k := range [][2]string{
+		{"ref_name", "VARCHAR(256) DEFAULT ''"},
+		{"target_url", "TEXT DEFAULT ''"},
+		{"request_header", "TEXT DEFAULT ''"},
+		{"request_payload", "TEXT DEFAULT ''"},
+		{"response_status", "INTEGER DEFAULT 0"},
+		{"response_body", "TEXT DEFAULT ''"},
+		{"delivery_error", "TEXT DEFAULT ''"},
+		{"duration", "BIGINT DEFAULT 0"},
+		{"delivery_status", "SMALLINT DEFAULT 0"},
+		{"attempt", "INTEGER DEFAULT 0"},
+		{"create_time", "TIMESTAMP DEFAULT 'epoch'"},
+		{"delivery_time", "TIMESTAMP DEFAULT 'epoch'"},
+		{"redelivery_of", "VARCHAR(48) DEFAULT ''"},
+		{"webhook_id", "BIGINT DEFAULT 0"},
+		{"event", "VARCHAR(32) DEFAULT ''"},
+	} {
+		_, err = tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s_webhook_log ADD COLUMN IF NOT EXISTS %s %s", pfx, k[0], k[1]))
+		if err != nil { return err }
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_webhook_log_repo
+ON %s_webhook_log (repo_namespace, repo_name, create_time)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_webhook (
+    webhook_absid BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    webhook_namespace VARCHAR(64),
+    -- empty for namespace webhooks.
+    webhook_repo_name VARCHAR(64),
+    target_url TEXT,
+    secret TEXT,
+    payload_type VARCHAR(16),
+    -- comma-separated; see model.WEBHOOK_EVENT_*.
+    event_list TEXT,
+    -- comma-separated glob patterns.
+    branch_filter TEXT,
+    enabled BOOLEAN,
+    create_time TIMESTAMP
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_webhook_namespace_repo_name
+ON %s_webhook (webhook_namespace, webhook_repo_name)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_user_access_token (
+    user_name VARCHAR(64) REFERENCES %s_user(user_name),
+    token_name VARCHAR(64),
+    token_hash VARCHAR(64) UNIQUE,
+    token_scope VARCHAR(256),
+    token_create_time TIMESTAMP,
+    -- NULL - never expires.
+    token_expire_time TIMESTAMP,
+    UNIQUE (user_name, token_name)
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_branch_protection (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    pattern VARCHAR(256),
+    disallow_force_push BOOLEAN,
+    disallow_deletion BOOLEAN,
+    -- comma-separated; empty means no restriction.
+    allowed_pusher VARCHAR(4096),
+    require_pull_request BOOLEAN,
+    UNIQUE (repo_namespace, repo_name, pattern)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_pull_request_setting (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    required_approval_count INTEGER,
+    -- comma-separated.
+    required_reviewer VARCHAR(4096),
+    -- comma-separated merge strategies. empty means all.
+    allowed_merge_strategy VARCHAR(64),
+    -- comma-separated commit status contexts.
+    required_status_context VARCHAR(4096),
+    UNIQUE (repo_namespace, repo_name)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_commit_status (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    commit_id VARCHAR(64),
+    status_context VARCHAR(256),
+    -- see model.COMMIT_STATUS_*.
+    status_state VARCHAR(16),
+    target_url TEXT,
+    status_description TEXT,
+    status_creator VARCHAR(64),
+    create_time TIMESTAMP,
+    update_time TIMESTAMP,
+    UNIQUE (repo_namespace, repo_name, commit_id, status_context)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_pull_request_review (
+    pull_request_absid BIGINT,
+    reviewer VARCHAR(64),
+    -- 1 - comment, 2 - approve, 3 - request changes.
+    review_state SMALLINT,
+    review_summary TEXT,
+    review_commit_id VARCHAR(64),
+    review_timestamp TIMESTAMP,
+    review_dismissed BOOLEAN,
+    FOREIGN KEY (pull_request_absid) REFERENCES %s_pull_request(pull_request_absid)
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_notification (
+    notification_absid BIGINT GENERATED ALWAYS AS IDENTITY,
+    username VARCHAR(64) REFERENCES %s_user(user_name),
+    -- see model.NOTIFICATION_*.
+    notification_type SMALLINT,
+    actor VARCHAR(64),
+    title VARCHAR(256),
+    content TEXT,
+    link VARCHAR(2048),
+    notification_timestamp TIMESTAMP,
+    is_read BOOLEAN
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_notification_username
+ON %s_notification (username)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_user_notification_setting (
+    username VARCHAR(64) UNIQUE REFERENCES %s_user(user_name),
+    setting JSONB
+)`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_watch (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    username VARCHAR(64),
+    UNIQUE (repo_namespace, repo_name, username)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_star (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    username VARCHAR(64),
+    star_time TIMESTAMP,
+    UNIQUE (repo_namespace, repo_name, username)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_activity (
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    last_push_time TIMESTAMP,
+    UNIQUE (repo_namespace, repo_name)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_job (
+    job_absid BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    job_type VARCHAR(32),
+    payload TEXT,
+    -- see model.JOB_*.
+    job_status SMALLINT,
+    attempt INTEGER,
+    max_attempt INTEGER,
+    last_error TEXT,
+    next_run_time TIMESTAMP,
+    create_time TIMESTAMP
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_job_status_next_run_time
+ON %s_job (job_status, next_run_time)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_ci_run (
+    run_absid BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    repo_namespace VARCHAR(64),
+    repo_name VARCHAR(64),
+    commit_id VARCHAR(64),
+    ref_name TEXT,
+    job_name VARCHAR(64),
+    run_trigger VARCHAR(64),
+    -- see model.CI_RUN_*.
+    run_status SMALLINT,
+    run_log TEXT,
+    create_time TIMESTAMP,
+    start_time TIMESTAMP,
+    end_time TIMESTAMP
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_ci_run_repo
+ON %s_ci_run (repo_namespace, repo_name)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_ci_run_status
+ON %s_ci_run (run_status)
+`, pfx, pfx))
 	if err != nil { return err }
 	err = tx.Commit(ctx)
 	if err != nil { return err }
@@ -1,14 +1,12 @@
 package postgres
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"slices"
 	"strings"
@@ -260,15 +258,18 @@ func (dbif *PostgresGitusDatabaseInterface) GetRepositoryByName(nsName string, r
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
 	stmt := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
-SELECT repo_type, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook, repo_absid
-FROM %s_repository
+SELECT repo_type, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook, repo_absid,
+  (SELECT COUNT(*) FROM %s_repo_star st WHERE st.repo_namespace = repo.repo_namespace AND st.repo_name = repo.repo_name),
+  (SELECT COUNT(*) FROM %s_repo_watch wt WHERE wt.repo_namespace = repo.repo_namespace AND wt.repo_name = repo.repo_name),
+  COALESCE((SELECT EXTRACT(EPOCH FROM act.last_push_time)::BIGINT FROM %s_repo_activity act WHERE act.repo_namespace = repo.repo_namespace AND act.repo_name = repo.repo_name), 0)
+FROM %s_repository repo
 WHERE repo_namespace = $1 AND repo_name = $2
-`, pfx), nsName, repoName)
+`, pfx, pfx, pfx, pfx), nsName, repoName)
 	var description, owner, acl, forkOriginNamespace, forkOriginName, webhook string
 	var repoType, repoStatus int
-	var rowid int64
+	var rowid, starCount, watcherCount, lastPushTime int64
 	var labelList string
-	err := stmt.Scan(&repoType, &description, &owner, &acl, &repoStatus, &forkOriginNamespace, &forkOriginName, &labelList, &webhook, &rowid)
+	err := stmt.Scan(&repoType, &description, &owner, &acl, &repoStatus, &forkOriginNamespace, &forkOriginName, &labelList, &webhook, &rowid, &starCount, &watcherCount, &lastPushTime)
 	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
 	if err != nil { return nil, err }
 	p := path.Join(dbif.config.GitRoot, nsName, repoName)
@@ -287,6 +288,9 @@ WHERE repo_namespace = $1 AND repo_name = $2
 		tags = strings.Split(labelList[1:len(labelList)-1], "}{")
 	}
 	res.RepoLabelList = tags
+	res.StarCount = starCount
+	res.WatcherCount = watcherCount
+	res.LastPushTime = lastPushTime
 	aclobj, err := model.ParseACL(acl)
 	if err != nil { return nil, err }
 	res.AccessControlList = aclobj
@@ -1036,12 +1040,333 @@ func (dbif *PostgresGitusDatabaseInterface) HardDeleteRepository(ns string, name
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
 DELETE FROM %s_repository
 WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_branch_protection
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_pull_request_setting
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_watch
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_webhook
+WHERE webhook_namespace = $1 AND webhook_repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_commit_status
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_ci_run
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_redirect
+WHERE new_ns = $1 AND new_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_star
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_activity
+WHERE repo_namespace = $1 AND repo_name = $2
 `, pfx), ns, name)
 	if err != nil { return err }
 	if err = tx.Commit(ctx); err != nil { return err }
 	return nil
 }
 
+func (dbif *PostgresGitusDatabaseInterface) MoveRepository(oldNs string, oldName string, newNs string, newName string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	var forkOriginNs, forkOriginName string
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+SELECT repo_fork_origin_namespace, repo_fork_origin_name FROM %s_repository
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), oldNs, oldName).Scan(&forkOriginNs, &forkOriginName)
+	if errors.Is(err, pgx.ErrNoRows) { return db.ErrEntityNotFound }
+	if err != nil { return err }
+	var c int64
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_repository WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), newNs, newName).Scan(&c)
+	if err != nil { return err }
+	if c > 0 { return db.ErrEntityAlreadyExists }
+	// the repositories that could have remotes referring to the
+	// moved one. see `db.RelocateMovedRepositoryRemote`.
+	remoteRepoList := [][2]string{{forkOriginNs, forkOriginName}}
+	for _, q := range []string{
+		"SELECT repo_namespace, repo_name FROM %s_repository WHERE repo_fork_origin_namespace = $1 AND repo_fork_origin_name = $2",
+		"SELECT DISTINCT receiver_namespace, receiver_name FROM %s_pull_request WHERE provider_namespace = $1 AND provider_name = $2",
+	} {
+		rs, err := tx.Query(ctx, fmt.Sprintf(q, pfx), oldNs, oldName)
+		if err != nil { return err }
+		for rs.Next() {
+			var ns, name string
+			err = rs.Scan(&ns, &name)
+			if err != nil { rs.Close(); return err }
+			if ns == oldNs && name == oldName { ns, name = newNs, newName }
+			remoteRepoList = append(remoteRepoList, [2]string{ns, name})
+		}
+		rs.Close()
+	}
+	// the issues refer to the repository w/ a foreign key, so the
+	// row is copied under the new name (w/ the same absid) first
+	// & the old one is deleted after the issues are moved.
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repository(repo_absid, repo_type, repo_namespace, repo_name, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook)
+OVERRIDING SYSTEM VALUE
+SELECT repo_absid, repo_type, $3, $4, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook
+FROM %s_repository WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx, pfx), oldNs, oldName, newNs, newName)
+	if err != nil { return err }
+	for _, k := range []string{
+		"UPDATE %s_repository SET repo_fork_origin_namespace = $1, repo_fork_origin_name = $2 WHERE repo_fork_origin_namespace = $3 AND repo_fork_origin_name = $4",
+		"UPDATE %s_issue SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_issue_label SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_issue_milestone SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_pull_request SET receiver_namespace = $1, receiver_name = $2 WHERE receiver_namespace = $3 AND receiver_name = $4",
+		"UPDATE %s_pull_request SET provider_namespace = $1, provider_name = $2 WHERE provider_namespace = $3 AND provider_name = $4",
+		"UPDATE %s_webhook_log SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_webhook SET webhook_namespace = $1, webhook_repo_name = $2 WHERE webhook_namespace = $3 AND webhook_repo_name = $4",
+		"UPDATE %s_branch_protection SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_pull_request_setting SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_commit_status SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_repo_watch SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_repo_star SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_repo_activity SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		"UPDATE %s_ci_run SET repo_namespace = $1, repo_name = $2 WHERE repo_namespace = $3 AND repo_name = $4",
+		// older redirects to the moved repository now point to
+		// its new name.
+		"UPDATE %s_repo_redirect SET new_ns = $1, new_name = $2 WHERE new_ns = $3 AND new_name = $4",
+	} {
+		_, err = tx.Exec(ctx, fmt.Sprintf(k, pfx), newNs, newName, oldNs, oldName)
+		if err != nil { return err }
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repository WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), oldNs, oldName)
+	if err != nil { return err }
+	// the new name might've been redirected somewhere else before,
+	// & the old name might've been used by another repository that's
+	// been moved away.
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_redirect
+WHERE (old_ns = $1 AND old_name = $2) OR (old_ns = $3 AND old_name = $4)
+`, pfx), newNs, newName, oldNs, oldName)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repo_redirect(old_ns, old_name, new_ns, new_name, redirect_timestamp)
+VALUES ($1, $2, $3, $4, $5)
+`, pfx), oldNs, oldName, newNs, newName, time.Now())
+	if err != nil { return err }
+	rs, err := tx.Query(ctx, fmt.Sprintf(`
+SELECT job_absid, payload FROM %s_job WHERE job_type = $1
+`, pfx), model.JOB_TYPE_WEBHOOK)
+	if err != nil { return err }
+	jobPayloadMap := make(map[int64]string, 0)
+	for rs.Next() {
+		var id int64
+		var payload string
+		err = rs.Scan(&id, &payload)
+		if err != nil { rs.Close(); return err }
+		newPayload, ok := db.MoveWebhookJobPayload(payload, oldNs, oldName, newNs, newName)
+		if ok { jobPayloadMap[id] = newPayload }
+	}
+	rs.Close()
+	for id, payload := range jobPayloadMap {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`UPDATE %s_job SET payload = $1 WHERE job_absid = $2`, pfx), payload, id)
+		if err != nil { return err }
+	}
+	err = db.MoveRepositoryDirectory(dbif.config.GitRoot, oldNs, oldName, newNs, newName)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil {
+		db.UndoMoveRepositoryDirectory(dbif.config.GitRoot, oldNs, oldName, newNs, newName)
+		return err
+	}
+	db.RelocateMovedRepositoryRemote(dbif.config.GitRoot, remoteRepoList, oldNs, oldName, newNs, newName)
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetRepositoryRedirect(ns string, name string) (string, string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var newNs, newName string
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT new_ns, new_name FROM %s_repo_redirect
+WHERE old_ns = $1 AND old_name = $2
+ORDER BY redirect_timestamp DESC LIMIT 1
+`, pfx), ns, name).Scan(&newNs, &newName)
+	if errors.Is(err, pgx.ErrNoRows) { return "", "", db.ErrEntityNotFound }
+	if err != nil { return "", "", err }
+	return newNs, newName, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RenameNamespace(oldName string, newName string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	var c int64
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_namespace WHERE ns_name = $1
+`, pfx), oldName).Scan(&c)
+	if err != nil { return err }
+	if c <= 0 { return db.ErrEntityNotFound }
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_namespace WHERE ns_name = $1
+`, pfx), newName).Scan(&c)
+	if err != nil { return err }
+	if c > 0 { return db.ErrEntityAlreadyExists }
+	rs, err := tx.Query(ctx, fmt.Sprintf(`
+SELECT repo_name FROM %s_repository WHERE repo_namespace = $1
+`, pfx), oldName)
+	if err != nil { return err }
+	repoNameList := make([]string, 0)
+	for rs.Next() {
+		var name string
+		err = rs.Scan(&name)
+		if err != nil { rs.Close(); return err }
+		repoNameList = append(repoNameList, name)
+	}
+	rs.Close()
+	// the repositories that could have remotes referring to each of
+	// the moved ones. see `db.RelocateMovedRepositoryRemote`.
+	remoteRepoMap := make(map[string][][2]string, 0)
+	for _, repoName := range repoNameList {
+		l := make([][2]string, 0)
+		for _, q := range []string{
+			"SELECT repo_fork_origin_namespace, repo_fork_origin_name FROM %s_repository WHERE repo_namespace = $1 AND repo_name = $2",
+			"SELECT repo_namespace, repo_name FROM %s_repository WHERE repo_fork_origin_namespace = $1 AND repo_fork_origin_name = $2",
+			"SELECT DISTINCT receiver_namespace, receiver_name FROM %s_pull_request WHERE provider_namespace = $1 AND provider_name = $2",
+		} {
+			rs, err := tx.Query(ctx, fmt.Sprintf(q, pfx), oldName, repoName)
+			if err != nil { return err }
+			for rs.Next() {
+				var ns, name string
+				err = rs.Scan(&ns, &name)
+				if err != nil { rs.Close(); return err }
+				if ns == oldName { ns = newName }
+				l = append(l, [2]string{ns, name})
+			}
+			rs.Close()
+		}
+		remoteRepoMap[repoName] = l
+	}
+	// the repositories refer to the namespace & the issues refer to
+	// the repositories w/ foreign keys, so the rows are copied under
+	// the new name first & the old ones are deleted after everything
+	// else is moved. see `MoveRepository`.
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_namespace(ns_absid, ns_name, ns_title, ns_description, ns_email, ns_owner, ns_reg_datetime, ns_acl, ns_status)
+OVERRIDING SYSTEM VALUE
+SELECT ns_absid, $2, ns_title, ns_description, ns_email, ns_owner, ns_reg_datetime, ns_acl, ns_status
+FROM %s_namespace WHERE ns_name = $1
+`, pfx, pfx), oldName, newName)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repository(repo_absid, repo_type, repo_namespace, repo_name, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook)
+OVERRIDING SYSTEM VALUE
+SELECT repo_absid, repo_type, $2, repo_name, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook
+FROM %s_repository WHERE repo_namespace = $1
+`, pfx, pfx), oldName, newName)
+	if err != nil { return err }
+	for _, k := range []string{
+		"UPDATE %s_repository SET repo_fork_origin_namespace = $1 WHERE repo_fork_origin_namespace = $2",
+		"UPDATE %s_issue SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_issue_label SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_issue_milestone SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_pull_request SET receiver_namespace = $1 WHERE receiver_namespace = $2",
+		"UPDATE %s_pull_request SET provider_namespace = $1 WHERE provider_namespace = $2",
+		"UPDATE %s_webhook_log SET repo_namespace = $1 WHERE repo_namespace = $2",
+		// incl. the webhooks of the namespace itself.
+		"UPDATE %s_webhook SET webhook_namespace = $1 WHERE webhook_namespace = $2",
+		"UPDATE %s_branch_protection SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_pull_request_setting SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_commit_status SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_repo_watch SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_repo_star SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_repo_activity SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_ci_run SET repo_namespace = $1 WHERE repo_namespace = $2",
+		"UPDATE %s_repo_redirect SET new_ns = $1 WHERE new_ns = $2",
+	} {
+		_, err = tx.Exec(ctx, fmt.Sprintf(k, pfx), newName, oldName)
+		if err != nil { return err }
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repository WHERE repo_namespace = $1
+`, pfx), oldName)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_namespace WHERE ns_name = $1
+`, pfx), oldName)
+	if err != nil { return err }
+	// same as `MoveRepository` but for every repository in the
+	// namespace.
+	t := time.Now()
+	for _, repoName := range repoNameList {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_redirect
+WHERE (old_ns = $1 AND old_name = $2) OR (old_ns = $3 AND old_name = $2)
+`, pfx), newName, repoName, oldName)
+		if err != nil { return err }
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repo_redirect(old_ns, old_name, new_ns, new_name, redirect_timestamp)
+VALUES ($1, $2, $3, $2, $4)
+`, pfx), oldName, repoName, newName, t)
+		if err != nil { return err }
+	}
+	rs, err = tx.Query(ctx, fmt.Sprintf(`
+SELECT job_absid, payload FROM %s_job WHERE job_type = $1
+`, pfx), model.JOB_TYPE_WEBHOOK)
+	if err != nil { return err }
+	jobPayloadMap := make(map[int64]string, 0)
+	for rs.Next() {
+		var id int64
+		var payload string
+		err = rs.Scan(&id, &payload)
+		if err != nil { rs.Close(); return err }
+		newPayload, ok := db.RenameNamespaceWebhookJobPayload(payload, oldName, newName)
+		if ok { jobPayloadMap[id] = newPayload }
+	}
+	rs.Close()
+	for id, payload := range jobPayloadMap {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`UPDATE %s_job SET payload = $1 WHERE job_absid = $2`, pfx), payload, id)
+		if err != nil { return err }
+	}
+	err = db.MoveNamespaceDirectory(dbif.config.GitRoot, oldName, newName)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil {
+		db.UndoMoveNamespaceDirectory(dbif.config.GitRoot, oldName, newName)
+		return err
+	}
+	for _, repoName := range repoNameList {
+		db.RelocateMovedRepositoryRemote(dbif.config.GitRoot, remoteRepoMap[repoName], oldName, repoName, newName, repoName)
+	}
+	return nil
+}
+
 func (dbif *PostgresGitusDatabaseInterface) GetAllUsers(pageNum int64, pageSize int64) ([]*model.GitusUser, error) {
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
@@ -1582,25 +1907,28 @@ SELECT issue_absid, issue_timestamp, issue_author, issue_title, issue_content, i
 FROM %s_issue
 WHERE repo_namespace = $1 AND repo_name = $2 AND issue_id = $3
 `, pfx), ns, name, iid)
-	var absid, id, status int64
+	var absid, status int64
 	var t time.Time
 	var priority int
 	var author, title, content string
 	err := stmt.Scan(&absid, &t, &author, &title, &content, &status, &priority)
 	if err == pgx.ErrNoRows { return nil, db.ErrEntityNotFound }
 	if err != nil { return nil, err }
-	return &model.Issue{
+	res := &model.Issue{
 		IssueAbsId: absid,
 		RepoNamespace: ns,
 		RepoName: name,
-		IssueId: int(id),
+		IssueId: iid,
 		IssueAuthor: author,
 		IssueTitle: title,
 		IssueContent: content,
 		IssueTime: t.Unix(),
 		IssueStatus: int(status),
 		IssuePriority: priority,
-	}, nil
+	}
+	err = dbif.fillIssueMetadata([]*model.Issue{res})
+	if err != nil { return nil, err }
+	return res, nil
 }
 
 func (dbif *PostgresGitusDatabaseInterface) CountAllRepositoryIssue(ns string, name string) (int, error) {
@@ -1620,7 +1948,32 @@ WHERE repo_namespace = $1 AND repo_name = $2
 
 // filterType: 0 - all, 1 - open, 2 - closed, 3 - solved, 4 - discarded
 // when query = "" it looks for all issue.
-func (dbif *PostgresGitusDatabaseInterface) CountIssue(query string, namespace string, name string, filterType int) (int64, error) {
+// the extra conditions for `model.IssueFilter`. each condition
+// starts w/ "AND"; parameters are numbered from `start`.
+func issueFilterClause(pfx string, filter *model.IssueFilter, start int) (string, []any) {
+	if filter.IsEmpty() { return "", nil }
+	res := make([]string, 0)
+	args := make([]any, 0)
+	if len(filter.Author) > 0 {
+		res = append(res, fmt.Sprintf("AND issue_author = $%d", start+len(args)))
+		args = append(args, filter.Author)
+	}
+	if len(filter.Assignee) > 0 {
+		res = append(res, fmt.Sprintf("AND issue_absid IN (SELECT issue_absid FROM %s_issue_assignee WHERE username = $%d)", pfx, start+len(args)))
+		args = append(args, filter.Assignee)
+	}
+	if len(filter.Milestone) > 0 {
+		res = append(res, fmt.Sprintf("AND issue_absid IN (SELECT issue_absid FROM %s_issue_milestone_assoc WHERE milestone_title = $%d)", pfx, start+len(args)))
+		args = append(args, filter.Milestone)
+	}
+	for _, k := range filter.Label {
+		res = append(res, fmt.Sprintf("AND issue_absid IN (SELECT issue_absid FROM %s_issue_label_assoc WHERE label_name = $%d)", pfx, start+len(args)))
+		args = append(args, k)
+	}
+	return strings.Join(res, " "), args
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CountIssue(query string, namespace string, name string, filterType int, filter *model.IssueFilter) (int64, error) {
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
 	statusClause := ""
@@ -1631,21 +1984,19 @@ func (dbif *PostgresGitusDatabaseInterface) CountIssue(query string, namespace s
 	case 3: statusClause = "issue_status = 2"
 	case 4: statusClause = "issue_status = 3"
 	}
-	var stmt pgx.Row
+	args := []any{namespace, name}
+	queryClause := ""
 	if len(query) > 0 {
-		stmt = dbif.pool.QueryRow(ctx, fmt.Sprintf(`
-SELECT COUNT(*) FROM %s_issue
-WHERE repo_namespace = $1 AND repo_name = $2
-AND %s
-AND (issue_title LIKE $3 ESCAPE $4)
-`, pfx, statusClause), namespace, name, query, "%")
-	} else {
-		stmt = dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+		queryClause = "AND (issue_title LIKE $3 ESCAPE $4)"
+		args = append(args, db.ToSqlSearchPattern(query), "\\")
+	}
+	filterClause, filterArgs := issueFilterClause(pfx, filter, len(args)+1)
+	args = append(args, filterArgs...)
+	stmt := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
 SELECT COUNT(*) FROM %s_issue
 WHERE repo_namespace = $1 AND repo_name = $2
-AND %s
-`, pfx, statusClause), namespace, name)
-	}
+AND %s %s %s
+`, pfx, statusClause, queryClause, filterClause), args...)
 	var res int64
 	err := stmt.Scan(&res)
 	if err == pgx.ErrNoRows { return 0, db.ErrEntityNotFound }
@@ -1653,7 +2004,7 @@ AND %s
 	return res, nil
 }
 
-func (dbif *PostgresGitusDatabaseInterface) SearchIssuePaginated(query string, namespace string, name string, filterType int, pageNum int64, pageSize int64) ([]*model.Issue, error) {
+func (dbif *PostgresGitusDatabaseInterface) SearchIssuePaginated(query string, namespace string, name string, filterType int, filter *model.IssueFilter, pageNum int64, pageSize int64) ([]*model.Issue, error) {
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
 	statusClause := ""
@@ -1664,24 +2015,22 @@ func (dbif *PostgresGitusDatabaseInterface) SearchIssuePaginated(query string, n
 	case 3: statusClause = "issue_status = 2"
 	case 4: statusClause = "issue_status = 3"
 	}
-	var err error
-	var stmt pgx.Rows
+	args := []any{namespace, name}
+	queryClause := ""
 	if len(query) > 0 {
-		pat := db.ToSqlSearchPattern(query)
-		stmt, err = dbif.pool.Query(ctx, fmt.Sprintf(`
-SELECT issue_absid, issue_id, issue_timestamp, issue_author, issue_title, issue_content, issue_status, issue_priority
-FROM %s_issue
-WHERE repo_namespace = $1 AND repo_name = $2 AND %s AND (issue_title LIKE $3 ESCAPE $4)
-ORDER BY issue_priority DESC, issue_timestamp DESC LIMIT $5 OFFSET $6
-`, pfx, statusClause), namespace, name, pat, "\\", pageSize, pageNum*pageSize)
-	} else {
-		stmt, err = dbif.pool.Query(ctx, fmt.Sprintf(`
+		queryClause = "AND (issue_title LIKE $3 ESCAPE $4)"
+		args = append(args, db.ToSqlSearchPattern(query), "\\")
+	}
+	filterClause, filterArgs := issueFilterClause(pfx, filter, len(args)+1)
+	args = append(args, filterArgs...)
+	limitClause := fmt.Sprintf("LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, pageSize, pageNum*pageSize)
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
 SELECT issue_absid, issue_id, issue_timestamp, issue_author, issue_title, issue_content, issue_status, issue_priority
 FROM %s_issue
-WHERE repo_namespace = $1 AND repo_name = $2 AND %s
-ORDER BY issue_priority DESC, issue_timestamp DESC LIMIT $3 OFFSET $4
-`, pfx, statusClause), namespace, name, pageSize, pageNum*pageSize)
-	}
+WHERE repo_namespace = $1 AND repo_name = $2 AND %s %s %s
+ORDER BY issue_priority DESC, issue_timestamp DESC %s
+`, pfx, statusClause, queryClause, filterClause, limitClause), args...)
 	if err != nil { return nil, err }
 	defer stmt.Close()
 	res := make([]*model.Issue, 0)
@@ -1705,6 +2054,9 @@ ORDER BY issue_priority DESC, issue_timestamp DESC LIMIT $3 OFFSET $4
 			IssuePriority: priority,
 		})
 	}
+	stmt.Close()
+	err = dbif.fillIssueMetadata(res)
+	if err != nil { return nil, err }
 	return res, nil
 }
 
@@ -1737,6 +2089,13 @@ func (dbif *PostgresGitusDatabaseInterface) HardDeleteRepositoryIssue(ns string,
 	ctx := context.Background()
 	tx, err := dbif.pool.Begin(ctx)
 	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	for _, t := range []string{"issue_label_assoc", "issue_assignee", "issue_milestone_assoc"} {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_%s WHERE issue_absid IN (SELECT issue_absid FROM %s_issue WHERE repo_namespace = $1 AND repo_name = $2 AND issue_id = $3)
+`, pfx, t, pfx), ns, name, issueId)
+		if err != nil { return err }
+	}
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
 DELETE FROM %s_issue WHERE repo_namespace = $1 AND repo_name = $2 AND issue_id = $3
 `, pfx), ns, name, issueId)
@@ -2167,6 +2526,7 @@ WHERE receiver_namespace = $1 AND receiver_name = $2 AND pull_request_id = $3
 	var mergeConflictTime, pullRequestTime time.Time
 	var status int
 	err := stmt.Scan(&absid, &author, &title, &receiverBranch, &providerNs, &providerName, &providerBranch, &mergeCheckString, &mergeConflictTime, &status, &pullRequestTime)
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
 	if err != nil { return nil, err }
 	var mergeCheckResult *gitlib.MergeCheckResult = nil
 	if len(mergeCheckString) > 0 {		
@@ -2195,7 +2555,7 @@ func (dbif *PostgresGitusDatabaseInterface) GetPullRequestByAbsId(absId int64) (
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
 	stmt := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
-SELECT author_username, pull_request_id, title, receiver_anmespace, receiver_name, receiver_branch, provider_namespace, provider_name, provider_branch, merge_conflict_check_result, merge_conflict_check_timestamp, pull_request_status, pull_request_timestamp
+SELECT author_username, pull_request_id, title, receiver_namespace, receiver_name, receiver_branch, provider_namespace, provider_name, provider_branch, merge_conflict_check_result, merge_conflict_check_timestamp, pull_request_status, pull_request_timestamp
 FROM %s_pull_request
 WHERE receiver_namespace = $1 AND receiver_name = $2 AND pull_request_id = $3
 `, pfx), absId)
@@ -2275,6 +2635,10 @@ func (dbif *PostgresGitusDatabaseInterface) DeletePullRequest(absId int64) error
 	if err != nil { return err }
 	defer tx.Rollback(ctx)
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_pull_request_review WHERE pull_request_absid = $1
+`, pfx), absId)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
 DELETE FROM %s_pull_request WHERE pull_request_absid = $1
 `, pfx), absId)
 	if err != nil { return err }
@@ -2312,7 +2676,7 @@ ORDER BY event_timestamp ASC LIMIT $2 OFFSET $3
 	return res, nil
 }
 
-func (dbif *PostgresGitusDatabaseInterface) CheckAndMergePullRequest(absId int64, username string) error {
+func (dbif *PostgresGitusDatabaseInterface) CheckAndMergePullRequest(absId int64, username string, strategy int, message string) error {
 	// WARNING: currently only works when when the source &
 	// the target is git repo. currently (2025.8.27) this check
 	// is performed at the controller side, i.e. users cannot
@@ -2322,6 +2686,29 @@ func (dbif *PostgresGitusDatabaseInterface) CheckAndMergePullRequest(absId int64
 	// TODO: fix this after figuring things out. (doing the
 	// following possibly bad for performance?) this would
 	// need to be fixed in the future...
+	// the receiver branch may be protected. see
+	// docs/protected-branch.org.
+	pr, err := dbif.GetPullRequestByAbsId(absId)
+	if err != nil { return err }
+	ruleList, err := dbif.GetAllBranchProtectionRule(pr.ReceiverNamespace, pr.ReceiverName)
+	if err != nil { return err }
+	err = model.CheckBranchUpdate(ruleList, username, pr.ReceiverBranch, model.BRANCH_UPDATE_MERGE_PULL_REQUEST)
+	if err != nil { return err }
+	// see docs/pull-request-review.org.
+	setting, err := dbif.GetPullRequestSetting(pr.ReceiverNamespace, pr.ReceiverName)
+	if err != nil { return err }
+	reviewList, err := dbif.GetAllPullRequestReview(absId)
+	if err != nil { return err }
+	err = model.CheckPullRequestReview(setting, reviewList)
+	if err != nil { return err }
+	if !setting.MergeStrategyAllowed(strategy) { return model.ErrMergeStrategyNotAllowed }
+	// see docs/commit-status.org.
+	if len(setting.RequiredStatusContext) > 0 {
+		statusList, _, err := db.GetPullRequestCommitStatus(dbif, pr)
+		if err != nil { return err }
+		err = model.CheckRequiredCommitStatus(setting, statusList)
+		if err != nil { return err }
+	}
 	r, err := dbif.CheckPullRequestMergeConflict(absId)
 	if err != nil { return err }
 	if !r.Successful { return nil }
@@ -2333,41 +2720,14 @@ SELECT user_email, user_title FROM %s_user WHERE user_name = $1
 	var email, userTitle string
 	err = stmt0.Scan(&email, &userTitle)
 	if err != nil { return err }
-	// fetch
-	buf := new(bytes.Buffer)
-	cmd1 := exec.Command("git", "fetch", r.ProviderRemoteName, r.ProviderBranch)
-	cmd1.Dir = r.ReceiverLocation
-	cmd1.Stderr = buf
-	err = cmd1.Run()
-	if err != nil { return errors.New(err.Error() + ": " + buf.String()) }
-	buf.Reset()
-	providerFullName := fmt.Sprintf("%s/%s", r.ProviderRemoteName, r.ProviderBranch)
-	cmd2 := exec.Command("git", "merge-tree", "--write-tree", r.ReceiverBranch, providerFullName)
-	cmd2.Dir = r.ReceiverLocation
-	cmd2.Stdout = buf
-	err = cmd2.Run()
-	if err != nil { return fmt.Errorf("Failed while merge-tree: %s", err.Error()) }
-	treeId := strings.TrimSpace(buf.String())
-	mergeMessage := fmt.Sprintf("merge: from %s/%s to %s", r.ProviderRemoteName, r.ProviderBranch, r.ReceiverBranch)
-	buf.Reset()
-	cmd3 := exec.Command("git", "commit-tree", treeId, "-m", mergeMessage, "-p", r.ReceiverBranch, "-p", providerFullName)
-	cmd3.Dir = r.ReceiverLocation
-	cmd3.Stdout = buf
-	cmd3.Env = os.Environ()
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_AUTHOR_NAME=%s", userTitle))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", email))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_COMMITTER_NAME=%s", userTitle))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", email))
-	err = cmd3.Run()
-	if err != nil { return fmt.Errorf("Failed while commit-tree: %s", err.Error()) }
-	commitId := strings.TrimSpace(buf.String())
-	buf.Reset()
-	receiverBranchFullName := fmt.Sprintf("refs/heads/%s", r.ReceiverBranch)
-	cmd4 := exec.Command("git", "update-ref", receiverBranchFullName, commitId)
-	cmd4.Dir = r.ReceiverLocation
-	cmd4.Stderr = buf
-	err = cmd4.Run()
-	if err != nil { return fmt.Errorf("Failed while update-ref: %s; %s", err.Error(), buf.String()) }
+	lgr := gitlib.NewLocalGitRepository(r.ReceiverLocation)
+	_, err = lgr.Merge(r.ProviderRemoteName, r.ProviderBranch, r.ReceiverBranch, &gitlib.MergeOption{
+		Strategy: strategy,
+		Message: message,
+		UserName: userTitle,
+		UserEmail: email,
+	})
+	if err != nil { return err }
 	tx, err := dbif.pool.Begin(ctx)
 	if err != nil { return err }
 	defer tx.Rollback(ctx)
@@ -2377,9 +2737,9 @@ UPDATE %s_pull_request SET pull_request_status = $1, pull_request_timestamp = $2
 `, pfx), model.PULL_REQUEST_CLOSED_AS_MERGED, t, absId)
 	if err != nil { return err }
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
-INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+INSERT INTO %s_pull_request_event(pull_request_absid, event_type, event_timestamp, event_author, event_content)
 VALUES ($1,$2,$3,$4,$5)
-`, pfx), absId, model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED, t, username, "")
+`, pfx), absId, model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED, t, username, gitlib.MergeStrategyName(strategy))
 	if err != nil { return err }
 	err = tx.Commit(ctx)
 	if err != nil { return err }
@@ -3063,7 +3423,7 @@ VALUES ($1, $2, $3, $4, $5, $6)
 	}
 	err = os.RemoveAll(p)
 	if err != nil { return nil, err }
-	err = os.MkdirAll(p, os.ModeDir|0755)
+	err = model.InitSnippetRepository(p)
 	if err != nil { return nil, err }
 	err = tx.Commit(ctx)
 	if err != nil { return nil, err }
@@ -3223,6 +3583,17 @@ func (dbif *PostgresGitusDatabaseInterface) DeleteSnippet(username string, name
 	defer tx.Rollback(ctx)
 	_, err = tx.Exec(ctx, fmt.Sprintf(`
 DELETE FROM %s_snippet WHERE username = $1 AND name = $2
+`, pfx), username, name)
+	if err != nil { return err }
+	for _, k := range []string{"snippet_event", "snippet_star", "snippet_fork"} {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_%s WHERE username = $1 AND name = $2
+`, pfx, k), username, name)
+		if err != nil { return err }
+	}
+	// forks of this snippet stay but are no longer forks.
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_snippet_fork WHERE origin_username = $1 AND origin_name = $2
 `, pfx), username, name)
 	if err != nil { return err }
 	p := path.Join(dbif.config.SnippetRoot, username, name)
@@ -3276,57 +3647,1569 @@ WHERE username = $1 AND name = $2
 	}, nil
 }
 
+func (dbif *PostgresGitusDatabaseInterface) GetAllSnippetEvent(username string, name string) ([]*model.SnippetEvent, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT event_absid, event_type, event_time, event_author, event_content
+FROM %s_snippet_event WHERE username = $1 AND name = $2
+ORDER BY event_absid ASC
+`, pfx), username, name)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	var etype int
+	var eid int64
+	var time time.Time
+	var author, content string
+	res := make([]*model.SnippetEvent, 0)
+	for stmt.Next() {
+		err = stmt.Scan(&eid, &etype, &time, &author, &content)
+		if err != nil { return nil, err }
+		res = append(res, &model.SnippetEvent{
+			EventAbsId: eid,
+			EventType: etype,
+			EventTimestamp: time.Unix(),
+			EventAuthor: author,
+			EventContent: content,
+		})
+	}
+	return res, nil
+}
 
-func (dbif *PostgresGitusDatabaseInterface) RegisterWebhookRequest(uuid string, reportUuid string, repoNs string, repoName string, commitId string) error {
+func (dbif *PostgresGitusDatabaseInterface) NewSnippetEvent(username string, name string, eType int, author string, content string) error {
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
-	tx, err := dbif.pool.Begin(ctx)
-	if err != nil { return err }
-	defer tx.Rollback(ctx)
-	d := new(model.WebhookResult)
-	d.Status = model.WEBHOOK_RESULT_UNDEFINED
-	d.ReportUUID = reportUuid
-	d.UUID = uuid
-	d.RepoNamespace = repoNs
-	d.RepoName = repoName
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
-INSERT INTO %s_webhook_log(uuid, repo_namespace, repo_name, commit_id, webhook_result)
-VALUES ($1,$2,$3,$4,$5)
-`, pfx), uuid, repoNs, repoName, commitId, d)
-	if err != nil { return err }
-	err = tx.Commit(ctx)
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_snippet_event(username, name, event_type, event_time, event_author, event_content)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, pfx), username, name, eType, time.Now(), author, content)
 	if err != nil { return err }
 	return nil
 }
 
-func (dbif *PostgresGitusDatabaseInterface) UpdateWebhookResult(uuid string, result *model.WebhookResult) error {
+func (dbif *PostgresGitusDatabaseInterface) HardDeleteSnippetEvent(username string, name string, eventAbsId int64) error {
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
-	tx, err := dbif.pool.Begin(ctx)
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_snippet_event WHERE event_absid = $1 AND username = $2 AND name = $3
+`, pfx), eventAbsId, username, name)
 	if err != nil { return err }
-	defer tx.Rollback(ctx)
-	if result.Timestamp == 0 { result.Timestamp = time.Now().Unix() }
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
-UPDATE %s_webhook_log SET webhook_result = $1 WHERE uuid = $2
-`, pfx), result, uuid)
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) StarSnippet(username string, name string, starUser string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_snippet_star(username, name, star_user)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`, pfx), username, name, starUser)
 	if err != nil { return err }
-	err = tx.Commit(ctx)
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UnstarSnippet(username string, name string, starUser string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_snippet_star
+WHERE username = $1 AND name = $2 AND star_user = $3
+`, pfx), username, name, starUser)
 	if err != nil { return err }
 	return nil
 }
 
-func (dbif *PostgresGitusDatabaseInterface) GetWebhookResultByUUID(uuid string) (*model.WebhookResult, error) {
+func (dbif *PostgresGitusDatabaseInterface) IsStarringSnippet(username string, name string, starUser string) (bool, error) {
 	pfx := dbif.config.Database.TablePrefix
 	ctx := context.Background()
-	stmt := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
-SELECT webhook_result
-FROM %s_webhook_log
-WHERE uuid = $1
-`, pfx), uuid)
-	var webhookRes *model.WebhookResult
-	err := stmt.Scan(&webhookRes)
-	if err != nil { return nil, err }
-	return webhookRes, nil
+	var c int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet_star
+WHERE username = $1 AND name = $2 AND star_user = $3
+`, pfx), username, name, starUser).Scan(&c)
+	if err != nil { return false, err }
+	return c > 0, nil
 }
 
+func (dbif *PostgresGitusDatabaseInterface) CountSnippetStar(username string, name string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var c int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet_star WHERE username = $1 AND name = $2
+`, pfx), username, name).Scan(&c)
+	if err != nil { return 0, err }
+	return c, nil
+}
 
+func (dbif *PostgresGitusDatabaseInterface) ForkSnippet(originUser string, originName string, username string, name string, status uint8) (*model.Snippet, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	origin, err := dbif.GetSnippet(originUser, originName)
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	p := path.Join(dbif.config.SnippetRoot, username, name)
+	if !db.IsSubDir(dbif.config.SnippetRoot, p) {
+		return nil, db.ErrInvalidLocation
+	}
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return nil, err }
+	defer tx.Rollback(ctx)
+	var c int64
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet WHERE username = $1 AND name = $2
+`, pfx), username, name).Scan(&c)
+	if err != nil { return nil, err }
+	if c > 0 { return nil, db.ErrEntityAlreadyExists }
+	t := time.Now()
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_snippet(name, username, description, timestamp, status, shared_user)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, pfx), name, username, origin.Description, t, status, "{}")
+	if err != nil { return nil, err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_snippet_fork(username, name, origin_username, origin_name)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (username, name) DO UPDATE SET origin_username = $3, origin_name = $4
+`, pfx), username, name, originUser, originName)
+	if err != nil { return nil, err }
+	err = origin.CopyRepositoryTo(dbif.config.SnippetRoot, p)
+	if err != nil {
+		os.RemoveAll(p)
+		return nil, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		os.RemoveAll(p)
+		return nil, err
+	}
+	return &model.Snippet{
+		Name: name,
+		BelongingUser: username,
+		Description: origin.Description,
+		Time: t.Unix(),
+		FileList: make(map[string]string, 0),
+		Status: status,
+	}, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetSnippetForkOrigin(username string, name string) (string, string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var originUser, originName string
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT origin_username, origin_name FROM %s_snippet_fork
+WHERE username = $1 AND name = $2
+`, pfx), username, name).Scan(&originUser, &originName)
+	if errors.Is(err, pgx.ErrNoRows) { return "", "", db.ErrEntityNotFound }
+	if err != nil { return "", "", err }
+	return originUser, originName, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CountSnippetFork(username string, name string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var c int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet_fork WHERE origin_username = $1 AND origin_name = $2
+`, pfx), username, name).Scan(&c)
+	if err != nil { return 0, err }
+	return c, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UpdateWebhookResult(uuid string, result *model.WebhookResult) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	if result.Timestamp == 0 { result.Timestamp = time.Now().Unix() }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_webhook_log SET webhook_result = $1 WHERE uuid = $2
+`, pfx), result, uuid)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetWebhookResultByUUID(uuid string) (*model.WebhookResult, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT webhook_result
+FROM %s_webhook_log
+WHERE uuid = $1
+`, pfx), uuid)
+	var webhookRes *model.WebhookResult
+	err := stmt.Scan(&webhookRes)
+	if err != nil { return nil, err }
+	return webhookRes, nil
+}
+
+
+
+func (dbif *PostgresGitusDatabaseInterface) RegisterAccessToken(username string, name string, tokenHash string, scope []string, expireTime int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	var verdict int
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+SELECT 1 FROM %s_user_access_token WHERE user_name = $1 AND token_name = $2
+`, pfx), username, name).Scan(&verdict)
+	if err == nil { return db.ErrEntityAlreadyExists }
+	if !errors.Is(err, pgx.ErrNoRows) { return err }
+	var expire *time.Time = nil
+	if expireTime > 0 {
+		t := time.Unix(expireTime, 0)
+		expire = &t
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_user_access_token(user_name, token_name, token_hash, token_scope, token_create_time, token_expire_time)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, pfx), username, name, tokenHash, model.SerializeAccessTokenScope(scope), time.Now(), expire)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllAccessTokenByUsername(username string) ([]*model.GitusAccessToken, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT token_name, token_hash, token_scope, token_create_time, token_expire_time
+FROM %s_user_access_token
+WHERE user_name = $1
+ORDER BY token_create_time DESC
+`, pfx), username)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.GitusAccessToken, 0)
+	for stmt.Next() {
+		var name, hash, scope string
+		var createTime time.Time
+		var expireTime *time.Time
+		err := stmt.Scan(&name, &hash, &scope, &createTime, &expireTime)
+		if err != nil { return nil, err }
+		var expire int64 = 0
+		if expireTime != nil { expire = expireTime.Unix() }
+		res = append(res, &model.GitusAccessToken{
+			UserName: username,
+			Name: name,
+			TokenHash: hash,
+			Scope: model.ParseAccessTokenScope(scope),
+			CreateTime: createTime.Unix(),
+			ExpireTime: expire,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAccessTokenByHash(tokenHash string) (*model.GitusAccessToken, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT user_name, token_name, token_scope, token_create_time, token_expire_time
+FROM %s_user_access_token
+WHERE token_hash = $1
+`, pfx), tokenHash)
+	var username, name, scope string
+	var createTime time.Time
+	var expireTime *time.Time
+	err := stmt.Scan(&username, &name, &scope, &createTime, &expireTime)
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	var expire int64 = 0
+	if expireTime != nil { expire = expireTime.Unix() }
+	return &model.GitusAccessToken{
+		UserName: username,
+		Name: name,
+		TokenHash: tokenHash,
+		Scope: model.ParseAccessTokenScope(scope),
+		CreateTime: createTime.Unix(),
+		ExpireTime: expire,
+	}, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RevokeAccessToken(username string, name string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_user_access_token
+WHERE user_name = $1 AND token_name = $2
+`, pfx), username, name)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllBranchProtectionRule(ns string, name string) ([]*model.BranchProtectionRule, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT pattern, disallow_force_push, disallow_deletion, allowed_pusher, require_pull_request
+FROM %s_branch_protection
+WHERE repo_namespace = $1 AND repo_name = $2
+ORDER BY pattern ASC
+`, pfx), ns, name)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.BranchProtectionRule, 0)
+	for stmt.Next() {
+		var pattern, allowedPusher string
+		var disallowForcePush, disallowDeletion, requirePullRequest bool
+		err := stmt.Scan(&pattern, &disallowForcePush, &disallowDeletion, &allowedPusher, &requirePullRequest)
+		if err != nil { return nil, err }
+		res = append(res, &model.BranchProtectionRule{
+			Pattern: pattern,
+			DisallowForcePush: disallowForcePush,
+			DisallowDeletion: disallowDeletion,
+			AllowedPusher: model.ParseUsernameList(allowedPusher),
+			RequirePullRequest: requirePullRequest,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetBranchProtectionRule(ns string, name string, rule *model.BranchProtectionRule) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_branch_protection(repo_namespace, repo_name, pattern, disallow_force_push, disallow_deletion, allowed_pusher, require_pull_request)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (repo_namespace, repo_name, pattern) DO UPDATE
+SET disallow_force_push = $4, disallow_deletion = $5, allowed_pusher = $6, require_pull_request = $7
+`, pfx), ns, name, rule.Pattern, rule.DisallowForcePush, rule.DisallowDeletion, model.SerializeUsernameList(rule.AllowedPusher), rule.RequirePullRequest)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) DeleteBranchProtectionRule(ns string, name string, pattern string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_branch_protection
+WHERE repo_namespace = $1 AND repo_name = $2 AND pattern = $3
+`, pfx), ns, name, pattern)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetPullRequestSetting(ns string, name string) (*model.PullRequestSetting, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var requiredApprovalCount int
+	var requiredReviewer, allowedMergeStrategy string
+	var requiredStatusContext *string
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT required_approval_count, required_reviewer, allowed_merge_strategy, required_status_context
+FROM %s_pull_request_setting
+WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name).Scan(&requiredApprovalCount, &requiredReviewer, &allowedMergeStrategy, &requiredStatusContext)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &model.PullRequestSetting{
+			RequiredApprovalCount: 0,
+			RequiredReviewer: make([]string, 0),
+			AllowedMergeStrategy: make([]int, 0),
+			RequiredStatusContext: make([]string, 0),
+		}, nil
+	}
+	if err != nil { return nil, err }
+	res := &model.PullRequestSetting{
+		RequiredApprovalCount: requiredApprovalCount,
+		RequiredReviewer: model.ParseUsernameList(requiredReviewer),
+		AllowedMergeStrategy: model.ParseMergeStrategyList(allowedMergeStrategy),
+		RequiredStatusContext: make([]string, 0),
+	}
+	if requiredStatusContext != nil {
+		res.RequiredStatusContext = model.ParseCommitStatusContextList(*requiredStatusContext)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetPullRequestSetting(ns string, name string, setting *model.PullRequestSetting) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_pull_request_setting(repo_namespace, repo_name, required_approval_count, required_reviewer, allowed_merge_strategy, required_status_context)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (repo_namespace, repo_name) DO UPDATE
+SET required_approval_count = $3, required_reviewer = $4, allowed_merge_strategy = $5, required_status_context = $6
+`, pfx), ns, name, setting.RequiredApprovalCount, model.SerializeUsernameList(setting.RequiredReviewer), model.SerializeMergeStrategyList(setting.AllowedMergeStrategy), strings.Join(setting.RequiredStatusContext, ","))
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) ReviewPullRequest(review *model.PullRequestReview) (*model.PullRequestEvent, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return nil, err }
+	defer tx.Rollback(ctx)
+	t := time.Now()
+	review.Timestamp = t.Unix()
+	review.Dismissed = false
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_pull_request_review(pull_request_absid, reviewer, review_state, review_summary, review_commit_id, review_timestamp, review_dismissed)
+VALUES ($1, $2, $3, $4, $5, $6, false)
+`, pfx), review.PRAbsId, review.Reviewer, review.State, review.Summary, review.CommitId, t)
+	if err != nil { return nil, err }
+	contentBytes, _ := json.Marshal(review)
+	contentString := string(contentBytes)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_pull_request_event(pull_request_absid, event_type, event_timestamp, event_author, event_content)
+VALUES ($1, $2, $3, $4, $5)
+`, pfx), review.PRAbsId, model.PULL_REQUEST_EVENT_REVIEW, t, review.Reviewer, contentString)
+	if err != nil { return nil, err }
+	err = tx.Commit(ctx)
+	if err != nil { return nil, err }
+	return &model.PullRequestEvent{
+		PRAbsId: review.PRAbsId,
+		EventType: model.PULL_REQUEST_EVENT_REVIEW,
+		EventTimestamp: t.Unix(),
+		EventAuthor: review.Reviewer,
+		EventContent: contentString,
+	}, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllPullRequestReview(absId int64) ([]*model.PullRequestReview, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT reviewer, review_state, review_summary, review_commit_id, review_timestamp, review_dismissed
+FROM %s_pull_request_review
+WHERE pull_request_absid = $1
+ORDER BY review_timestamp ASC
+`, pfx), absId)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.PullRequestReview, 0)
+	for stmt.Next() {
+		var reviewer, summary, commitId string
+		var state int
+		var timestamp time.Time
+		var dismissed bool
+		err := stmt.Scan(&reviewer, &state, &summary, &commitId, &timestamp, &dismissed)
+		if err != nil { return nil, err }
+		res = append(res, &model.PullRequestReview{
+			PRAbsId: absId,
+			Reviewer: reviewer,
+			State: state,
+			Summary: summary,
+			CommitId: commitId,
+			Timestamp: timestamp.Unix(),
+			Dismissed: dismissed,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UpdatePullRequestProviderBranch(providerNamespace string, providerName string, providerBranch string, author string, commitId string) ([]int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return nil, err }
+	defer tx.Rollback(ctx)
+	t := time.Now()
+	r, err := tx.Query(ctx, fmt.Sprintf(`
+INSERT INTO %s_pull_request_event(pull_request_absid, event_type, event_timestamp, event_author, event_content)
+SELECT pull_request_absid, $1, $2, $3, $4 FROM %s_pull_request
+WHERE provider_namespace = $5 AND provider_name = $6 AND provider_branch = $7 AND pull_request_status = $8
+RETURNING pull_request_absid
+`, pfx, pfx), model.PULL_REQUEST_EVENT_UPDATE_ON_BRANCH, t, author, commitId, providerNamespace, providerName, providerBranch, model.PULL_REQUEST_OPEN)
+	if err != nil { return nil, err }
+	res := make([]int64, 0)
+	for r.Next() {
+		var absId int64
+		err = r.Scan(&absId)
+		if err != nil { r.Close(); return nil, err }
+		res = append(res, absId)
+	}
+	r.Close()
+	if r.Err() != nil { return nil, r.Err() }
+	if len(res) <= 0 { return res, nil }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_pull_request_review SET review_dismissed = true
+WHERE review_state = $1 AND pull_request_absid = ANY($2)
+`, pfx), model.PULL_REQUEST_REVIEW_APPROVE, res)
+	if err != nil { return nil, err }
+	err = tx.Commit(ctx)
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+// fills the labels, assignees & milestone of the issues.
+func (dbif *PostgresGitusDatabaseInterface) fillIssueMetadata(issueList []*model.Issue) error {
+	if len(issueList) <= 0 { return nil }
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	m := make(map[int64]*model.Issue, len(issueList))
+	idList := make([]int64, 0, len(issueList))
+	for _, k := range issueList {
+		k.IssueLabel = make([]string, 0)
+		k.IssueAssignee = make([]string, 0)
+		k.IssueMilestone = ""
+		m[k.IssueAbsId] = k
+		idList = append(idList, k.IssueAbsId)
+	}
+	for i, q := range []string{
+		"SELECT issue_absid, label_name FROM %s_issue_label_assoc WHERE issue_absid = ANY($1) ORDER BY label_name ASC",
+		"SELECT issue_absid, username FROM %s_issue_assignee WHERE issue_absid = ANY($1) ORDER BY username ASC",
+		"SELECT issue_absid, milestone_title FROM %s_issue_milestone_assoc WHERE issue_absid = ANY($1)",
+	} {
+		rs, err := dbif.pool.Query(ctx, fmt.Sprintf(q, pfx), idList)
+		if err != nil { return err }
+		for rs.Next() {
+			var absId int64
+			var v string
+			err = rs.Scan(&absId, &v)
+			if err != nil { rs.Close(); return err }
+			issue, ok := m[absId]
+			if !ok { continue }
+			switch i {
+			case 0: issue.IssueLabel = append(issue.IssueLabel, v)
+			case 1: issue.IssueAssignee = append(issue.IssueAssignee, v)
+			case 2: issue.IssueMilestone = v
+			}
+		}
+		rs.Close()
+	}
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllRepositoryIssueLabel(ns string, name string) ([]*model.IssueLabel, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT label_name, label_color, label_description
+FROM %s_issue_label
+WHERE repo_namespace = $1 AND repo_name = $2
+ORDER BY label_name ASC
+`, pfx), ns, name)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.IssueLabel, 0)
+	for stmt.Next() {
+		var labelName, color, description string
+		err = stmt.Scan(&labelName, &color, &description)
+		if err != nil { return nil, err }
+		res = append(res, &model.IssueLabel{
+			Name: labelName,
+			Color: color,
+			Description: description,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetRepositoryIssueLabel(ns string, name string, label *model.IssueLabel) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_issue_label(repo_namespace, repo_name, label_name, label_color, label_description)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (repo_namespace, repo_name, label_name) DO UPDATE
+SET label_color = EXCLUDED.label_color, label_description = EXCLUDED.label_description
+`, pfx), ns, name, label.Name, label.Color, label.Description)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) DeleteRepositoryIssueLabel(ns string, name string, labelName string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_issue_label_assoc
+WHERE label_name = $1 AND issue_absid IN (SELECT issue_absid FROM %s_issue WHERE repo_namespace = $2 AND repo_name = $3)
+`, pfx, pfx), labelName, ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_issue_label
+WHERE repo_namespace = $1 AND repo_name = $2 AND label_name = $3
+`, pfx), ns, name, labelName)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllRepositoryIssueMilestone(ns string, name string) ([]*model.IssueMilestone, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT milestone_title, milestone_description, milestone_due, milestone_closed
+FROM %s_issue_milestone
+WHERE repo_namespace = $1 AND repo_name = $2
+ORDER BY milestone_closed ASC, milestone_due ASC NULLS LAST, milestone_title ASC
+`, pfx), ns, name)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.IssueMilestone, 0)
+	for stmt.Next() {
+		var title, description string
+		var due sql.NullTime
+		var closed bool
+		err = stmt.Scan(&title, &description, &due, &closed)
+		if err != nil { return nil, err }
+		var dueTime int64 = 0
+		if due.Valid { dueTime = due.Time.Unix() }
+		res = append(res, &model.IssueMilestone{
+			Title: title,
+			Description: description,
+			DueTime: dueTime,
+			Closed: closed,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetRepositoryIssueMilestone(ns string, name string, milestone *model.IssueMilestone) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	due := sql.NullTime{}
+	if milestone.DueTime > 0 { due = sql.NullTime{ Time: time.Unix(milestone.DueTime, 0), Valid: true } }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_issue_milestone(repo_namespace, repo_name, milestone_title, milestone_description, milestone_due, milestone_closed)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (repo_namespace, repo_name, milestone_title) DO UPDATE
+SET milestone_description = EXCLUDED.milestone_description, milestone_due = EXCLUDED.milestone_due, milestone_closed = EXCLUDED.milestone_closed
+`, pfx), ns, name, milestone.Title, milestone.Description, due, milestone.Closed)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) DeleteRepositoryIssueMilestone(ns string, name string, title string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_issue_milestone_assoc
+WHERE milestone_title = $1 AND issue_absid IN (SELECT issue_absid FROM %s_issue WHERE repo_namespace = $2 AND repo_name = $3)
+`, pfx, pfx), title, ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_issue_milestone
+WHERE repo_namespace = $1 AND repo_name = $2 AND milestone_title = $3
+`, pfx), ns, name, title)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+// runs `query` (which should have 2 parameters: the issue abs id &
+// the value) & records an issue event if any row is affected.
+func (dbif *PostgresGitusDatabaseInterface) updateIssueAssoc(query string, issueAbsId int64, value string, eType int, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	r, err := tx.Exec(ctx, query, issueAbsId, value)
+	if err != nil { return err }
+	if r.RowsAffected() <= 0 { return nil }
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_issue_event(issue_absid, issue_event_type, issue_event_time, issue_event_author, issue_event_content)
+VALUES ($1, $2, $3, $4, $5)
+`, pfx), issueAbsId, eType, time.Now(), author, value)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) AddIssueLabel(issueAbsId int64, labelName string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+INSERT INTO %s_issue_label_assoc(issue_absid, label_name) VALUES ($1, $2) ON CONFLICT DO NOTHING
+`, pfx), issueAbsId, labelName, model.EVENT_LABEL_ADDED, author)
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RemoveIssueLabel(issueAbsId int64, labelName string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+DELETE FROM %s_issue_label_assoc WHERE issue_absid = $1 AND label_name = $2
+`, pfx), issueAbsId, labelName, model.EVENT_LABEL_REMOVED, author)
+}
+
+func (dbif *PostgresGitusDatabaseInterface) AddIssueAssignee(issueAbsId int64, username string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+INSERT INTO %s_issue_assignee(issue_absid, username) VALUES ($1, $2) ON CONFLICT DO NOTHING
+`, pfx), issueAbsId, username, model.EVENT_ASSIGNEE_ADDED, author)
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RemoveIssueAssignee(issueAbsId int64, username string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+DELETE FROM %s_issue_assignee WHERE issue_absid = $1 AND username = $2
+`, pfx), issueAbsId, username, model.EVENT_ASSIGNEE_REMOVED, author)
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetIssueMilestone(issueAbsId int64, title string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var oldTitle string
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT milestone_title FROM %s_issue_milestone_assoc WHERE issue_absid = $1
+`, pfx), issueAbsId).Scan(&oldTitle)
+	if err != nil && err != pgx.ErrNoRows { return err }
+	if oldTitle == title { return nil }
+	if len(title) <= 0 {
+		return dbif.updateIssueAssoc(fmt.Sprintf(`
+DELETE FROM %s_issue_milestone_assoc WHERE issue_absid = $1 AND milestone_title = $2
+`, pfx), issueAbsId, oldTitle, model.EVENT_MILESTONE_REMOVED, author)
+	}
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+INSERT INTO %s_issue_milestone_assoc(issue_absid, milestone_title) VALUES ($1, $2)
+ON CONFLICT (issue_absid) DO UPDATE SET milestone_title = EXCLUDED.milestone_title
+`, pfx), issueAbsId, title, model.EVENT_MILESTONE_SET, author)
+}
+
+func (dbif *PostgresGitusDatabaseInterface) AddIssueReferenceEvent(issueAbsId int64, eType int, author string, content string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_issue_event(issue_absid, issue_event_type, issue_event_time, issue_event_author, issue_event_content)
+SELECT $1::BIGINT, $2::SMALLINT, $3::TIMESTAMP, $4::VARCHAR, $5::TEXT
+WHERE NOT EXISTS (SELECT 1 FROM %s_issue_event WHERE issue_absid = $1 AND issue_event_type = $2 AND issue_event_content = $5)
+`, pfx, pfx), issueAbsId, eType, time.Now(), author, content)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) AddPullRequestReferenceEvent(absId int64, author string, content string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	tx, err := dbif.pool.Begin(ctx)
+	if err != nil { return err }
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_pull_request_event(pull_request_absid, event_type, event_timestamp, event_author, event_content)
+SELECT $1::BIGINT, $2::SMALLINT, $3::TIMESTAMP, $4::VARCHAR, $5::TEXT
+WHERE NOT EXISTS (SELECT 1 FROM %s_pull_request_event WHERE pull_request_absid = $1 AND event_type = $2 AND event_content = $5)
+`, pfx, pfx), absId, model.PULL_REQUEST_EVENT_REFERENCED, time.Now(), author, content)
+	if err != nil { return err }
+	err = tx.Commit(ctx)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) NewNotification(n *model.Notification) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	t := time.Now()
+	n.Timestamp = t.Unix()
+	n.Read = false
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO %s_notification(username, notification_type, actor, title, content, link, notification_timestamp, is_read)
+VALUES ($1, $2, $3, $4, $5, $6, $7, false)
+RETURNING notification_absid
+`, pfx), n.Username, n.Type, n.Actor, n.Title, n.Content, n.Link, t).Scan(&n.Id)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CountNotification(username string, unreadOnly bool) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	cond := ""
+	if unreadOnly { cond = " AND NOT is_read" }
+	var res int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_notification WHERE username = $1%s
+`, pfx, cond), username).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetNotificationPaginated(username string, unreadOnly bool, pageNum int64, pageSize int64) ([]*model.Notification, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	cond := ""
+	if unreadOnly { cond = " AND NOT is_read" }
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT notification_absid, notification_type, actor, title, content, link, notification_timestamp, is_read
+FROM %s_notification
+WHERE username = $1%s
+ORDER BY notification_absid DESC LIMIT $2 OFFSET $3
+`, pfx, cond), username, pageSize, pageNum*pageSize)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.Notification, 0)
+	for stmt.Next() {
+		var id int64
+		var nType int
+		var actor, title, content, link string
+		var timestamp time.Time
+		var isRead bool
+		err = stmt.Scan(&id, &nType, &actor, &title, &content, &link, &timestamp, &isRead)
+		if err != nil { return nil, err }
+		res = append(res, &model.Notification{
+			Id: id,
+			Username: username,
+			Type: nType,
+			Actor: actor,
+			Title: title,
+			Content: content,
+			Link: link,
+			Timestamp: timestamp.Unix(),
+			Read: isRead,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) MarkNotificationRead(username string, id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_notification SET is_read = true
+WHERE username = $1 AND notification_absid = $2
+`, pfx), username, id)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) MarkAllNotificationRead(username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_notification SET is_read = true
+WHERE username = $1
+`, pfx), username)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) DeleteAllReadNotification(username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_notification
+WHERE username = $1 AND is_read
+`, pfx), username)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetNotificationSetting(username string) (*model.NotificationSetting, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	res := model.DefaultNotificationSetting()
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT setting FROM %s_user_notification_setting WHERE username = $1
+`, pfx), username).Scan(res)
+	if errors.Is(err, pgx.ErrNoRows) { return model.DefaultNotificationSetting(), nil }
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetNotificationSetting(username string, setting *model.NotificationSetting) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_user_notification_setting(username, setting)
+VALUES ($1, $2)
+ON CONFLICT (username) DO UPDATE SET setting = $2
+`, pfx), username, setting)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) WatchRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repo_watch(repo_namespace, repo_name, username)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`, pfx), ns, name, username)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UnwatchRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_watch
+WHERE repo_namespace = $1 AND repo_name = $2 AND username = $3
+`, pfx), ns, name, username)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) IsWatchingRepository(ns string, name string, username string) (bool, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var c int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_repo_watch
+WHERE repo_namespace = $1 AND repo_name = $2 AND username = $3
+`, pfx), ns, name, username).Scan(&c)
+	if err != nil { return false, err }
+	return c > 0, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllRepositoryWatcher(ns string, name string) ([]string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT username FROM %s_repo_watch
+WHERE repo_namespace = $1 AND repo_name = $2
+ORDER BY username ASC
+`, pfx), ns, name)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]string, 0)
+	for stmt.Next() {
+		var s string
+		err = stmt.Scan(&s)
+		if err != nil { return nil, err }
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) StarRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repo_star(repo_namespace, repo_name, username, star_time)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+`, pfx), ns, name, username, time.Now())
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UnstarRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_repo_star
+WHERE repo_namespace = $1 AND repo_name = $2 AND username = $3
+`, pfx), ns, name, username)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) IsStarringRepository(ns string, name string, username string) (bool, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var c int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_repo_star
+WHERE repo_namespace = $1 AND repo_name = $2 AND username = $3
+`, pfx), ns, name, username).Scan(&c)
+	if err != nil { return false, err }
+	return c > 0, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RecordRepositoryPush(ns string, name string, pushTime int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_repo_activity(repo_namespace, repo_name, last_push_time)
+VALUES ($1, $2, $3)
+ON CONFLICT (repo_namespace, repo_name) DO UPDATE SET last_push_time = EXCLUDED.last_push_time
+`, pfx), ns, name, time.Unix(pushTime, 0))
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllVisibleRepositoryExplorePaginated(username string, sortBy string, pageNum int64, pageSize int64) ([]*model.Repository, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var order string
+	switch sortBy {
+	case model.REPO_EXPLORE_SORT_STAR:
+		order = "star_count DESC, repo_absid DESC"
+	case model.REPO_EXPLORE_SORT_NEWEST:
+		order = "repo_absid DESC"
+	default:
+		order = "last_push_time DESC, repo_absid DESC"
+	}
+	// the visibility part is the same as `GetAllVisibleRepositoryPaginated`.
+	cond := "repo_status = 1"
+	args := []any{pageSize, pageNum*pageSize}
+	if len(username) > 0 {
+		cond = "repo_status = 1 OR repo_owner = $3 OR repo_acl->'acl' ? $3"
+		args = append(args, username)
+	}
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT repo_type, repo_namespace, repo_name, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_webhook, repo_absid,
+  (SELECT COUNT(*) FROM %s_repo_star st WHERE st.repo_namespace = repo.repo_namespace AND st.repo_name = repo.repo_name) AS star_count,
+  (SELECT COUNT(*) FROM %s_repo_watch wt WHERE wt.repo_namespace = repo.repo_namespace AND wt.repo_name = repo.repo_name) AS watcher_count,
+  COALESCE((SELECT EXTRACT(EPOCH FROM act.last_push_time)::BIGINT FROM %s_repo_activity act WHERE act.repo_namespace = repo.repo_namespace AND act.repo_name = repo.repo_name), 0) AS last_push_time
+FROM %s_repository repo
+WHERE %s
+ORDER BY %s LIMIT $1 OFFSET $2
+`, pfx, pfx, pfx, pfx, cond, order), args...)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.Repository, 0)
+	var ns, name, description, owner, acl, forkOriginNs, forkOriginName, webhookstr string
+	var rType, rStatus int
+	var rowid, starCount, watcherCount, lastPushTime int64
+	for stmt.Next() {
+		err = stmt.Scan(&rType, &ns, &name, &description, &owner, &acl, &rStatus, &forkOriginNs, &forkOriginName, &webhookstr, &rowid, &starCount, &watcherCount, &lastPushTime)
+		if err != nil { return nil, err }
+		a, err := model.ParseACL(acl)
+		if err != nil { return nil, err }
+		p := path.Join(dbif.config.GitRoot, ns, name)
+		m, err := model.CreateLocalRepository(uint8(rType), ns, name, p)
+		if err != nil { return nil, err }
+		webhookobj, err := model.ParseWebHookConfig(webhookstr)
+		if err != nil { return nil, err }
+		res = append(res, &model.Repository{
+			AbsId: rowid,
+			Namespace: ns,
+			Name: name,
+			Description: description,
+			Owner: owner,
+			AccessControlList: a,
+			Status: model.GitusRepositoryStatus(rStatus),
+			Type: uint8(rType),
+			ForkOriginNamespace: forkOriginNs,
+			ForkOriginName: forkOriginName,
+			Repository: m,
+			WebHookConfig: webhookobj,
+			StarCount: starCount,
+			WatcherCount: watcherCount,
+			LastPushTime: lastPushTime,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllAdminUsername() ([]string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	stmt, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT user_name FROM %s_user
+WHERE user_status = $1 OR user_status = $2
+ORDER BY user_id ASC
+`, pfx), model.ADMIN, model.SUPER_ADMIN)
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]string, 0)
+	for stmt.Next() {
+		var s string
+		err = stmt.Scan(&s)
+		if err != nil { return nil, err }
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) EnqueueJob(job *model.Job) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	t := time.Now()
+	if job.NextRunTime <= 0 { job.NextRunTime = t.Unix() }
+	if job.MaxAttempt <= 0 { job.MaxAttempt = model.DEFAULT_JOB_MAX_ATTEMPT }
+	job.Status = model.JOB_PENDING
+	job.Attempt = 0
+	job.CreateTime = t.Unix()
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO %s_job(job_type, payload, job_status, attempt, max_attempt, last_error, next_run_time, create_time)
+VALUES ($1, $2, $3, 0, $4, '', $5, $6)
+RETURNING job_absid
+`, pfx), job.Type, job.Payload, model.JOB_PENDING, job.MaxAttempt, time.Unix(job.NextRunTime, 0), t).Scan(&job.Id)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) ClaimJob() (*model.Job, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var job model.Job
+	var nextRunTime, createTime time.Time
+	// SKIP LOCKED so that concurrent workers don't claim the same
+	// job.
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+UPDATE %s_job SET job_status = $1, attempt = attempt + 1
+WHERE job_absid = (
+    SELECT job_absid FROM %s_job
+    WHERE job_status = $2 AND next_run_time <= $3
+    ORDER BY next_run_time ASC, job_absid ASC LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING job_absid, job_type, payload, attempt, max_attempt, last_error, next_run_time, create_time
+`, pfx, pfx), model.JOB_RUNNING, model.JOB_PENDING, time.Now()).Scan(&job.Id, &job.Type, &job.Payload, &job.Attempt, &job.MaxAttempt, &job.LastError, &nextRunTime, &createTime)
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	job.Status = model.JOB_RUNNING
+	job.NextRunTime = nextRunTime.Unix()
+	job.CreateTime = createTime.Unix()
+	return &job, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CompleteJob(id int64) error {
+	return dbif.DeleteJob(id)
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RescheduleJob(id int64, lastError string, nextRunTime int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_job SET job_status = $1, last_error = $2, next_run_time = $3
+WHERE job_absid = $4 AND job_status = $5
+`, pfx), model.JOB_PENDING, lastError, time.Unix(nextRunTime, 0), id, model.JOB_RUNNING)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) FailJob(id int64, lastError string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_job SET job_status = $1, last_error = $2
+WHERE job_absid = $3 AND job_status = $4
+`, pfx), model.JOB_FAILED, lastError, id, model.JOB_RUNNING)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) RetryJob(id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_job SET job_status = $1, attempt = 0, next_run_time = $2
+WHERE job_absid = $3 AND job_status = $4
+`, pfx), model.JOB_PENDING, time.Now(), id, model.JOB_FAILED)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) DeleteJob(id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_job WHERE job_absid = $1
+`, pfx), id)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) ResetRunningJob() error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_job SET job_status = $1 WHERE job_status = $2
+`, pfx), model.JOB_PENDING, model.JOB_RUNNING)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CountJob(status int) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	cond := ""
+	args := make([]any, 0)
+	if status != 0 {
+		cond = " WHERE job_status = $1"
+		args = append(args, status)
+	}
+	var res int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_job%s
+`, pfx, cond), args...).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetJobPaginated(status int, pageNum int64, pageSize int64) ([]*model.Job, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var stmt pgx.Rows
+	var err error
+	if status != 0 {
+		stmt, err = dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT job_absid, job_type, payload, job_status, attempt, max_attempt, last_error, next_run_time, create_time
+FROM %s_job WHERE job_status = $1
+ORDER BY job_absid ASC LIMIT $2 OFFSET $3
+`, pfx), status, pageSize, pageNum*pageSize)
+	} else {
+		stmt, err = dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT job_absid, job_type, payload, job_status, attempt, max_attempt, last_error, next_run_time, create_time
+FROM %s_job
+ORDER BY job_absid ASC LIMIT $1 OFFSET $2
+`, pfx), pageSize, pageNum*pageSize)
+	}
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res := make([]*model.Job, 0)
+	for stmt.Next() {
+		var job model.Job
+		var nextRunTime, createTime time.Time
+		err = stmt.Scan(&job.Id, &job.Type, &job.Payload, &job.Status, &job.Attempt, &job.MaxAttempt, &job.LastError, &nextRunTime, &createTime)
+		if err != nil { return nil, err }
+		job.NextRunTime = nextRunTime.Unix()
+		job.CreateTime = createTime.Unix()
+		res = append(res, &job)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) NewWebhookDelivery(d *model.WebhookDelivery) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	res := new(model.WebhookResult)
+	res.Status = model.WEBHOOK_RESULT_UNDEFINED
+	res.ReportUUID = d.ReportUUID
+	res.UUID = d.UUID
+	res.RepoNamespace = d.RepoNamespace
+	res.RepoName = d.RepoName
+	if d.CreateTime <= 0 { d.CreateTime = time.Now().Unix() }
+	if d.Status == 0 { d.Status = model.WEBHOOK_DELIVERY_PENDING }
+	d.Result = res
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_webhook_log(uuid, repo_namespace, repo_name, commit_id, webhook_result, ref_name, target_url, request_header, request_payload, response_status, response_body, delivery_error, duration, delivery_status, attempt, create_time, delivery_time, redelivery_of, webhook_id, event)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)
+`, pfx), d.UUID, d.RepoNamespace, d.RepoName, d.CommitId, res, d.RefName, d.TargetURL, d.RequestHeader, d.RequestPayload, d.ResponseStatus, d.ResponseBody, d.Error, d.Duration, d.Status, d.Attempt, time.Unix(d.CreateTime, 0), time.Unix(d.DeliveryTime, 0), d.RedeliveryOf, d.WebhookId, d.Event)
+	return err
+}
+
+const postgresWebhookDeliveryColumn = "uuid, repo_namespace, repo_name, commit_id, webhook_result, ref_name, target_url, request_header, request_payload, response_status, response_body, delivery_error, duration, delivery_status, attempt, create_time, delivery_time, redelivery_of, webhook_id, event"
+
+func scanPostgresWebhookDelivery(r pgx.Row) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	var createTime, deliveryTime time.Time
+	err := r.Scan(&d.UUID, &d.RepoNamespace, &d.RepoName, &d.CommitId, &d.Result, &d.RefName, &d.TargetURL, &d.RequestHeader, &d.RequestPayload, &d.ResponseStatus, &d.ResponseBody, &d.Error, &d.Duration, &d.Status, &d.Attempt, &createTime, &deliveryTime, &d.RedeliveryOf, &d.WebhookId, &d.Event)
+	if err != nil { return nil, err }
+	d.CreateTime = createTime.Unix()
+	d.DeliveryTime = deliveryTime.Unix()
+	if d.Result != nil { d.ReportUUID = d.Result.ReportUUID }
+	return &d, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetWebhookDelivery(uuid string) (*model.WebhookDelivery, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	res, err := scanPostgresWebhookDelivery(dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT %s FROM %s_webhook_log WHERE uuid = $1
+`, postgresWebhookDeliveryColumn, pfx), uuid))
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UpdateWebhookDelivery(d *model.WebhookDelivery) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_webhook_log
+SET request_header = $1, response_status = $2, response_body = $3, delivery_error = $4, duration = $5, delivery_status = $6, attempt = $7, delivery_time = $8
+WHERE uuid = $9
+`, pfx), d.RequestHeader, d.ResponseStatus, d.ResponseBody, d.Error, d.Duration, d.Status, d.Attempt, time.Unix(d.DeliveryTime, 0), d.UUID)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CountWebhookDelivery(repoNs string, repoName string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var res int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_webhook_log WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), repoNs, repoName).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetWebhookDeliveryPaginated(repoNs string, repoName string, pageNum int64, pageSize int64) ([]*model.WebhookDelivery, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	r, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT %s FROM %s_webhook_log
+WHERE repo_namespace = $1 AND repo_name = $2
+ORDER BY create_time DESC LIMIT $3 OFFSET $4
+`, postgresWebhookDeliveryColumn, pfx), repoNs, repoName, pageSize, pageNum*pageSize)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.WebhookDelivery, 0)
+	for r.Next() {
+		d, err := scanPostgresWebhookDelivery(r)
+		if err != nil { return nil, err }
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) NewWebhook(w *model.Webhook) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	if w.CreateTime <= 0 { w.CreateTime = time.Now().Unix() }
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO %s_webhook(webhook_namespace, webhook_repo_name, target_url, secret, payload_type, event_list, branch_filter, enabled, create_time)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+RETURNING webhook_absid
+`, pfx), w.Namespace, w.RepoName, w.TargetURL, w.Secret, w.PayloadType, strings.Join(w.EventList, ","), strings.Join(w.BranchFilter, ","), w.Enable, time.Unix(w.CreateTime, 0)).Scan(&w.Id)
+	if err != nil { return 0, err }
+	return w.Id, nil
+}
+
+func scanPostgresWebhook(r pgx.Row) (*model.Webhook, error) {
+	var w model.Webhook
+	var eventList, branchFilter string
+	var createTime time.Time
+	err := r.Scan(&w.Id, &w.Namespace, &w.RepoName, &w.TargetURL, &w.Secret, &w.PayloadType, &eventList, &branchFilter, &w.Enable, &createTime)
+	if err != nil { return nil, err }
+	w.EventList = model.ParseWebhookList(eventList)
+	w.BranchFilter = model.ParseWebhookList(branchFilter)
+	w.CreateTime = createTime.Unix()
+	return &w, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetWebhook(id int64) (*model.Webhook, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	res, err := scanPostgresWebhook(dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT webhook_absid, webhook_namespace, webhook_repo_name, target_url, secret, payload_type, event_list, branch_filter, enabled, create_time
+FROM %s_webhook WHERE webhook_absid = $1
+`, pfx), id))
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UpdateWebhook(w *model.Webhook) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_webhook
+SET target_url = $1, secret = $2, payload_type = $3, event_list = $4, branch_filter = $5, enabled = $6
+WHERE webhook_absid = $7
+`, pfx), w.TargetURL, w.Secret, w.PayloadType, strings.Join(w.EventList, ","), strings.Join(w.BranchFilter, ","), w.Enable, w.Id)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) DeleteWebhook(id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+DELETE FROM %s_webhook WHERE webhook_absid = $1
+`, pfx), id)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllWebhook(ns string, repoName string) ([]*model.Webhook, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	r, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT webhook_absid, webhook_namespace, webhook_repo_name, target_url, secret, payload_type, event_list, branch_filter, enabled, create_time
+FROM %s_webhook WHERE webhook_namespace = $1 AND webhook_repo_name = $2
+ORDER BY webhook_absid ASC
+`, pfx), ns, repoName)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.Webhook, 0)
+	for r.Next() {
+		w, err := scanPostgresWebhook(r)
+		if err != nil { return nil, err }
+		res = append(res, w)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) SetCommitStatus(s *model.CommitStatus) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	t := time.Now()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s_commit_status(repo_namespace, repo_name, commit_id, status_context, status_state, target_url, status_description, status_creator, create_time, update_time)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9)
+ON CONFLICT (repo_namespace, repo_name, commit_id, status_context) DO UPDATE
+SET status_state = $5, target_url = $6, status_description = $7, status_creator = $8, update_time = $9
+`, pfx), s.RepoNamespace, s.RepoName, s.CommitId, s.Context, s.State, s.TargetURL, s.Description, s.Creator, t)
+	if err != nil { return err }
+	s.UpdateTime = t.Unix()
+	return nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetAllCommitStatus(ns string, name string, commitId string) ([]*model.CommitStatus, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	r, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT status_context, status_state, target_url, status_description, status_creator, create_time, update_time
+FROM %s_commit_status
+WHERE repo_namespace = $1 AND repo_name = $2 AND commit_id = $3
+ORDER BY status_context ASC
+`, pfx), ns, name, commitId)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.CommitStatus, 0)
+	for r.Next() {
+		s := &model.CommitStatus{RepoNamespace: ns, RepoName: name, CommitId: commitId}
+		var createTime, updateTime time.Time
+		err = r.Scan(&s.Context, &s.State, &s.TargetURL, &s.Description, &s.Creator, &createTime, &updateTime)
+		if err != nil { return nil, err }
+		s.CreateTime = createTime.Unix()
+		s.UpdateTime = updateTime.Unix()
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) NewCIRun(r *model.CIRun) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	t := time.Now()
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO %s_ci_run(repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, run_status, run_log, create_time, start_time, end_time)
+VALUES ($1, $2, $3, $4, $5, $6, $7, '', $8, NULL, NULL)
+RETURNING run_absid
+`, pfx), r.RepoNamespace, r.RepoName, r.CommitId, r.RefName, r.JobName, r.Trigger, model.CI_RUN_QUEUED, t).Scan(&r.Id)
+	if err != nil { return err }
+	r.Status = model.CI_RUN_QUEUED
+	r.CreateTime = t.Unix()
+	return nil
+}
+
+func scanCIRun(row pgx.Row, withLog bool) (*model.CIRun, error) {
+	var r model.CIRun
+	var createTime time.Time
+	var startTime, endTime *time.Time
+	var err error
+	if withLog {
+		err = row.Scan(&r.Id, &r.RepoNamespace, &r.RepoName, &r.CommitId, &r.RefName, &r.JobName, &r.Trigger, &r.Status, &r.Log, &createTime, &startTime, &endTime)
+	} else {
+		err = row.Scan(&r.Id, &r.RepoNamespace, &r.RepoName, &r.CommitId, &r.RefName, &r.JobName, &r.Trigger, &r.Status, &createTime, &startTime, &endTime)
+	}
+	if err != nil { return nil, err }
+	r.CreateTime = createTime.Unix()
+	if startTime != nil { r.StartTime = startTime.Unix() }
+	if endTime != nil { r.EndTime = endTime.Unix() }
+	return &r, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetCIRun(id int64) (*model.CIRun, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	r, err := scanCIRun(dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT run_absid, repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, run_status, run_log, create_time, start_time, end_time
+FROM %s_ci_run WHERE run_absid = $1
+`, pfx), id), true)
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return r, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) ClaimCIRun() (*model.CIRun, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	// SKIP LOCKED so that concurrent runners don't claim the same
+	// run.
+	r, err := scanCIRun(dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+UPDATE %s_ci_run SET run_status = $1, run_log = '', start_time = $2, end_time = NULL
+WHERE run_absid = (
+    SELECT run_absid FROM %s_ci_run
+    WHERE run_status = $3
+    ORDER BY run_absid ASC LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING run_absid, repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, run_status, create_time, start_time, end_time
+`, pfx, pfx), model.CI_RUN_RUNNING, time.Now(), model.CI_RUN_QUEUED), false)
+	if errors.Is(err, pgx.ErrNoRows) { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return r, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) UpdateCIRunLog(id int64, log string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_ci_run SET run_log = $1 WHERE run_absid = $2
+`, pfx), log, id)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) FinishCIRun(id int64, status int, log string) error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_ci_run SET run_status = $1, run_log = $2, end_time = $3
+WHERE run_absid = $4 AND run_status = $5
+`, pfx), status, log, time.Now(), id, model.CI_RUN_RUNNING)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) ResetRunningCIRun() error {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	_, err := dbif.pool.Exec(ctx, fmt.Sprintf(`
+UPDATE %s_ci_run SET run_status = $1 WHERE run_status = $2
+`, pfx), model.CI_RUN_QUEUED, model.CI_RUN_RUNNING)
+	return err
+}
+
+func (dbif *PostgresGitusDatabaseInterface) CountCIRun(ns string, name string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	var res int64
+	err := dbif.pool.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_ci_run WHERE repo_namespace = $1 AND repo_name = $2
+`, pfx), ns, name).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *PostgresGitusDatabaseInterface) GetCIRunPaginated(ns string, name string, pageNum int64, pageSize int64) ([]*model.CIRun, error) {
+	pfx := dbif.config.Database.TablePrefix
+	ctx := context.Background()
+	rs, err := dbif.pool.Query(ctx, fmt.Sprintf(`
+SELECT run_absid, repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, run_status, create_time, start_time, end_time
+FROM %s_ci_run
+WHERE repo_namespace = $1 AND repo_name = $2
+ORDER BY run_absid DESC LIMIT $3 OFFSET $4
+`, pfx), ns, name, pageSize, pageNum*pageSize)
+	if err != nil { return nil, err }
+	defer rs.Close()
+	res := make([]*model.CIRun, 0)
+	for rs.Next() {
+		r, err := scanCIRun(rs, false)
+		if err != nil { return nil, err }
+		res = append(res, r)
+	}
+	return res, nil
+}
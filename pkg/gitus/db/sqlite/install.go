@@ -1,7 +1,33 @@
 package sqlite
 
-import "fmt"
+import (
+	"database/sql"
+	"fmt"
+)
 
+// adds the columns in `columnList` (`{name, definition}`) that
+// `table` doesn't have yet. `CREATE TABLE IF NOT EXISTS` leaves the
+// tables of older installs as they are, so columns added to an
+// existing table need this as well.
+func addMissingColumn(tx *sql.Tx, table string, columnList [][2]string) error {
+	rs, err := tx.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil { return err }
+	existing := make(map[string]bool, 0)
+	for rs.Next() {
+		var name string
+		err = rs.Scan(&name)
+		if err != nil { rs.Close(); return err }
+		existing[name] = true
+	}
+	rs.Close()
+	if err = rs.Err(); err != nil { return err }
+	for _, k := range columnList {
+		if existing[k[0]] { continue }
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, k[0], k[1]))
+		if err != nil { return err }
+	}
+	return nil
+}
 
 func (dbif *SqliteGitusDatabaseInterface) InstallTables() error {
 	pfx := dbif.config.Database.TablePrefix
@@ -78,6 +104,11 @@ CREATE TABLE IF NOT EXISTS %s_repo_redirect (
 	redirect_timestamp INTEGER
 )`, pfx))
 	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_repo_redirect_old
+ON %s_repo_redirect (old_ns, old_name)
+`, pfx, pfx))
+	if err != nil { return err }
 
 	_, err = tx.Exec(fmt.Sprintf(`
 CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_user_user_name
@@ -137,6 +168,62 @@ CREATE TABLE IF NOT EXISTS %s_issue_event (
 `, pfx, pfx))
 	if err != nil { return err }
 
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_label (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    label_name TEXT,
+    -- "#rrggbb".
+    label_color TEXT,
+    label_description TEXT,
+    UNIQUE (repo_namespace, repo_name, label_name)
+);
+`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_milestone (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    milestone_title TEXT,
+    milestone_description TEXT,
+    -- 0 means no due date.
+    milestone_due INTEGER,
+    milestone_closed INTEGER,
+    UNIQUE (repo_namespace, repo_name, milestone_title)
+);
+`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_label_assoc (
+    issue_abs_id INTEGER,
+    label_name TEXT,
+    UNIQUE (issue_abs_id, label_name),
+    FOREIGN KEY (issue_abs_id) REFERENCES %s_issue(rowid)
+);
+`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_assignee (
+    issue_abs_id INTEGER,
+    username TEXT,
+    UNIQUE (issue_abs_id, username),
+    FOREIGN KEY (issue_abs_id) REFERENCES %s_issue(rowid)
+);
+`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_issue_milestone_assoc (
+    issue_abs_id INTEGER UNIQUE,
+    milestone_title TEXT,
+    FOREIGN KEY (issue_abs_id) REFERENCES %s_issue(rowid)
+);
+`, pfx, pfx))
+	if err != nil { return err }
+
 	_, err = tx.Exec(fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s_pull_request (
     username TEXT,
@@ -174,7 +261,7 @@ CREATE TABLE IF NOT EXISTS %s_pull_request_event (
 	event_timestamp INTEGER,
 	event_author TEXT,
 	event_content TEXT,
-	FOREIGN KEY (pull_request_abs_id) REFERENCES %spull_request(rowid)
+	FOREIGN KEY (pull_request_abs_id) REFERENCES %s_pull_request(rowid)
   )
 `, pfx, pfx))
 	if err != nil { return err }
@@ -217,15 +304,298 @@ CREATE TABLE IF NOT EXISTS %s_snippet (
 )`, pfx, pfx))
 	if err != nil { return err }
 
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_snippet_event (
+    username TEXT,
+    name TEXT,
+	-- see model.SNIPPET_EVENT_*.
+	event_type INTEGER,
+	event_time INTEGER,
+	event_author TEXT,
+	event_content TEXT
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_snippet_event_snippet
+ON %s_snippet_event (username, name)
+`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_snippet_star (
+    username TEXT,
+    name TEXT,
+    star_user TEXT,
+    UNIQUE (username, name, star_user)
+)`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_snippet_fork (
+    username TEXT,
+    name TEXT,
+    origin_username TEXT,
+    origin_name TEXT,
+    UNIQUE (username, name)
+)`, pfx))
+	if err != nil { return err }
+
 	_, err = tx.Exec(fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s_webhook_log (
     uuid TEXT UNIQUE,
 	repo_namespace TEXT,
 	repo_name TEXT,
 	commit_id TEXT,
-    webhook_result TEXT
+    webhook_result TEXT,
+    ref_name TEXT,
+    target_url TEXT,
+    request_header TEXT,
+    request_payload TEXT,
+    response_status INTEGER,
+    response_body TEXT,
+    delivery_error TEXT,
+    -- in milliseconds.
+    duration INTEGER,
+    -- see model.WEBHOOK_DELIVERY_*.
+    delivery_status INTEGER,
+    attempt INTEGER,
+    create_time INTEGER,
+    delivery_time INTEGER,
+    redelivery_of TEXT,
+    webhook_id INTEGER,
+    event TEXT
+)`, pfx))
+	if err != nil { return err }
+	// the columns added for webhook deliveries; older installs only
+	// have the ones up to `webhook_result`. the rows from before
+	// have no delivery info.
+	err = addMissingColumn(tx, pfx + "_webhook_log", [][2]string{
+		{"ref_name", "TEXT DEFAULT ''"},
+		{"target_url", "TEXT DEFAULT ''"},
+		{"request_header", "TEXT DEFAULT ''"},
+		{"request_payload", "TEXT DEFAULT ''"},
+		{"response_status", "INTEGER DEFAULT 0"},
+		{"response_body", "TEXT DEFAULT ''"},
+		{"delivery_error", "TEXT DEFAULT ''"},
+		{"duration", "INTEGER DEFAULT 0"},
+		{"delivery_status", "INTEGER DEFAULT 0"},
+		{"attempt", "INTEGER DEFAULT 0"},
+		{"create_time", "INTEGER DEFAULT 0"},
+		{"delivery_time", "INTEGER DEFAULT 0"},
+		{"redelivery_of", "TEXT DEFAULT ''"},
+		{"webhook_id", "INTEGER DEFAULT 0"},
+		{"event", "TEXT DEFAULT ''"},
+	})
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_webhook_log_repo
+ON %s_webhook_log (repo_namespace, repo_name, create_time)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_webhook (
+    webhook_namespace TEXT,
+    -- empty for namespace webhooks.
+    webhook_repo_name TEXT,
+    target_url TEXT,
+    secret TEXT,
+    payload_type TEXT,
+    -- comma-separated; see model.WEBHOOK_EVENT_*.
+    event_list TEXT,
+    -- comma-separated glob patterns.
+    branch_filter TEXT,
+    enabled INTEGER,
+    create_time INTEGER
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_webhook_namespace_repo_name
+ON %s_webhook (webhook_namespace, webhook_repo_name)
+`, pfx, pfx))
+	if err != nil { return err }
+	
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_user_access_token (
+    user_name TEXT,
+    token_name TEXT,
+    token_hash TEXT UNIQUE,
+    -- comma-separated, e.g. "repo:read,issue:write".
+    token_scope TEXT,
+    token_create_time INTEGER,
+    -- 0 - never expires.
+    token_expire_time INTEGER,
+    FOREIGN KEY (user_name) REFERENCES %s_user(user_name)
+)`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_user_access_token_user_name
+ON %s_user_access_token (user_name);
+`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_branch_protection (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    pattern TEXT,
+    disallow_force_push INTEGER,
+    disallow_deletion INTEGER,
+    -- comma-separated; empty means no restriction.
+    allowed_pusher TEXT,
+    require_pull_request INTEGER,
+    UNIQUE (repo_namespace, repo_name, pattern)
+)`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_pull_request_setting (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    required_approval_count INTEGER,
+    -- comma-separated.
+    required_reviewer TEXT,
+    -- comma-separated merge strategies. empty means all.
+    allowed_merge_strategy TEXT,
+    -- comma-separated commit status contexts.
+    required_status_context TEXT,
+    UNIQUE (repo_namespace, repo_name)
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_commit_status (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    commit_id TEXT,
+    status_context TEXT,
+    -- see model.COMMIT_STATUS_*.
+    status_state TEXT,
+    target_url TEXT,
+    status_description TEXT,
+    status_creator TEXT,
+    create_time INTEGER,
+    update_time INTEGER,
+    UNIQUE (repo_namespace, repo_name, commit_id, status_context)
 )`, pfx))
 	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_pull_request_review (
+    pull_request_abs_id INTEGER,
+    reviewer TEXT,
+    -- 1 - comment, 2 - approve, 3 - request changes.
+    review_state INTEGER,
+    review_summary TEXT,
+    review_commit_id TEXT,
+    review_timestamp INTEGER,
+    review_dismissed INTEGER,
+    FOREIGN KEY (pull_request_abs_id) REFERENCES %s_pull_request(rowid)
+)`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_notification (
+    username TEXT,
+    -- see model.NOTIFICATION_*.
+    notification_type INTEGER,
+    actor TEXT,
+    title TEXT,
+    content TEXT,
+    link TEXT,
+    notification_timestamp INTEGER,
+    is_read INTEGER,
+    FOREIGN KEY (username) REFERENCES %s_user(user_name)
+)`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_notification_username
+ON %s_notification (username);
+`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_user_notification_setting (
+    username TEXT UNIQUE,
+    -- json of model.NotificationSetting.
+    setting TEXT,
+    FOREIGN KEY (username) REFERENCES %s_user(user_name)
+)`, pfx, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_watch (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    username TEXT,
+    UNIQUE (repo_namespace, repo_name, username)
+)`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_star (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    username TEXT,
+    star_time INTEGER,
+    UNIQUE (repo_namespace, repo_name, username)
+)`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_repo_activity (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    last_push_time INTEGER,
+    UNIQUE (repo_namespace, repo_name)
+)`, pfx))
+	if err != nil { return err }
+
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_job (
+    job_type TEXT,
+    -- json.
+    payload TEXT,
+    -- see model.JOB_*.
+    job_status INTEGER,
+    attempt INTEGER,
+    max_attempt INTEGER,
+    last_error TEXT,
+    next_run_time INTEGER,
+    create_time INTEGER
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_job_status_next_run_time
+ON %s_job (job_status, next_run_time)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s_ci_run (
+    repo_namespace TEXT,
+    repo_name TEXT,
+    commit_id TEXT,
+    ref_name TEXT,
+    job_name TEXT,
+    run_trigger TEXT,
+    -- see model.CI_RUN_*.
+    run_status INTEGER,
+    run_log TEXT,
+    create_time INTEGER,
+    start_time INTEGER,
+    end_time INTEGER
+)`, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_ci_run_repo
+ON %s_ci_run (repo_namespace, repo_name)
+`, pfx, pfx))
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS idx_%s_ci_run_status
+ON %s_ci_run (run_status)
+`, pfx, pfx))
+	if err != nil { return err }
 	
 	tx.Commit()
 	return nil
@@ -1,14 +1,10 @@
 package sqlite
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/fs"
 	"os"
-	"os/exec"
 	"path"
 	"slices"
 	"strings"
@@ -427,18 +423,21 @@ WHERE ns_name = ?
 func (dbif *SqliteGitusDatabaseInterface) GetRepositoryByName(nsName string, repoName string) (*model.Repository, error) {
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
-SELECT repo_type, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook, rowid
-FROM %s_repository
+SELECT repo_type, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook, rowid,
+  (SELECT COUNT(*) FROM %s_repo_star st WHERE st.repo_namespace = repo.repo_namespace AND st.repo_name = repo.repo_name),
+  (SELECT COUNT(*) FROM %s_repo_watch wt WHERE wt.repo_namespace = repo.repo_namespace AND wt.repo_name = repo.repo_name),
+  COALESCE((SELECT act.last_push_time FROM %s_repo_activity act WHERE act.repo_namespace = repo.repo_namespace AND act.repo_name = repo.repo_name), 0)
+FROM %s_repository repo
 WHERE repo_namespace = ? AND repo_name = ?
-`, pfx))
+`, pfx, pfx, pfx, pfx))
 	if err != nil { return nil, err }
 	r := stmt.QueryRow(nsName, repoName)
 	if r.Err() != nil { return nil, r.Err() }
 	var desc, owner, acl, forkOriginNs, forkOriginName, labelList, webhookstr string
 	var status int
-	var rowid int64
+	var rowid, starCount, watcherCount, lastPushTime int64
 	var repoType uint8
-	err = r.Scan(&repoType, &desc, &owner, &acl, &status, &forkOriginNs, &forkOriginName, &labelList, &webhookstr, &rowid)
+	err = r.Scan(&repoType, &desc, &owner, &acl, &status, &forkOriginNs, &forkOriginName, &labelList, &webhookstr, &rowid, &starCount, &watcherCount, &lastPushTime)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, db.ErrEntityNotFound
@@ -458,6 +457,9 @@ WHERE repo_namespace = ? AND repo_name = ?
 	res.ForkOriginNamespace = forkOriginNs
 	res.ForkOriginName = forkOriginName
 	res.RepoLabelList = tags
+	res.StarCount = starCount
+	res.WatcherCount = watcherCount
+	res.LastPushTime = lastPushTime
 	aclobj, err := model.ParseACL(acl)
 	if err != nil { return nil, err }
 	res.AccessControlList = aclobj
@@ -960,6 +962,38 @@ WHERE repo_namespace = ? AND repo_name = ?
 	if err != nil { tx.Rollback(); return err }
 	_, err = stmt.Exec(ns, name)
 	if err != nil { tx.Rollback(); return err }
+	stmt2, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_branch_protection
+WHERE repo_namespace = ? AND repo_name = ?
+`, pfx))
+	if err != nil { tx.Rollback(); return err }
+	_, err = stmt2.Exec(ns, name)
+	if err != nil { tx.Rollback(); return err }
+	stmt3, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_pull_request_setting
+WHERE repo_namespace = ? AND repo_name = ?
+`, pfx))
+	if err != nil { tx.Rollback(); return err }
+	_, err = stmt3.Exec(ns, name)
+	if err != nil { tx.Rollback(); return err }
+	stmt4, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_repo_watch
+WHERE repo_namespace = ? AND repo_name = ?
+`, pfx))
+	if err != nil { tx.Rollback(); return err }
+	_, err = stmt4.Exec(ns, name)
+	if err != nil { tx.Rollback(); return err }
+	for _, k := range []string{
+		"DELETE FROM %s_webhook WHERE webhook_namespace = ? AND webhook_repo_name = ?",
+		"DELETE FROM %s_commit_status WHERE repo_namespace = ? AND repo_name = ?",
+		"DELETE FROM %s_ci_run WHERE repo_namespace = ? AND repo_name = ?",
+		"DELETE FROM %s_repo_redirect WHERE new_ns = ? AND new_name = ?",
+		"DELETE FROM %s_repo_star WHERE repo_namespace = ? AND repo_name = ?",
+		"DELETE FROM %s_repo_activity WHERE repo_namespace = ? AND repo_name = ?",
+	} {
+		_, err = tx.Exec(fmt.Sprintf(k, pfx), ns, name)
+		if err != nil { tx.Rollback(); return err }
+	}
 	p := path.Join(dbif.config.GitRoot, ns, name)
 	err = os.RemoveAll(p)
 	if err != nil { tx.Rollback(); return err }
@@ -968,6 +1002,256 @@ WHERE repo_namespace = ? AND repo_name = ?
 	return nil
 }
 
+func (dbif *SqliteGitusDatabaseInterface) MoveRepository(oldNs string, oldName string, newNs string, newName string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	var forkOriginNs, forkOriginName string
+	err = tx.QueryRow(fmt.Sprintf(`
+SELECT repo_fork_origin_namespace, repo_fork_origin_name FROM %s_repository
+WHERE repo_namespace = ? AND repo_name = ?
+`, pfx), oldNs, oldName).Scan(&forkOriginNs, &forkOriginName)
+	if err == sql.ErrNoRows { return db.ErrEntityNotFound }
+	if err != nil { return err }
+	var c int64
+	err = tx.QueryRow(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_repository WHERE repo_namespace = ? AND repo_name = ?
+`, pfx), newNs, newName).Scan(&c)
+	if err != nil { return err }
+	if c > 0 { return db.ErrEntityAlreadyExists }
+	// the repositories that could have remotes referring to the
+	// moved one. see `db.RelocateMovedRepositoryRemote`.
+	remoteRepoList := [][2]string{{forkOriginNs, forkOriginName}}
+	for _, q := range []string{
+		"SELECT repo_namespace, repo_name FROM %s_repository WHERE repo_fork_origin_namespace = ? AND repo_fork_origin_name = ?",
+		"SELECT DISTINCT receiver_namespace, receiver_name FROM %s_pull_request WHERE provider_namespace = ? AND provider_name = ?",
+	} {
+		rs, err := tx.Query(fmt.Sprintf(q, pfx), oldNs, oldName)
+		if err != nil { return err }
+		for rs.Next() {
+			var ns, name string
+			err = rs.Scan(&ns, &name)
+			if err != nil { rs.Close(); return err }
+			if ns == oldNs && name == oldName { ns, name = newNs, newName }
+			remoteRepoList = append(remoteRepoList, [2]string{ns, name})
+		}
+		rs.Close()
+	}
+	_, err = tx.Exec(fmt.Sprintf(`
+UPDATE %s_repository SET repo_namespace = ?, repo_name = ?, repo_fullname = ?
+WHERE repo_namespace = ? AND repo_name = ?
+`, pfx), newNs, newName, newNs + ":" + newName, oldNs, oldName)
+	if err != nil { return err }
+	for _, k := range []string{
+		"UPDATE %s_repository SET repo_fork_origin_namespace = ?, repo_fork_origin_name = ? WHERE repo_fork_origin_namespace = ? AND repo_fork_origin_name = ?",
+		"UPDATE %s_issue SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_issue_label SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_issue_milestone SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_pull_request SET receiver_namespace = ?, receiver_name = ? WHERE receiver_namespace = ? AND receiver_name = ?",
+		"UPDATE %s_pull_request SET provider_namespace = ?, provider_name = ? WHERE provider_namespace = ? AND provider_name = ?",
+		"UPDATE %s_webhook_log SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_webhook SET webhook_namespace = ?, webhook_repo_name = ? WHERE webhook_namespace = ? AND webhook_repo_name = ?",
+		"UPDATE %s_branch_protection SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_pull_request_setting SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_commit_status SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_repo_watch SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_repo_star SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_repo_activity SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		"UPDATE %s_ci_run SET repo_namespace = ?, repo_name = ? WHERE repo_namespace = ? AND repo_name = ?",
+		// older redirects to the moved repository now point to
+		// its new name.
+		"UPDATE %s_repo_redirect SET new_ns = ?, new_name = ? WHERE new_ns = ? AND new_name = ?",
+	} {
+		_, err = tx.Exec(fmt.Sprintf(k, pfx), newNs, newName, oldNs, oldName)
+		if err != nil { return err }
+	}
+	// the new name might've been redirected somewhere else before,
+	// & the old name might've been used by another repository that's
+	// been moved away.
+	_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_repo_redirect
+WHERE (old_ns = ? AND old_name = ?) OR (old_ns = ? AND old_name = ?)
+`, pfx), newNs, newName, oldNs, oldName)
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+INSERT INTO %s_repo_redirect(old_ns, old_name, new_ns, new_name, redirect_timestamp)
+VALUES (?,?,?,?,?)
+`, pfx), oldNs, oldName, newNs, newName, time.Now().Unix())
+	if err != nil { return err }
+	rs, err := tx.Query(fmt.Sprintf(`
+SELECT rowid, payload FROM %s_job WHERE job_type = ?
+`, pfx), model.JOB_TYPE_WEBHOOK)
+	if err != nil { return err }
+	jobPayloadMap := make(map[int64]string, 0)
+	for rs.Next() {
+		var id int64
+		var payload string
+		err = rs.Scan(&id, &payload)
+		if err != nil { rs.Close(); return err }
+		newPayload, ok := db.MoveWebhookJobPayload(payload, oldNs, oldName, newNs, newName)
+		if ok { jobPayloadMap[id] = newPayload }
+	}
+	rs.Close()
+	for id, payload := range jobPayloadMap {
+		_, err = tx.Exec(fmt.Sprintf(`UPDATE %s_job SET payload = ? WHERE rowid = ?`, pfx), payload, id)
+		if err != nil { return err }
+	}
+	err = db.MoveRepositoryDirectory(dbif.config.GitRoot, oldNs, oldName, newNs, newName)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil {
+		db.UndoMoveRepositoryDirectory(dbif.config.GitRoot, oldNs, oldName, newNs, newName)
+		return err
+	}
+	db.RelocateMovedRepositoryRemote(dbif.config.GitRoot, remoteRepoList, oldNs, oldName, newNs, newName)
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetRepositoryRedirect(ns string, name string) (string, string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT new_ns, new_name FROM %s_repo_redirect
+WHERE old_ns = ? AND old_name = ?
+ORDER BY redirect_timestamp DESC LIMIT 1
+`, pfx))
+	if err != nil { return "", "", err }
+	defer stmt.Close()
+	var newNs, newName string
+	err = stmt.QueryRow(ns, name).Scan(&newNs, &newName)
+	if err == sql.ErrNoRows { return "", "", db.ErrEntityNotFound }
+	if err != nil { return "", "", err }
+	return newNs, newName, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RenameNamespace(oldName string, newName string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	var c int64
+	err = tx.QueryRow(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_namespace WHERE ns_name = ?
+`, pfx), oldName).Scan(&c)
+	if err != nil { return err }
+	if c <= 0 { return db.ErrEntityNotFound }
+	err = tx.QueryRow(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_namespace WHERE ns_name = ?
+`, pfx), newName).Scan(&c)
+	if err != nil { return err }
+	if c > 0 { return db.ErrEntityAlreadyExists }
+	rs, err := tx.Query(fmt.Sprintf(`
+SELECT repo_name FROM %s_repository WHERE repo_namespace = ?
+`, pfx), oldName)
+	if err != nil { return err }
+	repoNameList := make([]string, 0)
+	for rs.Next() {
+		var name string
+		err = rs.Scan(&name)
+		if err != nil { rs.Close(); return err }
+		repoNameList = append(repoNameList, name)
+	}
+	rs.Close()
+	// the repositories that could have remotes referring to each of
+	// the moved ones. see `db.RelocateMovedRepositoryRemote`.
+	remoteRepoMap := make(map[string][][2]string, 0)
+	for _, repoName := range repoNameList {
+		l := make([][2]string, 0)
+		for _, q := range []string{
+			"SELECT repo_fork_origin_namespace, repo_fork_origin_name FROM %s_repository WHERE repo_namespace = ? AND repo_name = ?",
+			"SELECT repo_namespace, repo_name FROM %s_repository WHERE repo_fork_origin_namespace = ? AND repo_fork_origin_name = ?",
+			"SELECT DISTINCT receiver_namespace, receiver_name FROM %s_pull_request WHERE provider_namespace = ? AND provider_name = ?",
+		} {
+			rs, err := tx.Query(fmt.Sprintf(q, pfx), oldName, repoName)
+			if err != nil { return err }
+			for rs.Next() {
+				var ns, name string
+				err = rs.Scan(&ns, &name)
+				if err != nil { rs.Close(); return err }
+				if ns == oldName { ns = newName }
+				l = append(l, [2]string{ns, name})
+			}
+			rs.Close()
+		}
+		remoteRepoMap[repoName] = l
+	}
+	_, err = tx.Exec(fmt.Sprintf(`
+UPDATE %s_namespace SET ns_name = ? WHERE ns_name = ?
+`, pfx), newName, oldName)
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+UPDATE %s_repository SET repo_namespace = ?, repo_fullname = ? || ':' || repo_name
+WHERE repo_namespace = ?
+`, pfx), newName, newName, oldName)
+	if err != nil { return err }
+	for _, k := range []string{
+		"UPDATE %s_repository SET repo_fork_origin_namespace = ? WHERE repo_fork_origin_namespace = ?",
+		"UPDATE %s_issue SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_issue_label SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_issue_milestone SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_pull_request SET receiver_namespace = ? WHERE receiver_namespace = ?",
+		"UPDATE %s_pull_request SET provider_namespace = ? WHERE provider_namespace = ?",
+		"UPDATE %s_webhook_log SET repo_namespace = ? WHERE repo_namespace = ?",
+		// incl. the webhooks of the namespace itself.
+		"UPDATE %s_webhook SET webhook_namespace = ? WHERE webhook_namespace = ?",
+		"UPDATE %s_branch_protection SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_pull_request_setting SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_commit_status SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_repo_watch SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_repo_star SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_repo_activity SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_ci_run SET repo_namespace = ? WHERE repo_namespace = ?",
+		"UPDATE %s_repo_redirect SET new_ns = ? WHERE new_ns = ?",
+	} {
+		_, err = tx.Exec(fmt.Sprintf(k, pfx), newName, oldName)
+		if err != nil { return err }
+	}
+	// same as `MoveRepository` but for every repository in the
+	// namespace.
+	t := time.Now().Unix()
+	for _, repoName := range repoNameList {
+		_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_repo_redirect
+WHERE (old_ns = ? AND old_name = ?) OR (old_ns = ? AND old_name = ?)
+`, pfx), newName, repoName, oldName, repoName)
+		if err != nil { return err }
+		_, err = tx.Exec(fmt.Sprintf(`
+INSERT INTO %s_repo_redirect(old_ns, old_name, new_ns, new_name, redirect_timestamp)
+VALUES (?,?,?,?,?)
+`, pfx), oldName, repoName, newName, repoName, t)
+		if err != nil { return err }
+	}
+	rs, err = tx.Query(fmt.Sprintf(`
+SELECT rowid, payload FROM %s_job WHERE job_type = ?
+`, pfx), model.JOB_TYPE_WEBHOOK)
+	if err != nil { return err }
+	jobPayloadMap := make(map[int64]string, 0)
+	for rs.Next() {
+		var id int64
+		var payload string
+		err = rs.Scan(&id, &payload)
+		if err != nil { rs.Close(); return err }
+		newPayload, ok := db.RenameNamespaceWebhookJobPayload(payload, oldName, newName)
+		if ok { jobPayloadMap[id] = newPayload }
+	}
+	rs.Close()
+	for id, payload := range jobPayloadMap {
+		_, err = tx.Exec(fmt.Sprintf(`UPDATE %s_job SET payload = ? WHERE rowid = ?`, pfx), payload, id)
+		if err != nil { return err }
+	}
+	err = db.MoveNamespaceDirectory(dbif.config.GitRoot, oldName, newName)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil {
+		db.UndoMoveNamespaceDirectory(dbif.config.GitRoot, oldName, newName)
+		return err
+	}
+	for _, repoName := range repoNameList {
+		db.RelocateMovedRepositoryRemote(dbif.config.GitRoot, remoteRepoMap[repoName], oldName, repoName, newName, repoName)
+	}
+	return nil
+}
+
 func (dbif *SqliteGitusDatabaseInterface) GetAllUsers(pageNum int64, pageSize int64) ([]*model.GitusUser, error) {
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
@@ -1873,8 +2157,9 @@ WHERE repo_namespace = ? AND repo_name = ? AND issue_id = ?
 	var status, priority int
 	var author, title, content string
 	err = r.Scan(&absid, &timestamp, &author, &title, &content, &status, &priority)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
 	if err != nil { return nil, err }
-	return &model.Issue{
+	res := &model.Issue{
 		IssueAbsId: absid,
 		RepoNamespace: ns,
 		RepoName: name,
@@ -1885,7 +2170,10 @@ WHERE repo_namespace = ? AND repo_name = ? AND issue_id = ?
 		IssueContent: content,
 		IssueStatus: status,
 		IssuePriority: priority,
-	}, nil
+	}
+	err = dbif.fillIssueMetadata([]*model.Issue{res})
+	if err != nil { return nil, err }
+	return res, nil
 }
 
 func (dbif *SqliteGitusDatabaseInterface) CountAllRepositoryIssue(ns string, name string) (int, error) {
@@ -1938,6 +2226,12 @@ func (dbif *SqliteGitusDatabaseInterface) HardDeleteRepositoryIssue(ns string, n
 	tx, err := dbif.connection.Begin()
 	if err != nil { return err }
 	defer tx.Rollback()
+	for _, t := range []string{"issue_label_assoc", "issue_assignee", "issue_milestone_assoc"} {
+		_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_%s WHERE issue_abs_id IN (SELECT rowid FROM %s_issue WHERE repo_namespace = ? AND repo_name = ? AND issue_id = ?)
+`, pfx, t, pfx), ns, name, issueId)
+		if err != nil { return err }
+	}
 	stmt, err := tx.Prepare(fmt.Sprintf(`
 DELETE FROM %s_issue WHERE repo_namespace = ? AND repo_name = ? AND issue_id = ?
 `, pfx))
@@ -2370,7 +2664,7 @@ func (dbif *SqliteGitusDatabaseInterface) GetAllPullRequestPaginated(namespace s
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT rowid, pull_request_id, username, title, receiver_branch, provider_namespace, provider_name, provider_branch, merge_conflict_check_result, merge_conflict_check_timestamp, pull_request_status, pull_request_timestamp
-FROM %spull_request
+FROM %s_pull_request
 WHERE receiver_namespace = ? AND receiver_name = ?
 ORDER BY pull_request_id ASC LIMIT ? OFFSET ?
 `, pfx))
@@ -2412,51 +2706,63 @@ ORDER BY pull_request_id ASC LIMIT ? OFFSET ?
 	return res, nil
 }
 
-func (dbif *SqliteGitusDatabaseInterface) CountIssue(query string, namespace string, name string, filterType int) (int64, error) {
+// the extra conditions for `model.IssueFilter`. each condition
+// starts w/ "AND".
+func issueFilterClause(pfx string, filter *model.IssueFilter) (string, []any) {
+	if filter.IsEmpty() { return "", nil }
+	res := make([]string, 0)
+	args := make([]any, 0)
+	if len(filter.Author) > 0 {
+		res = append(res, "AND issue_author = ?")
+		args = append(args, filter.Author)
+	}
+	if len(filter.Assignee) > 0 {
+		res = append(res, fmt.Sprintf("AND rowid IN (SELECT issue_abs_id FROM %s_issue_assignee WHERE username = ?)", pfx))
+		args = append(args, filter.Assignee)
+	}
+	if len(filter.Milestone) > 0 {
+		res = append(res, fmt.Sprintf("AND rowid IN (SELECT issue_abs_id FROM %s_issue_milestone_assoc WHERE milestone_title = ?)", pfx))
+		args = append(args, filter.Milestone)
+	}
+	for _, k := range filter.Label {
+		res = append(res, fmt.Sprintf("AND rowid IN (SELECT issue_abs_id FROM %s_issue_label_assoc WHERE label_name = ?)", pfx))
+		args = append(args, k)
+	}
+	return strings.Join(res, " "), args
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountIssue(query string, namespace string, name string, filterType int, filter *model.IssueFilter) (int64, error) {
 	pfx := dbif.config.Database.TablePrefix
 	statusClause := ""
 	switch filterType {
-    case 1: statusClause = "issue_status = 1"
-	case 2: statusClause = "NOT (issue_status = 1)"
-	case 3: statusClause = "issue_status = 2"
-	case 4: statusClause = "issue_status = 3"
+    case 1: statusClause = "AND issue_status = 1"
+	case 2: statusClause = "AND NOT (issue_status = 1)"
+	case 3: statusClause = "AND issue_status = 2"
+	case 4: statusClause = "AND issue_status = 3"
 	}
+	args := []any{namespace, name}
 	queryClause := ""
-	if query == "" {
-		queryClause = "issue_title LIKE ? ESCAPE ?"
-	}
-	condition := ""
-	if statusClause == "" {
-		if queryClause == "" {
-		} else {
-			condition = fmt.Sprintf("WHERE %s", queryClause)
-		}
-	} else {
-		if queryClause == "" {
-			condition = fmt.Sprintf("WHERE %s", statusClause)
-		} else {
-			condition = fmt.Sprintf("WHERE %s AND %s", queryClause, statusClause)
-		}
+	if query != "" {
+		queryClause = "AND issue_title LIKE ? ESCAPE ?"
+		args = append(args, db.ToSqlSearchPattern(query), "\\")
 	}
+	filterClause, filterArgs := issueFilterClause(pfx, filter)
+	args = append(args, filterArgs...)
 	stmt1, err := dbif.connection.Prepare(fmt.Sprintf(`
-SELECT COUNT(*) FROM %s_issue %s
-`, pfx, condition))
+SELECT COUNT(*) FROM %s_issue
+WHERE repo_namespace = ? AND repo_name = ? %s %s %s
+`, pfx, statusClause, queryClause, filterClause))
 	if err != nil { return 0, err }
+	defer stmt1.Close()
 	var cnt int64
-	var r *sql.Row
-	if queryClause == "" {
-		r = stmt1.QueryRow()
-	} else {
-		queryPattern := db.ToSqlSearchPattern(query)
-		r = stmt1.QueryRow(queryPattern, "\\")
-	}
+	r := stmt1.QueryRow(args...)
 	if r.Err() != nil { return 0, r.Err() }
 	err = r.Scan(&cnt)
 	if err != nil { return 0, err }
 	return cnt, nil
 }
 
-func (dbif *SqliteGitusDatabaseInterface) SearchIssuePaginated(query string, namespace string, name string, filterType int, pageNum int64, pageSize int64) ([]*model.Issue, error) {
+func (dbif *SqliteGitusDatabaseInterface) SearchIssuePaginated(query string, namespace string, name string, filterType int, filter *model.IssueFilter, pageNum int64, pageSize int64) ([]*model.Issue, error) {
 	pfx := dbif.config.Database.TablePrefix
 	statusClause := ""
 	switch filterType {
@@ -2465,25 +2771,26 @@ func (dbif *SqliteGitusDatabaseInterface) SearchIssuePaginated(query string, nam
 	case 3: statusClause = "AND issue_status = 2"
 	case 4: statusClause = "AND issue_status = 3"
 	}
+	args := []any{namespace, name}
 	queryClause := ""
 	if query != "" {
 		queryClause = "AND issue_title LIKE ? ESCAPE ?"
+		args = append(args, db.ToSqlSearchPattern(query), "\\")
 	}
+	filterClause, filterArgs := issueFilterClause(pfx, filter)
+	args = append(args, filterArgs...)
+	args = append(args, pageSize, pageNum*pageSize)
 	stmt1, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT rowid, issue_id, issue_author, issue_status, issue_title, issue_content, issue_timestamp, issue_priority
 FROM %s_issue
-WHERE repo_namespace = ? AND repo_name = ? %s %s
+WHERE repo_namespace = ? AND repo_name = ? %s %s %s
 ORDER BY issue_priority DESC, issue_timestamp DESC LIMIT ? OFFSET ?
-`, pfx, statusClause, queryClause))
+`, pfx, statusClause, queryClause, filterClause))
 	if err != nil { return nil, err }
-	var r *sql.Rows
-	if queryClause == "" {
-		r, err = stmt1.Query(namespace, name, pageSize, pageNum*pageSize)
-	} else {
-		queryPattern := db.ToSqlSearchPattern(query)
-		r, err = stmt1.Query(namespace, name, queryPattern, "\\", pageSize, pageNum*pageSize)
-	}
+	defer stmt1.Close()
+	r, err := stmt1.Query(args...)
 	if err != nil { return nil, err }
+	defer r.Close()
 	res := make([]*model.Issue, 0)
 	for r.Next() {
 		var issueAbsId, issueTimestamp int64
@@ -2504,13 +2811,15 @@ ORDER BY issue_priority DESC, issue_timestamp DESC LIMIT ? OFFSET ?
 			IssuePriority: issuePriority,
 		})
 	}
+	err = dbif.fillIssueMetadata(res)
+	if err != nil { return nil, err }
 	return res, nil
 }
 
 func (dbif *SqliteGitusDatabaseInterface) NewPullRequest(username string, title string, receiverNamespace string, receiverName string, receiverBranch string, providerNamespace string, providerName string, providerBranch string) (int64, error) {
 	pfx := dbif.config.Database.TablePrefix
 	stmt1, err := dbif.connection.Prepare(fmt.Sprintf(`
-SELECT COUNT(*) FROM %spull_request
+SELECT COUNT(*) FROM %s_pull_request
 WHERE receiver_namespace = ? AND receiver_name = ?
 `, pfx))
 	if err != nil { return 0, err }
@@ -2522,7 +2831,7 @@ WHERE receiver_namespace = ? AND receiver_name = ?
 	if err != nil { return 0, err }
 	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %spull_request(
+INSERT INTO %s_pull_request(
     username, pull_request_id, title,
     receiver_namespace, receiver_name, receiver_branch,
     provider_namespace, provider_name, provider_branch,
@@ -2548,7 +2857,7 @@ func (dbif *SqliteGitusDatabaseInterface) GetPullRequest(namespace string, name
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT rowid, username, title, receiver_branch, provider_namespace, provider_name, provider_branch, merge_conflict_check_result, merge_conflict_check_timestamp, pull_request_status, pull_request_timestamp
-FROM %spull_request
+FROM %s_pull_request
 WHERE receiver_namespace = ? AND receiver_name = ? AND pull_request_id = ?
 `, pfx))
 	if err != nil { return nil, err }
@@ -2560,6 +2869,7 @@ WHERE receiver_namespace = ? AND receiver_name = ? AND pull_request_id = ?
 	var mchResult string
 	var prstatus int
 	err = r.Scan(&rowid, &username, &title, &receiverBranch, &providerNamespace, &providerName, &providerBranch, &mchResult, &mchtime, &prstatus, &prtime)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
 	if err != nil { return nil, err }
 	var mergeCheckResult *gitlib.MergeCheckResult = nil
 	if len(mchResult) > 0 {
@@ -2588,7 +2898,7 @@ func (dbif *SqliteGitusDatabaseInterface) GetPullRequestByAbsId(absId int64) (*m
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT username, pull_request_id, title, receiver_namespace, receiver_name, receiver_branch, provider_namespace, provider_name, provider_branch, merge_conflict_check_result, merge_conflict_check_timestamp, pull_request_status, pull_request_timestamp
-FROM %spull_request
+FROM %s_pull_request
 WHERE rowid = ?
 `, pfx))
 	if err != nil { return nil, err }
@@ -2601,12 +2911,15 @@ WHERE rowid = ?
 	var prstatus int
 	err = r.Scan(&username, &prid, &title, &receiverNamespace, &receiverName, &receiverBranch, &providerNamespace, &providerName, &providerBranch, &mchResult, &mchtime, &prstatus, &prtime)
 	if err != nil { return nil, err }
-	var mergeCheckResult gitlib.MergeCheckResult
-	err = json.Unmarshal([]byte(mchResult), &mergeCheckResult)
-	if err != nil { return nil, err }
+	var mergeCheckResult *gitlib.MergeCheckResult = nil
+	if len(mchResult) > 0 {
+		err = json.Unmarshal([]byte(mchResult), &mergeCheckResult)
+		if err != nil { return nil, err }
+	}
 	return &model.PullRequest{
 		PRId: prid,
 		PRAbsId: absId,
+		Author: username,
 		Title: title,
 		ReceiverNamespace: receiverNamespace,
 		ReceiverName: receiverName,
@@ -2614,7 +2927,7 @@ WHERE rowid = ?
 		ProviderNamespace: providerNamespace,
 		ProviderName: providerName,
 		ProviderBranch: providerBranch,
-		MergeCheckResult: &mergeCheckResult,
+		MergeCheckResult: mergeCheckResult,
 		MergeCheckTimestamp: mchtime,
 		Status: prstatus,
 		Timestamp: prtime,
@@ -2632,7 +2945,7 @@ func (dbif *SqliteGitusDatabaseInterface) CheckPullRequestMergeConflict(absId in
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT receiver_namespace, receiver_name, receiver_branch, provider_namespace, provider_name, provider_branch
-FROM %spull_request
+FROM %s_pull_request
 WHERE rowid = ?
 `, pfx))
 	if err != nil { return nil, err }
@@ -2651,7 +2964,7 @@ WHERE rowid = ?
 	mr, err := lgr.CheckBranchMergeConflict(receiverBranch, remoteName, providerBranch)
 	if err != nil { return nil, err }
 	stmt2, err := tx.Prepare(fmt.Sprintf(`
-UPDATE %spull_request
+UPDATE %s_pull_request
 SET merge_conflict_check_result = ?, merge_conflict_check_timestamp = ?
 WHERE rowid = ?
 `, pfx))
@@ -2670,8 +2983,14 @@ func (dbif *SqliteGitusDatabaseInterface) DeletePullRequest(absId int64) error {
 	tx, err := dbif.connection.Begin()
 	if err != nil { return err }
 	defer tx.Rollback()
+	stmt0, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_pull_request_review WHERE pull_request_abs_id = ?
+`, pfx))
+	if err != nil { return err }
+	_, err = stmt0.Exec(absId)
+	if err != nil { return err }
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-DELETE FROM %spull_request WHERE rowid = ?
+DELETE FROM %s_pull_request WHERE rowid = ?
 `, pfx))
 	if err != nil { return err }
 	_, err = stmt.Exec(absId)
@@ -2685,7 +3004,7 @@ func (dbif *SqliteGitusDatabaseInterface) GetAllPullRequestEventPaginated(absId
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT event_type, event_timestamp, event_author, event_content
-FROM %spull_request_event
+FROM %s_pull_request_event
 WHERE pull_request_abs_id = ?
 ORDER BY event_timestamp ASC LIMIT ? OFFSET ?
 `, pfx))
@@ -2710,7 +3029,7 @@ ORDER BY event_timestamp ASC LIMIT ? OFFSET ?
 	return res, nil
 }
 
-func (dbif *SqliteGitusDatabaseInterface) CheckAndMergePullRequest(absId int64, username string) error {
+func (dbif *SqliteGitusDatabaseInterface) CheckAndMergePullRequest(absId int64, username string, strategy int, message string) error {
 	// WARNING: currently only works when when the source &
 	// the target is git repo. currently (2025.7.28) this check
 	// is performed at the controller side, i.e. users cannot
@@ -2718,6 +3037,29 @@ func (dbif *SqliteGitusDatabaseInterface) CheckAndMergePullRequest(absId int64,
 	// code can still be called. DO NOT CALL UNLESS YOU KNOW
 	// WHAT YOU'RE DOING.
 	// TODO: fix this after figuring things out.
+	// the receiver branch may be protected. see
+	// docs/protected-branch.org.
+	pr, err := dbif.GetPullRequestByAbsId(absId)
+	if err != nil { return err }
+	ruleList, err := dbif.GetAllBranchProtectionRule(pr.ReceiverNamespace, pr.ReceiverName)
+	if err != nil { return err }
+	err = model.CheckBranchUpdate(ruleList, username, pr.ReceiverBranch, model.BRANCH_UPDATE_MERGE_PULL_REQUEST)
+	if err != nil { return err }
+	// see docs/pull-request-review.org.
+	setting, err := dbif.GetPullRequestSetting(pr.ReceiverNamespace, pr.ReceiverName)
+	if err != nil { return err }
+	reviewList, err := dbif.GetAllPullRequestReview(absId)
+	if err != nil { return err }
+	err = model.CheckPullRequestReview(setting, reviewList)
+	if err != nil { return err }
+	if !setting.MergeStrategyAllowed(strategy) { return model.ErrMergeStrategyNotAllowed }
+	// see docs/commit-status.org.
+	if len(setting.RequiredStatusContext) > 0 {
+		statusList, _, err := db.GetPullRequestCommitStatus(dbif, pr)
+		if err != nil { return err }
+		err = model.CheckRequiredCommitStatus(setting, statusList)
+		if err != nil { return err }
+	}
 	r, err := dbif.CheckPullRequestMergeConflict(absId)
 	if err != nil { return err }
 	// TODO: this would need to be fixed in the future...
@@ -2732,57 +3074,30 @@ SELECT user_email, user_title FROM %s_user WHERE user_name = ?
 	var email, userTitle string
 	err = rr.Scan(&email, &userTitle)
 	if err != nil { return err }
-	// fetch
-	buf := new(bytes.Buffer)
-	cmd1 := exec.Command("git", "fetch", r.ProviderRemoteName, r.ProviderBranch)
-	cmd1.Dir = r.ReceiverLocation
-	cmd1.Stderr = buf
-	err = cmd1.Run()
-	if err != nil { return errors.New(err.Error() + ": " + buf.String()) }
-	buf.Reset()
-	providerFullName := fmt.Sprintf("%s/%s", r.ProviderRemoteName, r.ProviderBranch)
-	cmd2 := exec.Command("git", "merge-tree", "--write-tree", r.ReceiverBranch, providerFullName)
-	cmd2.Dir = r.ReceiverLocation
-	cmd2.Stdout = buf
-	err = cmd2.Run()
-	if err != nil { return fmt.Errorf("Failed while merge-tree: %s", err.Error()) }
-	treeId := strings.TrimSpace(buf.String())
-	mergeMessage := fmt.Sprintf("merge: from %s/%s to %s", r.ProviderRemoteName, r.ProviderBranch, r.ReceiverBranch)
-	buf.Reset()
-	cmd3 := exec.Command("git", "commit-tree", treeId, "-m", mergeMessage, "-p", r.ReceiverBranch, "-p", providerFullName)
-	cmd3.Dir = r.ReceiverLocation
-	cmd3.Stdout = buf
-	cmd3.Env = os.Environ()
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_AUTHOR_NAME=%s", userTitle))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", email))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_COMMITTER_NAME=%s", userTitle))
-	cmd3.Env = append(cmd3.Env, fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", email))
-	err = cmd3.Run()
-	if err != nil { return fmt.Errorf("Failed while commit-tree: %s", err.Error()) }
-	commitId := strings.TrimSpace(buf.String())
-	buf.Reset()
-	receiverBranchFullName := fmt.Sprintf("refs/heads/%s", r.ReceiverBranch)
-	cmd4 := exec.Command("git", "update-ref", receiverBranchFullName, commitId)
-	cmd4.Dir = r.ReceiverLocation
-	cmd4.Stderr = buf
-	err = cmd4.Run()
-	if err != nil { return fmt.Errorf("Failed while update-ref: %s; %s", err.Error(), buf.String()) }
+	lgr := gitlib.NewLocalGitRepository(r.ReceiverLocation)
+	_, err = lgr.Merge(r.ProviderRemoteName, r.ProviderBranch, r.ReceiverBranch, &gitlib.MergeOption{
+		Strategy: strategy,
+		Message: message,
+		UserName: userTitle,
+		UserEmail: email,
+	})
+	if err != nil { return err }
 	tx, err := dbif.connection.Begin()
 	if err != nil { return err }
 	defer tx.Rollback()
 	t := time.Now().Unix()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-UPDATE %spull_request SET pull_request_status = ?, pull_request_timestamp = ? WHERE rowid = ?
+UPDATE %s_pull_request SET pull_request_status = ?, pull_request_timestamp = ? WHERE rowid = ?
 `, pfx))
 	if err != nil { return err }
 	_, err = stmt.Exec(model.PULL_REQUEST_CLOSED_AS_MERGED, t, absId)
 	if err != nil { return err }
 	stmt2, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %spull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
 VALUES (?,?,?,?,?)
 `, pfx))
 	if err != nil { return err }
-	_, err = stmt2.Exec(absId, model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED, t, username, "")
+	_, err = stmt2.Exec(absId, model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED, t, username, gitlib.MergeStrategyName(strategy))
 	if err != nil { return err }
 	err = tx.Commit()
 	if err != nil { return err }
@@ -2796,7 +3111,7 @@ func (dbif *SqliteGitusDatabaseInterface) CommentOnPullRequest(absId int64, auth
 	if err != nil { return nil, err }
 	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %spull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content) VALUES (?,?,?,?,?)
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content) VALUES (?,?,?,?,?)
 `, pfx))
 	if err != nil { return nil, err }
 	eventContentString := content
@@ -2819,7 +3134,7 @@ func (dbif *SqliteGitusDatabaseInterface) CommentOnPullRequestCode(absId int64,
 	if err != nil { return nil, err }
 	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %spull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
 VALUES (?,?,?,?,?)
 `, pfx))
 	if err != nil { return nil, err }
@@ -2845,7 +3160,7 @@ func (dbif *SqliteGitusDatabaseInterface) ClosePullRequestAsNotMerged(absid int6
 	if err != nil { return err }
 	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %spull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
 VALUES (?,?,?,?,?)
 `, pfx))
 	if err != nil { return err }
@@ -2853,7 +3168,7 @@ VALUES (?,?,?,?,?)
 	_, err = stmt.Exec(absid, model.PULL_REQUEST_EVENT_CLOSE_AS_NOT_MERGED, t, author, new(string))
 	if err != nil { return err }
 	stmt2, err := tx.Prepare(fmt.Sprintf(`
-UPDATE %spull_request
+UPDATE %s_pull_request
 SET pull_request_status = ?
 WHERE rowid = ?
 `, pfx))
@@ -2871,7 +3186,7 @@ func (dbif *SqliteGitusDatabaseInterface) ReopenPullRequest(absid int64, author
 	if err != nil { return err }
 	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %spull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
 VALUES (?,?,?,?,?)
 `, pfx))
 	if err != nil { return err }
@@ -2879,7 +3194,7 @@ VALUES (?,?,?,?,?)
 	_, err = stmt.Exec(absid, model.PULL_REQUEST_EVENT_REOPEN, t, author, new(string))
 	if err != nil { return err }
 	stmt2, err := tx.Prepare(fmt.Sprintf(`
-UPDATE %spull_request
+UPDATE %s_pull_request
 SET pull_request_status = ?
 WHERE rowid = ?
 `, pfx))
@@ -2902,7 +3217,7 @@ func (dbif *SqliteGitusDatabaseInterface) CountPullRequest(query string, namespa
 	queryClause := ""
 	if query != "" { queryClause = "AND title LIKE ? ESCAPE ?" }
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
-SELECT COUNT(*) FROM %spull_request
+SELECT COUNT(*) FROM %s_pull_request
 WHERE receiver_namespace = ? AND receiver_name = ? %s %s
 `, pfx, statusClause, queryClause))
 	if err != nil { return 0, err }
@@ -2934,7 +3249,7 @@ func (dbif *SqliteGitusDatabaseInterface) SearchPullRequestPaginated(query strin
 	if query != "" { queryClause = "AND title LIKE ? ESCAPE ?" }
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
 SELECT rowid, username, pull_request_id, title, receiver_branch, provider_namespace, provider_name, provider_branch, merge_conflict_check_result, merge_conflict_check_timestamp, pull_request_status, pull_request_timestamp
-FROM %spull_request
+FROM %s_pull_request
 WHERE receiver_namespace = ? AND receiver_name = ? %s %s
 ORDER BY pull_request_timestamp DESC LIMIT ? OFFSET ?
 `, pfx, statusClause, queryClause))
@@ -3521,7 +3836,7 @@ VALUES (?,?,?,?,?,?,?)
 		return nil, db.ErrInvalidLocation
 	}
 	os.RemoveAll(p)
-	err = os.MkdirAll(p, fs.ModeDir|0755)
+	err = model.InitSnippetRepository(p)
 	if err != nil { return nil, err }
 	err = tx.Commit()
 	if err != nil { return nil, err }
@@ -3725,6 +4040,17 @@ DELETE FROM %s_snippet WHERE username = ? AND name = ?
 `, pfx))
 	if err != nil { return err }
 	_, err = stmt.Exec(username, name)
+	if err != nil { return err }
+	for _, k := range []string{"snippet_event", "snippet_star", "snippet_fork"} {
+		_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_%s WHERE username = ? AND name = ?
+`, pfx, k), username, name)
+		if err != nil { return err }
+	}
+	// forks of this snippet stay but are no longer forks.
+	_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_snippet_fork WHERE origin_username = ? AND origin_name = ?
+`, pfx), username, name)
 	if err != nil { return err }
 	p := path.Join(dbif.config.SnippetRoot, username, name)
 	err = os.RemoveAll(p)
@@ -3780,64 +4106,1778 @@ WHERE username = ? AND name = ?
 	}, nil
 }
 
-func (dbif *SqliteGitusDatabaseInterface) RegisterWebhookRequest(uuid string, reportUuid string, repoNs string, repoName string, commitId string) error {
+func (dbif *SqliteGitusDatabaseInterface) GetAllSnippetEvent(username string, name string) ([]*model.SnippetEvent, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, event_type, event_time, event_author, event_content
+FROM %s_snippet_event
+WHERE username = ? AND name = ?
+ORDER BY rowid ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	rs, err := stmt.Query(username, name)
+	if err != nil { return nil, err }
+	defer rs.Close()
+	res := make([]*model.SnippetEvent, 0)
+	for rs.Next() {
+		var author, content string
+		var eventType int
+		var eventAbsId, timestamp int64
+		err = rs.Scan(&eventAbsId, &eventType, &timestamp, &author, &content)
+		if err != nil { return nil, err }
+		res = append(res, &model.SnippetEvent{
+			EventAbsId: eventAbsId,
+			EventType: eventType,
+			EventTimestamp: timestamp,
+			EventAuthor: author,
+			EventContent: content,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) NewSnippetEvent(username string, name string, eType int, author string, content string) error {
 	pfx := dbif.config.Database.TablePrefix
 	tx, err := dbif.connection.Begin()
 	if err != nil { return err }
 	defer tx.Rollback()
-	d := new(model.WebhookResult)
-	d.Status = model.WEBHOOK_RESULT_UNDEFINED
-	d.ReportUUID = reportUuid
-	d.UUID = uuid
-	d.RepoNamespace = repoNs
-	d.RepoName = repoName
-	s, err := json.Marshal(d)
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT INTO %s_snippet_event(username, name, event_type, event_time, event_author, event_content) VALUES (?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(username, name, eType, time.Now().Unix(), author, content)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) HardDeleteSnippetEvent(username string, name string, eventAbsId int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
 	if err != nil { return err }
+	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-INSERT INTO %s_webhook_log(uuid, repo_namespace, repo_name, commit_id, webhook_result)
-VALUES (?,?,?,?,?)
+DELETE FROM %s_snippet_event WHERE rowid = ? AND username = ? AND name = ?
 `, pfx))
 	if err != nil { return err }
-	_, err = stmt.Exec(uuid, repoNs, repoName, commitId, string(s))
+	defer stmt.Close()
+	_, err = stmt.Exec(eventAbsId, username, name)
 	if err != nil { return err }
 	err = tx.Commit()
 	if err != nil { return err }
 	return nil
 }
 
-func (dbif *SqliteGitusDatabaseInterface) UpdateWebhookResult(uuid string, result *model.WebhookResult) error {
+func (dbif *SqliteGitusDatabaseInterface) StarSnippet(username string, name string, starUser string) error {
 	pfx := dbif.config.Database.TablePrefix
 	tx, err := dbif.connection.Begin()
 	if err != nil { return err }
 	defer tx.Rollback()
 	stmt, err := tx.Prepare(fmt.Sprintf(`
-UPDATE %s_webhook_log SET webhook_result = ? WHERE uuid = ?
+INSERT OR IGNORE INTO %s_snippet_star(username, name, star_user)
+VALUES (?,?,?)
 `, pfx))
 	if err != nil { return err }
-	s, err := json.Marshal(result)
+	defer stmt.Close()
+	_, err = stmt.Exec(username, name, starUser)
 	if err != nil { return err }
-	_, err = stmt.Exec(string(s), uuid)
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UnstarSnippet(username string, name string, starUser string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_snippet_star
+WHERE username = ? AND name = ? AND star_user = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(username, name, starUser)
 	if err != nil { return err }
 	err = tx.Commit()
 	if err != nil { return err }
 	return nil
 }
 
-func (dbif *SqliteGitusDatabaseInterface) GetWebhookResultByUUID(uuid string) (*model.WebhookResult, error) {
+func (dbif *SqliteGitusDatabaseInterface) IsStarringSnippet(username string, name string, starUser string) (bool, error) {
 	pfx := dbif.config.Database.TablePrefix
 	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
-SELECT webhook_result FROM %s_webhook_log
-WHERE uuid = ?
+SELECT COUNT(*) FROM %s_snippet_star
+WHERE username = ? AND name = ? AND star_user = ?
+`, pfx))
+	if err != nil { return false, err }
+	defer stmt.Close()
+	var c int64
+	err = stmt.QueryRow(username, name, starUser).Scan(&c)
+	if err != nil { return false, err }
+	return c > 0, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountSnippetStar(username string, name string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet_star WHERE username = ? AND name = ?
 `, pfx))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	var c int64
+	err = stmt.QueryRow(username, name).Scan(&c)
+	if err != nil { return 0, err }
+	return c, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) ForkSnippet(originUser string, originName string, username string, name string, status uint8) (*model.Snippet, error) {
+	pfx := dbif.config.Database.TablePrefix
+	origin, err := dbif.GetSnippet(originUser, originName)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
 	if err != nil { return nil, err }
-	r := stmt.QueryRow(uuid)
-	if r.Err() != nil { return nil, r.Err() }
-	var webhookResultStr string
-	err = r.Scan(&webhookResultStr)
+	p := path.Join(dbif.config.SnippetRoot, username, name)
+	if !db.IsSubDir(dbif.config.SnippetRoot, p) {
+		return nil, db.ErrInvalidLocation
+	}
+	tx, err := dbif.connection.Begin()
 	if err != nil { return nil, err }
-	webhookResult := new(model.WebhookResult)
-	err = json.Unmarshal([]byte(webhookResultStr), &webhookResult)
+	defer tx.Rollback()
+	var c int64
+	err = tx.QueryRow(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet WHERE username = ? AND name = ?
+`, pfx), username, name).Scan(&c)
 	if err != nil { return nil, err }
-	return webhookResult, nil
+	if c > 0 { return nil, db.ErrEntityAlreadyExists }
+	t := time.Now()
+	_, err = tx.Exec(fmt.Sprintf(`
+INSERT INTO %s_snippet(snippet_full_name, name, username, description, timestamp, status, shared_user)
+VALUES (?,?,?,?,?,?,?)
+`, pfx), fmt.Sprintf("%s:%s", username, name), name, username, origin.Description, t.Unix(), status, "{}")
+	if err != nil { return nil, err }
+	_, err = tx.Exec(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_snippet_fork(username, name, origin_username, origin_name)
+VALUES (?,?,?,?)
+`, pfx), username, name, originUser, originName)
+	if err != nil { return nil, err }
+	err = origin.CopyRepositoryTo(dbif.config.SnippetRoot, p)
+	if err != nil {
+		os.RemoveAll(p)
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		os.RemoveAll(p)
+		return nil, err
+	}
+	return &model.Snippet{
+		Name: name,
+		BelongingUser: username,
+		Description: origin.Description,
+		Time: t.Unix(),
+		FileList: nil,
+		Status: status,
+		SharedUser: nil,
+	}, nil
 }
 
+func (dbif *SqliteGitusDatabaseInterface) GetSnippetForkOrigin(username string, name string) (string, string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT origin_username, origin_name FROM %s_snippet_fork
+WHERE username = ? AND name = ?
+`, pfx))
+	if err != nil { return "", "", err }
+	defer stmt.Close()
+	var originUser, originName string
+	err = stmt.QueryRow(username, name).Scan(&originUser, &originName)
+	if err == sql.ErrNoRows { return "", "", db.ErrEntityNotFound }
+	if err != nil { return "", "", err }
+	return originUser, originName, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountSnippetFork(username string, name string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_snippet_fork WHERE origin_username = ? AND origin_name = ?
+`, pfx))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	var c int64
+	err = stmt.QueryRow(username, name).Scan(&c)
+	if err != nil { return 0, err }
+	return c, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UpdateWebhookResult(uuid string, result *model.WebhookResult) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+UPDATE %s_webhook_log SET webhook_result = ? WHERE uuid = ?
+`, pfx))
+	if err != nil { return err }
+	s, err := json.Marshal(result)
+	if err != nil { return err }
+	_, err = stmt.Exec(string(s), uuid)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetWebhookResultByUUID(uuid string) (*model.WebhookResult, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT webhook_result FROM %s_webhook_log
+WHERE uuid = ?
+`, pfx))
+	if err != nil { return nil, err }
+	r := stmt.QueryRow(uuid)
+	if r.Err() != nil { return nil, r.Err() }
+	var webhookResultStr string
+	err = r.Scan(&webhookResultStr)
+	if err != nil { return nil, err }
+	webhookResult := new(model.WebhookResult)
+	err = json.Unmarshal([]byte(webhookResultStr), &webhookResult)
+	if err != nil { return nil, err }
+	return webhookResult, nil
+}
+
+
+func (dbif *SqliteGitusDatabaseInterface) RegisterAccessToken(username string, name string, tokenHash string, scope []string, expireTime int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt1, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT 1 FROM %s_user_access_token WHERE user_name = ? AND token_name = ?
+`, pfx))
+	if err != nil { return err }
+	r := stmt1.QueryRow(username, name)
+	if r.Err() != nil { return r.Err() }
+	var verdict string
+	err = r.Scan(&verdict)
+	if err != nil && err != sql.ErrNoRows { return err }
+	if err == nil {
+		return db.ErrEntityAlreadyExists
+	}
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt2, err := tx.Prepare(fmt.Sprintf(`
+INSERT INTO %s_user_access_token(user_name, token_name, token_hash, token_scope, token_create_time, token_expire_time)
+VALUES (?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	_, err = stmt2.Exec(username, name, tokenHash, model.SerializeAccessTokenScope(scope), time.Now().Unix(), expireTime)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllAccessTokenByUsername(username string) ([]*model.GitusAccessToken, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT token_name, token_hash, token_scope, token_create_time, token_expire_time
+FROM %s_user_access_token
+WHERE user_name = ?
+ORDER BY token_create_time DESC
+`, pfx))
+	if err != nil { return nil, err }
+	r, err := stmt.Query(username)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.GitusAccessToken, 0)
+	for r.Next() {
+		var name, hash, scope string
+		var createTime, expireTime int64
+		err = r.Scan(&name, &hash, &scope, &createTime, &expireTime)
+		if err != nil { return nil, err }
+		res = append(res, &model.GitusAccessToken{
+			UserName: username,
+			Name: name,
+			TokenHash: hash,
+			Scope: model.ParseAccessTokenScope(scope),
+			CreateTime: createTime,
+			ExpireTime: expireTime,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAccessTokenByHash(tokenHash string) (*model.GitusAccessToken, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT user_name, token_name, token_scope, token_create_time, token_expire_time
+FROM %s_user_access_token
+WHERE token_hash = ?
+`, pfx))
+	if err != nil { return nil, err }
+	var username, name, scope string
+	var createTime, expireTime int64
+	err = stmt.QueryRow(tokenHash).Scan(&username, &name, &scope, &createTime, &expireTime)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return &model.GitusAccessToken{
+		UserName: username,
+		Name: name,
+		TokenHash: tokenHash,
+		Scope: model.ParseAccessTokenScope(scope),
+		CreateTime: createTime,
+		ExpireTime: expireTime,
+	}, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RevokeAccessToken(username string, name string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_user_access_token
+WHERE user_name = ? AND token_name = ?
+`, pfx))
+	if err != nil { return err }
+	_, err = stmt.Exec(username, name)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllBranchProtectionRule(ns string, name string) ([]*model.BranchProtectionRule, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT pattern, disallow_force_push, disallow_deletion, allowed_pusher, require_pull_request
+FROM %s_branch_protection
+WHERE repo_namespace = ? AND repo_name = ?
+ORDER BY pattern ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(ns, name)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.BranchProtectionRule, 0)
+	for r.Next() {
+		var pattern, allowedPusher string
+		var disallowForcePush, disallowDeletion, requirePullRequest int
+		err = r.Scan(&pattern, &disallowForcePush, &disallowDeletion, &allowedPusher, &requirePullRequest)
+		if err != nil { return nil, err }
+		res = append(res, &model.BranchProtectionRule{
+			Pattern: pattern,
+			DisallowForcePush: disallowForcePush != 0,
+			DisallowDeletion: disallowDeletion != 0,
+			AllowedPusher: model.ParseUsernameList(allowedPusher),
+			RequirePullRequest: requirePullRequest != 0,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetBranchProtectionRule(ns string, name string, rule *model.BranchProtectionRule) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_branch_protection(repo_namespace, repo_name, pattern, disallow_force_push, disallow_deletion, allowed_pusher, require_pull_request)
+VALUES (?,?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	b2i := func(b bool) int { if b { return 1 } else { return 0 } }
+	_, err = stmt.Exec(ns, name, rule.Pattern, b2i(rule.DisallowForcePush), b2i(rule.DisallowDeletion), model.SerializeUsernameList(rule.AllowedPusher), b2i(rule.RequirePullRequest))
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) DeleteBranchProtectionRule(ns string, name string, pattern string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_branch_protection
+WHERE repo_namespace = ? AND repo_name = ? AND pattern = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, pattern)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetPullRequestSetting(ns string, name string) (*model.PullRequestSetting, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT required_approval_count, required_reviewer, allowed_merge_strategy, required_status_context
+FROM %s_pull_request_setting
+WHERE repo_namespace = ? AND repo_name = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	var requiredApprovalCount int
+	var requiredReviewer, allowedMergeStrategy string
+	var requiredStatusContext sql.NullString
+	err = stmt.QueryRow(ns, name).Scan(&requiredApprovalCount, &requiredReviewer, &allowedMergeStrategy, &requiredStatusContext)
+	if err == sql.ErrNoRows {
+		return &model.PullRequestSetting{
+			RequiredApprovalCount: 0,
+			RequiredReviewer: make([]string, 0),
+			AllowedMergeStrategy: make([]int, 0),
+			RequiredStatusContext: make([]string, 0),
+		}, nil
+	}
+	if err != nil { return nil, err }
+	return &model.PullRequestSetting{
+		RequiredApprovalCount: requiredApprovalCount,
+		RequiredReviewer: model.ParseUsernameList(requiredReviewer),
+		AllowedMergeStrategy: model.ParseMergeStrategyList(allowedMergeStrategy),
+		RequiredStatusContext: model.ParseCommitStatusContextList(requiredStatusContext.String),
+	}, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetPullRequestSetting(ns string, name string, setting *model.PullRequestSetting) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_pull_request_setting(repo_namespace, repo_name, required_approval_count, required_reviewer, allowed_merge_strategy, required_status_context)
+VALUES (?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, setting.RequiredApprovalCount, model.SerializeUsernameList(setting.RequiredReviewer), model.SerializeMergeStrategyList(setting.AllowedMergeStrategy), strings.Join(setting.RequiredStatusContext, ","))
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) ReviewPullRequest(review *model.PullRequestReview) (*model.PullRequestEvent, error) {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return nil, err }
+	defer tx.Rollback()
+	t := time.Now().Unix()
+	review.Timestamp = t
+	review.Dismissed = false
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT INTO %s_pull_request_review(pull_request_abs_id, reviewer, review_state, review_summary, review_commit_id, review_timestamp, review_dismissed)
+VALUES (?,?,?,?,?,?,0)
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	_, err = stmt.Exec(review.PRAbsId, review.Reviewer, review.State, review.Summary, review.CommitId, t)
+	if err != nil { return nil, err }
+	stmt2, err := tx.Prepare(fmt.Sprintf(`
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+VALUES (?,?,?,?,?)
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt2.Close()
+	contentBytes, _ := json.Marshal(review)
+	contentString := string(contentBytes)
+	_, err = stmt2.Exec(review.PRAbsId, model.PULL_REQUEST_EVENT_REVIEW, t, review.Reviewer, contentString)
+	if err != nil { return nil, err }
+	err = tx.Commit()
+	if err != nil { return nil, err }
+	return &model.PullRequestEvent{
+		PRAbsId: review.PRAbsId,
+		EventType: model.PULL_REQUEST_EVENT_REVIEW,
+		EventTimestamp: t,
+		EventAuthor: review.Reviewer,
+		EventContent: contentString,
+	}, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllPullRequestReview(absId int64) ([]*model.PullRequestReview, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT reviewer, review_state, review_summary, review_commit_id, review_timestamp, review_dismissed
+FROM %s_pull_request_review
+WHERE pull_request_abs_id = ?
+ORDER BY review_timestamp ASC, rowid ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(absId)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.PullRequestReview, 0)
+	for r.Next() {
+		var reviewer, summary, commitId string
+		var state, dismissed int
+		var timestamp int64
+		err = r.Scan(&reviewer, &state, &summary, &commitId, &timestamp, &dismissed)
+		if err != nil { return nil, err }
+		res = append(res, &model.PullRequestReview{
+			PRAbsId: absId,
+			Reviewer: reviewer,
+			State: state,
+			Summary: summary,
+			CommitId: commitId,
+			Timestamp: timestamp,
+			Dismissed: dismissed != 0,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UpdatePullRequestProviderBranch(providerNamespace string, providerName string, providerBranch string, author string, commitId string) ([]int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return nil, err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+SELECT rowid FROM %s_pull_request
+WHERE provider_namespace = ? AND provider_name = ? AND provider_branch = ? AND pull_request_status = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(providerNamespace, providerName, providerBranch, model.PULL_REQUEST_OPEN)
+	if err != nil { return nil, err }
+	absIdList := make([]int64, 0)
+	for r.Next() {
+		var absId int64
+		err = r.Scan(&absId)
+		if err != nil { r.Close(); return nil, err }
+		absIdList = append(absIdList, absId)
+	}
+	r.Close()
+	if len(absIdList) <= 0 { return absIdList, nil }
+	stmt2, err := tx.Prepare(fmt.Sprintf(`
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+VALUES (?,?,?,?,?)
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt2.Close()
+	stmt3, err := tx.Prepare(fmt.Sprintf(`
+UPDATE %s_pull_request_review SET review_dismissed = 1
+WHERE pull_request_abs_id = ? AND review_state = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt3.Close()
+	t := time.Now().Unix()
+	for _, absId := range absIdList {
+		_, err = stmt2.Exec(absId, model.PULL_REQUEST_EVENT_UPDATE_ON_BRANCH, t, author, commitId)
+		if err != nil { return nil, err }
+		_, err = stmt3.Exec(absId, model.PULL_REQUEST_REVIEW_APPROVE)
+		if err != nil { return nil, err }
+	}
+	err = tx.Commit()
+	if err != nil { return nil, err }
+	return absIdList, nil
+}
+
+// fills the labels, assignees & milestone of the issues.
+func (dbif *SqliteGitusDatabaseInterface) fillIssueMetadata(issueList []*model.Issue) error {
+	if len(issueList) <= 0 { return nil }
+	pfx := dbif.config.Database.TablePrefix
+	m := make(map[int64]*model.Issue, len(issueList))
+	placeholder := make([]string, 0, len(issueList))
+	args := make([]any, 0, len(issueList))
+	for _, k := range issueList {
+		k.IssueLabel = make([]string, 0)
+		k.IssueAssignee = make([]string, 0)
+		k.IssueMilestone = ""
+		m[k.IssueAbsId] = k
+		placeholder = append(placeholder, "?")
+		args = append(args, k.IssueAbsId)
+	}
+	inClause := strings.Join(placeholder, ",")
+	for i, q := range []string{
+		"SELECT issue_abs_id, label_name FROM %s_issue_label_assoc WHERE issue_abs_id IN (%s) ORDER BY label_name ASC",
+		"SELECT issue_abs_id, username FROM %s_issue_assignee WHERE issue_abs_id IN (%s) ORDER BY username ASC",
+		"SELECT issue_abs_id, milestone_title FROM %s_issue_milestone_assoc WHERE issue_abs_id IN (%s)",
+	} {
+		rs, err := dbif.connection.Query(fmt.Sprintf(q, pfx, inClause), args...)
+		if err != nil { return err }
+		for rs.Next() {
+			var absId int64
+			var v string
+			err = rs.Scan(&absId, &v)
+			if err != nil { rs.Close(); return err }
+			issue, ok := m[absId]
+			if !ok { continue }
+			switch i {
+			case 0: issue.IssueLabel = append(issue.IssueLabel, v)
+			case 1: issue.IssueAssignee = append(issue.IssueAssignee, v)
+			case 2: issue.IssueMilestone = v
+			}
+		}
+		rs.Close()
+	}
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllRepositoryIssueLabel(ns string, name string) ([]*model.IssueLabel, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT label_name, label_color, label_description
+FROM %s_issue_label
+WHERE repo_namespace = ? AND repo_name = ?
+ORDER BY label_name ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(ns, name)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.IssueLabel, 0)
+	for r.Next() {
+		var labelName, color, description string
+		err = r.Scan(&labelName, &color, &description)
+		if err != nil { return nil, err }
+		res = append(res, &model.IssueLabel{
+			Name: labelName,
+			Color: color,
+			Description: description,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetRepositoryIssueLabel(ns string, name string, label *model.IssueLabel) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_issue_label(repo_namespace, repo_name, label_name, label_color, label_description)
+VALUES (?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, label.Name, label.Color, label.Description)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) DeleteRepositoryIssueLabel(ns string, name string, labelName string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_issue_label_assoc
+WHERE label_name = ? AND issue_abs_id IN (SELECT rowid FROM %s_issue WHERE repo_namespace = ? AND repo_name = ?)
+`, pfx, pfx), labelName, ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_issue_label
+WHERE repo_namespace = ? AND repo_name = ? AND label_name = ?
+`, pfx), ns, name, labelName)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllRepositoryIssueMilestone(ns string, name string) ([]*model.IssueMilestone, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT milestone_title, milestone_description, milestone_due, milestone_closed
+FROM %s_issue_milestone
+WHERE repo_namespace = ? AND repo_name = ?
+ORDER BY milestone_closed ASC, milestone_due = 0 ASC, milestone_due ASC, milestone_title ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(ns, name)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.IssueMilestone, 0)
+	for r.Next() {
+		var title, description string
+		var due int64
+		var closed int
+		err = r.Scan(&title, &description, &due, &closed)
+		if err != nil { return nil, err }
+		res = append(res, &model.IssueMilestone{
+			Title: title,
+			Description: description,
+			DueTime: due,
+			Closed: closed != 0,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetRepositoryIssueMilestone(ns string, name string, milestone *model.IssueMilestone) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_issue_milestone(repo_namespace, repo_name, milestone_title, milestone_description, milestone_due, milestone_closed)
+VALUES (?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	closed := 0
+	if milestone.Closed { closed = 1 }
+	_, err = stmt.Exec(ns, name, milestone.Title, milestone.Description, milestone.DueTime, closed)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) DeleteRepositoryIssueMilestone(ns string, name string, title string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_issue_milestone_assoc
+WHERE milestone_title = ? AND issue_abs_id IN (SELECT rowid FROM %s_issue WHERE repo_namespace = ? AND repo_name = ?)
+`, pfx, pfx), title, ns, name)
+	if err != nil { return err }
+	_, err = tx.Exec(fmt.Sprintf(`
+DELETE FROM %s_issue_milestone
+WHERE repo_namespace = ? AND repo_name = ? AND milestone_title = ?
+`, pfx), ns, name, title)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+// runs `query` (which should have 2 parameters: the issue abs id &
+// the value) & records an issue event if any row is affected.
+func (dbif *SqliteGitusDatabaseInterface) updateIssueAssoc(query string, issueAbsId int64, value string, eType int, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	r, err := tx.Exec(query, issueAbsId, value)
+	if err != nil { return err }
+	n, err := r.RowsAffected()
+	if err != nil { return err }
+	if n <= 0 { return nil }
+	_, err = tx.Exec(fmt.Sprintf(`
+INSERT INTO %s_issue_event(issue_abs_id, issue_event_type, issue_event_time, issue_event_author, issue_event_content) VALUES (?,?,?,?,?)
+`, pfx), issueAbsId, eType, time.Now().Unix(), author, value)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) AddIssueLabel(issueAbsId int64, labelName string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+INSERT OR IGNORE INTO %s_issue_label_assoc(issue_abs_id, label_name) VALUES (?,?)
+`, pfx), issueAbsId, labelName, model.EVENT_LABEL_ADDED, author)
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RemoveIssueLabel(issueAbsId int64, labelName string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+DELETE FROM %s_issue_label_assoc WHERE issue_abs_id = ? AND label_name = ?
+`, pfx), issueAbsId, labelName, model.EVENT_LABEL_REMOVED, author)
+}
+
+func (dbif *SqliteGitusDatabaseInterface) AddIssueAssignee(issueAbsId int64, username string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+INSERT OR IGNORE INTO %s_issue_assignee(issue_abs_id, username) VALUES (?,?)
+`, pfx), issueAbsId, username, model.EVENT_ASSIGNEE_ADDED, author)
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RemoveIssueAssignee(issueAbsId int64, username string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+DELETE FROM %s_issue_assignee WHERE issue_abs_id = ? AND username = ?
+`, pfx), issueAbsId, username, model.EVENT_ASSIGNEE_REMOVED, author)
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetIssueMilestone(issueAbsId int64, title string, author string) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT milestone_title FROM %s_issue_milestone_assoc WHERE issue_abs_id = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	var oldTitle string
+	err = stmt.QueryRow(issueAbsId).Scan(&oldTitle)
+	if err != nil && err != sql.ErrNoRows { return err }
+	if oldTitle == title { return nil }
+	if len(title) <= 0 {
+		return dbif.updateIssueAssoc(fmt.Sprintf(`
+DELETE FROM %s_issue_milestone_assoc WHERE issue_abs_id = ? AND milestone_title = ?
+`, pfx), issueAbsId, oldTitle, model.EVENT_MILESTONE_REMOVED, author)
+	}
+	return dbif.updateIssueAssoc(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_issue_milestone_assoc(issue_abs_id, milestone_title) VALUES (?,?)
+`, pfx), issueAbsId, title, model.EVENT_MILESTONE_SET, author)
+}
+
+func (dbif *SqliteGitusDatabaseInterface) AddIssueReferenceEvent(issueAbsId int64, eType int, author string, content string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf(`
+INSERT INTO %s_issue_event(issue_abs_id, issue_event_type, issue_event_time, issue_event_author, issue_event_content)
+SELECT ?, ?, ?, ?, ?
+WHERE NOT EXISTS (SELECT 1 FROM %s_issue_event WHERE issue_abs_id = ? AND issue_event_type = ? AND issue_event_content = ?)
+`, pfx, pfx), issueAbsId, eType, time.Now().Unix(), author, content, issueAbsId, eType, content)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) AddPullRequestReferenceEvent(absId int64, author string, content string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf(`
+INSERT INTO %s_pull_request_event(pull_request_abs_id, event_type, event_timestamp, event_author, event_content)
+SELECT ?, ?, ?, ?, ?
+WHERE NOT EXISTS (SELECT 1 FROM %s_pull_request_event WHERE pull_request_abs_id = ? AND event_type = ? AND event_content = ?)
+`, pfx, pfx), absId, model.PULL_REQUEST_EVENT_REFERENCED, time.Now().Unix(), author, content, absId, model.PULL_REQUEST_EVENT_REFERENCED, content)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) NewNotification(n *model.Notification) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT INTO %s_notification(username, notification_type, actor, title, content, link, notification_timestamp, is_read)
+VALUES (?,?,?,?,?,?,?,0)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	n.Timestamp = time.Now().Unix()
+	n.Read = false
+	r, err := stmt.Exec(n.Username, n.Type, n.Actor, n.Title, n.Content, n.Link, n.Timestamp)
+	if err != nil { return err }
+	n.Id, err = r.LastInsertId()
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountNotification(username string, unreadOnly bool) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	cond := ""
+	if unreadOnly { cond = " AND is_read = 0" }
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_notification WHERE username = ?%s
+`, pfx, cond))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	var res int64
+	err = stmt.QueryRow(username).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetNotificationPaginated(username string, unreadOnly bool, pageNum int64, pageSize int64) ([]*model.Notification, error) {
+	pfx := dbif.config.Database.TablePrefix
+	cond := ""
+	if unreadOnly { cond = " AND is_read = 0" }
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, notification_type, actor, title, content, link, notification_timestamp, is_read
+FROM %s_notification
+WHERE username = ?%s
+ORDER BY rowid DESC LIMIT ? OFFSET ?
+`, pfx, cond))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(username, pageSize, pageNum*pageSize)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.Notification, 0)
+	for r.Next() {
+		var id, timestamp int64
+		var nType, isRead int
+		var actor, title, content, link string
+		err = r.Scan(&id, &nType, &actor, &title, &content, &link, &timestamp, &isRead)
+		if err != nil { return nil, err }
+		res = append(res, &model.Notification{
+			Id: id,
+			Username: username,
+			Type: nType,
+			Actor: actor,
+			Title: title,
+			Content: content,
+			Link: link,
+			Timestamp: timestamp,
+			Read: isRead != 0,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) MarkNotificationRead(username string, id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+UPDATE %s_notification SET is_read = 1
+WHERE username = ? AND rowid = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(username, id)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) MarkAllNotificationRead(username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+UPDATE %s_notification SET is_read = 1
+WHERE username = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(username)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) DeleteAllReadNotification(username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_notification
+WHERE username = ? AND is_read = 1
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(username)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetNotificationSetting(username string) (*model.NotificationSetting, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT setting FROM %s_user_notification_setting WHERE username = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	var s string
+	err = stmt.QueryRow(username).Scan(&s)
+	if err == sql.ErrNoRows { return model.DefaultNotificationSetting(), nil }
+	if err != nil { return nil, err }
+	res := model.DefaultNotificationSetting()
+	err = json.Unmarshal([]byte(s), res)
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetNotificationSetting(username string, setting *model.NotificationSetting) error {
+	pfx := dbif.config.Database.TablePrefix
+	s, err := json.Marshal(setting)
+	if err != nil { return err }
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT OR REPLACE INTO %s_user_notification_setting(username, setting)
+VALUES (?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(username, string(s))
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) WatchRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+INSERT OR IGNORE INTO %s_repo_watch(repo_namespace, repo_name, username)
+VALUES (?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, username)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UnwatchRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return err }
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+DELETE FROM %s_repo_watch
+WHERE repo_namespace = ? AND repo_name = ? AND username = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, username)
+	if err != nil { return err }
+	err = tx.Commit()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) IsWatchingRepository(ns string, name string, username string) (bool, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_repo_watch
+WHERE repo_namespace = ? AND repo_name = ? AND username = ?
+`, pfx))
+	if err != nil { return false, err }
+	defer stmt.Close()
+	var c int64
+	err = stmt.QueryRow(ns, name, username).Scan(&c)
+	if err != nil { return false, err }
+	return c > 0, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllRepositoryWatcher(ns string, name string) ([]string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT username FROM %s_repo_watch
+WHERE repo_namespace = ? AND repo_name = ?
+ORDER BY username ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(ns, name)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]string, 0)
+	for r.Next() {
+		var s string
+		err = r.Scan(&s)
+		if err != nil { return nil, err }
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) StarRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT OR IGNORE INTO %s_repo_star(repo_namespace, repo_name, username, star_time)
+VALUES (?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, username, time.Now().Unix())
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UnstarRepository(ns string, name string, username string) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+DELETE FROM %s_repo_star
+WHERE repo_namespace = ? AND repo_name = ? AND username = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, username)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) IsStarringRepository(ns string, name string, username string) (bool, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_repo_star
+WHERE repo_namespace = ? AND repo_name = ? AND username = ?
+`, pfx))
+	if err != nil { return false, err }
+	defer stmt.Close()
+	var c int64
+	err = stmt.QueryRow(ns, name, username).Scan(&c)
+	if err != nil { return false, err }
+	return c > 0, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RecordRepositoryPush(ns string, name string, pushTime int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT INTO %s_repo_activity(repo_namespace, repo_name, last_push_time)
+VALUES (?,?,?)
+ON CONFLICT (repo_namespace, repo_name) DO UPDATE SET last_push_time = excluded.last_push_time
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(ns, name, pushTime)
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllVisibleRepositoryExplorePaginated(username string, sortBy string, pageNum int64, pageSize int64) ([]*model.Repository, error) {
+	pfx := dbif.config.Database.TablePrefix
+	var order string
+	switch sortBy {
+	case model.REPO_EXPLORE_SORT_STAR:
+		order = "star_count DESC, repo.rowid DESC"
+	case model.REPO_EXPLORE_SORT_NEWEST:
+		order = "repo.rowid DESC"
+	default:
+		order = "last_push_time DESC, repo.rowid DESC"
+	}
+	// the visibility part is the same as `GetAllVisibleRepositoryPaginated`.
+	var nsQuery, cond string
+	var args []any
+	if len(username) > 0 {
+		upat := db.ToSqlSearchPattern(username)
+		nsQuery = fmt.Sprintf("SELECT ns_name, ns_status FROM %s_namespace WHERE ns_status = 1 OR ns_status = 3 OR (ns_owner = ? OR ns_acl LIKE ? ESCAPE ?)", pfx)
+		cond = `((ns_status = 1 OR ns_status = 3) AND ns.ns_name IS NOT NULL)
+OR (repo_status = 1 OR repo_status = 4 OR repo_status = 5)
+OR (repo_owner = ? OR repo_acl LIKE ? ESCAPE ?)`
+		args = []any{username, upat, "\\", username, upat, "\\", pageSize, pageNum*pageSize}
+	} else {
+		nsQuery = fmt.Sprintf("SELECT ns_name, ns_status FROM %s_namespace WHERE ns_status = 1 OR ns_status = 3", pfx)
+		cond = `((ns_status = 1 OR ns_status = 3) AND ns.ns_name IS NOT NULL)
+OR (repo_status = 1 OR repo_status = 4)`
+		args = []any{pageSize, pageNum*pageSize}
+	}
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT repo.repo_type, repo.repo_namespace, repo.repo_name, repo_description, repo_owner, repo_acl, repo_status, repo_fork_origin_namespace, repo_fork_origin_name, repo_label_list, repo_webhook, repo.rowid,
+  (SELECT COUNT(*) FROM %s_repo_star st WHERE st.repo_namespace = repo.repo_namespace AND st.repo_name = repo.repo_name) AS star_count,
+  (SELECT COUNT(*) FROM %s_repo_watch wt WHERE wt.repo_namespace = repo.repo_namespace AND wt.repo_name = repo.repo_name) AS watcher_count,
+  COALESCE((SELECT act.last_push_time FROM %s_repo_activity act WHERE act.repo_namespace = repo.repo_namespace AND act.repo_name = repo.repo_name), 0) AS last_push_time
+FROM %s_repository repo
+LEFT JOIN (%s) ns
+ON repo.repo_namespace = ns.ns_name
+WHERE %s
+ORDER BY %s LIMIT ? OFFSET ?
+`, pfx, pfx, pfx, pfx, nsQuery, cond, order))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	rs, err := stmt.Query(args...)
+	if err != nil { return nil, err }
+	defer rs.Close()
+	res := make([]*model.Repository, 0)
+	var ns, name, desc, owner, acl, forkOriginNs, forkOriginName, labelList, webhookstr string
+	var status, rowid, starCount, watcherCount, lastPushTime int64
+	var repoType uint8
+	for rs.Next() {
+		err = rs.Scan(&repoType, &ns, &name, &desc, &owner, &acl, &status, &forkOriginNs, &forkOriginName, &labelList, &webhookstr, &rowid, &starCount, &watcherCount, &lastPushTime)
+		if err != nil { return nil, err }
+		a, err := model.ParseACL(acl)
+		if err != nil { return nil, err }
+		p := path.Join(dbif.config.GitRoot, ns, name)
+		lr, err := model.CreateLocalRepository(repoType, ns, name, p)
+		if err != nil { return nil, err }
+		var tags []string = nil
+		if len(labelList) > 0 {
+			tags = strings.Split(labelList[1:len(labelList)-1], "}{")
+		}
+		webhookobj, err := model.ParseWebHookConfig(webhookstr)
+		if err != nil { return nil, err }
+		res = append(res, &model.Repository{
+			AbsId: rowid,
+			Type: repoType,
+			Namespace: ns,
+			Name: name,
+			Owner: owner,
+			Description: desc,
+			AccessControlList: a,
+			Status: model.GitusRepositoryStatus(status),
+			Repository: lr,
+			ForkOriginNamespace: forkOriginNs,
+			ForkOriginName: forkOriginName,
+			RepoLabelList: tags,
+			WebHookConfig: webhookobj,
+			StarCount: starCount,
+			WatcherCount: watcherCount,
+			LastPushTime: lastPushTime,
+		})
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllAdminUsername() ([]string, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT user_name FROM %s_user
+WHERE user_status = ? OR user_status = ?
+ORDER BY rowid ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(model.ADMIN, model.SUPER_ADMIN)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]string, 0)
+	for r.Next() {
+		var s string
+		err = r.Scan(&s)
+		if err != nil { return nil, err }
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) EnqueueJob(job *model.Job) error {
+	pfx := dbif.config.Database.TablePrefix
+	t := time.Now().Unix()
+	if job.NextRunTime <= 0 { job.NextRunTime = t }
+	if job.MaxAttempt <= 0 { job.MaxAttempt = model.DEFAULT_JOB_MAX_ATTEMPT }
+	job.Status = model.JOB_PENDING
+	job.Attempt = 0
+	job.CreateTime = t
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT INTO %s_job(job_type, payload, job_status, attempt, max_attempt, last_error, next_run_time, create_time)
+VALUES (?, ?, ?, 0, ?, '', ?, ?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	r, err := stmt.Exec(job.Type, job.Payload, model.JOB_PENDING, job.MaxAttempt, job.NextRunTime, t)
+	if err != nil { return err }
+	job.Id, err = r.LastInsertId()
+	if err != nil { return err }
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) ClaimJob() (*model.Job, error) {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return nil, err }
+	defer tx.Rollback()
+	stmt1, err := tx.Prepare(fmt.Sprintf(`
+SELECT rowid, job_type, payload, attempt, max_attempt, last_error, next_run_time, create_time
+FROM %s_job
+WHERE job_status = ? AND next_run_time <= ?
+ORDER BY next_run_time ASC, rowid ASC LIMIT 1
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt1.Close()
+	var job model.Job
+	err = stmt1.QueryRow(model.JOB_PENDING, time.Now().Unix()).Scan(&job.Id, &job.Type, &job.Payload, &job.Attempt, &job.MaxAttempt, &job.LastError, &job.NextRunTime, &job.CreateTime)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	stmt2, err := tx.Prepare(fmt.Sprintf(`
+UPDATE %s_job SET job_status = ?, attempt = attempt + 1 WHERE rowid = ? AND job_status = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt2.Close()
+	// the status is checked again in case another worker (e.g. in
+	// another process sharing the same db file) claimed it first.
+	r, err := stmt2.Exec(model.JOB_RUNNING, job.Id, model.JOB_PENDING)
+	if err != nil { return nil, err }
+	n, err := r.RowsAffected()
+	if err != nil { return nil, err }
+	if n != 1 { return nil, db.ErrEntityNotFound }
+	err = tx.Commit()
+	if err != nil { return nil, err }
+	job.Status = model.JOB_RUNNING
+	job.Attempt += 1
+	return &job, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CompleteJob(id int64) error {
+	return dbif.DeleteJob(id)
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RescheduleJob(id int64, lastError string, nextRunTime int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_job SET job_status = ?, last_error = ?, next_run_time = ?
+WHERE rowid = ? AND job_status = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(model.JOB_PENDING, lastError, nextRunTime, id, model.JOB_RUNNING)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) FailJob(id int64, lastError string) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_job SET job_status = ?, last_error = ?
+WHERE rowid = ? AND job_status = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(model.JOB_FAILED, lastError, id, model.JOB_RUNNING)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) RetryJob(id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_job SET job_status = ?, attempt = 0, next_run_time = ?
+WHERE rowid = ? AND job_status = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(model.JOB_PENDING, time.Now().Unix(), id, model.JOB_FAILED)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) DeleteJob(id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+DELETE FROM %s_job WHERE rowid = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(id)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) ResetRunningJob() error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_job SET job_status = ? WHERE job_status = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(model.JOB_PENDING, model.JOB_RUNNING)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountJob(status int) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	cond := ""
+	args := make([]any, 0)
+	if status != 0 {
+		cond = " WHERE job_status = ?"
+		args = append(args, status)
+	}
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_job%s
+`, pfx, cond))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	var res int64
+	err = stmt.QueryRow(args...).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetJobPaginated(status int, pageNum int64, pageSize int64) ([]*model.Job, error) {
+	pfx := dbif.config.Database.TablePrefix
+	cond := ""
+	args := make([]any, 0)
+	if status != 0 {
+		cond = " WHERE job_status = ?"
+		args = append(args, status)
+	}
+	args = append(args, pageSize, pageNum*pageSize)
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, job_type, payload, job_status, attempt, max_attempt, last_error, next_run_time, create_time
+FROM %s_job%s
+ORDER BY rowid ASC LIMIT ? OFFSET ?
+`, pfx, cond))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(args...)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.Job, 0)
+	for r.Next() {
+		var job model.Job
+		err = r.Scan(&job.Id, &job.Type, &job.Payload, &job.Status, &job.Attempt, &job.MaxAttempt, &job.LastError, &job.NextRunTime, &job.CreateTime)
+		if err != nil { return nil, err }
+		res = append(res, &job)
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) NewWebhookDelivery(d *model.WebhookDelivery) error {
+	pfx := dbif.config.Database.TablePrefix
+	res := new(model.WebhookResult)
+	res.Status = model.WEBHOOK_RESULT_UNDEFINED
+	res.ReportUUID = d.ReportUUID
+	res.UUID = d.UUID
+	res.RepoNamespace = d.RepoNamespace
+	res.RepoName = d.RepoName
+	s, err := json.Marshal(res)
+	if err != nil { return err }
+	if d.CreateTime <= 0 { d.CreateTime = time.Now().Unix() }
+	if d.Status == 0 { d.Status = model.WEBHOOK_DELIVERY_PENDING }
+	d.Result = res
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT INTO %s_webhook_log(uuid, repo_namespace, repo_name, commit_id, webhook_result, ref_name, target_url, request_header, request_payload, response_status, response_body, delivery_error, duration, delivery_status, attempt, create_time, delivery_time, redelivery_of, webhook_id, event)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(d.UUID, d.RepoNamespace, d.RepoName, d.CommitId, string(s), d.RefName, d.TargetURL, d.RequestHeader, d.RequestPayload, d.ResponseStatus, d.ResponseBody, d.Error, d.Duration, d.Status, d.Attempt, d.CreateTime, d.DeliveryTime, d.RedeliveryOf, d.WebhookId, d.Event)
+	return err
+}
+
+const sqliteWebhookDeliveryColumn = "uuid, repo_namespace, repo_name, commit_id, webhook_result, ref_name, target_url, request_header, request_payload, response_status, response_body, delivery_error, duration, delivery_status, attempt, create_time, delivery_time, redelivery_of, webhook_id, event"
+
+func scanSqliteWebhookDelivery(r interface{ Scan(dest ...any) error }) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	var resultStr string
+	err := r.Scan(&d.UUID, &d.RepoNamespace, &d.RepoName, &d.CommitId, &resultStr, &d.RefName, &d.TargetURL, &d.RequestHeader, &d.RequestPayload, &d.ResponseStatus, &d.ResponseBody, &d.Error, &d.Duration, &d.Status, &d.Attempt, &d.CreateTime, &d.DeliveryTime, &d.RedeliveryOf, &d.WebhookId, &d.Event)
+	if err != nil { return nil, err }
+	d.Result = new(model.WebhookResult)
+	err = json.Unmarshal([]byte(resultStr), d.Result)
+	if err != nil { return nil, err }
+	d.ReportUUID = d.Result.ReportUUID
+	return &d, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetWebhookDelivery(uuid string) (*model.WebhookDelivery, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT %s FROM %s_webhook_log WHERE uuid = ?
+`, sqliteWebhookDeliveryColumn, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res, err := scanSqliteWebhookDelivery(stmt.QueryRow(uuid))
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UpdateWebhookDelivery(d *model.WebhookDelivery) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_webhook_log
+SET request_header = ?, response_status = ?, response_body = ?, delivery_error = ?, duration = ?, delivery_status = ?, attempt = ?, delivery_time = ?
+WHERE uuid = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(d.RequestHeader, d.ResponseStatus, d.ResponseBody, d.Error, d.Duration, d.Status, d.Attempt, d.DeliveryTime, d.UUID)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountWebhookDelivery(repoNs string, repoName string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_webhook_log WHERE repo_namespace = ? AND repo_name = ?
+`, pfx))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	var res int64
+	err = stmt.QueryRow(repoNs, repoName).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetWebhookDeliveryPaginated(repoNs string, repoName string, pageNum int64, pageSize int64) ([]*model.WebhookDelivery, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT %s FROM %s_webhook_log
+WHERE repo_namespace = ? AND repo_name = ?
+ORDER BY create_time DESC, rowid DESC LIMIT ? OFFSET ?
+`, sqliteWebhookDeliveryColumn, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(repoNs, repoName, pageSize, pageNum*pageSize)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.WebhookDelivery, 0)
+	for r.Next() {
+		d, err := scanSqliteWebhookDelivery(r)
+		if err != nil { return nil, err }
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) NewWebhook(w *model.Webhook) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	if w.CreateTime <= 0 { w.CreateTime = time.Now().Unix() }
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT INTO %s_webhook(webhook_namespace, webhook_repo_name, target_url, secret, payload_type, event_list, branch_filter, enabled, create_time)
+VALUES (?,?,?,?,?,?,?,?,?)
+`, pfx))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	enabled := 0
+	if w.Enable { enabled = 1 }
+	r, err := stmt.Exec(w.Namespace, w.RepoName, w.TargetURL, w.Secret, w.PayloadType, strings.Join(w.EventList, ","), strings.Join(w.BranchFilter, ","), enabled, w.CreateTime)
+	if err != nil { return 0, err }
+	w.Id, err = r.LastInsertId()
+	if err != nil { return 0, err }
+	return w.Id, nil
+}
+
+func scanSqliteWebhook(r interface{ Scan(dest ...any) error }) (*model.Webhook, error) {
+	var w model.Webhook
+	var eventList, branchFilter string
+	var enabled int
+	err := r.Scan(&w.Id, &w.Namespace, &w.RepoName, &w.TargetURL, &w.Secret, &w.PayloadType, &eventList, &branchFilter, &enabled, &w.CreateTime)
+	if err != nil { return nil, err }
+	w.EventList = model.ParseWebhookList(eventList)
+	w.BranchFilter = model.ParseWebhookList(branchFilter)
+	w.Enable = enabled != 0
+	return &w, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetWebhook(id int64) (*model.Webhook, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, webhook_namespace, webhook_repo_name, target_url, secret, payload_type, event_list, branch_filter, enabled, create_time
+FROM %s_webhook WHERE rowid = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	res, err := scanSqliteWebhook(stmt.QueryRow(id))
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UpdateWebhook(w *model.Webhook) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_webhook
+SET target_url = ?, secret = ?, payload_type = ?, event_list = ?, branch_filter = ?, enabled = ?
+WHERE rowid = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	enabled := 0
+	if w.Enable { enabled = 1 }
+	_, err = stmt.Exec(w.TargetURL, w.Secret, w.PayloadType, strings.Join(w.EventList, ","), strings.Join(w.BranchFilter, ","), enabled, w.Id)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) DeleteWebhook(id int64) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+DELETE FROM %s_webhook WHERE rowid = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(id)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllWebhook(ns string, repoName string) ([]*model.Webhook, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, webhook_namespace, webhook_repo_name, target_url, secret, payload_type, event_list, branch_filter, enabled, create_time
+FROM %s_webhook WHERE webhook_namespace = ? AND webhook_repo_name = ?
+ORDER BY rowid ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(ns, repoName)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.Webhook, 0)
+	for r.Next() {
+		w, err := scanSqliteWebhook(r)
+		if err != nil { return nil, err }
+		res = append(res, w)
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) SetCommitStatus(s *model.CommitStatus) error {
+	pfx := dbif.config.Database.TablePrefix
+	t := time.Now().Unix()
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT INTO %s_commit_status(repo_namespace, repo_name, commit_id, status_context, status_state, target_url, status_description, status_creator, create_time, update_time)
+VALUES (?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT (repo_namespace, repo_name, commit_id, status_context) DO UPDATE
+SET status_state = excluded.status_state, target_url = excluded.target_url, status_description = excluded.status_description, status_creator = excluded.status_creator, update_time = excluded.update_time
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(s.RepoNamespace, s.RepoName, s.CommitId, s.Context, s.State, s.TargetURL, s.Description, s.Creator, t, t)
+	if err != nil { return err }
+	s.UpdateTime = t
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetAllCommitStatus(ns string, name string, commitId string) ([]*model.CommitStatus, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT status_context, status_state, target_url, status_description, status_creator, create_time, update_time
+FROM %s_commit_status
+WHERE repo_namespace = ? AND repo_name = ? AND commit_id = ?
+ORDER BY status_context ASC
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	r, err := stmt.Query(ns, name, commitId)
+	if err != nil { return nil, err }
+	defer r.Close()
+	res := make([]*model.CommitStatus, 0)
+	for r.Next() {
+		s := &model.CommitStatus{RepoNamespace: ns, RepoName: name, CommitId: commitId}
+		err = r.Scan(&s.Context, &s.State, &s.TargetURL, &s.Description, &s.Creator, &s.CreateTime, &s.UpdateTime)
+		if err != nil { return nil, err }
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) NewCIRun(r *model.CIRun) error {
+	pfx := dbif.config.Database.TablePrefix
+	t := time.Now().Unix()
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+INSERT INTO %s_ci_run(repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, run_status, run_log, create_time, start_time, end_time)
+VALUES (?,?,?,?,?,?,?,'',?,0,0)
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	res, err := stmt.Exec(r.RepoNamespace, r.RepoName, r.CommitId, r.RefName, r.JobName, r.Trigger, model.CI_RUN_QUEUED, t)
+	if err != nil { return err }
+	r.Id, err = res.LastInsertId()
+	if err != nil { return err }
+	r.Status = model.CI_RUN_QUEUED
+	r.CreateTime = t
+	return nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetCIRun(id int64) (*model.CIRun, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, run_status, run_log, create_time, start_time, end_time
+FROM %s_ci_run WHERE rowid = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	var r model.CIRun
+	err = stmt.QueryRow(id).Scan(&r.Id, &r.RepoNamespace, &r.RepoName, &r.CommitId, &r.RefName, &r.JobName, &r.Trigger, &r.Status, &r.Log, &r.CreateTime, &r.StartTime, &r.EndTime)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	return &r, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) ClaimCIRun() (*model.CIRun, error) {
+	pfx := dbif.config.Database.TablePrefix
+	tx, err := dbif.connection.Begin()
+	if err != nil { return nil, err }
+	defer tx.Rollback()
+	stmt1, err := tx.Prepare(fmt.Sprintf(`
+SELECT rowid, repo_namespace, repo_name, commit_id, ref_name, job_name, run_trigger, create_time
+FROM %s_ci_run
+WHERE run_status = ?
+ORDER BY rowid ASC LIMIT 1
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt1.Close()
+	var r model.CIRun
+	err = stmt1.QueryRow(model.CI_RUN_QUEUED).Scan(&r.Id, &r.RepoNamespace, &r.RepoName, &r.CommitId, &r.RefName, &r.JobName, &r.Trigger, &r.CreateTime)
+	if err == sql.ErrNoRows { return nil, db.ErrEntityNotFound }
+	if err != nil { return nil, err }
+	t := time.Now().Unix()
+	stmt2, err := tx.Prepare(fmt.Sprintf(`
+UPDATE %s_ci_run SET run_status = ?, run_log = '', start_time = ?, end_time = 0 WHERE rowid = ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt2.Close()
+	_, err = stmt2.Exec(model.CI_RUN_RUNNING, t, r.Id)
+	if err != nil { return nil, err }
+	err = tx.Commit()
+	if err != nil { return nil, err }
+	r.Status = model.CI_RUN_RUNNING
+	r.StartTime = t
+	return &r, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) UpdateCIRunLog(id int64, log string) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_ci_run SET run_log = ? WHERE rowid = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(log, id)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) FinishCIRun(id int64, status int, log string) error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_ci_run SET run_status = ?, run_log = ?, end_time = ? WHERE rowid = ? AND run_status = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(status, log, time.Now().Unix(), id, model.CI_RUN_RUNNING)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) ResetRunningCIRun() error {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+UPDATE %s_ci_run SET run_status = ? WHERE run_status = ?
+`, pfx))
+	if err != nil { return err }
+	defer stmt.Close()
+	_, err = stmt.Exec(model.CI_RUN_QUEUED, model.CI_RUN_RUNNING)
+	return err
+}
+
+func (dbif *SqliteGitusDatabaseInterface) CountCIRun(ns string, name string) (int64, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT COUNT(*) FROM %s_ci_run WHERE repo_namespace = ? AND repo_name = ?
+`, pfx))
+	if err != nil { return 0, err }
+	defer stmt.Close()
+	var res int64
+	err = stmt.QueryRow(ns, name).Scan(&res)
+	if err != nil { return 0, err }
+	return res, nil
+}
+
+func (dbif *SqliteGitusDatabaseInterface) GetCIRunPaginated(ns string, name string, pageNum int64, pageSize int64) ([]*model.CIRun, error) {
+	pfx := dbif.config.Database.TablePrefix
+	stmt, err := dbif.connection.Prepare(fmt.Sprintf(`
+SELECT rowid, commit_id, ref_name, job_name, run_trigger, run_status, create_time, start_time, end_time
+FROM %s_ci_run
+WHERE repo_namespace = ? AND repo_name = ?
+ORDER BY rowid DESC LIMIT ? OFFSET ?
+`, pfx))
+	if err != nil { return nil, err }
+	defer stmt.Close()
+	rs, err := stmt.Query(ns, name, pageSize, pageNum*pageSize)
+	if err != nil { return nil, err }
+	defer rs.Close()
+	res := make([]*model.CIRun, 0)
+	for rs.Next() {
+		r := &model.CIRun{RepoNamespace: ns, RepoName: name}
+		err = rs.Scan(&r.Id, &r.CommitId, &r.RefName, &r.JobName, &r.Trigger, &r.Status, &r.CreateTime, &r.StartTime, &r.EndTime)
+		if err != nil { return nil, err }
+		res = append(res, r)
+	}
+	return res, nil
+}
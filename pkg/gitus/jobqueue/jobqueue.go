@@ -0,0 +1,195 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+)
+
+// a persistent job queue backed by the main database. see
+// docs/job-queue.org.
+//
+// any process w/ a database interface can enqueue jobs (e.g. the
+// hook dispatcher), but only the web server runs the workers; jobs
+// enqueued by other processes are picked up the next time a worker
+// polls the database.
+
+const DEFAULT_WORKER_COUNT = 2
+
+// how long an idle worker waits before checking the database again.
+const POLL_INTERVAL = 5 * time.Second
+
+// a handler returns an error if the job should be retried (unless
+// it's a `*PermanentError`).
+type Handler func(job *model.Job) error
+
+type JobQueue struct {
+	dbif db.GitusDatabaseInterface
+	handlerMap map[string]Handler
+	// poked when a job is enqueued by this process so that an idle
+	// worker doesn't have to wait for the next poll.
+	wakeChan chan struct{}
+	stopChan chan struct{}
+	wg sync.WaitGroup
+	started bool
+}
+
+func NewJobQueue(dbif db.GitusDatabaseInterface) *JobQueue {
+	return &JobQueue{
+		dbif: dbif,
+		handlerMap: make(map[string]Handler, 0),
+		wakeChan: make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// must be called before `Start`.
+func (q *JobQueue) RegisterHandler(jobType string, h Handler) {
+	q.handlerMap[jobType] = h
+}
+
+// `payload` is serialized to json.
+func (q *JobQueue) Enqueue(jobType string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil { return err }
+	err = q.dbif.EnqueueJob(&model.Job{
+		Type: jobType,
+		Payload: string(b),
+	})
+	if err != nil { return err }
+	select {
+	case q.wakeChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// starts `workerCount` workers.
+func (q *JobQueue) Start(workerCount int) error {
+	if q.started { return nil }
+	err := q.dbif.ResetRunningJob()
+	if err != nil { return err }
+	if workerCount <= 0 { workerCount = DEFAULT_WORKER_COUNT }
+	q.started = true
+	for i := 0; i < workerCount; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return nil
+}
+
+// stops taking new jobs & waits for the running ones to finish, or
+// until `ctx` is done. jobs that are still running by then are run
+// again the next time the queue starts.
+func (q *JobQueue) Stop(ctx context.Context) error {
+	if !q.started { return nil }
+	close(q.stopChan)
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.stopChan:
+			return
+		default:
+		}
+		job, err := q.dbif.ClaimJob()
+		if err == nil {
+			q.run(job)
+			continue
+		}
+		if err != db.ErrEntityNotFound {
+			log.Printf("Failed to claim job: %s\n", err)
+		}
+		select {
+		case <-q.stopChan:
+			return
+		case <-q.wakeChan:
+		case <-time.After(POLL_INTERVAL):
+		}
+	}
+}
+
+func (q *JobQueue) run(job *model.Job) {
+	h, ok := q.handlerMap[job.Type]
+	if !ok {
+		q.fail(job, fmt.Errorf("No handler for job type %s", job.Type), false)
+		return
+	}
+	err := q.invoke(h, job)
+	if err != nil {
+		var pe *PermanentError
+		q.fail(job, err, !errors.As(err, &pe) && job.Attempt < job.MaxAttempt)
+		return
+	}
+	err = q.dbif.CompleteJob(job.Id)
+	if err != nil {
+		log.Printf("Failed to complete job %d: %s\n", job.Id, err)
+	}
+}
+
+// a panicking handler counts as a failure instead of bringing the
+// whole server down.
+func (q *JobQueue) invoke(h Handler, job *model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Handler panicked: %v", r)
+		}
+	}()
+	return h(job)
+}
+
+func (q *JobQueue) fail(job *model.Job, jobErr error, retry bool) {
+	var err error
+	if retry {
+		nextRunTime := time.Now().Add(model.JobRetryDelay(job.Attempt)).Unix()
+		err = q.dbif.RescheduleJob(job.Id, jobErr.Error(), nextRunTime)
+	} else {
+		log.Printf("Job %d (%s) failed: %s\n", job.Id, job.Type, jobErr)
+		err = q.dbif.FailJob(job.Id, jobErr.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to update job %d: %s\n", job.Id, err)
+	}
+}
+
+// wraps an error that won't go away by retrying; the job is failed
+// right away.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func UnmarshalPayload(job *model.Job, v any) error {
+	err := json.Unmarshal([]byte(job.Payload), v)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("Invalid payload: %s", err)}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package jobqueue
+
+import (
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/mail"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+)
+
+// a mailer that enqueues the mails instead of sending them. the
+// returned error only tells whether the mail is enqueued.
+type QueuedMailer struct {
+	queue *JobQueue
+}
+
+func NewQueuedMailer(queue *JobQueue) *QueuedMailer {
+	return &QueuedMailer{queue: queue}
+}
+
+func (m *QueuedMailer) SendPlainTextMail(target string, title string, body string) error {
+	return m.queue.Enqueue(model.JOB_TYPE_MAIL, &model.MailJobPayload{
+		Target: target,
+		Title: title,
+		Body: body,
+	})
+}
+
+func (m *QueuedMailer) SendHTMLMail(target string, title string, body string) error {
+	return m.queue.Enqueue(model.JOB_TYPE_MAIL, &model.MailJobPayload{
+		Target: target,
+		Title: title,
+		Body: body,
+		HTML: true,
+	})
+}
+
+// the handler of mail jobs, which sends them w/ `mailer`.
+func MailJobHandler(mailer mail.GitusMailerInterface) Handler {
+	return func(job *model.Job) error {
+		var p model.MailJobPayload
+		err := UnmarshalPayload(job, &p)
+		if err != nil { return err }
+		if p.HTML {
+			return mailer.SendHTMLMail(p.Target, p.Title, p.Body)
+		}
+		return mailer.SendPlainTextMail(p.Target, p.Title, p.Body)
+	}
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// personal access tokens. see docs/access-token.org.
+
+const (
+	TOKEN_SCOPE_REPO_READ = "repo:read"
+	TOKEN_SCOPE_REPO_WRITE = "repo:write"
+	TOKEN_SCOPE_ISSUE_WRITE = "issue:write"
+	TOKEN_SCOPE_ADMIN = "admin"
+)
+
+var AccessTokenScopeList = []string{
+	TOKEN_SCOPE_REPO_READ,
+	TOKEN_SCOPE_REPO_WRITE,
+	TOKEN_SCOPE_ISSUE_WRITE,
+	TOKEN_SCOPE_ADMIN,
+}
+
+// all token texts start with this prefix so that they're easier to
+// spot (e.g. by secret scanners) when leaked.
+const ACCESS_TOKEN_PREFIX = "gitus_"
+
+type GitusAccessToken struct {
+	UserName string `json:"userName"`
+	Name string `json:"name"`
+	// the sha256 of the token text in hex. the token text itself is
+	// only shown to the user once when it's created and is never
+	// stored.
+	TokenHash string `json:"tokenHash"`
+	Scope []string `json:"scope"`
+	CreateTime int64 `json:"createTime"`
+	// 0 means the token never expires.
+	ExpireTime int64 `json:"expireTime"`
+}
+
+func ValidAccessTokenName(s string) bool {
+	if len(s) <= 0 || len(s) > 64 { return false }
+	for _, k := range s {
+		if !(('0' <= k && k <= '9') || ('A' <= k && k <= 'Z') || ('a' <= k && k <= 'z') || k == '_' || k == '-' || k == '.') { return false }
+	}
+	return true
+}
+
+func ValidAccessTokenScope(s string) bool {
+	return slices.Contains(AccessTokenScopeList, s)
+}
+
+func ParseAccessTokenScope(s string) []string {
+	res := make([]string, 0)
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if ValidAccessTokenScope(k) { res = append(res, k) }
+	}
+	return res
+}
+
+func SerializeAccessTokenScope(s []string) string {
+	return strings.Join(s, ",")
+}
+
+// NOTE THAT "admin" implies every other scope and "repo:write"
+// implies "repo:read". having "admin" does not mean the owner of the
+// token is an admin; the caller should check the status of the user
+// as well.
+func (t *GitusAccessToken) HasScope(scope string) bool {
+	if t == nil { return false }
+	if slices.Contains(t.Scope, TOKEN_SCOPE_ADMIN) { return true }
+	if slices.Contains(t.Scope, scope) { return true }
+	if scope == TOKEN_SCOPE_REPO_READ && slices.Contains(t.Scope, TOKEN_SCOPE_REPO_WRITE) { return true }
+	return false
+}
+
+func (t *GitusAccessToken) IsExpired() bool {
+	if t.ExpireTime <= 0 { return false }
+	return time.Now().Unix() >= t.ExpireTime
+}
+
+func NewAccessTokenString() (string, error) {
+	b := make([]byte, 20)
+	_, err := rand.Read(b)
+	if err != nil { return "", err }
+	return ACCESS_TOKEN_PREFIX + hex.EncodeToString(b), nil
+}
+
+func HashAccessToken(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// branch protection rules. see docs/protected-branch.org.
+
+type BranchProtectionRule struct {
+	// glob pattern of the branch names, e.g. "main" or "release/*".
+	// matched w/ `path.Match`, so "*" does not match "/".
+	Pattern string `json:"pattern"`
+	DisallowForcePush bool `json:"disallowForcePush"`
+	DisallowDeletion bool `json:"disallowDeletion"`
+	// when not empty, only the users in this list can update the
+	// branch (this includes merging pull requests into it).
+	AllowedPusher []string `json:"allowedPusher"`
+	// when true, the branch can only be updated by merging pull
+	// requests.
+	RequirePullRequest bool `json:"requirePullRequest"`
+}
+
+const (
+	// creating a new branch.
+	BRANCH_UPDATE_CREATE = 1
+	// fast-forward.
+	BRANCH_UPDATE_PUSH = 2
+	BRANCH_UPDATE_FORCE_PUSH = 3
+	BRANCH_UPDATE_DELETE = 4
+	BRANCH_UPDATE_MERGE_PULL_REQUEST = 5
+)
+
+type BranchProtectionError struct {
+	Branch string
+	Pattern string
+	Reason string
+}
+
+func (e *BranchProtectionError) Error() string {
+	return fmt.Sprintf("Branch %s is protected by rule \"%s\": %s", e.Branch, e.Pattern, e.Reason)
+}
+
+func ValidBranchProtectionPattern(s string) bool {
+	if len(s) <= 0 || len(s) > 256 { return false }
+	if strings.ContainsAny(s, " \t\r\n") { return false }
+	_, err := path.Match(s, "")
+	return err == nil
+}
+
+func (rule *BranchProtectionRule) Matches(branchName string) bool {
+	r, err := path.Match(rule.Pattern, branchName)
+	return err == nil && r
+}
+
+// returns a `*BranchProtectionError` if `username` is not allowed to
+// perform the update of type `updateType` (see the constants above)
+// on the branch `branchName`. all matching rules are checked.
+func CheckBranchUpdate(ruleList []*BranchProtectionRule, username string, branchName string, updateType int) error {
+	for _, rule := range ruleList {
+		if !rule.Matches(branchName) { continue }
+		reason := ""
+		if len(rule.AllowedPusher) > 0 && !slices.Contains(rule.AllowedPusher, username) {
+			reason = fmt.Sprintf("user %s is not allowed to update this branch.", username)
+		} else {
+			switch updateType {
+			case BRANCH_UPDATE_DELETE:
+				if rule.DisallowDeletion { reason = "deletion is not allowed." }
+			case BRANCH_UPDATE_FORCE_PUSH:
+				if rule.DisallowForcePush {
+					reason = "force push is not allowed."
+				} else if rule.RequirePullRequest {
+					reason = "changes must be made thru pull requests."
+				}
+			case BRANCH_UPDATE_PUSH:
+				if rule.RequirePullRequest { reason = "changes must be made thru pull requests." }
+			}
+		}
+		if len(reason) > 0 {
+			return &BranchProtectionError{
+				Branch: branchName,
+				Pattern: rule.Pattern,
+				Reason: reason,
+			}
+		}
+	}
+	return nil
+}
+
+// parses a comma-separated (or whitespace-separated) list of user names.
+func ParseUsernameList(s string) []string {
+	res := make([]string, 0)
+	for _, k := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	}) {
+		if slices.Contains(res, k) { continue }
+		res = append(res, k)
+	}
+	return res
+}
+
+func SerializeUsernameList(l []string) string {
+	return strings.Join(l, ",")
+}
+
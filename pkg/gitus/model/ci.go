@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// runs of the built-in ci runner. see docs/ci-runner.org.
+
+const (
+	CI_RUN_QUEUED = 1
+	CI_RUN_RUNNING = 2
+	// all steps exited w/ 0.
+	CI_RUN_SUCCESS = 3
+	// a step exited w/ non-zero or the job timed out.
+	CI_RUN_FAILURE = 4
+	// the runner failed to run the job, e.g. the checkout failed.
+	CI_RUN_ERROR = 5
+)
+
+// the log of a run is only kept up to this many bytes.
+const CI_RUN_LOG_LIMIT = 1024 * 1024
+
+// one job (a `[job "..."]` section in the job file) run on one
+// commit.
+type CIRun struct {
+	Id int64
+	RepoNamespace string
+	RepoName string
+	CommitId string
+	RefName string
+	JobName string
+	// the user whose push triggered the run.
+	Trigger string
+	Status int
+	// the output of all the steps, along w/ the lines the runner
+	// adds for each step. not filled when the runs are listed.
+	Log string
+	CreateTime int64
+	// 0 if not started/finished yet.
+	StartTime int64
+	EndTime int64
+}
+
+func (r *CIRun) Finished() bool {
+	return r.Status == CI_RUN_SUCCESS || r.Status == CI_RUN_FAILURE || r.Status == CI_RUN_ERROR
+}
+
+// the state of the commit status the run reports.
+func (r *CIRun) CommitStatusState() string {
+	switch r.Status {
+	case CI_RUN_SUCCESS: return COMMIT_STATUS_SUCCESS
+	case CI_RUN_FAILURE: return COMMIT_STATUS_FAILURE
+	case CI_RUN_ERROR: return COMMIT_STATUS_ERROR
+	}
+	return COMMIT_STATUS_PENDING
+}
+
+func CIRunStatusName(status int) string {
+	switch status {
+	case CI_RUN_QUEUED: return "queued"
+	case CI_RUN_RUNNING: return "running"
+	case CI_RUN_SUCCESS: return "success"
+	case CI_RUN_FAILURE: return "failure"
+	case CI_RUN_ERROR: return "error"
+	}
+	return "unknown"
+}
+
+var reCIJobName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+func ValidCIJobName(s string) bool {
+	return len(s) > 0 && len(s) <= 64 && reCIJobName.MatchString(s)
+}
+
+// the context of the commit status reported by the runs of `jobName`.
+// see docs/commit-status.org.
+func CIRunCommitStatusContext(jobName string) string {
+	return fmt.Sprintf("ci/%s", jobName)
+}
+
+// the ref name of the runs on the head of the pull request `prId`
+// from another repository. there's no such ref in the repository; the
+// runner reads the jobs of these runs from the receiver branch
+// instead of the commit. see docs/ci-runner.org.
+func PullRequestCIRefName(prId int64) string {
+	return fmt.Sprintf("refs/pull/%d/head", prId)
+}
+
+// the id of the pull request if `refName` is from
+// `PullRequestCIRefName`.
+func ParsePullRequestCIRefName(refName string) (int64, bool) {
+	if !strings.HasPrefix(refName, "refs/pull/") || !strings.HasSuffix(refName, "/head") { return 0, false }
+	prId, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(refName, "refs/pull/"), "/head"), 10, 64)
+	if err != nil || prId <= 0 { return 0, false }
+	return prId, true
+}
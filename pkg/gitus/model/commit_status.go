@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// commit statuses, reported by ci services & such thru the api or
+// the webhook result report. see docs/commit-status.org.
+
+const (
+	COMMIT_STATUS_PENDING = "pending"
+	COMMIT_STATUS_SUCCESS = "success"
+	COMMIT_STATUS_FAILURE = "failure"
+	COMMIT_STATUS_ERROR = "error"
+)
+
+func ValidCommitStatusState(s string) bool {
+	return s == COMMIT_STATUS_PENDING || s == COMMIT_STATUS_SUCCESS || s == COMMIT_STATUS_FAILURE || s == COMMIT_STATUS_ERROR
+}
+
+// contexts are stored comma-separated in the pull request setting,
+// so they can't contain commas.
+func ValidCommitStatusContext(s string) bool {
+	if len(s) <= 0 || len(s) > 255 { return false }
+	if strings.TrimSpace(s) != s { return false }
+	return !strings.ContainsAny(s, ",\n\r")
+}
+
+// there's only one status for each context of a commit; setting it
+// again replaces the old one.
+type CommitStatus struct {
+	RepoNamespace string `json:"repoNamespace"`
+	RepoName string `json:"repoName"`
+	CommitId string `json:"commitId"`
+	Context string `json:"context"`
+	State string `json:"state"`
+	TargetURL string `json:"targetUrl"`
+	Description string `json:"description"`
+	Creator string `json:"creator"`
+	CreateTime int64 `json:"createTime"`
+	UpdateTime int64 `json:"updateTime"`
+}
+
+// the state of all the statuses in `l` combined: failure if any of
+// them failed (error counts as failure), pending if any of them is
+// pending, success if all of them succeeded. empty if `l` is empty.
+func CombineCommitStatus(l []*CommitStatus) string {
+	if len(l) <= 0 { return "" }
+	res := COMMIT_STATUS_SUCCESS
+	for _, k := range l {
+		switch k.State {
+		case COMMIT_STATUS_FAILURE: fallthrough
+		case COMMIT_STATUS_ERROR:
+			return COMMIT_STATUS_FAILURE
+		case COMMIT_STATUS_PENDING:
+			res = COMMIT_STATUS_PENDING
+		}
+	}
+	return res
+}
+
+type CommitStatusError struct {
+	Reason string
+}
+
+func (e *CommitStatusError) Error() string {
+	return fmt.Sprintf("Required status checks not passed: %s", e.Reason)
+}
+
+// returns a `*CommitStatusError` if the head of a pull request w/
+// the statuses `statusList` can't be merged under `setting`.
+func CheckRequiredCommitStatus(setting *PullRequestSetting, statusList []*CommitStatus) error {
+	if setting == nil { return nil }
+	m := make(map[string]string, 0)
+	for _, k := range statusList { m[k.Context] = k.State }
+	for _, k := range setting.RequiredStatusContext {
+		state, ok := m[k]
+		if !ok {
+			return &CommitStatusError{Reason: fmt.Sprintf("%s has not reported yet.", k)}
+		}
+		if state != COMMIT_STATUS_SUCCESS {
+			return &CommitStatusError{Reason: fmt.Sprintf("%s is %s.", k, state)}
+		}
+	}
+	return nil
+}
+
+// the context of the statuses recorded from webhook result reports.
+// see docs/webhooks.org.
+func WebhookCommitStatusContext(webhookId int64) string {
+	if webhookId == 0 { return "webhook" }
+	return fmt.Sprintf("webhook/%d", webhookId)
+}
+
+// the state of the status recorded from the webhook result `status`
+// (see `WEBHOOK_RESULT_*`).
+func WebhookResultCommitStatusState(status uint8) string {
+	switch status {
+	case WEBHOOK_RESULT_SUCCESS: return COMMIT_STATUS_SUCCESS
+	case WEBHOOK_RESULT_FAILURE: return COMMIT_STATUS_FAILURE
+	}
+	return COMMIT_STATUS_ERROR
+}
+
+// stored as comma-separated contexts in the database.
+func ParseCommitStatusContextList(s string) []string {
+	res := make([]string, 0)
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if !ValidCommitStatusContext(k) { continue }
+		if slices.Contains(res, k) { continue }
+		res = append(res, k)
+	}
+	return res
+}
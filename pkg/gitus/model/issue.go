@@ -11,6 +11,11 @@ type Issue struct {
 	IssueTime int64
 	IssueStatus int
 	IssuePriority int
+	// names of the labels of this issue.
+	IssueLabel []string
+	IssueAssignee []string
+	// title of the milestone; empty if the issue isn't in any.
+	IssueMilestone string
 }
 
 const (
@@ -24,6 +29,19 @@ const (
 	EVENT_CLOSED_AS_SOLVED = 2
 	EVENT_CLOSED_AS_DISCARDED = 3
 	EVENT_REOPENED = 4
+	// for the following events the content is the name of the label,
+	// the username of the assignee or the title of the milestone.
+	EVENT_LABEL_ADDED = 5
+	EVENT_LABEL_REMOVED = 6
+	EVENT_ASSIGNEE_ADDED = 7
+	EVENT_ASSIGNEE_REMOVED = 8
+	EVENT_MILESTONE_SET = 9
+	EVENT_MILESTONE_REMOVED = 10
+	// for the following events the content is the json dump of
+	// `IssueReferenceSource`.
+	EVENT_REFERENCED_BY_COMMIT = 11
+	EVENT_REFERENCED_BY_ISSUE = 12
+	EVENT_REFERENCED_BY_PULL_REQUEST = 13
 )
 
 type IssueEvent struct {
@@ -34,3 +52,44 @@ type IssueEvent struct {
 	EventContent string	
 }
 
+
+// labels & milestones are defined per repository; a repository can't
+// have two labels w/ the same name or two milestones w/ the same
+// title.
+
+type IssueLabel struct {
+	Name string
+	// "#rrggbb".
+	Color string
+	Description string
+}
+
+type IssueMilestone struct {
+	Title string
+	Description string
+	// unix timestamp. 0 means no due date.
+	DueTime int64
+	Closed bool
+}
+
+// additional conditions for searching issues. empty fields are
+// ignored; when multiple labels are specified an issue must have all
+// of them.
+type IssueFilter struct {
+	Label []string
+	Assignee string
+	Author string
+	Milestone string
+}
+
+func (f *IssueFilter) IsEmpty() bool {
+	return f == nil || (len(f.Label) <= 0 && len(f.Assignee) <= 0 && len(f.Author) <= 0 && len(f.Milestone) <= 0)
+}
+
+func ValidIssueLabelColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' { return false }
+	for _, k := range s[1:] {
+		if !(('0' <= k && k <= '9') || ('a' <= k && k <= 'f') || ('A' <= k && k <= 'F')) { return false }
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// references to issues & pull requests in commit messages, issue
+// comments and pull request comments. see docs/issue.org.
+//
+//   + "#12" is issue 12 of the same repository;
+//   + "!12" is pull request 12 of the same repository;
+//   + "ns:repo#12" & "ns:repo!12" refer to other repositories. when
+//     namespace is disabled it's "repo#12" & "repo!12" instead.
+//
+// a reference to an issue preceded by a closing keyword (e.g. "fixes
+// #12", "Closes: #12") is a closing reference.
+
+type IssueReference struct {
+	// both empty means the repository where the reference is made.
+	RepoNamespace string
+	RepoName string
+	Id int64
+	IsPullRequest bool
+	Closing bool
+}
+
+var issueReferenceRegex = regexp.MustCompile(`((?:[0-9A-Za-z_-]+:)?[0-9A-Za-z_-]+)?([#!])([0-9]+)\b`)
+var closingKeywordRegex = regexp.MustCompile(`(?i)\b(?:close|closes|closed|fix|fixes|fixed|resolve|resolves|resolved)\s*:?\s*$`)
+
+func ParseIssueReference(s string) []IssueReference {
+	res := make([]IssueReference, 0)
+	seen := make(map[IssueReference]int, 0)
+	for _, m := range issueReferenceRegex.FindAllStringSubmatchIndex(s, -1) {
+		// things like "&#123;" or "example.com/#12" aren't references.
+		if m[0] > 0 {
+			c := s[m[0]-1]
+			if c == '&' || c == '/' || c == '#' || c == '!' || c == '.' || c == ':' { continue }
+		}
+		id, err := strconv.ParseInt(s[m[6]:m[7]], 10, 64)
+		if err != nil { continue }
+		ref := IssueReference{
+			Id: id,
+			IsPullRequest: s[m[4]:m[5]] == "!",
+		}
+		if m[2] >= 0 {
+			fullName := s[m[2]:m[3]]
+			if i := strings.Index(fullName, ":"); i >= 0 {
+				ref.RepoNamespace = fullName[:i]
+				ref.RepoName = fullName[i+1:]
+			} else {
+				ref.RepoName = fullName
+			}
+		}
+		closing := !ref.IsPullRequest && closingKeywordRegex.MatchString(s[:m[0]])
+		if i, ok := seen[ref]; ok {
+			if closing { res[i].Closing = true }
+			continue
+		}
+		seen[ref] = len(res)
+		ref.Closing = closing
+		res = append(res, ref)
+	}
+	return res
+}
+
+// where a reference is made. exactly one of `CommitId`, `IssueId` &
+// `PullRequestId` is set. stored as json in the content of the
+// corresponding issue events & pull request events.
+type IssueReferenceSource struct {
+	RepoNamespace string `json:"repoNamespace"`
+	RepoName string `json:"repoName"`
+	CommitId string `json:"commitId,omitempty"`
+	IssueId int64 `json:"issueId,omitempty"`
+	PullRequestId int64 `json:"pullRequestId,omitempty"`
+	// the first line of the commit message, or the title of the
+	// issue or the pull request.
+	Title string `json:"title"`
+}
+
+func (src *IssueReferenceSource) RepoFullName() string {
+	if len(src.RepoNamespace) > 0 { return src.RepoNamespace + ":" + src.RepoName }
+	return src.RepoName
+}
@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// background jobs. see docs/job-queue.org.
+
+const (
+	JOB_PENDING = 1
+	JOB_RUNNING = 2
+	// failed `MaxAttempt` times; only retried when an admin says so.
+	JOB_FAILED = 3
+)
+
+// jobs that are done are deleted, so there's no status for that.
+
+const (
+	JOB_TYPE_MAIL = "mail"
+	JOB_TYPE_WEBHOOK = "webhook"
+	JOB_TYPE_WEBHOOK_DELIVERY = "webhook-delivery"
+	JOB_TYPE_MERGE_CHECK = "merge-check"
+	JOB_TYPE_PULL_REQUEST_CI = "pull-request-ci"
+)
+
+const DEFAULT_JOB_MAX_ATTEMPT = 5
+
+type Job struct {
+	Id int64
+	Type string
+	// json; see the `*JobPayload` types.
+	Payload string
+	Status int
+	// the number of times the job has been run, including the
+	// current one if it's running.
+	Attempt int
+	MaxAttempt int
+	LastError string
+	NextRunTime int64
+	CreateTime int64
+}
+
+// the delay before running the job again after its `attempt`-th
+// failure: 30s, 1m, 2m, 4m... up to 1 hour.
+func JobRetryDelay(attempt int) time.Duration {
+	if attempt < 1 { attempt = 1 }
+	if attempt > 8 { return time.Hour }
+	res := (30 * time.Second) << (attempt-1)
+	if res > time.Hour { res = time.Hour }
+	return res
+}
+
+type MailJobPayload struct {
+	Target string `json:"target"`
+	Title string `json:"title"`
+	Body string `json:"body"`
+	HTML bool `json:"html"`
+}
+
+type WebhookJobPayload struct {
+	RepoNamespace string `json:"repoNamespace"`
+	RepoName string `json:"repoName"`
+	RefName string `json:"refName"`
+	OldRev string `json:"oldRev"`
+	NewRev string `json:"newRev"`
+}
+
+type MergeCheckJobPayload struct {
+	PRAbsId int64 `json:"prAbsId"`
+}
+
+type PullRequestCIJobPayload struct {
+	PRAbsId int64 `json:"prAbsId"`
+	// the user whose action queued the runs.
+	Trigger string `json:"trigger"`
+}
+
+type WebhookDeliveryJobPayload struct {
+	UUID string `json:"uuid"`
+}
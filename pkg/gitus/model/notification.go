@@ -0,0 +1,66 @@
+package model
+
+// notifications. see docs/notification.org.
+
+const (
+	// new issues, comments, closing & reopening, assignment.
+	NOTIFICATION_ISSUE = 1
+	// new pull requests, comments, reviews, merging, closing &
+	// reopening.
+	NOTIFICATION_PULL_REQUEST = 2
+	// being added to/removed from the acl of a namespace or a
+	// repository.
+	NOTIFICATION_MEMBERSHIP = 3
+	// new registration requests (for admins) & approved ones (for
+	// the new user).
+	NOTIFICATION_REGISTRATION = 4
+)
+
+type Notification struct {
+	Id int64
+	// the recipient.
+	Username string
+	Type int
+	// the user whose action caused the notification. empty if it's
+	// caused by the system.
+	Actor string
+	Title string
+	// plain text.
+	Content string
+	// path of the page the notification is about, e.g.
+	// "/repo/ns:repo/issue/3"; empty if there isn't any.
+	Link string
+	Timestamp int64
+	Read bool
+}
+
+// per-user. all notifications go to the inbox no matter what; these
+// controls which ones are sent by email as well.
+type NotificationSetting struct {
+	EmailIssue bool `json:"emailIssue"`
+	EmailPullRequest bool `json:"emailPullRequest"`
+	EmailMembership bool `json:"emailMembership"`
+	EmailRegistration bool `json:"emailRegistration"`
+	// watch the repositories the user creates or forks.
+	AutoWatch bool `json:"autoWatch"`
+}
+
+func DefaultNotificationSetting() *NotificationSetting {
+	return &NotificationSetting{
+		EmailIssue: true,
+		EmailPullRequest: true,
+		EmailMembership: true,
+		EmailRegistration: true,
+		AutoWatch: true,
+	}
+}
+
+func (s *NotificationSetting) ShouldSendEmail(nType int) bool {
+	switch nType {
+	case NOTIFICATION_ISSUE: return s.EmailIssue
+	case NOTIFICATION_PULL_REQUEST: return s.EmailPullRequest
+	case NOTIFICATION_MEMBERSHIP: return s.EmailMembership
+	case NOTIFICATION_REGISTRATION: return s.EmailRegistration
+	}
+	return false
+}
@@ -33,6 +33,8 @@ const (
 	PULL_REQUEST_EVENT_CLOSE_AS_NOT_MERGED = 5
 	PULL_REQUEST_EVENT_CLOSE_AS_MERGED = 6
 	PULL_REQUEST_EVENT_REOPEN = 7
+	PULL_REQUEST_EVENT_REVIEW = 8
+	PULL_REQUEST_EVENT_REFERENCED = 9
 )
 
 type PullRequestEvent struct {
@@ -43,6 +45,9 @@ type PullRequestEvent struct {
 	// 4 - merge conflict check.
 	// 5 - close as not merged.
 	// 6 - close (merged).
+	// 7 - reopen.
+	// 8 - review.
+	// 9 - referenced by a commit, an issue or another pull request.
 	EventType int
 	EventTimestamp int64
 	EventAuthor string
@@ -51,8 +56,11 @@ type PullRequestEvent struct {
 	// type=3: string (commit id)
 	// type=4: empty
 	// type=5: empty
-	// type=6: empty
+	// type=6: name of the merge strategy (see `gitlib.MergeStrategyName`);
+	//         empty for pull requests merged before strategies existed.
 	// type=7: empty
+	// type=8: json dump of PullRequestReview
+	// type=9: json dump of IssueReferenceSource
 	EventContent string
 }
 
@@ -60,15 +68,23 @@ type PullRequestComment struct {
 	Content string `json:"content"`
 }
 
+// comments on the combined diff of a pull request (see
+// docs/pull-request.org). a comment on the new side of the diff has
+// the head of the provider branch as `CommitId`, a comment on the old
+// side has the merge base; the repository is always the receiver
+// repository, since the provider branch is fetched into it.
 type PullRequestCommentOnCode struct {
 	RepoNamespace string `json:"repoNamespace"`
 	RepoName string `json:"repoName"`
 	CommitId string `json:"commitId"`
 	Path string `json:"path"`
+	// 0-based, `LineRangeEnd` exclusive; i.e. line 3 to line 5 (as
+	// displayed) would be 2 & 5.
 	LineRangeStart int `json:"lineRangeStart"`
 	LineRangeEnd int `json:"lineRangeEnd"`
 	Username string `json:"userName"`
 	Content string `json:"content"`
+	// the commented lines at the time of commenting.
 	Code []string
 }
 
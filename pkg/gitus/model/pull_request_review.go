@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// pull request reviews. see docs/pull-request-review.org.
+
+const (
+	// a review w/ only a summary; doesn't change whether the pull
+	// request can be merged.
+	PULL_REQUEST_REVIEW_COMMENT = 1
+	PULL_REQUEST_REVIEW_APPROVE = 2
+	PULL_REQUEST_REVIEW_REQUEST_CHANGES = 3
+)
+
+type PullRequestReview struct {
+	PRAbsId int64 `json:"prAbsId"`
+	Reviewer string `json:"reviewer"`
+	State int `json:"state"`
+	Summary string `json:"summary"`
+	// the head of the provider branch at the time of the review.
+	CommitId string `json:"commitId"`
+	Timestamp int64 `json:"timestamp"`
+	// approvals are dismissed when the provider branch is updated.
+	Dismissed bool `json:"dismissed"`
+}
+
+func ValidPullRequestReviewState(s int) bool {
+	return s == PULL_REQUEST_REVIEW_COMMENT || s == PULL_REQUEST_REVIEW_APPROVE || s == PULL_REQUEST_REVIEW_REQUEST_CHANGES
+}
+
+type PullRequestReviewError struct {
+	Reason string
+}
+
+func (e *PullRequestReviewError) Error() string {
+	return fmt.Sprintf("Review requirements not met: %s", e.Reason)
+}
+
+// the latest state of each reviewer, ignoring reviews that are only
+// comments. a reviewer whose latest review is dismissed has no state,
+// i.e. the reviews it superseded don't come back. `reviewList` must
+// be sorted by time.
+func LatestPullRequestReviewState(reviewList []*PullRequestReview) map[string]int {
+	res := make(map[string]int, 0)
+	for _, k := range reviewList {
+		if k.State == PULL_REQUEST_REVIEW_COMMENT { continue }
+		if k.Dismissed {
+			delete(res, k.Reviewer)
+			continue
+		}
+		res[k.Reviewer] = k.State
+	}
+	return res
+}
+
+// returns a `*PullRequestReviewError` if the pull request w/ the
+// reviews `reviewList` (sorted by time) can't be merged under
+// `setting`.
+func CheckPullRequestReview(setting *PullRequestSetting, reviewList []*PullRequestReview) error {
+	latest := LatestPullRequestReviewState(reviewList)
+	requested := make([]string, 0)
+	approvalCount := 0
+	for reviewer, state := range latest {
+		switch state {
+		case PULL_REQUEST_REVIEW_APPROVE: approvalCount += 1
+		case PULL_REQUEST_REVIEW_REQUEST_CHANGES: requested = append(requested, reviewer)
+		}
+	}
+	if len(requested) > 0 {
+		slices.Sort(requested)
+		return &PullRequestReviewError{
+			Reason: fmt.Sprintf("changes requested by %s.", strings.Join(requested, ", ")),
+		}
+	}
+	if setting == nil { return nil }
+	if approvalCount < setting.RequiredApprovalCount {
+		return &PullRequestReviewError{
+			Reason: fmt.Sprintf("%d approval(s) required, %d given.", setting.RequiredApprovalCount, approvalCount),
+		}
+	}
+	for _, k := range setting.RequiredReviewer {
+		if latest[k] != PULL_REQUEST_REVIEW_APPROVE {
+			return &PullRequestReviewError{
+				Reason: fmt.Sprintf("approval from %s is required.", k),
+			}
+		}
+	}
+	return nil
+}
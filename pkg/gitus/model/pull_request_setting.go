@@ -0,0 +1,63 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+)
+
+// per-repository settings on what a pull request needs before it
+// can be merged & how it can be merged.
+type PullRequestSetting struct {
+	RequiredApprovalCount int `json:"requiredApprovalCount"`
+	// when not empty, all of these users have to approve.
+	RequiredReviewer []string `json:"requiredReviewer"`
+	// `gitlib.MERGE_STRATEGY_*`. empty means all strategies are
+	// allowed.
+	AllowedMergeStrategy []int `json:"allowedMergeStrategy"`
+	// the head of the provider branch must have a successful
+	// status for each of these contexts. see model.CommitStatus.
+	RequiredStatusContext []string `json:"requiredStatusContext"`
+}
+
+var ErrMergeStrategyNotAllowed = errors.New("This merge strategy is not allowed in this repository.")
+
+func (s *PullRequestSetting) MergeStrategyAllowed(strategy int) bool {
+	if !gitlib.ValidMergeStrategy(strategy) { return false }
+	if s == nil || len(s.AllowedMergeStrategy) <= 0 { return true }
+	return slices.Contains(s.AllowedMergeStrategy, strategy)
+}
+
+// the strategies that can be picked when merging, in the order of
+// `gitlib.MERGE_STRATEGY_*`.
+func (s *PullRequestSetting) AvailableMergeStrategy() []int {
+	res := make([]int, 0)
+	for k := gitlib.MERGE_STRATEGY_MERGE; k <= gitlib.MERGE_STRATEGY_FAST_FORWARD; k++ {
+		if s.MergeStrategyAllowed(k) { res = append(res, k) }
+	}
+	return res
+}
+
+// stored as comma-separated numbers in the database.
+func ParseMergeStrategyList(s string) []int {
+	res := make([]int, 0)
+	for _, k := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(k))
+		if err != nil { continue }
+		if !gitlib.ValidMergeStrategy(i) { continue }
+		if slices.Contains(res, i) { continue }
+		res = append(res, i)
+	}
+	return res
+}
+
+func SerializeMergeStrategyList(l []int) string {
+	res := make([]string, 0)
+	for _, k := range l {
+		res = append(res, strconv.Itoa(k))
+	}
+	return strings.Join(res, ",")
+}
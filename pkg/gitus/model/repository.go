@@ -23,6 +23,17 @@ const (
 	REPO_TYPE_GIT uint8 = 1
 )
 
+// the orders of the explore page. see docs/explore.org.
+const (
+	REPO_EXPLORE_SORT_ACTIVITY = "activity"
+	REPO_EXPLORE_SORT_STAR = "star"
+	REPO_EXPLORE_SORT_NEWEST = "newest"
+)
+
+func ValidRepositoryExploreSort(s string) bool {
+	return s == REPO_EXPLORE_SORT_ACTIVITY || s == REPO_EXPLORE_SORT_STAR || s == REPO_EXPLORE_SORT_NEWEST
+}
+
 func ValidRepositoryName(s string) bool {
 	colonPassed := false
 	for _, k := range s {
@@ -63,6 +74,11 @@ type Repository struct {
 	ForkOriginName string `json:"forkOriginName"`
 	RepoLabelList []string `json:"labelList"`
 	WebHookConfig *WebHookConfig `json:"webHookConfig"`
+	// only filled in by `GetRepositoryByName` & the explore page.
+	StarCount int64 `json:"starCount"`
+	WatcherCount int64 `json:"watcherCount"`
+	// unix timestamp; 0 if no push has been recorded.
+	LastPushTime int64 `json:"lastPushTime"`
 	// used in simple mode only.
 	Visibility string `json:"visibility"`
 	Users map[string]*SimpleModeUserACL `json:"users"`
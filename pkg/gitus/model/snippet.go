@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
 )
 
 const (
@@ -18,6 +21,23 @@ const (
 	SNIPPET_SHARED_LINK_INTERNAL uint8 = 6
 )
 
+// snippets are stored as bare git repositories w/ all the files
+// living at the root of this branch. see docs/snippets.org.
+const SNIPPET_BRANCH = "master"
+
+const (
+	SNIPPET_EVENT_COMMENT = 1
+)
+
+// comments on snippets. stored the same way as `IssueEvent`.
+type SnippetEvent struct {
+	EventAbsId int64
+	EventType int
+	EventTimestamp int64
+	EventAuthor string
+	EventContent string
+}
+
 type Snippet struct {
 	Name string
 	BelongingUser string
@@ -32,75 +52,267 @@ func (s *Snippet) FullName() string {
 	return fmt.Sprintf("%s:%s", s.BelongingUser, s.Name)
 }
 
-// retrieve file from disk.
-func (s *Snippet) Retrieve(basePath string, filePath string) error {
-	fullPath := path.Join(basePath, s.BelongingUser, s.Name, filePath)
-	f, err := os.ReadFile(fullPath)
-	if err != nil { return err }
-	if s.FileList == nil {
-		s.FileList = make(map[string]string, 0)
+// snippet files are flat (i.e. no subdirectories), and since the
+// names are fed to git-fast-import line by line they must not
+// contain anything that could break the stream.
+func ValidSnippetFileName(s string) bool {
+	if len(s) <= 0 || len(s) > 255 { return false }
+	if s == "." || s == ".." { return false }
+	if strings.HasPrefix(s, "\"") { return false }
+	for _, k := range s {
+		if k < 0x20 || k == 0x7f || k == '/' || k == '\\' { return false }
 	}
-	s.FileList[filePath] = string(f)
-	return nil
+	return true
 }
 
-// retrieve all file from disk.
-func (s *Snippet) RetrieveAllFile(basePath string) error {
-	rootPath := path.Join(basePath, s.BelongingUser, s.Name)
-	p, err := os.ReadDir(rootPath)
+// the location of the bare git repository of the snippet.
+func (s *Snippet) LocalPath(basePath string) string {
+	return path.Join(basePath, s.BelongingUser, s.Name)
+}
+
+// creates a new, empty bare repository for a snippet at `p`.
+func InitSnippetRepository(p string) error {
+	err := os.MkdirAll(p, os.ModeDir|0755)
+	if err != nil { return err }
+	cmd := exec.Command("git", "init", "--bare")
+	cmd.Dir = p
+	err = cmd.Run()
+	if err != nil { return err }
+	// older version of git does not support `--initial-branch`.
+	cmd = exec.Command("git", "symbolic-ref", "HEAD", "refs/heads/" + SNIPPET_BRANCH)
+	cmd.Dir = p
+	return cmd.Run()
+}
+
+func isSnippetRepository(p string) bool {
+	s, err := os.Stat(path.Join(p, "HEAD"))
+	if err != nil || s.IsDir() { return false }
+	s, err = os.Stat(path.Join(p, "objects"))
+	if err != nil || !s.IsDir() { return false }
+	return true
+}
+
+// snippets created before they were backed by git are plain
+// directories of files. we convert them into a repository with a
+// single commit the first time they're opened.
+func (s *Snippet) convertLegacyDirectory(p string) error {
+	entryList, err := os.ReadDir(p)
+	if err != nil { return err }
+	fileList := make(map[string]string, 0)
+	for _, k := range entryList {
+		if k.IsDir() { continue }
+		if !ValidSnippetFileName(k.Name()) { continue }
+		f, err := os.ReadFile(path.Join(p, k.Name()))
+		if err != nil { return err }
+		fileList[k.Name()] = string(f)
+	}
+	legacyPath := p + ".legacy"
+	os.RemoveAll(legacyPath)
+	err = os.Rename(p, legacyPath)
+	if err != nil { return err }
+	err = InitSnippetRepository(p)
+	if err == nil && len(fileList) > 0 {
+		lgr := gitlib.NewLocalGitRepository(p)
+		_, err = lgr.AddMultipleFileToRepoString(
+			SNIPPET_BRANCH,
+			s.BelongingUser, "", s.BelongingUser, "",
+			"Import snippet", fileList,
+		)
+	}
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			os.MkdirAll(rootPath, os.ModeDir | 0644)
-		} else { return err }
+		os.RemoveAll(p)
+		os.Rename(legacyPath, p)
+		return err
+	}
+	return os.RemoveAll(legacyPath)
+}
+
+// the visibility of a fork of a snippet w/ the visibility `status`.
+// public & internal snippets stay the same; everything else becomes
+// private so that a fork never shows up to someone who can't see the
+// original. the owner can change it afterwards.
+func SnippetForkStatus(status uint8) uint8 {
+	switch status {
+	case SNIPPET_PUBLIC, SNIPPET_INTERNAL: return status
+	default: return SNIPPET_PRIVATE
+	}
+}
+
+// copies the repository of the snippet (incl. all the revisions) to
+// `p`, which is replaced if exists.
+func (s *Snippet) CopyRepositoryTo(basePath string, p string) error {
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return err }
+	err = os.RemoveAll(p)
+	if err != nil { return err }
+	err = os.MkdirAll(path.Dir(p), os.ModeDir|0755)
+	if err != nil { return err }
+	cmd := exec.Command("git", "clone", "--bare", "--no-local", lgr.GitDirectoryPath, p)
+	err = cmd.Run()
+	if err != nil { return err }
+	// the fork shouldn't know where the original is.
+	cmd = exec.Command("git", "remote", "remove", "origin")
+	cmd.Dir = p
+	return cmd.Run()
+}
+
+// opens the repository of the snippet, creating it (or converting
+// it from the old plain-directory format) if necessary.
+func (s *Snippet) OpenRepository(basePath string) (*gitlib.LocalGitRepository, error) {
+	p := s.LocalPath(basePath)
+	rp, err := filepath.Rel(basePath, p)
+	if err != nil || strings.HasPrefix(rp, "..") { return nil, errors.New("Invalid location") }
+	_, err = os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		err = InitSnippetRepository(p)
+		if err != nil { return nil, err }
+	} else if err != nil {
+		return nil, err
+	} else if !isSnippetRepository(p) {
+		err = s.convertLegacyDirectory(p)
+		if err != nil { return nil, err }
 	}
+	return gitlib.NewLocalGitRepository(p), nil
+}
+
+// returns the id of the latest commit. an empty string means the
+// snippet has no commit yet.
+func (s *Snippet) HeadId(lgr *gitlib.LocalGitRepository) (string, error) {
+	err := lgr.SyncBranch(SNIPPET_BRANCH)
+	if err != nil { return "", err }
+	b, ok := lgr.BranchIndex[SNIPPET_BRANCH]
+	if !ok { return "", nil }
+	return b.HeadId, nil
+}
+
+// reads the files of the snippet at commit `commitId`. when
+// `readContent` is false only the names are filled.
+func (s *Snippet) readFileListAt(lgr *gitlib.LocalGitRepository, commitId string, filePath string, readContent bool) error {
 	if s.FileList == nil { s.FileList = make(map[string]string, 0) }
-	for _, k := range p {
-		if !k.IsDir() {
-			f, err := os.ReadFile(path.Join(rootPath, k.Name()))
-			if err != nil { return err }
-			s.FileList[k.Name()] = string(f)
+	if commitId == "" { return nil }
+	cobj, err := lgr.ReadObject(commitId)
+	if err != nil { return err }
+	if cobj.Type() != gitlib.COMMIT { return errors.New("Not a commit") }
+	tobj, err := lgr.ReadObject(cobj.(*gitlib.CommitObject).TreeObjId)
+	if err != nil { return err }
+	if tobj.Type() != gitlib.TREE { return errors.New("Not a tree") }
+	for _, k := range tobj.(*gitlib.TreeObject).ObjectList {
+		if k.Mode != gitlib.TREE_NORMAL_FILE && k.Mode != gitlib.TREE_EXECUTABLE_FILE { continue }
+		if filePath != "" && k.Name != filePath { continue }
+		if !readContent {
+			s.FileList[k.Name] = ""
+			continue
 		}
+		bobj, err := lgr.ReadObject(k.Hash)
+		if err != nil { return err }
+		if bobj.Type() != gitlib.BLOB { return errors.New("Not a blob") }
+		s.FileList[k.Name] = string(bobj.(*gitlib.BlobObject).Data)
 	}
 	return nil
 }
 
+// retrieve file from the latest revision.
+func (s *Snippet) Retrieve(basePath string, filePath string) error {
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return err }
+	headId, err := s.HeadId(lgr)
+	if err != nil { return err }
+	err = s.readFileListAt(lgr, headId, filePath, true)
+	if err != nil { return err }
+	_, ok := s.FileList[filePath]
+	if !ok { return os.ErrNotExist }
+	return nil
+}
+
+// retrieve all file from the latest revision.
+func (s *Snippet) RetrieveAllFile(basePath string) error {
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return err }
+	headId, err := s.HeadId(lgr)
+	if err != nil { return err }
+	return s.readFileListAt(lgr, headId, "", true)
+}
+
+// retrieve all file from the revision `commitId`. .FileList is reset
+// before reading so that files that only exist in later revisions
+// wouldn't show up.
+func (s *Snippet) RetrieveAllFileAt(basePath string, commitId string) error {
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return err }
+	s.FileList = make(map[string]string, 0)
+	return s.readFileListAt(lgr, commitId, "", true)
+}
+
 // retrieve file list. NOTE THAT this will populate .FileList but
 // will NOT read the content of the files. whoever needs the file
 // content should always use .Retrieve and .RetrieveAllFile.
 func (s *Snippet) CalculateFileList(basePath string) error {
-	rootPath := path.Join(basePath, s.BelongingUser, s.Name)
-	p, err := os.ReadDir(rootPath)
+	lgr, err := s.OpenRepository(basePath)
 	if err != nil { return err }
-	if s.FileList == nil { s.FileList = make(map[string]string, 0) }
-	for _, k := range p {
-		if !k.IsDir() { s.FileList[k.Name()] = "" }
+	headId, err := s.HeadId(lgr)
+	if err != nil { return err }
+	return s.readFileListAt(lgr, headId, "", false)
+}
+
+// returns the revisions of the snippet, newest first. `n` <= 0
+// means no limit.
+func (s *Snippet) GetRevisionList(basePath string, n int) ([]gitlib.CommitObject, error) {
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return nil, err }
+	headId, err := s.HeadId(lgr)
+	if err != nil { return nil, err }
+	if headId == "" { return make([]gitlib.CommitObject, 0), nil }
+	return lgr.GetCommitHistoryN(headId, n)
+}
+
+// returns the commit & the changes made in revision `commitId`.
+func (s *Snippet) GetRevision(basePath string, commitId string) (*gitlib.CommitObject, *gitlib.Diff, error) {
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return nil, nil, err }
+	cobj, err := lgr.ReadObject(commitId)
+	if err != nil { return nil, nil, err }
+	if cobj.Type() != gitlib.COMMIT { return nil, nil, errors.New("Not a commit") }
+	co := cobj.(*gitlib.CommitObject)
+	// git-diff-tree shows nothing for root commits unless we diff
+	// against the empty tree.
+	var diff *gitlib.Diff
+	if co.ParentId() == "" {
+		diff, err = lgr.GetRangeDiff(lgr.EmptyTreeId(), co.Id)
+	} else {
+		diff, err = lgr.GetDiff(co.Id)
 	}
-	return nil
+	if err != nil { return nil, nil, err }
+	return co, diff, nil
 }
 
-// save file to disk.
-func (s *Snippet) SyncFile(basePath string, p string) error {
+// commit file to the snippet repository.
+func (s *Snippet) SyncFile(basePath string, p string, authorName string, authorEmail string, commitMessage string) error {
 	source, ok := s.FileList[p]
 	if !ok { return nil }
-	targetPath := path.Join(basePath, s.BelongingUser, s.Name, p)
-	p, err := filepath.Rel(basePath, targetPath)
-	if err != nil { return errors.New("Invalid location") }
-	if strings.HasPrefix(p, "..") { return errors.New("Invalid location") }
-	f, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil { return err }
-	f.WriteString(source)
-	f.Sync()
-	f.Close()
-	return nil
+	if !ValidSnippetFileName(p) { return errors.New("Invalid file name") }
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return err }
+	_, err = lgr.AddMultipleFileToRepoString(
+		SNIPPET_BRANCH,
+		authorName, authorEmail, authorName, authorEmail,
+		commitMessage, map[string]string{p: source},
+	)
+	return err
 }
 
-// save all file to disk.
-func (s *Snippet) SyncAllFile(basePath string) error {
+// commit all file to the snippet repository as a single revision.
+func (s *Snippet) SyncAllFile(basePath string, authorName string, authorEmail string, commitMessage string) error {
 	for k := range s.FileList {
-		err := s.SyncFile(basePath, k)
-		if err != nil { return err }
+		if !ValidSnippetFileName(k) { return errors.New("Invalid file name") }
 	}
-	return nil
+	lgr, err := s.OpenRepository(basePath)
+	if err != nil { return err }
+	_, err = lgr.AddMultipleFileToRepoString(
+		SNIPPET_BRANCH,
+		authorName, authorEmail, authorName, authorEmail,
+		commitMessage, s.FileList,
+	)
+	return err
 }
 
 func (s *Snippet) SetFile(p string, content string) {
@@ -108,11 +320,23 @@ func (s *Snippet) SetFile(p string, content string) {
 	s.FileList[p] = content
 }
 
-func (s *Snippet) DeleteFile(basePath string, p string) error {
+// removes file from the snippet w/ a new revision. does nothing if
+// the file does not exist in the latest revision.
+func (s *Snippet) DeleteFile(basePath string, p string, authorName string, authorEmail string, commitMessage string) error {
 	delete(s.FileList, p)
-	targetPath := path.Join(basePath, s.BelongingUser, s.Name, p)
-	err := os.RemoveAll(targetPath)
+	if !ValidSnippetFileName(p) { return nil }
+	lgr, err := s.OpenRepository(basePath)
 	if err != nil { return err }
-	return nil
+	headId, err := s.HeadId(lgr)
+	if err != nil { return err }
+	current := &Snippet{}
+	err = current.readFileListAt(lgr, headId, p, false)
+	if err != nil { return err }
+	if _, ok := current.FileList[p]; !ok { return nil }
+	_, err = lgr.RemoveFileFromRepo(
+		SNIPPET_BRANCH, p,
+		authorName, authorEmail, authorName, authorEmail,
+		commitMessage,
+	)
+	return err
 }
-
@@ -44,6 +44,37 @@ type GitusUser2FAConfig struct {
 	Email struct{
 		Enable bool `json:"enable"`
 	} `json:"email"`
+	TOTP GitusUserTOTPConfig `json:"totp"`
+	// security keys. since webauthn requires javascript these can
+	// only be used when the user has opted into javascript.
+	WebAuthn []GitusUserWebAuthnCredential `json:"webauthn"`
+	// sha256 hashes (in hex) of the recovery codes that are not used
+	// yet. a recovery code is removed from this list once it's used.
+	RecoveryCode []string `json:"recoveryCode"`
+}
+
+type GitusUserTOTPConfig struct {
+	Enable bool `json:"enable"`
+	// base32-encoded secret.
+	Secret string `json:"secret"`
+	// the secret generated at the start of the enrollment. it's moved
+	// to `Secret` once the user has entered a valid code w/ it.
+	PendingSecret string `json:"pendingSecret"`
+	// the last time step that's been accepted. codes from this step
+	// and the steps before are rejected so that a code can't be
+	// reused.
+	LastStep int64 `json:"lastStep"`
+}
+
+type GitusUserWebAuthnCredential struct {
+	// name given by the user, e.g. "yubikey".
+	Name string `json:"name"`
+	// credential id (raw bytes).
+	Id []byte `json:"id"`
+	// cose-encoded public key.
+	PublicKey []byte `json:"publicKey"`
+	SignCount uint32 `json:"signCount"`
+	RegisterTime int64 `json:"regTime"`
 }
 
 type GitusUserWebsitePreference struct {
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/totp"
+)
+
+const RECOVERY_CODE_COUNT = 10
+
+// whether a security key can be used; see the comment on `WebAuthn`.
+func (u *GitusUser) WebAuthnUsable() bool {
+	return len(u.TFAConfig.WebAuthn) > 0 && u.WebsitePreference.UseJavascript
+}
+
+// whether any second factor has been set up, incl. security keys
+// that can't be used right now. the password alone isn't enough
+// outside of the web ui for these users (e.g. http clone/push).
+func (u *GitusUser) HasSecondFactorConfigured() bool {
+	return u.TFAConfig.Email.Enable || u.TFAConfig.TOTP.Enable || len(u.TFAConfig.WebAuthn) > 0
+}
+
+// whether security keys are the only second factor; turning off
+// javascript would leave these users w/ the password alone, so the
+// preference can't be turned off for them.
+func (u *GitusUser) WebAuthnIsOnlySecondFactor() bool {
+	return len(u.TFAConfig.WebAuthn) > 0 && !u.TFAConfig.Email.Enable && !u.TFAConfig.TOTP.Enable
+}
+
+// whether the user would be asked for a second factor when logging in.
+func (u *GitusUser) HasSecondFactor() bool {
+	return u.TFAConfig.Email.Enable || u.TFAConfig.TOTP.Enable || u.WebAuthnUsable()
+}
+
+func (cfg *GitusUser2FAConfig) GetWebAuthnCredentialById(id []byte) *GitusUserWebAuthnCredential {
+	for i, k := range cfg.WebAuthn {
+		if subtle.ConstantTimeCompare(k.Id, id) == 1 { return &cfg.WebAuthn[i] }
+	}
+	return nil
+}
+
+// recovery codes are shown to the user once & only their hashes are
+// stored. since they're random (unlike passwords) a plain sha256 is
+// enough here.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
+
+// generates a new set of recovery codes, replacing the old ones.
+// returns the codes in plain text, formatted as "xxxxx-xxxxx".
+func (cfg *GitusUser2FAConfig) NewRecoveryCodeList() ([]string, error) {
+	const charset = "abcdefghijkmnpqrstuvwxyz23456789"
+	res := make([]string, 0, RECOVERY_CODE_COUNT)
+	hashList := make([]string, 0, RECOVERY_CODE_COUNT)
+	for range RECOVERY_CODE_COUNT {
+		buf := make([]byte, 10)
+		_, err := rand.Read(buf)
+		if err != nil { return nil, err }
+		for i := range buf { buf[i] = charset[int(buf[i]) % len(charset)] }
+		code := string(buf[:5]) + "-" + string(buf[5:])
+		res = append(res, code)
+		hashList = append(hashList, hashRecoveryCode(code))
+	}
+	cfg.RecoveryCode = hashList
+	return res, nil
+}
+
+// checks & consumes a recovery code. the caller is responsible for
+// saving the config afterwards.
+func (cfg *GitusUser2FAConfig) UseRecoveryCode(code string) bool {
+	h := hashRecoveryCode(code)
+	for i, k := range cfg.RecoveryCode {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(h)) == 1 {
+			cfg.RecoveryCode = append(cfg.RecoveryCode[:i], cfg.RecoveryCode[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// checks a totp code against the enabled secret. a code is only
+// accepted once; the caller is responsible for saving the config
+// afterwards.
+func (cfg *GitusUser2FAConfig) UseTOTPCode(code string) bool {
+	if !cfg.TOTP.Enable { return false }
+	step := totp.Validate(cfg.TOTP.Secret, code, time.Now())
+	if step < 0 || step <= cfg.TOTP.LastStep { return false }
+	cfg.TOTP.LastStep = step
+	return true
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"path"
+	"strings"
+)
+
+// webhooks. see docs/webhooks.org.
+//
+// besides the webhook in `Repository.WebHookConfig` (the "default
+// webhook", which only receives ref updates), a repository or a
+// namespace could have any number of webhooks, each subscribing to
+// its own events.
+
+const (
+	WEBHOOK_EVENT_PUSH = "push"
+	WEBHOOK_EVENT_TAG = "tag"
+	WEBHOOK_EVENT_BRANCH_DELETE = "branch-delete"
+	WEBHOOK_EVENT_ISSUE_OPENED = "issue-opened"
+	WEBHOOK_EVENT_ISSUE_COMMENTED = "issue-commented"
+	WEBHOOK_EVENT_ISSUE_CLOSED = "issue-closed"
+	WEBHOOK_EVENT_PULL_REQUEST_OPENED = "pull-request-opened"
+	WEBHOOK_EVENT_PULL_REQUEST_UPDATED = "pull-request-updated"
+	WEBHOOK_EVENT_PULL_REQUEST_MERGED = "pull-request-merged"
+	WEBHOOK_EVENT_FORK = "fork"
+)
+
+var WebhookEventList = []string{
+	WEBHOOK_EVENT_PUSH,
+	WEBHOOK_EVENT_TAG,
+	WEBHOOK_EVENT_BRANCH_DELETE,
+	WEBHOOK_EVENT_ISSUE_OPENED,
+	WEBHOOK_EVENT_ISSUE_COMMENTED,
+	WEBHOOK_EVENT_ISSUE_CLOSED,
+	WEBHOOK_EVENT_PULL_REQUEST_OPENED,
+	WEBHOOK_EVENT_PULL_REQUEST_UPDATED,
+	WEBHOOK_EVENT_PULL_REQUEST_MERGED,
+	WEBHOOK_EVENT_FORK,
+}
+
+func ValidWebhookEvent(s string) bool {
+	for _, k := range WebhookEventList {
+		if k == s { return true }
+	}
+	return false
+}
+
+// events that happen on a branch, which is what the branch filter
+// of a webhook is checked against. for pull requests it's the
+// receiving branch.
+func IsBranchWebhookEvent(event string) bool {
+	switch event {
+	case WEBHOOK_EVENT_PUSH: fallthrough
+	case WEBHOOK_EVENT_BRANCH_DELETE: fallthrough
+	case WEBHOOK_EVENT_PULL_REQUEST_OPENED: fallthrough
+	case WEBHOOK_EVENT_PULL_REQUEST_UPDATED: fallthrough
+	case WEBHOOK_EVENT_PULL_REQUEST_MERGED:
+		return true
+	}
+	return false
+}
+
+// the event of updating `refFullName` to `newRev`, and the branch
+// name if it's a branch. the event is empty if the ref is neither a
+// branch nor a tag.
+func WebhookRefEvent(refFullName string, newRev string) (string, string) {
+	if strings.HasPrefix(refFullName, "refs/tags/") { return WEBHOOK_EVENT_TAG, "" }
+	if !strings.HasPrefix(refFullName, "refs/heads/") { return "", "" }
+	branch := strings.TrimPrefix(refFullName, "refs/heads/")
+	if len(strings.Trim(newRev, "0")) <= 0 { return WEBHOOK_EVENT_BRANCH_DELETE, branch }
+	return WEBHOOK_EVENT_PUSH, branch
+}
+
+type Webhook struct {
+	// 0 for the default webhook of a repository.
+	Id int64
+	Namespace string
+	// empty for the webhooks of a namespace, which receive the
+	// events of all the repositories in it.
+	RepoName string
+	TargetURL string
+	Secret string
+	// only "json" for now.
+	PayloadType string
+	EventList []string
+	// glob patterns (see `path.Match`) of branch names; empty means
+	// all branches. see `IsBranchWebhookEvent`.
+	BranchFilter []string
+	Enable bool
+	CreateTime int64
+}
+
+func (w *Webhook) Subscribes(event string) bool {
+	for _, k := range w.EventList {
+		if k == event { return true }
+	}
+	return false
+}
+
+func (w *Webhook) MatchBranch(branch string) bool {
+	if len(w.BranchFilter) <= 0 { return true }
+	for _, k := range w.BranchFilter {
+		ok, err := path.Match(k, branch)
+		if err == nil && ok { return true }
+	}
+	return false
+}
+
+// whether `w` should receive `event` on `branch`.
+func (w *Webhook) Wants(event string, branch string) bool {
+	if !w.Enable || !w.Subscribes(event) { return false }
+	if IsBranchWebhookEvent(event) && !w.MatchBranch(branch) { return false }
+	return true
+}
+
+// the default webhook of `repo` as a `Webhook`; nil if it's not
+// enabled.
+func DefaultWebhookOf(repo *Repository) *Webhook {
+	if repo.WebHookConfig == nil || !repo.WebHookConfig.Enable { return nil }
+	return &Webhook{
+		Id: 0,
+		Namespace: repo.Namespace,
+		RepoName: repo.Name,
+		TargetURL: repo.WebHookConfig.TargetURL,
+		Secret: repo.WebHookConfig.Secret,
+		PayloadType: repo.WebHookConfig.PayloadType,
+		EventList: []string{WEBHOOK_EVENT_PUSH, WEBHOOK_EVENT_TAG, WEBHOOK_EVENT_BRANCH_DELETE},
+		Enable: true,
+	}
+}
+
+// comma- or whitespace-separated; empty items are dropped.
+func ParseWebhookBranchFilter(s string) []string {
+	res := make([]string, 0)
+	for _, k := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' }) {
+		res = append(res, k)
+	}
+	return res
+}
+
+// parses the comma-separated lists stored in the database.
+func ParseWebhookList(s string) []string {
+	res := make([]string, 0)
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if len(k) > 0 { res = append(res, k) }
+	}
+	return res
+}
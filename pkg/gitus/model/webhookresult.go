@@ -17,3 +17,50 @@ type WebhookResult struct {
 	Timestamp int64 `json:"timestamp"`
 }
 
+
+// a webhook delivery is a webhook request that's sent (possibly
+// several times, see docs/job-queue.org) to the target url. it's
+// stored in the same row as the `WebhookResult` reported back by
+// the receiving end. see docs/webhooks.org.
+const (
+	WEBHOOK_DELIVERY_PENDING = 1
+	WEBHOOK_DELIVERY_SUCCESS = 2
+	WEBHOOK_DELIVERY_FAILED = 3
+)
+
+// the response body is only kept up to this many bytes.
+const WEBHOOK_RESPONSE_BODY_LIMIT = 4096
+
+type WebhookDelivery struct {
+	UUID string
+	ReportUUID string
+	RepoNamespace string
+	RepoName string
+	// the webhook this delivery is for; 0 for the default webhook of
+	// the repository. see `Webhook`.
+	WebhookId int64
+	// see model.WEBHOOK_EVENT_*.
+	Event string
+	// only for ref updates & pull requests.
+	CommitId string
+	RefName string
+	TargetURL string
+	// "Key: Value" lines of the last attempt.
+	RequestHeader string
+	RequestPayload string
+	// 0 if there's no response at all, in which case `Error` tells
+	// why.
+	ResponseStatus int
+	ResponseBody string
+	Error string
+	// in milliseconds.
+	Duration int64
+	Status int
+	Attempt int
+	CreateTime int64
+	DeliveryTime int64
+	// the uuid of the delivery this one is redelivering; empty if
+	// it's not a redelivery.
+	RedeliveryOf string
+	Result *WebhookResult
+}
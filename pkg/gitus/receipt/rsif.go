@@ -99,6 +99,8 @@ const (
 	RESET_PASSWORD = "reset-password"
 	// verify-email,{username},{email}
 	VERIFY_EMAIL = "verify-email"
+	// transfer-namespace,{namespace},{currentOwner},{newOwner}
+	TRANSFER_NAMESPACE = "transfer-namespace"
 )
 
 var ErrUnsupportedSystemType = errors.New("Unsupported receipt system type")
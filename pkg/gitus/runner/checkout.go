@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checks out the tree of `commitId` into `dir` thru `git archive`.
+// unlike `git worktree`, the result has nothing pointing back to the
+// repository, so the steps can't get to it from the scratch
+// worktree. the files are owned by `uid`/`gid`.
+func checkoutCommit(gitDir string, commitId string, dir string, uid int, gid int) error {
+	cmd := exec.Command("git", "archive", "--format=tar", commitId)
+	cmd.Dir = gitDir
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil { return err }
+	err = cmd.Start()
+	if err != nil { return err }
+	err = extractTar(stdout, dir, uid, gid)
+	// drain the rest so that git doesn't get stuck on a full pipe.
+	io.Copy(io.Discard, stdout)
+	werr := cmd.Wait()
+	if werr != nil { return fmt.Errorf("git archive failed: %s: %s", werr, strings.TrimSpace(stderr.String())) }
+	return err
+}
+
+func extractTar(r io.Reader, dir string, uid int, gid int) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF { return nil }
+		if err != nil { return err }
+		name := filepath.Clean(filepath.FromSlash(h.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".." + string(filepath.Separator)) {
+			return fmt.Errorf("Invalid path in archive: %s", h.Name)
+		}
+		// the runner runs as root & the tree comes from whoever
+		// pushed it, so a symlink in the tree must never be
+		// followed when writing the entries after it.
+		err = checkNoSymlinkParent(dir, name)
+		if err != nil { return err }
+		p := filepath.Join(dir, name)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, 0755)
+		case tar.TypeReg:
+			err = writeFile(p, tr, os.FileMode(h.Mode) & 0777)
+		case tar.TypeSymlink:
+			err = os.Symlink(h.Linkname, p)
+		default:
+			// e.g. the global header `git archive` puts the commit
+			// id in.
+			continue
+		}
+		if err != nil { return err }
+		err = os.Lchown(p, uid, gid)
+		if err != nil { return err }
+	}
+}
+
+// returns an error if any of the parent directories of `name` that
+// already exist inside `dir` is a symlink. the ones that don't exist
+// yet are created as plain directories by `MkdirAll`.
+func checkNoSymlinkParent(dir string, name string) error {
+	p := dir
+	for _, k := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if k == "." { continue }
+		p = filepath.Join(p, k)
+		fi, err := os.Lstat(p)
+		if errors.Is(err, fs.ErrNotExist) { return nil }
+		if err != nil { return err }
+		if fi.Mode() & os.ModeSymlink != 0 {
+			return fmt.Errorf("Invalid path in archive: %s goes through a symlink", name)
+		}
+	}
+	return nil
+}
+
+func writeFile(p string, r io.Reader, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil { return err }
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil { return err }
+	_, err = io.Copy(f, r)
+	cerr := f.Close()
+	return errors.Join(err, cerr)
+}
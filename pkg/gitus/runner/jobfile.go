@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/pkg/ini"
+)
+
+// the job file of a repository, read from the pushed commit. see
+// docs/ci-runner.org. e.g.:
+//
+//     [job "test"]
+//         branch = main, release/*
+//         timeout = 600
+//         step-1 = go build ./...
+//         step-2 = go test ./...
+
+const CI_JOB_FILE_PATH = ".gitus/ci.ini"
+
+type CIJob struct {
+	Name string
+	// glob patterns (see `path.Match`) of the branches the job runs
+	// on. empty means all branches.
+	BranchFilter []string
+	// 0 means the default timeout of the runner.
+	Timeout time.Duration
+	// shell commands, run w/ `sh -c` one after another.
+	StepList []string
+}
+
+func (j *CIJob) MatchBranch(branch string) bool {
+	if len(j.BranchFilter) <= 0 { return true }
+	for _, k := range j.BranchFilter {
+		ok, err := path.Match(k, branch)
+		if err == nil && ok { return true }
+	}
+	return false
+}
+
+// steps are `step-{n}` where `n` is a positive integer; they're run
+// in the order of `n`, which doesn't have to be continuous.
+func parseStepKey(k string) (int, bool) {
+	if !strings.HasPrefix(k, "step-") { return 0, false }
+	n, err := strconv.Atoi(strings.TrimPrefix(k, "step-"))
+	if err != nil || n <= 0 { return 0, false }
+	return n, true
+}
+
+// the jobs are sorted by name.
+func ParseCIJobFile(r io.Reader) ([]*CIJob, error) {
+	cfg, err := ini.ParseINI(r)
+	if err != nil { return nil, err }
+	sectionList, ok := cfg.GetSectionList("job")
+	if !ok { return nil, errors.New("No job defined.") }
+	res := make([]*CIJob, 0)
+	for name, sec := range sectionList {
+		if !model.ValidCIJobName(name) {
+			return nil, fmt.Errorf("Invalid job name: \"%s\"", name)
+		}
+		job := &CIJob{Name: name, BranchFilter: make([]string, 0), StepList: make([]string, 0)}
+		stepNumList := make([]int, 0)
+		stepMap := make(map[int]string, 0)
+		for k, v := range sec.Value {
+			switch k {
+			case "branch":
+				job.BranchFilter = model.ParseWebhookBranchFilter(v)
+				for _, p := range job.BranchFilter {
+					_, err := path.Match(p, "")
+					if err != nil { return nil, fmt.Errorf("Job %s: invalid branch pattern: %s", name, p) }
+				}
+			case "timeout":
+				n, err := strconv.Atoi(strings.TrimSpace(v))
+				if err != nil || n <= 0 { return nil, fmt.Errorf("Job %s: timeout must be a positive number of seconds.", name) }
+				job.Timeout = time.Duration(n) * time.Second
+			default:
+				n, ok := parseStepKey(k)
+				if !ok { return nil, fmt.Errorf("Job %s: unknown key: %s", name, k) }
+				if len(strings.TrimSpace(v)) <= 0 { continue }
+				stepNumList = append(stepNumList, n)
+				stepMap[n] = v
+			}
+		}
+		if len(stepNumList) <= 0 { return nil, fmt.Errorf("Job %s has no step.", name) }
+		slices.Sort(stepNumList)
+		for _, n := range stepNumList { job.StepList = append(job.StepList, stepMap[n]) }
+		res = append(res, job)
+	}
+	if len(res) <= 0 { return nil, errors.New("No job defined.") }
+	slices.SortFunc(res, func(a *CIJob, b *CIJob) int { return strings.Compare(a.Name, b.Name) })
+	return res, nil
+}
+
+// reads the job file from `commitId`. returns nil (w/o error) if
+// the commit doesn't have one.
+func ReadCIJobFile(lgr *gitlib.LocalGitRepository, commitId string) ([]*CIJob, error) {
+	obj, err := lgr.ReadObject(commitId)
+	if err != nil { return nil, err }
+	cobj, ok := obj.(*gitlib.CommitObject)
+	if !ok { return nil, nil }
+	obj, err = lgr.ReadObject(cobj.TreeObjId)
+	if err != nil { return nil, err }
+	tobj, ok := obj.(*gitlib.TreeObject)
+	if !ok { return nil, nil }
+	obj, err = lgr.ResolveTreePath(tobj, CI_JOB_FILE_PATH)
+	if err == gitlib.ErrObjectNotFound { return nil, nil }
+	if err != nil { return nil, err }
+	bobj, ok := obj.(*gitlib.BlobObject)
+	if !ok { return nil, nil }
+	return ParseCIJobFile(bytes.NewReader(bobj.Data))
+}
+
+// reads the job file from the head of the receiver branch of `pr`.
+// the runs on pull requests from other repositories use this instead
+// of the job file of the pull request itself, which could be anything
+// its author wants.
+func ReadPullRequestCIJobFile(lgr *gitlib.LocalGitRepository, pr *model.PullRequest) ([]*CIJob, error) {
+	err := lgr.SyncBranch(pr.ReceiverBranch)
+	if err != nil { return nil, err }
+	br, ok := lgr.BranchIndex[pr.ReceiverBranch]
+	if !ok { return nil, fmt.Errorf("Branch %s not found.", pr.ReceiverBranch) }
+	return ReadCIJobFile(lgr, br.HeadId)
+}
@@ -0,0 +1,357 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+)
+
+// the built-in ci runner (`gitus runner`). see docs/ci-runner.org.
+//
+// the runs are queued (by the post-receive hook & the web editor) in
+// their own table instead of the job queue, since the job workers of
+// the web server would otherwise claim them.
+
+// how long an idle worker waits before checking the database again.
+const POLL_INTERVAL = 5 * time.Second
+
+// how often the log of a running job is written to the database.
+const LOG_FLUSH_INTERVAL = 2 * time.Second
+
+const DEFAULT_TIMEOUT = 3600
+
+type Runner struct {
+	dbif db.GitusDatabaseInterface
+	config *gitus.GitusConfig
+	workDir string
+	// the steps are always run as this user; see `NewRunner`.
+	uid int
+	gid int
+	stopChan chan struct{}
+	// cancelled on stop, which kills the running steps.
+	ctx context.Context
+	cancel context.CancelFunc
+	wg sync.WaitGroup
+	started bool
+}
+
+func NewRunner(dbif db.GitusDatabaseInterface, config *gitus.GitusConfig) (*Runner, error) {
+	res := &Runner{
+		dbif: dbif,
+		config: config,
+		workDir: config.Runner.WorkDirectory,
+		stopChan: make(chan struct{}),
+	}
+	res.ctx, res.cancel = context.WithCancel(context.Background())
+	if len(res.workDir) <= 0 { res.workDir = filepath.Join(os.TempDir(), "gitus-runner") }
+	// the user running `gitus runner` can read the config & the
+	// database, so the steps (which anyone who can push gets to
+	// write) must never run as that user.
+	if len(config.Runner.User) <= 0 {
+		return nil, errors.New("runner.user is not set; the steps must be run as a separate unprivileged user")
+	}
+	u, err := user.Lookup(config.Runner.User)
+	if err != nil { return nil, fmt.Errorf("Failed to find runner user %s: %s", config.Runner.User, err) }
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil { return nil, err }
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil { return nil, err }
+	if uid == os.Getuid() {
+		return nil, fmt.Errorf("runner.user %s is the user running the runner; the steps must be run as a separate unprivileged user", config.Runner.User)
+	}
+	res.uid = uid
+	res.gid = gid
+	err = os.MkdirAll(res.workDir, 0755)
+	if err != nil { return nil, err }
+	return res, nil
+}
+
+// starts `workerCount` workers. runs left running by a previous
+// runner that didn't stop properly are queued again.
+func (rn *Runner) Start(workerCount int) error {
+	if rn.started { return nil }
+	err := rn.dbif.ResetRunningCIRun()
+	if err != nil { return err }
+	if workerCount <= 0 { workerCount = 1 }
+	rn.started = true
+	for i := 0; i < workerCount; i++ {
+		rn.wg.Add(1)
+		go rn.work()
+	}
+	return nil
+}
+
+// stops taking new runs & kills the running ones, which are
+// finished as errors. waits for them to be recorded, or until `ctx`
+// is done.
+func (rn *Runner) Stop(ctx context.Context) error {
+	if !rn.started { return nil }
+	close(rn.stopChan)
+	rn.cancel()
+	done := make(chan struct{})
+	go func() {
+		rn.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (rn *Runner) work() {
+	defer rn.wg.Done()
+	for {
+		select {
+		case <-rn.stopChan:
+			return
+		default:
+		}
+		r, err := rn.dbif.ClaimCIRun()
+		if err == nil {
+			rn.run(r)
+			continue
+		}
+		if err != db.ErrEntityNotFound {
+			log.Printf("Failed to claim ci run: %s\n", err)
+		}
+		select {
+		case <-rn.stopChan:
+			return
+		case <-time.After(POLL_INTERVAL):
+		}
+	}
+}
+
+// the timeout of `job` w/ the defaults & the limit of the config
+// applied.
+func (rn *Runner) jobTimeout(job *CIJob) time.Duration {
+	res := job.Timeout
+	if res <= 0 {
+		n := rn.config.Runner.DefaultTimeout
+		if n <= 0 { n = DEFAULT_TIMEOUT }
+		res = time.Duration(n) * time.Second
+	}
+	if rn.config.Runner.MaxTimeout > 0 {
+		maxTimeout := time.Duration(rn.config.Runner.MaxTimeout) * time.Second
+		if res > maxTimeout { res = maxTimeout }
+	}
+	return res
+}
+
+// the log of a run. the output of the steps is written into it
+// directly; it's flushed to the database periodically so that the
+// log page can show the progress.
+type runLog struct {
+	mu sync.Mutex
+	buf bytes.Buffer
+	truncated bool
+	dirty bool
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(p)
+	if l.truncated { return n, nil }
+	remaining := model.CI_RUN_LOG_LIMIT - l.buf.Len()
+	if len(p) > remaining {
+		p = p[:remaining]
+		l.truncated = true
+	}
+	l.buf.Write(p)
+	if l.truncated { l.buf.WriteString("\n==> log truncated\n") }
+	l.dirty = true
+	return n, nil
+}
+
+func (l *runLog) Printf(format string, a ...any) {
+	fmt.Fprintf(l, format, a...)
+}
+
+// returns false if nothing has been written since the last call.
+func (l *runLog) take() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.dirty { return "", false }
+	l.dirty = false
+	return l.buf.String(), true
+}
+
+func (l *runLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+func (rn *Runner) run(r *model.CIRun) {
+	rl := &runLog{}
+	stopFlush := make(chan struct{})
+	flushDone := make(chan struct{})
+	go func() {
+		defer close(flushDone)
+		for {
+			select {
+			case <-stopFlush:
+				return
+			case <-time.After(LOG_FLUSH_INTERVAL):
+			}
+			s, ok := rl.take()
+			if !ok { continue }
+			err := rn.dbif.UpdateCIRunLog(r.Id, s)
+			if err != nil { log.Printf("Failed to update log of ci run %d: %s\n", r.Id, err) }
+		}
+	}()
+	status, err := rn.runJob(r, rl)
+	if err != nil {
+		rl.Printf("==> error: %s\n", err)
+		status = model.CI_RUN_ERROR
+	}
+	close(stopFlush)
+	<-flushDone
+	r.Status = status
+	err = rn.dbif.FinishCIRun(r.Id, status, rl.String())
+	if err != nil {
+		log.Printf("Failed to finish ci run %d: %s\n", r.Id, err)
+		return
+	}
+	err = rn.reportStatus(r, "")
+	if err != nil {
+		log.Printf("Failed to report commit status of ci run %d: %s\n", r.Id, err)
+	}
+}
+
+// reports the status of `r` on its commit. `description` defaults
+// to the one for the status of the run.
+func (rn *Runner) reportStatus(r *model.CIRun, description string) error {
+	if len(description) <= 0 {
+		switch r.Status {
+		case model.CI_RUN_RUNNING: description = "Running."
+		case model.CI_RUN_SUCCESS: description = "The job succeeded."
+		case model.CI_RUN_FAILURE: description = "The job failed."
+		case model.CI_RUN_ERROR: description = "The runner failed to run the job."
+		}
+	}
+	return rn.dbif.SetCommitStatus(&model.CommitStatus{
+		RepoNamespace: r.RepoNamespace,
+		RepoName: r.RepoName,
+		CommitId: r.CommitId,
+		Context: model.CIRunCommitStatusContext(r.JobName),
+		State: r.CommitStatusState(),
+		TargetURL: CIRunURL(rn.config, r),
+		Description: description,
+		Creator: r.Trigger,
+	})
+}
+
+// the url of the log page of `r`.
+func CIRunURL(config *gitus.GitusConfig, r *model.CIRun) string {
+	fullName := r.RepoName
+	if len(r.RepoNamespace) > 0 { fullName = fmt.Sprintf("%s:%s", r.RepoNamespace, r.RepoName) }
+	return fmt.Sprintf("%s/repo/%s/ci/%d", config.ProperHTTPHostName(), fullName, r.Id)
+}
+
+// returns the final status of the run; the error is non-nil if the
+// job couldn't be run at all.
+func (rn *Runner) runJob(r *model.CIRun, rl *runLog) (int, error) {
+	repo, err := rn.dbif.GetRepositoryByName(r.RepoNamespace, r.RepoName)
+	if err != nil { return 0, fmt.Errorf("Failed to get repository: %s", err) }
+	lgr, ok := repo.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return 0, errors.New("Not a Git repository.") }
+	// the job is read from the commit again instead of being
+	// stored w/ the run, which is also what allows re-running.
+	var jobList []*CIJob
+	prId, isPullRequest := model.ParsePullRequestCIRefName(r.RefName)
+	if isPullRequest {
+		var pr *model.PullRequest
+		pr, err = rn.dbif.GetPullRequest(r.RepoNamespace, r.RepoName, prId)
+		if err != nil { return 0, fmt.Errorf("Failed to get pull request #%d: %s", prId, err) }
+		jobList, err = ReadPullRequestCIJobFile(lgr, pr)
+	} else {
+		jobList, err = ReadCIJobFile(lgr, r.CommitId)
+	}
+	if err != nil { return 0, fmt.Errorf("Failed to read %s: %s", CI_JOB_FILE_PATH, err) }
+	var job *CIJob = nil
+	for _, k := range jobList {
+		if k.Name == r.JobName { job = k; break }
+	}
+	if job == nil { return 0, fmt.Errorf("Job %s no longer exists in %s.", r.JobName, CI_JOB_FILE_PATH) }
+	r.Status = model.CI_RUN_RUNNING
+	err = rn.reportStatus(r, "")
+	if err != nil { log.Printf("Failed to report commit status of ci run %d: %s\n", r.Id, err) }
+
+	dir, err := os.MkdirTemp(rn.workDir, fmt.Sprintf("run-%d-", r.Id))
+	if err != nil { return 0, err }
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil { log.Printf("Failed to remove work directory %s: %s\n", dir, err) }
+	}()
+	err = os.Chown(dir, rn.uid, rn.gid)
+	if err != nil { return 0, err }
+	rl.Printf("==> checking out %s\n", r.CommitId)
+	err = checkoutCommit(lgr.GitDirectoryPath, r.CommitId, dir, rn.uid, rn.gid)
+	if err != nil { return 0, fmt.Errorf("Failed to check out: %s", err) }
+
+	timeout := rn.jobTimeout(job)
+	ctx, cancel := context.WithTimeout(rn.ctx, timeout)
+	defer cancel()
+	env := []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		fmt.Sprintf("HOME=%s", dir),
+		"GITUS_CI=1",
+		fmt.Sprintf("GITUS_REPO=%s", repo.FullName()),
+		fmt.Sprintf("GITUS_COMMIT=%s", r.CommitId),
+		fmt.Sprintf("GITUS_REF=%s", r.RefName),
+		fmt.Sprintf("GITUS_JOB=%s", r.JobName),
+		fmt.Sprintf("GITUS_RUN_ID=%d", r.Id),
+	}
+	for i, step := range job.StepList {
+		rl.Printf("==> step %d: %s\n", i+1, step)
+		cmd := exec.CommandContext(ctx, "sh", "-c", step)
+		cmd.Dir = dir
+		cmd.Env = env
+		cmd.Stdout = rl
+		cmd.Stderr = rl
+		// the steps get their own process group so that whatever
+		// they start is killed along w/ them on timeout.
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Setpgid: true,
+			Credential: &syscall.Credential{Uid: uint32(rn.uid), Gid: uint32(rn.gid)},
+		}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = 10 * time.Second
+		err = cmd.Run()
+		if rn.ctx.Err() != nil { return 0, errors.New("The runner was stopped.") }
+		if ctx.Err() == context.DeadlineExceeded {
+			rl.Printf("==> timed out after %s\n", timeout)
+			return model.CI_RUN_FAILURE, nil
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			rl.Printf("==> step %d exited w/ %d\n", i+1, exitErr.ExitCode())
+			return model.CI_RUN_FAILURE, nil
+		}
+		if err != nil { return 0, fmt.Errorf("Failed to run step %d: %s", i+1, err) }
+	}
+	rl.Printf("==> done\n")
+	return model.CI_RUN_SUCCESS, nil
+}
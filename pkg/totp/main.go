@@ -0,0 +1,99 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// rfc 6238 time-based one-time password.
+// we use the parameters that every authenticator app supports:
+// hmac-sha1, 30 second time step, 6 digits.
+
+const (
+	TIME_STEP = 30
+	DIGITS = 6
+	// how many steps before & after the current one we accept, to
+	// tolerate clock drift between the server & the user's device.
+	SKEW = 1
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generates a new 160-bit secret encoded in base32 (w/o padding),
+// which is the format authenticator apps expect.
+func NewSecret() (string, error) {
+	buf := make([]byte, 20)
+	_, err := rand.Read(buf)
+	if err != nil { return "", err }
+	return b32.EncodeToString(buf), nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	return b32.DecodeString(strings.TrimRight(s, "="))
+}
+
+// rfc 4226 hotp.
+func hotp(key []byte, counter int64) string {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(buf)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range DIGITS { mod *= 10 }
+	return fmt.Sprintf("%0*d", DIGITS, v % mod)
+}
+
+func Step(t time.Time) int64 {
+	return t.Unix() / TIME_STEP
+}
+
+// returns the code for the time step that contains `t`.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil { return "", err }
+	return hotp(key, Step(t)), nil
+}
+
+// checks `code` against the steps around `t`. returns the step that
+// matched so that the caller can reject codes from steps that have
+// already been used (a code should only be accepted once); returns
+// -1 if nothing matched.
+func Validate(secret string, code string, t time.Time) int64 {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != DIGITS { return -1 }
+	key, err := decodeSecret(secret)
+	if err != nil { return -1 }
+	s := Step(t)
+	for i := int64(-SKEW); i <= SKEW; i++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, s+i)), []byte(code)) == 1 {
+			return s+i
+		}
+	}
+	return -1
+}
+
+// the otpauth uri that's usually encoded in qr codes. since we don't
+// render qr codes the user would have to copy this (or the secret
+// itself) into their authenticator app.
+func URI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprintf("%d", DIGITS))
+	v.Set("period", fmt.Sprintf("%d", TIME_STEP))
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, v.Encode())
+}
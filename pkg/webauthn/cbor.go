@@ -0,0 +1,144 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// a minimal cbor (rfc 8949) decoder that handles only what's needed
+// for webauthn: attestation objects & cose keys. indefinite-length
+// items are not supported since authenticators don't produce them
+// (ctap2 requires the canonical encoding).
+//
+// values are decoded into:
+//   + uint64 / int64 for major type 0 / 1
+//   + []byte for major type 2
+//   + string for major type 3
+//   + []any for major type 4
+//   + map[any]any for major type 5
+//   + bool / nil / float64 for major type 7
+// tags (major type 6) are skipped and the tagged value is returned.
+
+var ErrInvalidCBOR = errors.New("Invalid CBOR data")
+
+const maxCBORDepth = 16
+
+// decodes the first data item in `b`. returns the item & the number
+// of bytes consumed.
+func decodeCBOR(b []byte) (any, int, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func readCBORHead(b []byte) (byte, uint64, int, error) {
+	if len(b) < 1 { return 0, 0, 0, ErrInvalidCBOR }
+	major := b[0] >> 5
+	info := b[0] & 0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), 1, nil
+	case info == 24:
+		if len(b) < 2 { return 0, 0, 0, ErrInvalidCBOR }
+		return major, uint64(b[1]), 2, nil
+	case info == 25:
+		if len(b) < 3 { return 0, 0, 0, ErrInvalidCBOR }
+		return major, uint64(binary.BigEndian.Uint16(b[1:3])), 3, nil
+	case info == 26:
+		if len(b) < 5 { return 0, 0, 0, ErrInvalidCBOR }
+		return major, uint64(binary.BigEndian.Uint32(b[1:5])), 5, nil
+	case info == 27:
+		if len(b) < 9 { return 0, 0, 0, ErrInvalidCBOR }
+		return major, binary.BigEndian.Uint64(b[1:9]), 9, nil
+	}
+	return 0, 0, 0, ErrInvalidCBOR
+}
+
+func decodeCBORItem(b []byte, depth int) (any, int, error) {
+	if depth > maxCBORDepth { return nil, 0, ErrInvalidCBOR }
+	major, arg, n, err := readCBORHead(b)
+	if err != nil { return nil, 0, err }
+	switch major {
+	case 0:
+		return arg, n, nil
+	case 1:
+		if arg > math.MaxInt64 { return nil, 0, ErrInvalidCBOR }
+		return -1 - int64(arg), n, nil
+	case 2: fallthrough
+	case 3:
+		if arg > uint64(len(b) - n) { return nil, 0, ErrInvalidCBOR }
+		end := n + int(arg)
+		if major == 2 {
+			res := make([]byte, int(arg))
+			copy(res, b[n:end])
+			return res, end, nil
+		}
+		return string(b[n:end]), end, nil
+	case 4:
+		// every item takes at least one byte.
+		if arg > uint64(len(b) - n) { return nil, 0, ErrInvalidCBOR }
+		res := make([]any, 0, int(arg))
+		for range arg {
+			v, m, err := decodeCBORItem(b[n:], depth+1)
+			if err != nil { return nil, 0, err }
+			res = append(res, v)
+			n += m
+		}
+		return res, n, nil
+	case 5:
+		if arg > uint64(len(b) - n) / 2 { return nil, 0, ErrInvalidCBOR }
+		res := make(map[any]any, int(arg))
+		for range arg {
+			k, m, err := decodeCBORItem(b[n:], depth+1)
+			if err != nil { return nil, 0, err }
+			n += m
+			switch k.(type) {
+			case uint64, int64, string:
+			default:
+				return nil, 0, ErrInvalidCBOR
+			}
+			v, m, err := decodeCBORItem(b[n:], depth+1)
+			if err != nil { return nil, 0, err }
+			n += m
+			res[k] = v
+		}
+		return res, n, nil
+	case 6:
+		v, m, err := decodeCBORItem(b[n:], depth+1)
+		if err != nil { return nil, 0, err }
+		return v, n + m, nil
+	case 7:
+		switch b[0] & 0x1f {
+		case 20: return false, n, nil
+		case 21: return true, n, nil
+		case 22, 23: return nil, n, nil
+		case 26: return float64(math.Float32frombits(uint32(arg))), n, nil
+		case 27: return math.Float64frombits(arg), n, nil
+		}
+		// half-precision floats & other simple values never appear
+		// in the structures we care about.
+		return nil, 0, ErrInvalidCBOR
+	}
+	return nil, 0, ErrInvalidCBOR
+}
+
+// cose keys use small integers as map keys; positive ones decode as
+// uint64 & negative ones as int64.
+func cborMapGetInt(m map[any]any, k int64) (any, bool) {
+	if k >= 0 {
+		v, ok := m[uint64(k)]
+		return v, ok
+	}
+	v, ok := m[k]
+	return v, ok
+}
+
+func cborToInt(v any) (int64, bool) {
+	switch x := v.(type) {
+	case uint64:
+		if x > math.MaxInt64 { return 0, false }
+		return int64(x), true
+	case int64:
+		return x, true
+	}
+	return 0, false
+}
@@ -0,0 +1,257 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// server-side verification of webauthn (level 2) registration &
+// assertion ceremonies. only the parts needed for a second factor are
+// implemented:
+//
+//   + attestation statements are not verified (we ask for "none"
+//     attestation); we don't care which authenticator the user uses,
+//     only that the same one is used again.
+//   + user verification is not required; the user has already
+//     entered their password.
+//   + supported algorithms are ES256, RS256 & EdDSA (ed25519), which
+//     covers every authenticator in the wild. RS256 keys must be at
+//     least `RSA_MIN_KEY_BITS` bits long & have an odd exponent.
+
+const (
+	COSE_ALG_ES256 = -7
+	COSE_ALG_EDDSA = -8
+	COSE_ALG_RS256 = -257
+)
+
+const RSA_MIN_KEY_BITS = 2048
+
+var (
+	ErrInvalidClientData = errors.New("Invalid client data")
+	ErrChallengeMismatch = errors.New("Challenge mismatch")
+	ErrOriginMismatch = errors.New("Origin mismatch")
+	ErrRelyingPartyMismatch = errors.New("Relying party ID mismatch")
+	ErrUserNotPresent = errors.New("User presence flag not set")
+	ErrInvalidAuthenticatorData = errors.New("Invalid authenticator data")
+	ErrInvalidAttestationObject = errors.New("Invalid attestation object")
+	ErrUnsupportedKey = errors.New("Unsupported public key")
+	ErrInvalidSignature = errors.New("Invalid signature")
+	ErrSignCountRegressed = errors.New("Signature counter did not increase; the authenticator may have been cloned")
+)
+
+const (
+	flagUserPresent = 0x01
+	flagAttestedCredentialData = 0x40
+)
+
+var b64 = base64.RawURLEncoding
+
+// webauthn uses base64url w/o padding everywhere.
+func EncodeBase64(b []byte) string {
+	return b64.EncodeToString(b)
+}
+
+func DecodeBase64(s string) ([]byte, error) {
+	return b64.DecodeString(s)
+}
+
+// a new random challenge, in base64url.
+func NewChallenge() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil { return "", err }
+	return EncodeBase64(buf), nil
+}
+
+// a newly registered credential.
+type Credential struct {
+	Id []byte
+	// the cose-encoded public key, stored as-is.
+	PublicKey []byte
+	SignCount uint32
+}
+
+type clientData struct {
+	Type string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin string `json:"origin"`
+	CrossOrigin bool `json:"crossOrigin"`
+}
+
+func verifyClientData(clientDataJSON []byte, typ string, origin string, challenge string) error {
+	var cd clientData
+	err := json.Unmarshal(clientDataJSON, &cd)
+	if err != nil { return ErrInvalidClientData }
+	if cd.Type != typ { return ErrInvalidClientData }
+	if len(challenge) <= 0 || subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	}
+	if cd.Origin != origin || cd.CrossOrigin { return ErrOriginMismatch }
+	return nil
+}
+
+type authenticatorData struct {
+	rpIdHash []byte
+	flags byte
+	signCount uint32
+	// the following are only available when the "attested
+	// credential data" flag is set.
+	credentialId []byte
+	publicKey []byte
+}
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 { return nil, ErrInvalidAuthenticatorData }
+	res := &authenticatorData{
+		rpIdHash: b[:32],
+		flags: b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	if res.flags & flagAttestedCredentialData == 0 { return res, nil }
+	rest := b[37:]
+	// aaguid (16) + credential id length (2)
+	if len(rest) < 18 { return nil, ErrInvalidAuthenticatorData }
+	l := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < l { return nil, ErrInvalidAuthenticatorData }
+	res.credentialId = rest[:l]
+	rest = rest[l:]
+	// the public key may be followed by extension data, so we need
+	// to know where the key ends.
+	_, n, err := decodeCBOR(rest)
+	if err != nil { return nil, ErrInvalidAuthenticatorData }
+	res.publicKey = rest[:n]
+	return res, nil
+}
+
+func (ad *authenticatorData) verify(rpId string) error {
+	h := sha256.Sum256([]byte(rpId))
+	if !bytes.Equal(ad.rpIdHash, h[:]) { return ErrRelyingPartyMismatch }
+	if ad.flags & flagUserPresent == 0 { return ErrUserNotPresent }
+	return nil
+}
+
+// verifies the response of `navigator.credentials.create`.
+// `clientDataJSON` & `attestationObject` are the raw bytes from the
+// `AuthenticatorAttestationResponse`.
+func VerifyRegistration(rpId string, origin string, challenge string, clientDataJSON []byte, attestationObject []byte) (*Credential, error) {
+	err := verifyClientData(clientDataJSON, "webauthn.create", origin, challenge)
+	if err != nil { return nil, err }
+	v, _, err := decodeCBOR(attestationObject)
+	if err != nil { return nil, ErrInvalidAttestationObject }
+	m, ok := v.(map[any]any)
+	if !ok { return nil, ErrInvalidAttestationObject }
+	authDataRaw, ok := m["authData"].([]byte)
+	if !ok { return nil, ErrInvalidAttestationObject }
+	ad, err := parseAuthenticatorData(authDataRaw)
+	if err != nil { return nil, err }
+	err = ad.verify(rpId)
+	if err != nil { return nil, err }
+	if ad.credentialId == nil { return nil, ErrInvalidAuthenticatorData }
+	_, err = parsePublicKey(ad.publicKey)
+	if err != nil { return nil, err }
+	return &Credential{
+		Id: ad.credentialId,
+		PublicKey: ad.publicKey,
+		SignCount: ad.signCount,
+	}, nil
+}
+
+// verifies the response of `navigator.credentials.get`. returns the
+// new signature counter that should be stored.
+func VerifyAssertion(rpId string, origin string, challenge string, publicKey []byte, signCount uint32, clientDataJSON []byte, authData []byte, signature []byte) (uint32, error) {
+	err := verifyClientData(clientDataJSON, "webauthn.get", origin, challenge)
+	if err != nil { return 0, err }
+	ad, err := parseAuthenticatorData(authData)
+	if err != nil { return 0, err }
+	err = ad.verify(rpId)
+	if err != nil { return 0, err }
+	pk, err := parsePublicKey(publicKey)
+	if err != nil { return 0, err }
+	h := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authData) + len(h))
+	signed = append(signed, authData...)
+	signed = append(signed, h[:]...)
+	if !verifySignature(pk, signed, signature) { return 0, ErrInvalidSignature }
+	// authenticators that don't implement the counter always send 0.
+	if (ad.signCount != 0 || signCount != 0) && ad.signCount <= signCount {
+		return 0, ErrSignCountRegressed
+	}
+	return ad.signCount, nil
+}
+
+func parsePublicKey(b []byte) (crypto.PublicKey, error) {
+	v, _, err := decodeCBOR(b)
+	if err != nil { return nil, ErrUnsupportedKey }
+	m, ok := v.(map[any]any)
+	if !ok { return nil, ErrUnsupportedKey }
+	kv, _ := cborMapGetInt(m, 1)
+	kty, _ := cborToInt(kv)
+	av, _ := cborMapGetInt(m, 3)
+	alg, _ := cborToInt(av)
+	switch {
+	case kty == 2 && alg == COSE_ALG_ES256:
+		cv, _ := cborMapGetInt(m, -1)
+		crv, _ := cborToInt(cv)
+		xv, _ := cborMapGetInt(m, -2)
+		yv, _ := cborMapGetInt(m, -3)
+		x, ok1 := xv.([]byte)
+		y, ok2 := yv.([]byte)
+		if crv != 1 || !ok1 || !ok2 || len(x) != 32 || len(y) != 32 { return nil, ErrUnsupportedKey }
+		pk := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X: new(big.Int).SetBytes(x),
+			Y: new(big.Int).SetBytes(y),
+		}
+		if !pk.Curve.IsOnCurve(pk.X, pk.Y) { return nil, ErrUnsupportedKey }
+		return pk, nil
+	case kty == 3 && alg == COSE_ALG_RS256:
+		nv, _ := cborMapGetInt(m, -1)
+		ev, _ := cborMapGetInt(m, -2)
+		n, ok1 := nv.([]byte)
+		e, ok2 := ev.([]byte)
+		if !ok1 || !ok2 || len(e) <= 0 || len(e) > 4 { return nil, ErrUnsupportedKey }
+		ei := 0
+		for _, k := range e { ei = ei << 8 | int(k) }
+		// weak keys are refused, so they can't be registered as a
+		// second factor in the first place.
+		if ei < 3 || ei % 2 == 0 { return nil, ErrUnsupportedKey }
+		ni := new(big.Int).SetBytes(n)
+		if ni.BitLen() < RSA_MIN_KEY_BITS { return nil, ErrUnsupportedKey }
+		return &rsa.PublicKey{ N: ni, E: ei }, nil
+	case kty == 1 && alg == COSE_ALG_EDDSA:
+		cv, _ := cborMapGetInt(m, -1)
+		crv, _ := cborToInt(cv)
+		xv, _ := cborMapGetInt(m, -2)
+		x, ok := xv.([]byte)
+		if crv != 6 || !ok || len(x) != ed25519.PublicKeySize { return nil, ErrUnsupportedKey }
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+func verifySignature(pk crypto.PublicKey, data []byte, sig []byte) bool {
+	switch k := pk.(type) {
+	case *ecdsa.PublicKey:
+		h := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(k, h[:], sig)
+	case *rsa.PublicKey:
+		h := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, data, sig)
+	}
+	return false
+}
@@ -1,11 +1,19 @@
 package routes
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/templates"
 )
@@ -120,6 +128,7 @@ func GenerateLoginInfoModel(ctx *RouterContext, r *http.Request) (*templates.Log
 	}
 	u, err := ctx.DatabaseInterface.GetUserByName(un)
 	if err != nil { return nil, err }
+	secondFactorRequired := AdminSecondFactorRequired(ctx, u)
 	return &templates.LoginInfoModel{
 		LoggedIn: res,
 		UserName: un,
@@ -127,11 +136,145 @@ func GenerateLoginInfoModel(ctx *RouterContext, r *http.Request) (*templates.Log
 		UserEmail: u.Email,
 		IsOwner: false,
 		IsSettingMember: false,
-		IsAdmin: u.Status == model.ADMIN || u.Status == model.SUPER_ADMIN,
-		IsSuperAdmin: u.Status == model.SUPER_ADMIN,
+		IsAdmin: (u.Status == model.ADMIN || u.Status == model.SUPER_ADMIN) && !secondFactorRequired,
+		IsSuperAdmin: u.Status == model.SUPER_ADMIN && !secondFactorRequired,
+		SecondFactorRequired: secondFactorRequired,
+		CountUnreadNotification: func() int64 {
+			res, err := ctx.DatabaseInterface.CountNotification(un, true)
+			if err != nil { LogIfError(err); return 0 }
+			return res
+		},
 	}, nil
 }
 
+// returns true if the user is an admin that's withheld their admin
+// privileges because the site requires admins to have a second
+// factor & they haven't enrolled one.
+func AdminSecondFactorRequired(ctx *RouterContext, u *model.GitusUser) bool {
+	if !ctx.Config.RequireAdminSecondFactor { return false }
+	if u.Status != model.ADMIN && u.Status != model.SUPER_ADMIN { return false }
+	return !u.HasSecondFactor()
+}
+
+// resolves a personal access token (the token text itself, not the
+// hash). returns nil (with nil error) if the token does not exist, is
+// expired, or belongs to a user that currently can't log in.
+func ResolveAccessToken(ctx *RouterContext, tokenText string) (*model.GitusAccessToken, *model.GitusUser, error) {
+	if !strings.HasPrefix(tokenText, model.ACCESS_TOKEN_PREFIX) { return nil, nil, nil }
+	t, err := ctx.DatabaseInterface.GetAccessTokenByHash(model.HashAccessToken(tokenText))
+	if err == db.ErrEntityNotFound { return nil, nil, nil }
+	if err != nil { return nil, nil, err }
+	if t.IsExpired() { return nil, nil, nil }
+	u, err := ctx.DatabaseInterface.GetUserByName(t.UserName)
+	if err == db.ErrEntityNotFound { return nil, nil, nil }
+	if err != nil { return nil, nil, err }
+	switch u.Status {
+	case model.BANNED: fallthrough
+	case model.NORMAL_USER_APPROVAL_NEEDED: fallthrough
+	case model.NORMAL_USER_CONFIRM_NEEDED:
+		return nil, nil, nil
+	}
+	return t, u, nil
+}
+
+// returns the extra environment variables git-receive-pack should be
+// run with when `pusher` pushes to `repo` (over ssh or http). the
+// gitus hook dispatchers that make use of these variables are
+// installed here as well. see docs/hooks.org.
+func PreparePushEnvironment(ctx *RouterContext, pusher string, repo *model.Repository) ([]string, error) {
+	lgr, ok := repo.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return nil, errors.New("Pushing is only supported for Git repositories.") }
+	err := lgr.InstallGitusHooks()
+	if err == gitlib.ErrForeignHook {
+		// we can't run our checks; refuse instead of silently
+		// letting the push thru.
+		return nil, errors.New("The repository has hooks not managed by Gitus that cannot be moved aside; please contact the site admin.")
+	}
+	if err != nil { return nil, err }
+	executable, err := os.Executable()
+	if err != nil { return nil, err }
+	return []string{
+		fmt.Sprintf("GITUS_EXECUTABLE=%s", executable),
+		fmt.Sprintf("GITUS_CONFIG=%s", ctx.Config.FilePath),
+		fmt.Sprintf("GITUS_PUSHER=%s", pusher),
+		fmt.Sprintf("GITUS_REPO_NAMESPACE=%s", repo.Namespace),
+		fmt.Sprintf("GITUS_REPO_NAME=%s", repo.Name),
+	}, nil
+}
+
+// the statuses of `commitId` to be shown along w/ the commit info.
+// errors are logged & treated as no status, since they shouldn't
+// stop the page from showing. see docs/commit-status.org.
+func GetCommitStatusList(ctx *RouterContext, repo *model.Repository, commitId string) []*model.CommitStatus {
+	if ctx.Config.OperationMode != gitus.OP_MODE_NORMAL { return nil }
+	res, err := ctx.DatabaseInterface.GetAllCommitStatus(repo.Namespace, repo.Name, commitId)
+	if err != nil { LogIfError(err); return nil }
+	return res
+}
+
+// the current head of the provider branch of `pr`. used to record
+// which commit a review is made on.
+func GetPullRequestProviderHead(ctx *RouterContext, pr *model.PullRequest) (string, error) {
+	repo, err := ctx.DatabaseInterface.GetRepositoryByName(pr.ProviderNamespace, pr.ProviderName)
+	if err != nil { return "", err }
+	lgr, ok := repo.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return "", errors.New("Pull requests are only supported for Git repositories.") }
+	err = lgr.SyncBranch(pr.ProviderBranch)
+	if err != nil { return "", err }
+	br, ok := lgr.BranchIndex[pr.ProviderBranch]
+	if !ok { return "", fmt.Errorf("Branch %s not found in %s.", pr.ProviderBranch, repo.FullName()) }
+	return br.HeadId, nil
+}
+
+// the range of a pull request: the merge base of the receiver branch
+// & the provider branch, and the head of the provider branch. the
+// provider branch is fetched into the receiver repository thru the
+// remote `{providerNamespace}/{providerName}` (the same remote the
+// merge check uses; set up here if it's not there yet, e.g. for
+// pull requests within the same repository), so both commits can be
+// read from the receiver repository afterwards.
+func GetPullRequestRange(ctx *RouterContext, receiver *model.Repository, pr *model.PullRequest) (string, string, error) {
+	lgr, ok := receiver.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return "", "", errors.New("Pull requests are only supported for Git repositories.") }
+	provider, err := ctx.DatabaseInterface.GetRepositoryByName(pr.ProviderNamespace, pr.ProviderName)
+	if err != nil { return "", "", err }
+	plgr, ok := provider.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return "", "", errors.New("Pull requests are only supported for Git repositories.") }
+	remoteName := fmt.Sprintf("%s/%s", pr.ProviderNamespace, pr.ProviderName)
+	err = lgr.SetUpMergeTarget(remoteName, plgr.GitDirectoryPath)
+	if err != nil { return "", "", err }
+	return lgr.GetBranchRange(pr.ReceiverBranch, remoteName, pr.ProviderBranch)
+}
+
+// the commits in `pr` that aren't in the receiving branch yet.
+func GetPullRequestCommitList(ctx *RouterContext, receiver *model.Repository, pr *model.PullRequest) ([]gitlib.CommitObject, error) {
+	baseId, headId, err := GetPullRequestRange(ctx, receiver, pr)
+	if err != nil { return nil, err }
+	lgr := receiver.Repository.(*gitlib.LocalGitRepository)
+	return lgr.GetCommitRange(baseId, headId)
+}
+
+// all comments on code of a pull request, oldest first.
+func GetAllPullRequestCommentOnCode(ctx *RouterContext, pr *model.PullRequest) ([]*model.PullRequestCommentOnCode, error) {
+	res := make([]*model.PullRequestCommentOnCode, 0)
+	var pageNum int64 = 0
+	var pageSize int64 = 100
+	for {
+		l, err := ctx.DatabaseInterface.GetAllPullRequestEventPaginated(pr.PRAbsId, pageNum, pageSize)
+		if err != nil { return nil, err }
+		for _, k := range l {
+			if k.EventType != model.PULL_REQUEST_EVENT_COMMENT_ON_CODE { continue }
+			var c model.PullRequestCommentOnCode
+			err = json.Unmarshal([]byte(k.EventContent), &c)
+			if err != nil { continue }
+			res = append(res, &c)
+		}
+		if int64(len(l)) < pageSize { break }
+		pageNum += 1
+	}
+	return res, nil
+}
+
 func GenerateRepoHeader(typeStr string, nodeName string) *templates.RepoHeaderTemplateModel {
 	repoHeaderInfo := &templates.RepoHeaderTemplateModel{
 		TypeStr: typeStr,
@@ -175,3 +318,21 @@ func GeneratePageInfo(r *http.Request, size int64) (*templates.PageInfoModel, er
 
 
 
+
+// reads the issue filter from the query string: `label` (can be
+// specified multiple times), `assignee`, `author` & `milestone`.
+// used by both the issue list page & the api.
+func ParseIssueFilter(r *http.Request) *model.IssueFilter {
+	q := r.URL.Query()
+	labelList := make([]string, 0)
+	for _, k := range q["label"] {
+		k = strings.TrimSpace(k)
+		if len(k) > 0 { labelList = append(labelList, k) }
+	}
+	return &model.IssueFilter{
+		Label: labelList,
+		Assignee: strings.TrimSpace(q.Get("assignee")),
+		Author: strings.TrimSpace(q.Get("author")),
+		Milestone: strings.TrimSpace(q.Get("milestone")),
+	}
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/runner"
+)
+
+// queueing of the runs of the built-in ci runner. see
+// docs/ci-runner.org.
+
+// queues a run for `commitId` & reports it as pending on the commit.
+func queueCIRun(ctx *RouterContext, repo *model.Repository, commitId string, refName string, jobName string, trigger string) (*model.CIRun, error) {
+	r := &model.CIRun{
+		RepoNamespace: repo.Namespace,
+		RepoName: repo.Name,
+		CommitId: commitId,
+		RefName: refName,
+		JobName: jobName,
+		Trigger: trigger,
+	}
+	err := ctx.DatabaseInterface.NewCIRun(r)
+	if err != nil { return nil, err }
+	err = ctx.DatabaseInterface.SetCommitStatus(&model.CommitStatus{
+		RepoNamespace: repo.Namespace,
+		RepoName: repo.Name,
+		CommitId: commitId,
+		Context: model.CIRunCommitStatusContext(jobName),
+		State: model.COMMIT_STATUS_PENDING,
+		TargetURL: runner.CIRunURL(ctx.Config, r),
+		Description: "Queued.",
+		Creator: trigger,
+	})
+	if err != nil { return nil, err }
+	return r, nil
+}
+
+// queues the jobs in the job file of `newRev` that want the update
+// of `refFullName`. only branch updates run jobs.
+func EnqueueCIRun(ctx *RouterContext, repo *model.Repository, refFullName string, newRev string, trigger string) error {
+	if !ctx.Config.Runner.Enable { return nil }
+	if !strings.HasPrefix(refFullName, "refs/heads/") { return nil }
+	if len(strings.Trim(newRev, "0")) <= 0 { return nil }
+	lgr, ok := repo.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return nil }
+	branch := strings.TrimPrefix(refFullName, "refs/heads/")
+	jobList, err := runner.ReadCIJobFile(lgr, newRev)
+	if err != nil { return err }
+	for _, k := range jobList {
+		if !k.MatchBranch(branch) { continue }
+		_, err := queueCIRun(ctx, repo, newRev, refFullName, k.Name, trigger)
+		if err != nil { return err }
+	}
+	return nil
+}
+
+// the runs on pull requests from other repositories (e.g. forks) are
+// queued in the background, since the provider branch has to be
+// fetched into the receiver repository first (see
+// `GetPullRequestRange`). like notifications, failing to queue one
+// never fails the action that caused it.
+func EnqueuePullRequestCIRun(ctx *RouterContext, absIdList []int64, trigger string) {
+	if !ctx.Config.Runner.Enable || ctx.JobQueue == nil { return }
+	for _, k := range absIdList {
+		LogIfError(ctx.JobQueue.Enqueue(model.JOB_TYPE_PULL_REQUEST_CI, &model.PullRequestCIJobPayload{
+			PRAbsId: k,
+			Trigger: trigger,
+		}))
+	}
+}
+
+// queues the jobs in the job file of the receiver branch of `pr` that
+// want the receiver branch, on the head of the provider branch. the
+// runs are recorded on the receiver repository so that they can be
+// used as required status checks. pull requests within the same
+// repository are skipped since the push to the provider branch has
+// queued the runs already.
+func QueuePullRequestCIRun(ctx *RouterContext, pr *model.PullRequest, trigger string) error {
+	if !ctx.Config.Runner.Enable { return nil }
+	if pr.ProviderNamespace == pr.ReceiverNamespace && pr.ProviderName == pr.ReceiverName { return nil }
+	receiver, err := ctx.DatabaseInterface.GetRepositoryByName(pr.ReceiverNamespace, pr.ReceiverName)
+	if err != nil { return err }
+	lgr, ok := receiver.Repository.(*gitlib.LocalGitRepository)
+	if !ok { return nil }
+	_, headId, err := GetPullRequestRange(ctx, receiver, pr)
+	if err != nil { return err }
+	jobList, err := runner.ReadPullRequestCIJobFile(lgr, pr)
+	if err != nil { return err }
+	for _, k := range jobList {
+		if !k.MatchBranch(pr.ReceiverBranch) { continue }
+		_, err := queueCIRun(ctx, receiver, headId, model.PullRequestCIRefName(pr.PRId), k.Name, trigger)
+		if err != nil { return err }
+	}
+	return nil
+}
+
+// queues `r` again as a new run.
+func RerunCIRun(ctx *RouterContext, repo *model.Repository, r *model.CIRun, trigger string) (*model.CIRun, error) {
+	return queueCIRun(ctx, repo, r.CommitId, r.RefName, r.JobName, trigger)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/GitusCodeForge/Gitus/pkg/gitus"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/confirm_code"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/jobqueue"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/mail"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/receipt"
@@ -35,10 +36,15 @@ type RouterContext struct {
 	ReceiptSystem receipt.GitusReceiptSystemInterface
 	Mailer mail.GitusMailerInterface
 	LoginInfo *templates.LoginInfoModel
+	// only set when the request is authenticated w/ a personal access
+	// token (currently only the api does this).
+	AccessToken *model.GitusAccessToken
 	LastError error
 	RateLimiter *RateLimiter
 	ConfirmCodeManager confirm_code.GitusConfirmCodeManager
 	SimpleModeConfigCache model.SimpleModeConfigCache
+	// nil if there's no database (e.g. in plain mode).
+	JobQueue *jobqueue.JobQueue
 }
 
 func (ctx RouterContext) LoadTemplate(name string) *template.Template {
@@ -61,6 +67,7 @@ func (ctx *RouterContext) NewLocal() *RouterContext {
 		LastError: ctx.LastError,
 		RateLimiter: ctx.RateLimiter,
 		ConfirmCodeManager: ctx.ConfirmCodeManager,
+		JobQueue: ctx.JobQueue,
 	}
 }
 
@@ -236,9 +243,18 @@ func (ctx *RouterContext) ResolveRepositoryFullName(str string) (string, string,
 			return "", "", nil, nil, ErrNotFound
 		}
 	} else {
-		ns, err = ctx.DatabaseInterface.GetNamespaceByName(namespaceName)
-		if err != nil { return "", "", nil, nil, err }
 		rp, err = ctx.DatabaseInterface.GetRepositoryByName(namespaceName, repoName)
+		if err == db.ErrEntityNotFound {
+			// the repository might've been moved; the new names are
+			// returned in this case. see docs/repo-move.org.
+			newNs, newName, rerr := ctx.DatabaseInterface.GetRepositoryRedirect(namespaceName, repoName)
+			if rerr == nil {
+				namespaceName, repoName = newNs, newName
+				rp, err = ctx.DatabaseInterface.GetRepositoryByName(namespaceName, repoName)
+			}
+		}
+		if err != nil { return "", "", nil, nil, err }
+		ns, err = ctx.DatabaseInterface.GetNamespaceByName(namespaceName)
 		if err != nil { return "", "", nil, nil, err }
 	}
 	return namespaceName, repoName, ns, rp, nil
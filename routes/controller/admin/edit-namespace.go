@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	. "github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/templates"
@@ -73,5 +75,45 @@ func bindAdminEditNamespaceController(ctx *RouterContext) {
 			rc.ReportRedirect("/admin/db-setting", 5, "Setting Updated", "Your setting for this namespace has been updated.", w, r)
 		},
 	))
+
+	// see docs/repo-move.org.
+	http.HandleFunc("POST /admin/namespace/{name}/rename", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			LoginRequired, AdminRequired,
+			GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			nsn := r.PathValue("name")
+			if !model.ValidNamespaceName(nsn) { FoundAt(w, "/"); return }
+			editPath := fmt.Sprintf("/admin/namespace/%s/edit", nsn)
+			if !rc.Config.UseNamespace {
+				rc.ReportRedirect(editPath, 5, "Unsupported", "Namespaces can only be renamed when namespaces are enabled.", w, r)
+				return
+			}
+			newName := strings.TrimSpace(r.Form.Get("new-name"))
+			if len(newName) <= 0 || !model.ValidNamespaceName(newName) {
+				rc.ReportRedirect(editPath, 5, "Invalid Namespace Name", "Namespace name must consists of only upper & lowercase letters (a-z, A-Z), 0-9, underscore and hyphen.", w, r)
+				return
+			}
+			if newName == nsn {
+				rc.ReportRedirect(editPath, 3, "Nothing Changed", "The new name is the same as the current one.", w, r)
+				return
+			}
+			err := rc.DatabaseInterface.RenameNamespace(nsn, newName)
+			if err == db.ErrEntityNotFound {
+				rc.ReportRedirect("/admin/namespace-list", 5, "Not Found", fmt.Sprintf("The namespace %s does not exist.", nsn), w, r)
+				return
+			}
+			if err == db.ErrEntityAlreadyExists {
+				rc.ReportRedirect(editPath, 5, "Name Taken", fmt.Sprintf("The name %s has already been taken.", newName), w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to rename namespace: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(fmt.Sprintf("/admin/namespace/%s/edit", newName), 3, "Renamed", "The namespace has been renamed; the repositories in it can still be reached by their old names.", w, r)
+		},
+	))
 }
 
@@ -77,6 +77,15 @@ func bindAdminEditUserInfoController(ctx *RouterContext) {
 					rc.ReportInternalError(fmt.Sprintf("Failed to update user info: %s", err.Error()), w, r)
 					return
 				}
+			case "2fa-reset":
+				// for users who've lost both their authenticator
+				// device & their recovery codes.
+				user.TFAConfig = model.GitusUser2FAConfig{}
+				err = rc.DatabaseInterface.UpdateUserInfo(un, user)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to reset two-factor authentication: %s", err.Error()), w, r)
+					return
+				}
 			case "password":
 				// we will have confirm check at the frontend; this is
 				// here for the people who disabled javascript.
@@ -8,5 +8,6 @@ func BindAdminEditUserController(ctx *RouterContext) {
 	bindAdminEditUserInfoController(ctx)
 	bindAdminEditUserGPGController(ctx)
 	bindAdminEditUserSSHController(ctx)
+	bindAdminEditUserAccessTokenController(ctx)
 }
 
@@ -0,0 +1,73 @@
+package edit_user
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+func bindAdminEditUserAccessTokenController(ctx *RouterContext) {
+	http.HandleFunc("GET /admin/user/{username}/token", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, AdminRequired,
+			GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			un := r.PathValue("username")
+			if !model.ValidUserName(un) { FoundAt(w, "/"); return }
+			u, err := ctx.DatabaseInterface.GetUserByName(un)
+			if err != nil {
+				ctx.ReportRedirect("/admin/user-list", 0, "Internal Error", fmt.Sprintf("Failed to fetch user %s: %s", un, err.Error()), w, r)
+				return
+			}
+			if !rc.LoginInfo.IsSuperAdmin && u.Status == model.SUPER_ADMIN {
+				ctx.ReportRedirect("/admin/user-list", 3, "Error", "Your account does not have enough privilege for this action.", w, r)
+				return
+			}
+			s, err := ctx.DatabaseInterface.GetAllAccessTokenByUsername(un)
+			if err != nil {
+				ctx.ReportRedirect("/admin/user-list", 0, "Internal Error", fmt.Sprintf("Failed to fetch access tokens of user %s: %s", un, err.Error()), w, r)
+				return
+			}
+			LogTemplateError(ctx.LoadTemplate("admin/user/access-token").Execute(w, &templates.AdminUserAccessTokenTemplateModel{
+				Config: ctx.Config,
+				LoginInfo: rc.LoginInfo,
+				User: u,
+				TokenList: s,
+			}))
+		},
+	))
+
+	http.HandleFunc("GET /admin/user/{username}/token/{tokenName}/revoke", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, AdminRequired,
+			GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			un := r.PathValue("username")
+			if !model.ValidUserName(un) { FoundAt(w, "/"); return }
+			u, err := ctx.DatabaseInterface.GetUserByName(un)
+			if err != nil {
+				ctx.ReportRedirect("/admin/user-list", 0, "Internal Error", fmt.Sprintf("Failed to fetch user %s: %s", un, err.Error()), w, r)
+				return
+			}
+			if !rc.LoginInfo.IsSuperAdmin && u.Status == model.SUPER_ADMIN {
+				ctx.ReportRedirect("/admin/user-list", 3, "Error", "Your account does not have enough privilege for this action.", w, r)
+				return
+			}
+			tokenName := r.PathValue("tokenName")
+			if !model.ValidAccessTokenName(tokenName) {
+				ctx.ReportNotFound(tokenName, "Access Token", un, w, r)
+				return
+			}
+			err = ctx.DatabaseInterface.RevokeAccessToken(un, tokenName)
+			if err != nil {
+				ctx.ReportRedirect(fmt.Sprintf("/admin/user/%s/token", un), 0, "Internal Error", fmt.Sprintf("Failed to revoke access token of user %s: %s", un, err.Error()), w, r)
+				return
+			}
+			ctx.ReportRedirect(fmt.Sprintf("/admin/user/%s/token", un), 3, "Revoked", "The specified access token has been revoked.", w, r)
+		},
+	))
+}
+
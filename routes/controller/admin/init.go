@@ -23,4 +23,5 @@ func BindAllAdminControllers(context *routes.RouterContext) {
 	bindAdminReceiptListController(context)
 	bindAdminSiteLockdownController(context)
 	bindAdminRegistrationRequestController(context)
+	bindAdminJobController(context)
 }
@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// /admin/job?status={status}&p={pagenum}&s={pagesize}
+// see docs/job-queue.org.
+func bindAdminJobController(ctx *RouterContext) {
+	http.HandleFunc("GET /admin/job", UseMiddleware(
+		[]Middleware{
+			Logged, LoginRequired, AdminRequired, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			p := r.URL.Query().Get("p")
+			if len(p) <= 0 { p = "1" }
+			s := r.URL.Query().Get("s")
+			if len(s) <= 0 { s = "50" }
+			pageNum, err := strconv.ParseInt(p, 10, 64)
+			if err != nil { rc.ReportNormalError("Invalid request", w, r); return }
+			pageSize, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || pageSize <= 0 { rc.ReportNormalError("Invalid request", w, r); return }
+			status, err := strconv.Atoi(r.URL.Query().Get("status"))
+			if err != nil { status = 0 }
+			switch status {
+			case 0: fallthrough
+			case model.JOB_PENDING: fallthrough
+			case model.JOB_RUNNING: fallthrough
+			case model.JOB_FAILED:
+			default: rc.ReportNormalError("Invalid request", w, r); return
+			}
+			i, err := rc.DatabaseInterface.CountJob(status)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to count jobs: %s", err), w, r)
+				return
+			}
+			totalPage := i / pageSize
+			if (i % pageSize) > 0 { totalPage += 1 }
+			if totalPage <= 0 { totalPage = 1 }
+			if pageNum > totalPage { pageNum = totalPage }
+			if pageNum <= 1 { pageNum = 1 }
+			jobList, err := rc.DatabaseInterface.GetJobPaginated(status, pageNum-1, pageSize)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get jobs: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("admin/job-list").Execute(w, &templates.AdminJobListTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				JobList: jobList,
+				PageInfo: &templates.PageInfoModel{
+					PageNum: pageNum,
+					PageSize: pageSize,
+					TotalPage: totalPage,
+				},
+				Status: status,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /admin/job/{id}", UseMiddleware(
+		[]Middleware{
+			Logged, ValidPOSTRequestRequired, LoginRequired, AdminRequired, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+			if err != nil {
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			switch r.Form.Get("type") {
+			case "retry":
+				// only failed jobs can be retried; pending ones
+				// would run by themselves anyway.
+				err = rc.DatabaseInterface.RetryJob(id)
+			case "delete":
+				err = rc.DatabaseInterface.DeleteJob(id)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			rc.ReportRedirect("/admin/job", 0, "Updated", "The job has been updated.", w, r)
+		},
+	))
+}
@@ -115,6 +115,7 @@ We wish you all the best in your future endeavours.
 						return
 					}
 				}
+				NotifyRegistrationApproved(rc, regreq.Username, rc.LoginInfo.UserName)
 			}
 			
 			rc.ReportRedirect("/admin/reg-request", 0, "Approved", "User registration approved.", w, r)
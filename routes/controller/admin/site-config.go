@@ -76,6 +76,10 @@ func bindAdminSiteConfigController(ctx *RouterContext) {
 				if r.Form.Has("manual-approval") && r.Form.Get("manual-approval") == "on" {
 					rc.Config.ManualApproval = true
 				}
+				rc.Config.RequireAdminSecondFactor = false
+				if r.Form.Has("require-admin-second-factor") && r.Form.Get("require-admin-second-factor") == "on" {
+					rc.Config.RequireAdminSecondFactor = true
+				}
 				rc.Config.RecalculateProperPath()
 				err := rc.Config.Sync()
 				if err != nil {
@@ -92,7 +96,18 @@ func bindAdminSiteConfigController(ctx *RouterContext) {
 				rc.Config.GitUser = r.Form.Get("git-user")
 				rc.Config.GitConfig.HTTPCloneProtocol.V1Dumb = len(strings.TrimSpace(r.Form.Get("git-http-enable-v1dumb"))) > 0
 				rc.Config.GitConfig.HTTPCloneProtocol.V2 = len(strings.TrimSpace(r.Form.Get("git-http-enable-v2"))) > 0
-				err := rc.Config.Sync()
+				rc.Config.GitConfig.HTTPPush = len(strings.TrimSpace(r.Form.Get("git-http-enable-push"))) > 0
+				maxPushSize, err := strconv.ParseInt(strings.TrimSpace(r.Form.Get("git-max-push-size")), 10, 64)
+				if err != nil || maxPushSize < 0 {
+					LogTemplateError(rc.LoadTemplate("admin/site-config").Execute(w, &templates.AdminConfigTemplateModel{
+						Config: rc.Config,
+						LoginInfo: rc.LoginInfo,
+						ErrorMsg: "Max push size must be a non-negative integer.",
+					}))
+					return
+				}
+				rc.Config.GitConfig.MaxPushSize = maxPushSize
+				err = rc.Config.Sync()
 				if err != nil {
 					LogTemplateError(rc.LoadTemplate("admin/site-config").Execute(w, &templates.AdminConfigTemplateModel{
 						Config: rc.Config,
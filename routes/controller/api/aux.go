@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the default & maximum page size of the api. the html views default
+// to 50 and have no upper limit, but we don't want bots to be able to
+// request the whole depot in one go.
+const (
+	API_DEFAULT_PAGE_SIZE = 50
+	API_MAX_PAGE_SIZE = 100
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	LogIfError(json.NewEncoder(w).Encode(v))
+}
+
+func reportError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &apiError{ Error: msg })
+}
+
+func reportNotFound(w http.ResponseWriter, objType string, objName string) {
+	reportError(w, 404, fmt.Sprintf("%s %s not found.", objType, objName))
+}
+
+func reportInternalError(w http.ResponseWriter, msg string) {
+	reportError(w, 500, msg)
+}
+
+// the maximum size of request bodies the api accepts.
+const API_MAX_REQUEST_BODY_SIZE = 1024 * 1024
+
+// decodes the json request body into `v`. returns false if failed, in
+// which case the response is already written.
+func readJSONBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mt != "application/json" {
+		reportError(w, 415, "Request body must be application/json.")
+		return false
+	}
+	err = json.NewDecoder(io.LimitReader(r.Body, API_MAX_REQUEST_BODY_SIZE)).Decode(v)
+	if err != nil {
+		reportError(w, 400, fmt.Sprintf("Invalid request body: %s", err))
+		return false
+	}
+	return true
+}
+
+// parses the `Authorization` header. we accept both `Bearer <token>`
+// and `token <token>`.
+func parseAuthorizationHeader(r *http.Request) (string, bool) {
+	h := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(h) <= 0 { return "", false }
+	k := strings.SplitN(h, " ", 2)
+	if len(k) < 2 { return "", true }
+	switch strings.ToLower(k[0]) {
+	case "bearer": fallthrough
+	case "token":
+		return strings.TrimSpace(k[1]), true
+	}
+	return "", true
+}
+
+// resolves the visitor of the request. clients can authenticate with
+// either a personal access token (see docs/access-token.org) or the
+// usual session cookie. unlike `UseLoginInfo`, a request that comes
+// with an invalid token is rejected instead of being treated as a
+// guest, so that broken bots won't silently lose access.
+var UseAPILoginInfo Middleware = func(f HandlerFunc) HandlerFunc {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+		tokenText, hasHeader := parseAuthorizationHeader(r)
+		if !hasHeader {
+			rc.LoginInfo, rc.LastError = GenerateLoginInfoModel(rc, r)
+			if rc.LastError != nil {
+				reportInternalError(w, fmt.Sprintf("Failed while checking login status: %s", rc.LastError))
+				return
+			}
+			f(rc, w, r)
+			return
+		}
+		t, u, err := ResolveAccessToken(rc, tokenText)
+		if err != nil {
+			reportInternalError(w, fmt.Sprintf("Failed while checking access token: %s", err))
+			return
+		}
+		if t == nil || u == nil {
+			reportError(w, 401, "Invalid access token.")
+			return
+		}
+		secondFactorRequired := AdminSecondFactorRequired(rc, u)
+		isAdmin := (u.Status == model.ADMIN || u.Status == model.SUPER_ADMIN) && !secondFactorRequired
+		rc.AccessToken = t
+		rc.LoginInfo = &templates.LoginInfoModel{
+			LoggedIn: true,
+			UserName: u.Name,
+			UserFullName: u.Title,
+			UserEmail: u.Email,
+			IsOwner: false,
+			IsSettingMember: false,
+			// a token without the admin scope only gets the
+			// privilege of a normal user.
+			IsAdmin: isAdmin && t.HasScope(model.TOKEN_SCOPE_ADMIN),
+			IsSuperAdmin: isAdmin && u.Status == model.SUPER_ADMIN && t.HasScope(model.TOKEN_SCOPE_ADMIN),
+			SecondFactorRequired: secondFactorRequired,
+		}
+		f(rc, w, r)
+	}
+}
+
+// same as `GlobalVisibility` but responds w/ json instead of
+// redirecting to notice pages.
+var APIGlobalVisibility Middleware = func(f HandlerFunc) HandlerFunc {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+		if !CheckGlobalVisibleToUser(rc, rc.LoginInfo) {
+			switch rc.Config.GlobalVisibility {
+			case gitus.GLOBAL_VISIBILITY_MAINTENANCE:
+				reportError(w, 503, "The site is under maintenance.")
+			case gitus.GLOBAL_VISIBILITY_SHUTDOWN:
+				reportError(w, 503, "The site is currently shut down.")
+			case gitus.GLOBAL_VISIBILITY_PRIVATE:
+				reportError(w, 401, "Authentication required.")
+			default:
+				reportError(w, 403, "Service not available right now.")
+			}
+			return
+		}
+		f(rc, w, r)
+	}
+}
+
+// requests authenticated w/ a session cookie can do anything the
+// user can do; requests authenticated w/ a token are further limited
+// by the scope of the token.
+func TokenScopeRequired(scope string) Middleware {
+	return func(f HandlerFunc) HandlerFunc {
+		return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			if rc.AccessToken != nil && !rc.AccessToken.HasScope(scope) {
+				reportError(w, 403, fmt.Sprintf("This action requires an access token with the scope %s.", scope))
+				return
+			}
+			f(rc, w, r)
+		}
+	}
+}
+
+func getAPIPageInfo(r *http.Request, count int64) (*apiPageInfo, error) {
+	// same as the html views: `p` is the page number starting from
+	// 1 and `s` is the page size.
+	q := r.URL.Query()
+	if !q.Has("s") {
+		q.Set("s", strconv.Itoa(API_DEFAULT_PAGE_SIZE))
+		r.URL.RawQuery = q.Encode()
+	}
+	s, err := strconv.ParseInt(q.Get("s"), 10, 64)
+	if err != nil || s <= 0 || s > API_MAX_PAGE_SIZE {
+		return nil, fmt.Errorf("Invalid page size; must be within 1 to %d.", API_MAX_PAGE_SIZE)
+	}
+	pageInfo, err := GeneratePageInfo(r, count)
+	if err != nil { return nil, errors.New("Invalid page number.") }
+	return toAPIPageInfo(pageInfo), nil
+}
+
+// slices `l` according to the page info. used for the listings that
+// aren't paginated by the database.
+func paginate[T any](l []T, pageInfo *apiPageInfo) []T {
+	start := (pageInfo.PageNum - 1) * pageInfo.PageSize
+	if start < 0 { start = 0 }
+	if start >= int64(len(l)) { return make([]T, 0) }
+	end := start + pageInfo.PageSize
+	if end > int64(len(l)) { end = int64(len(l)) }
+	return l[start:end]
+}
+
+// resolves the repository in the path & checks the visibility. returns
+// nil if any of the checks fails, in which case the response is
+// already written. repositories the visitor can't see are reported as
+// not found.
+func resolveAPIRepository(rc *RouterContext, w http.ResponseWriter, r *http.Request) (*model.Namespace, *model.Repository) {
+	rfn := r.PathValue("repoName")
+	if !model.ValidRepositoryName(rfn) {
+		reportNotFound(w, "Repository", rfn)
+		return nil, nil
+	}
+	_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+	if err == ErrNotFound || err == db.ErrEntityNotFound {
+		reportNotFound(w, "Repository", rfn)
+		return nil, nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to resolve repository: %s", err))
+		return nil, nil
+	}
+	if !CheckRepositoryVisibleToUser(rc.LoginInfo, ns, repo) {
+		reportNotFound(w, "Repository", rfn)
+		return nil, nil
+	}
+	return ns, repo
+}
+
+// same as `resolveAPIRepository` but also requires the repository to
+// be a git repository.
+func resolveAPIGitRepository(rc *RouterContext, w http.ResponseWriter, r *http.Request) (*model.Repository, *gitlib.LocalGitRepository) {
+	_, repo := resolveAPIRepository(rc, w, r)
+	if repo == nil { return nil, nil }
+	if repo.Type != model.REPO_TYPE_GIT {
+		reportError(w, 400, "The repository you have requested isn't a Git repository.")
+		return nil, nil
+	}
+	rr := repo.Repository.(*gitlib.LocalGitRepository)
+	err := rr.SyncAllBranchList()
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to sync branch list: %s", err))
+		return nil, nil
+	}
+	err = rr.SyncAllTagList()
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to sync tag list: %s", err))
+		return nil, nil
+	}
+	return repo, rr
+}
+
+// write actions require the visitor to be logged in (w/ either a
+// token or a session).
+var APILoginRequired Middleware = func(f HandlerFunc) HandlerFunc {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+		if rc.LoginInfo == nil || !rc.LoginInfo.LoggedIn {
+			reportError(w, 401, "Authentication required.")
+			return
+		}
+		f(rc, w, r)
+	}
+}
+
+// whether the visitor is a "member" of the repository, i.e. the
+// owner of the repository or its namespace, an admin, or someone in
+// the acl of either of them. members can close/reopen any issue &
+// pull request of the repository.
+func isRepositoryMember(rc *RouterContext, ns *model.Namespace, repo *model.Repository) bool {
+	un := rc.LoginInfo.UserName
+	if rc.LoginInfo.IsAdmin { return true }
+	if repo.Owner == un || ns.Owner == un { return true }
+	return repo.AccessControlList.GetUserPrivilege(un) != nil || ns.ACL.GetUserPrivilege(un) != nil
+}
+
+var errRefNotFound = errors.New("Ref not found")
+
+// resolves `ref` into a commit. `ref` can be a branch name, a tag
+// name or a commit id (in that order); empty string means the default
+// branch. tags are peeled until a commit is reached.
+func resolveRef(rr *gitlib.LocalGitRepository, ref string) (*gitlib.CommitObject, error) {
+	var oid string
+	if len(ref) <= 0 {
+		br := rr.GetDefaultBranch()
+		if br == nil { return nil, errRefNotFound }
+		oid = br.HeadId
+	} else if br, ok := rr.BranchIndex[ref]; ok {
+		oid = br.HeadId
+	} else if t, ok := rr.TagIndex[ref]; ok {
+		oid = t.HeadId
+	} else {
+		if !isObjectId(ref) { return nil, errRefNotFound }
+		oid = ref
+	}
+	obj, err := rr.ReadObject(oid)
+	if err != nil { return nil, errRefNotFound }
+	for obj.Type() == gitlib.TAG {
+		obj, err = rr.ReadObject(obj.(*gitlib.TagObject).TaggedObjId)
+		if err != nil { return nil, err }
+	}
+	if obj.Type() != gitlib.COMMIT { return nil, errRefNotFound }
+	return obj.(*gitlib.CommitObject), nil
+}
+
+func isObjectId(s string) bool {
+	if len(s) != 40 && len(s) != 64 { return false }
+	for _, k := range s {
+		if !(('0' <= k && k <= '9') || ('a' <= k && k <= 'f')) { return false }
+	}
+	return true
+}
+
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+// commit statuses. see docs/commit-status.org.
+
+type apiSetCommitStatusRequest struct {
+	Context string `json:"context"`
+	State string `json:"state"`
+	TargetURL string `json:"targetUrl"`
+	Description string `json:"description"`
+}
+
+// the maximum length of the description of a commit status.
+const API_MAX_COMMIT_STATUS_DESCRIPTION = 1024
+
+func bindAPICommitStatusController(ctx *RouterContext) {
+	http.HandleFunc("GET /api/v1/repo/{repoName}/commit/{commitId}/status", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, _ := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			commitId := r.PathValue("commitId")
+			if !isObjectId(commitId) {
+				reportNotFound(w, "Commit", commitId)
+				return
+			}
+			l, err := rc.DatabaseInterface.GetAllCommitStatus(repo.Namespace, repo.Name, commitId)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve commit statuses: %s", err))
+				return
+			}
+			res := make([]*apiCommitStatus, 0)
+			for _, k := range l { res = append(res, toAPICommitStatus(k)) }
+			writeJSON(w, 200, &apiCombinedCommitStatus{
+				CommitId: commitId,
+				State: model.CombineCommitStatus(l),
+				Statuses: res,
+			})
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/commit/{commitId}/status", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_REPO_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			if repo.Type != model.REPO_TYPE_GIT {
+				reportError(w, 400, "The repository you have requested isn't a Git repository.")
+				return
+			}
+			// same as pushing: ci services report w/ the token of a
+			// user who can push to the repository.
+			if !rc.LoginInfo.IsAdmin && !CheckUserPushPermission(rc.LoginInfo.UserName, ns, repo) {
+				reportError(w, 403, "You don't have the permission to set commit statuses of this repository.")
+				return
+			}
+			commitId := r.PathValue("commitId")
+			if !isObjectId(commitId) {
+				reportNotFound(w, "Commit", commitId)
+				return
+			}
+			rr := repo.Repository.(*gitlib.LocalGitRepository)
+			obj, err := rr.ReadObject(commitId)
+			if err != nil || obj.Type() != gitlib.COMMIT {
+				reportNotFound(w, "Commit", commitId)
+				return
+			}
+			var req apiSetCommitStatusRequest
+			if !readJSONBody(w, r, &req) { return }
+			req.Context = strings.TrimSpace(req.Context)
+			if !model.ValidCommitStatusContext(req.Context) {
+				reportError(w, 400, "Invalid context; must be 1 to 255 characters long and cannot contain commas.")
+				return
+			}
+			if !model.ValidCommitStatusState(req.State) {
+				reportError(w, 400, "Invalid state; must be one of pending, success, failure and error.")
+				return
+			}
+			req.TargetURL = strings.TrimSpace(req.TargetURL)
+			if len(req.TargetURL) > 0 && !strings.HasPrefix(req.TargetURL, "http://") && !strings.HasPrefix(req.TargetURL, "https://") {
+				reportError(w, 400, "The target URL must be an HTTP or HTTPS URL.")
+				return
+			}
+			if len(req.Description) > API_MAX_COMMIT_STATUS_DESCRIPTION {
+				reportError(w, 400, fmt.Sprintf("The description cannot be longer than %d bytes.", API_MAX_COMMIT_STATUS_DESCRIPTION))
+				return
+			}
+			s := &model.CommitStatus{
+				RepoNamespace: repo.Namespace,
+				RepoName: repo.Name,
+				CommitId: commitId,
+				Context: req.Context,
+				State: req.State,
+				TargetURL: req.TargetURL,
+				Description: req.Description,
+				Creator: rc.LoginInfo.UserName,
+			}
+			err = rc.DatabaseInterface.SetCommitStatus(s)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to set commit status: %s", err))
+				return
+			}
+			// for the create time, which is kept if the status is
+			// replaced.
+			l, err := rc.DatabaseInterface.GetAllCommitStatus(repo.Namespace, repo.Name, commitId)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve commit statuses: %s", err))
+				return
+			}
+			for _, k := range l {
+				if k.Context == s.Context { s = k; break }
+			}
+			writeJSON(w, 201, toAPICommitStatus(s))
+		},
+	))
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/auxfuncs"
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+// branches, tags, commits, trees & blobs of git repositories. things
+// that take a ref (branch name, tag name or commit id) take it from
+// the query parameter `ref` instead of the path, since branch names
+// can contain slashes.
+
+func bindAPIGitController(ctx *RouterContext) {
+	http.HandleFunc("GET /api/v1/repo/{repoName}/branch", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			k := auxfuncs.SortedKeys(rr.BranchIndex)
+			pageInfo, err := getAPIPageInfo(r, int64(len(k)))
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			res := make([]*apiBranch, 0)
+			for _, name := range paginate(k, pageInfo) {
+				res = append(res, &apiBranch{
+					Name: name,
+					HeadId: rr.BranchIndex[name].HeadId,
+				})
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/branch/{branchName...}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			branchName := r.PathValue("branchName")
+			br, ok := rr.BranchIndex[branchName]
+			if !ok {
+				reportNotFound(w, "Branch", branchName)
+				return
+			}
+			writeJSON(w, 200, &apiBranch{
+				Name: br.Name,
+				HeadId: br.HeadId,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/tag", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			k := auxfuncs.SortedKeys(rr.TagIndex)
+			pageInfo, err := getAPIPageInfo(r, int64(len(k)))
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			res := make([]*apiTag, 0)
+			for _, name := range paginate(k, pageInfo) {
+				t, err := toAPITag(rr, rr.TagIndex[name])
+				if err != nil {
+					reportInternalError(w, fmt.Sprintf("Failed to read tag %s: %s", name, err))
+					return
+				}
+				res = append(res, t)
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/tag/{tagName...}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			tagName := r.PathValue("tagName")
+			t, ok := rr.TagIndex[tagName]
+			if !ok {
+				reportNotFound(w, "Tag", tagName)
+				return
+			}
+			res, err := toAPITag(rr, t)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to read tag %s: %s", tagName, err))
+				return
+			}
+			writeJSON(w, 200, res)
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/commit", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			// the history follows the first parent only, same as the
+			// history page.
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			ref := strings.TrimSpace(r.URL.Query().Get("ref"))
+			cobj, err := resolveRef(rr, ref)
+			if err == errRefNotFound {
+				reportNotFound(w, "Ref", ref)
+				return
+			}
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to resolve ref %s: %s", ref, err))
+				return
+			}
+			// we don't know the length of the history beforehand, so
+			// the page number is not corrected here.
+			pageInfo, err := getAPIPageInfo(r, -1)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			if pageInfo.PageNum <= 0 {
+				reportError(w, 400, "Invalid page number.")
+				return
+			}
+			// read one more commit to see if there's a next page.
+			h, err := rr.GetCommitHistoryN(cobj.Id, int(pageInfo.PageNum * pageInfo.PageSize + 1))
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to read commit history of %s: %s", cobj.Id, err))
+				return
+			}
+			pageInfo.HasNextPage = int64(len(h)) > pageInfo.PageNum * pageInfo.PageSize
+			res := make([]*apiCommit, 0)
+			for _, k := range paginate(h, pageInfo) {
+				res = append(res, toAPICommit(&k))
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/commit/{commitId}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			commitId := r.PathValue("commitId")
+			if !isObjectId(commitId) {
+				reportNotFound(w, "Commit", commitId)
+				return
+			}
+			obj, err := rr.ReadObject(commitId)
+			if err != nil || obj.Type() != gitlib.COMMIT {
+				reportNotFound(w, "Commit", commitId)
+				return
+			}
+			writeJSON(w, 200, toAPICommit(obj.(*gitlib.CommitObject)))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/tree", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			cobj, p, obj := resolveAPIPath(rr, w, r)
+			if obj == nil { return }
+			if obj.Type() != gitlib.TREE {
+				reportError(w, 400, fmt.Sprintf("%s is not a directory.", p))
+				return
+			}
+			tobj := obj.(*gitlib.TreeObject)
+			res := make([]*apiTreeItem, 0)
+			for _, k := range tobj.ObjectList {
+				res = append(res, toAPITreeItem(k))
+			}
+			writeJSON(w, 200, &apiTree{
+				Id: tobj.Id,
+				CommitId: cobj.Id,
+				Path: p,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/blob", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo, rr := resolveAPIGitRepository(rc, w, r)
+			if repo == nil { return }
+			cobj, p, obj := resolveAPIPath(rr, w, r)
+			if obj == nil { return }
+			if obj.Type() != gitlib.BLOB {
+				reportError(w, 400, fmt.Sprintf("%s is not a file.", p))
+				return
+			}
+			bobj := obj.(*gitlib.BlobObject)
+			// `?raw` gives the file as is, which is easier to deal
+			// with for large files.
+			if r.URL.Query().Has("raw") {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.WriteHeader(200)
+				w.Write(bobj.Data)
+				return
+			}
+			writeJSON(w, 200, &apiBlob{
+				Id: bobj.Id,
+				CommitId: cobj.Id,
+				Path: p,
+				Size: len(bobj.Data),
+				Content: bobj.Data,
+			})
+		},
+	))
+}
+
+func toAPITag(rr *gitlib.LocalGitRepository, t *gitlib.Tag) (*apiTag, error) {
+	obj, err := rr.ReadObject(t.HeadId)
+	if err != nil { return nil, err }
+	res := &apiTag{
+		Name: t.Name,
+		HeadId: t.HeadId,
+		Annotated: false,
+		TaggedObjectId: t.HeadId,
+		TaggedObjectType: strings.ToLower(obj.Type().String()),
+	}
+	if obj.Type() == gitlib.TAG {
+		tobj := obj.(*gitlib.TagObject)
+		res.Annotated = true
+		res.TaggedObjectId = tobj.TaggedObjId
+		res.TaggedObjectType = tobj.TaggedObjType
+		res.Tagger = toAPIAuthorTime(tobj.TaggerInfo)
+		res.Message = tobj.TagMessage
+	}
+	return res, nil
+}
+
+// resolves the query parameters `ref` & `path` into an object. returns
+// nil if failed, in which case the response is already written.
+func resolveAPIPath(rr *gitlib.LocalGitRepository, w http.ResponseWriter, r *http.Request) (*gitlib.CommitObject, string, gitlib.GitObject) {
+	ref := strings.TrimSpace(r.URL.Query().Get("ref"))
+	cobj, err := resolveRef(rr, ref)
+	if err == errRefNotFound {
+		reportNotFound(w, "Ref", ref)
+		return nil, "", nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to resolve ref %s: %s", ref, err))
+		return nil, "", nil
+	}
+	p := path.Clean("/" + r.URL.Query().Get("path"))
+	tobj, err := rr.ReadObject(cobj.TreeObjId)
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to read tree %s: %s", cobj.TreeObjId, err))
+		return nil, "", nil
+	}
+	obj, err := rr.ResolveTreePath(tobj.(*gitlib.TreeObject), p)
+	if err == gitlib.ErrObjectNotFound {
+		reportNotFound(w, "Path", p)
+		return nil, "", nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to resolve path %s: %s", p, err))
+		return nil, "", nil
+	}
+	return cobj, p, obj
+}
+
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"github.com/GitusCodeForge/Gitus/routes"
+)
+
+// the json api. see docs/api.org.
+
+func BindAllAPIControllers(ctx *routes.RouterContext) {
+	bindAPIUserController(ctx)
+	bindAPINamespaceController(ctx)
+	bindAPIRepositoryController(ctx)
+	bindAPIGitController(ctx)
+	bindAPIIssueController(ctx)
+	bindAPIPullRequestController(ctx)
+	bindAPICommitStatusController(ctx)
+}
+
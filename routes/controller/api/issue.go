@@ -0,0 +1,249 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+// issues. the filter `f` is the same as the issue list page: 0 - all,
+// 1 - open, 2 - closed, 3 - closed as solved, 4 - closed as discarded.
+
+type apiNewIssueRequest struct {
+	Title string `json:"title"`
+	Content string `json:"content"`
+}
+
+type apiCommentRequest struct {
+	Content string `json:"content"`
+}
+
+type apiCloseIssueRequest struct {
+	// "solved" or "discarded". defaults to "solved".
+	Reason string `json:"reason"`
+}
+
+func bindAPIIssueController(ctx *RouterContext) {
+	http.HandleFunc("GET /api/v1/repo/{repoName}/issue", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			q := strings.TrimSpace(r.URL.Query().Get("q"))
+			f, ok := parseAPIFilter(w, r, 4)
+			if !ok { return }
+			filter := ParseIssueFilter(r)
+			count, err := rc.DatabaseInterface.CountIssue(q, repo.Namespace, repo.Name, f, filter)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to count issues: %s", err))
+				return
+			}
+			pageInfo, err := getAPIPageInfo(r, count)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			res := make([]*apiIssue, 0)
+			if count > 0 {
+				l, err := rc.DatabaseInterface.SearchIssuePaginated(q, repo.Namespace, repo.Name, f, filter, pageInfo.PageNum-1, pageInfo.PageSize)
+				if err != nil {
+					reportInternalError(w, fmt.Sprintf("Failed to retrieve issues: %s", err))
+					return
+				}
+				for _, k := range l {
+					res = append(res, toAPIIssue(k))
+				}
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/issue", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			var req apiNewIssueRequest
+			if !readJSONBody(w, r, &req) { return }
+			title := strings.TrimSpace(req.Title)
+			if len(title) <= 0 {
+				reportError(w, 400, "Issue title cannot be empty.")
+				return
+			}
+			iid, err := rc.DatabaseInterface.NewRepositoryIssue(repo.Namespace, repo.Name, rc.LoginInfo.UserName, title, req.Content)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to create issue: %s", err))
+				return
+			}
+			issue, err := rc.DatabaseInterface.GetRepositoryIssue(repo.Namespace, repo.Name, int(iid))
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve issue: %s", err))
+				return
+			}
+			LogIfError(ProcessIssueReference(rc, repo, IssueReferenceSourceOfIssue(issue), issue.IssueContent, rc.LoginInfo.UserName, false))
+			NotifyNewIssue(rc, repo, iid, rc.LoginInfo.UserName)
+			TriggerNewIssueWebhook(rc, repo, iid, rc.LoginInfo.UserName)
+			writeJSON(w, 201, toAPIIssue(issue))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/issue/{id}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			issue := resolveAPIIssue(rc, w, r, repo)
+			if issue == nil { return }
+			eventList, err := rc.DatabaseInterface.GetAllIssueEvent(repo.Namespace, repo.Name, issue.IssueId)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve issue events: %s", err))
+				return
+			}
+			res := toAPIIssue(issue)
+			res.EventList = make([]*apiIssueEvent, 0)
+			for _, k := range eventList {
+				res.EventList = append(res.EventList, toAPIIssueEvent(k))
+			}
+			writeJSON(w, 200, res)
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/issue/{id}/comment", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			issue := resolveAPIIssue(rc, w, r, repo)
+			if issue == nil { return }
+			var req apiCommentRequest
+			if !readJSONBody(w, r, &req) { return }
+			if len(strings.TrimSpace(req.Content)) <= 0 {
+				reportError(w, 400, "Comment cannot be empty.")
+				return
+			}
+			postAPIIssueEvent(rc, w, repo, issue, model.EVENT_COMMENT, req.Content)
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/issue/{id}/close", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			issue := resolveAPIIssue(rc, w, r, repo)
+			if issue == nil { return }
+			if issue.IssueAuthor != rc.LoginInfo.UserName && !isRepositoryMember(rc, ns, repo) {
+				reportError(w, 403, "Only the author of the issue and members of the repository can close it.")
+				return
+			}
+			if issue.IssueStatus != model.ISSUE_OPENED {
+				reportError(w, 409, "The issue is already closed.")
+				return
+			}
+			var req apiCloseIssueRequest
+			if !readJSONBody(w, r, &req) { return }
+			eType := model.EVENT_CLOSED_AS_SOLVED
+			switch req.Reason {
+			case "": fallthrough
+			case "solved": eType = model.EVENT_CLOSED_AS_SOLVED
+			case "discarded": eType = model.EVENT_CLOSED_AS_DISCARDED
+			default:
+				reportError(w, 400, "Reason must be either \"solved\" or \"discarded\".")
+				return
+			}
+			postAPIIssueEvent(rc, w, repo, issue, eType, "")
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/issue/{id}/reopen", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			issue := resolveAPIIssue(rc, w, r, repo)
+			if issue == nil { return }
+			if issue.IssueAuthor != rc.LoginInfo.UserName && !isRepositoryMember(rc, ns, repo) {
+				reportError(w, 403, "Only the author of the issue and members of the repository can reopen it.")
+				return
+			}
+			if issue.IssueStatus == model.ISSUE_OPENED {
+				reportError(w, 409, "The issue is already open.")
+				return
+			}
+			postAPIIssueEvent(rc, w, repo, issue, model.EVENT_REOPENED, "")
+		},
+	))
+}
+
+// parses the filter type `f`. returns false if failed, in which case
+// the response is already written.
+func parseAPIFilter(w http.ResponseWriter, r *http.Request, max int) (int, bool) {
+	fStr := strings.TrimSpace(r.URL.Query().Get("f"))
+	if len(fStr) <= 0 { return 0, true }
+	f, err := strconv.Atoi(fStr)
+	if err != nil || f < 0 || f > max {
+		reportError(w, 400, fmt.Sprintf("Invalid filter type; must be within 0 to %d.", max))
+		return 0, false
+	}
+	return f, true
+}
+
+func resolveAPIIssue(rc *RouterContext, w http.ResponseWriter, r *http.Request, repo *model.Repository) *model.Issue {
+	idStr := r.PathValue("id")
+	iid, err := strconv.Atoi(idStr)
+	if err != nil || iid <= 0 {
+		reportNotFound(w, "Issue", idStr)
+		return nil
+	}
+	issue, err := rc.DatabaseInterface.GetRepositoryIssue(repo.Namespace, repo.Name, iid)
+	if err == db.ErrEntityNotFound {
+		reportNotFound(w, "Issue", idStr)
+		return nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to retrieve issue: %s", err))
+		return nil
+	}
+	return issue
+}
+
+// adds the event & responds w/ the updated issue.
+func postAPIIssueEvent(rc *RouterContext, w http.ResponseWriter, repo *model.Repository, issue *model.Issue, eType int, content string) {
+	err := rc.DatabaseInterface.NewRepositoryIssueEvent(repo.Namespace, repo.Name, int64(issue.IssueId), eType, rc.LoginInfo.UserName, content)
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to update issue: %s", err))
+		return
+	}
+	if eType == model.EVENT_COMMENT {
+		LogIfError(ProcessIssueReference(rc, repo, IssueReferenceSourceOfIssue(issue), content, rc.LoginInfo.UserName, false))
+	}
+	NotifyIssueEvent(rc, repo, int64(issue.IssueId), eType, rc.LoginInfo.UserName, content)
+	TriggerIssueWebhook(rc, repo, int64(issue.IssueId), eType, rc.LoginInfo.UserName, content)
+	issue, err = rc.DatabaseInterface.GetRepositoryIssue(repo.Namespace, repo.Name, issue.IssueId)
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to retrieve issue: %s", err))
+		return
+	}
+	writeJSON(w, 200, toAPIIssue(issue))
+}
+
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/auxfuncs"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+func bindAPINamespaceController(ctx *RouterContext) {
+	if !ctx.Config.UseNamespace { return }
+
+	http.HandleFunc("GET /api/v1/namespace", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			q := strings.TrimSpace(r.URL.Query().Get("q"))
+			var count int64
+			var err error
+			if len(q) > 0 {
+				count, err = rc.DatabaseInterface.CountAllVisibleNamespaceSearchResult(rc.LoginInfo.UserName, q)
+			} else {
+				count, err = rc.DatabaseInterface.CountAllVisibleNamespace(rc.LoginInfo.UserName)
+			}
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to count namespaces: %s", err))
+				return
+			}
+			pageInfo, err := getAPIPageInfo(r, count)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			var nsl map[string]*model.Namespace
+			if len(q) > 0 {
+				nsl, err = rc.DatabaseInterface.SearchAllVisibleNamespacePaginated(rc.LoginInfo.UserName, q, pageInfo.PageNum-1, pageInfo.PageSize)
+			} else {
+				nsl, err = rc.DatabaseInterface.GetAllVisibleNamespacePaginated(rc.LoginInfo.UserName, pageInfo.PageNum-1, pageInfo.PageSize)
+			}
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve namespaces: %s", err))
+				return
+			}
+			res := make([]*apiNamespace, 0)
+			for _, k := range auxfuncs.SortedKeys(nsl) {
+				res = append(res, toAPINamespace(nsl[k]))
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/namespace/{namespace}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveAPINamespace(rc, w, r)
+			if ns == nil { return }
+			writeJSON(w, 200, toAPINamespace(ns))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/namespace/{namespace}/repo", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveAPINamespace(rc, w, r)
+			if ns == nil { return }
+			s, err := rc.DatabaseInterface.GetAllVisibleRepositoryFromNamespace(rc.LoginInfo.UserName, ns.Name)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve repositories: %s", err))
+				return
+			}
+			slices.SortFunc(s, func(a, b *model.Repository) int {
+				return strings.Compare(a.Name, b.Name)
+			})
+			pageInfo, err := getAPIPageInfo(r, int64(len(s)))
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			res := make([]*apiRepository, 0)
+			for _, k := range paginate(s, pageInfo) {
+				res = append(res, toAPIRepository(k))
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+}
+
+func resolveAPINamespace(rc *RouterContext, w http.ResponseWriter, r *http.Request) *model.Namespace {
+	nsName := r.PathValue("namespace")
+	if !model.ValidNamespaceName(nsName) {
+		reportNotFound(w, "Namespace", nsName)
+		return nil
+	}
+	ns, err := rc.DatabaseInterface.GetNamespaceByName(nsName)
+	if err == db.ErrEntityNotFound {
+		reportNotFound(w, "Namespace", nsName)
+		return nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to retrieve namespace: %s", err))
+		return nil
+	}
+	if !CheckNamespaceVisibleToUser(rc.LoginInfo, ns) {
+		reportNotFound(w, "Namespace", nsName)
+		return nil
+	}
+	return ns
+}
+
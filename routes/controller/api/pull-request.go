@@ -0,0 +1,503 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+// pull requests. the filter `f` is the same as the pull request list
+// page: 0 - all, 1 - open, 2 - closed (merged or not).
+
+type apiNewPullRequestRequest struct {
+	Title string `json:"title"`
+	ReceiverBranch string `json:"receiverBranch"`
+	// the full name of the provider repository (e.g. "ns:name" in
+	// namespace mode). defaults to the receiver repository itself.
+	ProviderRepository string `json:"providerRepository"`
+	ProviderBranch string `json:"providerBranch"`
+}
+
+// the body of the merge request is optional; it defaults to a merge
+// commit w/ the default message.
+type apiMergeRequest struct {
+	// see `gitlib.MERGE_STRATEGY_*`: 1 - merge commit, 2 - squash,
+	// 3 - rebase, 4 - fast-forward only.
+	Strategy int `json:"strategy"`
+	Message string `json:"message"`
+}
+
+type apiReviewRequest struct {
+	// see `model.PullRequestReview`: 1 - comment, 2 - approve,
+	// 3 - request changes.
+	State int `json:"state"`
+	Summary string `json:"summary"`
+}
+
+func bindAPIPullRequestController(ctx *RouterContext) {
+	http.HandleFunc("GET /api/v1/repo/{repoName}/pull-request", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			q := strings.TrimSpace(r.URL.Query().Get("q"))
+			f, ok := parseAPIFilter(w, r, 2)
+			if !ok { return }
+			count, err := rc.DatabaseInterface.CountPullRequest(q, repo.Namespace, repo.Name, f)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to count pull requests: %s", err))
+				return
+			}
+			pageInfo, err := getAPIPageInfo(r, count)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			res := make([]*apiPullRequest, 0)
+			if count > 0 {
+				l, err := rc.DatabaseInterface.SearchPullRequestPaginated(q, repo.Namespace, repo.Name, f, pageInfo.PageNum-1, pageInfo.PageSize)
+				if err != nil {
+					reportInternalError(w, fmt.Sprintf("Failed to retrieve pull requests: %s", err))
+					return
+				}
+				for _, k := range l {
+					res = append(res, toAPIPullRequest(k))
+				}
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/pull-request", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			var req apiNewPullRequestRequest
+			if !readJSONBody(w, r, &req) { return }
+			title := strings.TrimSpace(req.Title)
+			if len(title) <= 0 {
+				reportError(w, 400, "Pull request title cannot be empty.")
+				return
+			}
+			rr := repo.Repository.(*gitlib.LocalGitRepository)
+			err := rr.SyncAllBranchList()
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to sync branch list: %s", err))
+				return
+			}
+			if _, ok := rr.BranchIndex[req.ReceiverBranch]; !ok {
+				reportError(w, 400, fmt.Sprintf("Branch %s does not exist in repository %s.", req.ReceiverBranch, repo.FullName()))
+				return
+			}
+			provider := repo
+			if len(req.ProviderRepository) > 0 && req.ProviderRepository != repo.FullName() {
+				if !model.ValidRepositoryName(req.ProviderRepository) {
+					reportNotFound(w, "Repository", req.ProviderRepository)
+					return
+				}
+				_, _, pns, p, err := rc.ResolveRepositoryFullName(req.ProviderRepository)
+				if err == ErrNotFound || err == db.ErrEntityNotFound || (err == nil && !CheckRepositoryVisibleToUser(rc.LoginInfo, pns, p)) {
+					reportNotFound(w, "Repository", req.ProviderRepository)
+					return
+				}
+				if err != nil {
+					reportInternalError(w, fmt.Sprintf("Failed to resolve repository: %s", err))
+					return
+				}
+				if p.Type != model.REPO_TYPE_GIT {
+					reportError(w, 400, "The provider repository isn't a Git repository.")
+					return
+				}
+				provider = p
+			}
+			pr := provider.Repository.(*gitlib.LocalGitRepository)
+			err = pr.SyncAllBranchList()
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to sync branch list: %s", err))
+				return
+			}
+			if _, ok := pr.BranchIndex[req.ProviderBranch]; !ok {
+				reportError(w, 400, fmt.Sprintf("Branch %s does not exist in repository %s.", req.ProviderBranch, provider.FullName()))
+				return
+			}
+			prid, err := rc.DatabaseInterface.NewPullRequest(rc.LoginInfo.UserName, title, repo.Namespace, repo.Name, req.ReceiverBranch, provider.Namespace, provider.Name, req.ProviderBranch)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to create pull request: %s", err))
+				return
+			}
+			res, err := rc.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, prid)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request: %s", err))
+				return
+			}
+			NotifyNewPullRequest(rc, repo, prid, rc.LoginInfo.UserName)
+			TriggerNewPullRequestWebhook(rc, repo, prid, rc.LoginInfo.UserName)
+			EnqueuePullRequestMergeCheck(rc, repo, prid)
+			EnqueuePullRequestCIRun(rc, []int64{res.PRAbsId}, rc.LoginInfo.UserName)
+			writeJSON(w, 201, toAPIPullRequest(res))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/pull-request/{prid}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			writeJSON(w, 200, toAPIPullRequest(pr))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/pull-request/{prid}/event", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			// the database doesn't count pull request events, so the
+			// page number is not corrected here, same as the commit
+			// history.
+			pageInfo, err := getAPIPageInfo(r, -1)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			if pageInfo.PageNum <= 0 {
+				reportError(w, 400, "Invalid page number.")
+				return
+			}
+			l, err := rc.DatabaseInterface.GetAllPullRequestEventPaginated(pr.PRAbsId, pageInfo.PageNum-1, pageInfo.PageSize)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request events: %s", err))
+				return
+			}
+			pageInfo.HasNextPage = int64(len(l)) >= pageInfo.PageSize
+			res := make([]*apiPullRequestEvent, 0)
+			for _, k := range l {
+				res = append(res, toAPIPullRequestEvent(k))
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/pull-request/{prid}/comment", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			var req apiCommentRequest
+			if !readJSONBody(w, r, &req) { return }
+			if len(strings.TrimSpace(req.Content)) <= 0 {
+				reportError(w, 400, "Comment cannot be empty.")
+				return
+			}
+			e, err := rc.DatabaseInterface.CommentOnPullRequest(pr.PRAbsId, rc.LoginInfo.UserName, req.Content)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to comment on pull request: %s", err))
+				return
+			}
+			LogIfError(ProcessIssueReference(rc, repo, IssueReferenceSourceOfPullRequest(pr), req.Content, rc.LoginInfo.UserName, false))
+			NotifyPullRequestEvent(rc, repo, pr, model.PULL_REQUEST_EVENT_COMMENT, rc.LoginInfo.UserName, req.Content)
+			writeJSON(w, 201, toAPIPullRequestEvent(e))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}/pull-request/{prid}/review", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			l, err := rc.DatabaseInterface.GetAllPullRequestReview(pr.PRAbsId)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request reviews: %s", err))
+				return
+			}
+			res := make([]*apiPullRequestReview, 0)
+			for _, k := range l {
+				res = append(res, toAPIPullRequestReview(k))
+			}
+			writeJSON(w, 200, res)
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/pull-request/{prid}/review", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			var req apiReviewRequest
+			if !readJSONBody(w, r, &req) { return }
+			if !model.ValidPullRequestReviewState(req.State) {
+				reportError(w, 400, "Invalid review state.")
+				return
+			}
+			if pr.Status != model.PULL_REQUEST_OPEN {
+				reportError(w, 409, "The pull request is not open.")
+				return
+			}
+			if req.State != model.PULL_REQUEST_REVIEW_COMMENT && !CheckUserReviewPermission(rc.LoginInfo.UserName, rc.LoginInfo.IsAdmin, ns, repo, pr) {
+				reportError(w, 403, "You can only leave review comments on this pull request.")
+				return
+			}
+			commitId, err := GetPullRequestProviderHead(rc, pr)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to resolve provider branch: %s", err))
+				return
+			}
+			review := &model.PullRequestReview{
+				PRAbsId: pr.PRAbsId,
+				Reviewer: rc.LoginInfo.UserName,
+				State: req.State,
+				Summary: req.Summary,
+				CommitId: commitId,
+			}
+			_, err = rc.DatabaseInterface.ReviewPullRequest(review)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to review pull request: %s", err))
+				return
+			}
+			NotifyPullRequestReview(rc, repo, pr, review)
+			writeJSON(w, 201, toAPIPullRequestReview(review))
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/pull-request/{prid}/merge", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			if !rc.LoginInfo.IsAdmin && !CheckUserPushPermission(rc.LoginInfo.UserName, ns, repo) {
+				reportError(w, 403, "You don't have the permission to merge pull requests of this repository.")
+				return
+			}
+			if repo.Status == model.REPO_ARCHIVED {
+				reportError(w, 409, "The repository is archived.")
+				return
+			}
+			if pr.Status != model.PULL_REQUEST_OPEN {
+				reportError(w, 409, "The pull request is not open.")
+				return
+			}
+			req := apiMergeRequest{ Strategy: gitlib.MERGE_STRATEGY_MERGE }
+			if r.ContentLength != 0 {
+				if !readJSONBody(w, r, &req) { return }
+				if req.Strategy == 0 { req.Strategy = gitlib.MERGE_STRATEGY_MERGE }
+			}
+			if !gitlib.ValidMergeStrategy(req.Strategy) {
+				reportError(w, 400, "Invalid merge strategy.")
+				return
+			}
+			// `CheckAndMergePullRequest` silently does nothing when
+			// the merge check fails, so we check beforehand to be
+			// able to tell the client why.
+			mcr, err := rc.DatabaseInterface.CheckPullRequestMergeConflict(pr.PRAbsId)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to check merge conflict: %s", err))
+				return
+			}
+			if !mcr.Successful {
+				pr, err = rc.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, pr.PRId)
+				if err != nil {
+					reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request: %s", err))
+					return
+				}
+				writeJSON(w, 409, toAPIPullRequest(pr))
+				return
+			}
+			commitList, err := GetPullRequestCommitList(rc, repo, pr)
+			LogIfError(err)
+			err = rc.DatabaseInterface.CheckAndMergePullRequest(pr.PRAbsId, rc.LoginInfo.UserName, req.Strategy, req.Message)
+			if errors.Is(err, model.ErrMergeStrategyNotAllowed) {
+				reportError(w, 403, err.Error())
+				return
+			}
+			if errors.Is(err, gitlib.ErrCannotFastForward) || errors.Is(err, gitlib.ErrCannotRebaseMergeCommit) {
+				reportError(w, 409, err.Error())
+				return
+			}
+			var bpe *model.BranchProtectionError
+			if errors.As(err, &bpe) {
+				reportError(w, 403, err.Error())
+				return
+			}
+			var pre *model.PullRequestReviewError
+			if errors.As(err, &pre) {
+				reportError(w, 403, err.Error())
+				return
+			}
+			var cse *model.CommitStatusError
+			if errors.As(err, &cse) {
+				reportError(w, 403, err.Error())
+				return
+			}
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to merge pull request: %s", err))
+				return
+			}
+			pr, err = rc.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, pr.PRId)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request: %s", err))
+				return
+			}
+			if pr.Status != model.PULL_REQUEST_CLOSED_AS_MERGED {
+				// the branches might have changed between the check
+				// and the merge.
+				writeJSON(w, 409, toAPIPullRequest(pr))
+				return
+			}
+			NotifyPullRequestEvent(rc, repo, pr, model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED, rc.LoginInfo.UserName, "")
+			TriggerPullRequestWebhook(rc, repo, pr, model.WEBHOOK_EVENT_PULL_REQUEST_MERGED, rc.LoginInfo.UserName, "")
+			LogIfError(ProcessPullRequestMergeReference(rc, repo, pr, commitList, rc.LoginInfo.UserName))
+			writeJSON(w, 200, toAPIPullRequest(pr))
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/pull-request/{prid}/close", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			if !canManageAPIPullRequest(rc, ns, repo, pr) {
+				reportError(w, 403, "Only the author of the pull request and people who can push to the repository can close it.")
+				return
+			}
+			if pr.Status != model.PULL_REQUEST_OPEN {
+				reportError(w, 409, "The pull request is not open.")
+				return
+			}
+			err := rc.DatabaseInterface.ClosePullRequestAsNotMerged(pr.PRAbsId, rc.LoginInfo.UserName)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to close pull request: %s", err))
+				return
+			}
+			NotifyPullRequestEvent(rc, repo, pr, model.PULL_REQUEST_EVENT_CLOSE_AS_NOT_MERGED, rc.LoginInfo.UserName, "")
+			respondAPIPullRequest(rc, w, repo, pr.PRId)
+		},
+	))
+
+	http.HandleFunc("POST /api/v1/repo/{repoName}/pull-request/{prid}/reopen", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			APILoginRequired, TokenScopeRequired(model.TOKEN_SCOPE_ISSUE_WRITE),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveAPIPullRequestRepository(rc, w, r)
+			if repo == nil { return }
+			pr := resolveAPIPullRequest(rc, w, r, repo)
+			if pr == nil { return }
+			if !canManageAPIPullRequest(rc, ns, repo, pr) {
+				reportError(w, 403, "Only the author of the pull request and people who can push to the repository can reopen it.")
+				return
+			}
+			if pr.Status != model.PULL_REQUEST_CLOSED_AS_NOT_MERGED {
+				reportError(w, 409, "Only pull requests closed as not merged can be reopened.")
+				return
+			}
+			err := rc.DatabaseInterface.ReopenPullRequest(pr.PRAbsId, rc.LoginInfo.UserName)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to reopen pull request: %s", err))
+				return
+			}
+			NotifyPullRequestEvent(rc, repo, pr, model.PULL_REQUEST_EVENT_REOPEN, rc.LoginInfo.UserName, "")
+			respondAPIPullRequest(rc, w, repo, pr.PRId)
+		},
+	))
+}
+
+// same as `resolveAPIRepository` but also checks whether pull requests
+// are available, i.e. the depot is not in plain mode & the repository
+// is a git repository.
+func resolveAPIPullRequestRepository(rc *RouterContext, w http.ResponseWriter, r *http.Request) (*model.Namespace, *model.Repository) {
+	if rc.Config.IsInPlainMode() {
+		reportError(w, 404, "Pull requests are not available in plain mode.")
+		return nil, nil
+	}
+	ns, repo := resolveAPIRepository(rc, w, r)
+	if repo == nil { return nil, nil }
+	if repo.Type != model.REPO_TYPE_GIT {
+		reportError(w, 400, "The repository you have requested isn't a Git repository.")
+		return nil, nil
+	}
+	return ns, repo
+}
+
+func resolveAPIPullRequest(rc *RouterContext, w http.ResponseWriter, r *http.Request, repo *model.Repository) *model.PullRequest {
+	pridStr := r.PathValue("prid")
+	prid, err := strconv.ParseInt(pridStr, 10, 64)
+	if err != nil || prid <= 0 {
+		reportNotFound(w, "Pull request", pridStr)
+		return nil
+	}
+	pr, err := rc.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, prid)
+	if err == db.ErrEntityNotFound {
+		reportNotFound(w, "Pull request", pridStr)
+		return nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request: %s", err))
+		return nil
+	}
+	return pr
+}
+
+// the author of the pull request, admins & people who can push to the
+// receiver repository can close/reopen a pull request.
+func canManageAPIPullRequest(rc *RouterContext, ns *model.Namespace, repo *model.Repository, pr *model.PullRequest) bool {
+	if rc.LoginInfo.IsAdmin { return true }
+	if pr.Author == rc.LoginInfo.UserName { return true }
+	return CheckUserPushPermission(rc.LoginInfo.UserName, ns, repo)
+}
+
+func respondAPIPullRequest(rc *RouterContext, w http.ResponseWriter, repo *model.Repository, prid int64) {
+	pr, err := rc.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, prid)
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to retrieve pull request: %s", err))
+		return
+	}
+	writeJSON(w, 200, toAPIPullRequest(pr))
+}
+
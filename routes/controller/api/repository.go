@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+func bindAPIRepositoryController(ctx *RouterContext) {
+	http.HandleFunc("GET /api/v1/repo", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			// same as /all/repo: admins see every repository.
+			q := strings.TrimSpace(r.URL.Query().Get("q"))
+			var count int64
+			var err error
+			if rc.LoginInfo.IsAdmin {
+				if len(q) > 0 {
+					count, err = rc.DatabaseInterface.CountAllRepositoriesSearchResult(q)
+				} else {
+					count, err = rc.DatabaseInterface.CountAllRepositories()
+				}
+			} else {
+				if len(q) > 0 {
+					count, err = rc.DatabaseInterface.CountAllVisibleRepositoriesSearchResult(rc.LoginInfo.UserName, q)
+				} else {
+					count, err = rc.DatabaseInterface.CountAllVisibleRepositories(rc.LoginInfo.UserName)
+				}
+			}
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to count repositories: %s", err))
+				return
+			}
+			pageInfo, err := getAPIPageInfo(r, count)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			var repol []*model.Repository
+			if rc.LoginInfo.IsAdmin {
+				if len(q) > 0 {
+					repol, err = rc.DatabaseInterface.SearchForRepository(q, pageInfo.PageNum-1, pageInfo.PageSize)
+				} else {
+					repol, err = rc.DatabaseInterface.GetAllRepositories(pageInfo.PageNum-1, pageInfo.PageSize)
+				}
+			} else {
+				if len(q) > 0 {
+					repol, err = rc.DatabaseInterface.SearchAllVisibleRepositoryPaginated(rc.LoginInfo.UserName, q, pageInfo.PageNum-1, pageInfo.PageSize)
+				} else {
+					repol, err = rc.DatabaseInterface.GetAllVisibleRepositoryPaginated(rc.LoginInfo.UserName, pageInfo.PageNum-1, pageInfo.PageSize)
+				}
+			}
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve repositories: %s", err))
+				return
+			}
+			res := make([]*apiRepository, 0)
+			for _, k := range repol {
+				res = append(res, toAPIRepository(k))
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/repo/{repoName}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveAPIRepository(rc, w, r)
+			if repo == nil { return }
+			writeJSON(w, 200, toAPIRepository(repo))
+		},
+	))
+}
+
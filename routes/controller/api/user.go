@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+func bindAPIUserController(ctx *RouterContext) {
+	http.HandleFunc("GET /api/v1/user", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			// the user the request is authenticated as. useful for
+			// checking whether a token still works.
+			if !rc.LoginInfo.LoggedIn {
+				reportError(w, 401, "Authentication required.")
+				return
+			}
+			u, err := rc.DatabaseInterface.GetUserByName(rc.LoginInfo.UserName)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve user: %s", err))
+				return
+			}
+			writeJSON(w, 200, toAPIUser(u))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/user/{userName}", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			u := resolveAPIUser(rc, w, r)
+			if u == nil { return }
+			writeJSON(w, 200, toAPIUser(u))
+		},
+	))
+
+	http.HandleFunc("GET /api/v1/user/{userName}/repo", UseMiddleware(
+		[]Middleware{Logged, UseAPILoginInfo, APIGlobalVisibility,
+			TokenScopeRequired(model.TOKEN_SCOPE_REPO_READ),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			// same as the repository list on the user page.
+			u := resolveAPIUser(rc, w, r)
+			if u == nil { return }
+			q := strings.TrimSpace(r.URL.Query().Get("q"))
+			count, err := rc.DatabaseInterface.CountAllBelongingRepository(rc.LoginInfo.UserName, u.Name, q)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to count repositories: %s", err))
+				return
+			}
+			pageInfo, err := getAPIPageInfo(r, count)
+			if err != nil {
+				reportError(w, 400, err.Error())
+				return
+			}
+			repol, err := rc.DatabaseInterface.GetAllBelongingRepository(rc.LoginInfo.UserName, u.Name, q, pageInfo.PageNum-1, pageInfo.PageSize)
+			if err != nil {
+				reportInternalError(w, fmt.Sprintf("Failed to retrieve repositories: %s", err))
+				return
+			}
+			res := make([]*apiRepository, 0)
+			for _, k := range repol {
+				res = append(res, toAPIRepository(k))
+			}
+			writeJSON(w, 200, &apiList{
+				PageInfo: pageInfo,
+				Items: res,
+			})
+		},
+	))
+}
+
+func resolveAPIUser(rc *RouterContext, w http.ResponseWriter, r *http.Request) *model.GitusUser {
+	un := r.PathValue("userName")
+	if !model.ValidUserName(un) {
+		reportNotFound(w, "User", un)
+		return nil
+	}
+	u, err := rc.DatabaseInterface.GetUserByName(un)
+	if err == db.ErrEntityNotFound {
+		reportNotFound(w, "User", un)
+		return nil
+	}
+	if err != nil {
+		reportInternalError(w, fmt.Sprintf("Failed to retrieve user: %s", err))
+		return nil
+	}
+	return u
+}
+
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the json representations returned by the api. we don't serialize
+// the models directly because they contain things that shouldn't be
+// exposed (e.g. password hashes, webhook secrets, local paths).
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiPageInfo struct {
+	// starts from 1.
+	PageNum int64 `json:"pageNum"`
+	PageSize int64 `json:"pageSize"`
+	// 0 when the total number of items is unknown (e.g. commit
+	// history); use `hasNextPage` instead.
+	TotalPage int64 `json:"totalPage"`
+	HasNextPage bool `json:"hasNextPage"`
+}
+
+func toAPIPageInfo(p *templates.PageInfoModel) *apiPageInfo {
+	return &apiPageInfo{
+		PageNum: p.PageNum,
+		PageSize: p.PageSize,
+		TotalPage: p.TotalPage,
+		HasNextPage: p.PageNum < p.TotalPage,
+	}
+}
+
+type apiList struct {
+	PageInfo *apiPageInfo `json:"pageInfo,omitempty"`
+	Items any `json:"items"`
+}
+
+type apiUser struct {
+	Name string `json:"name"`
+	Title string `json:"title"`
+	Email string `json:"email"`
+	Bio string `json:"bio"`
+	Website string `json:"website"`
+	RegisterTime int64 `json:"regTime"`
+}
+
+func toAPIUser(u *model.GitusUser) *apiUser {
+	return &apiUser{
+		Name: u.Name,
+		Title: u.Title,
+		Email: u.Email,
+		Bio: u.Bio,
+		Website: u.Website,
+		RegisterTime: u.RegisterTime,
+	}
+}
+
+type apiNamespace struct {
+	Name string `json:"name"`
+	Title string `json:"title"`
+	Description string `json:"description"`
+	Email string `json:"email"`
+	Owner string `json:"owner"`
+	RegisterTime int64 `json:"regTime"`
+	Status model.GitusNamespaceStatus `json:"status"`
+}
+
+func toAPINamespace(ns *model.Namespace) *apiNamespace {
+	return &apiNamespace{
+		Name: ns.Name,
+		Title: ns.Title,
+		Description: ns.Description,
+		Email: ns.Email,
+		Owner: ns.Owner,
+		RegisterTime: ns.RegisterTime,
+		Status: ns.Status,
+	}
+}
+
+type apiRepository struct {
+	Namespace string `json:"namespace"`
+	Name string `json:"name"`
+	FullName string `json:"fullName"`
+	Description string `json:"description"`
+	Owner string `json:"owner"`
+	Status model.GitusRepositoryStatus `json:"status"`
+	Type uint8 `json:"type"`
+	ForkOriginNamespace string `json:"forkOriginNamespace"`
+	ForkOriginName string `json:"forkOriginName"`
+	LabelList []string `json:"labelList"`
+}
+
+func toAPIRepository(repo *model.Repository) *apiRepository {
+	labelList := repo.RepoLabelList
+	if labelList == nil { labelList = make([]string, 0) }
+	return &apiRepository{
+		Namespace: repo.Namespace,
+		Name: repo.Name,
+		FullName: repo.FullName(),
+		Description: repo.Description,
+		Owner: repo.Owner,
+		Status: repo.Status,
+		Type: repo.Type,
+		ForkOriginNamespace: repo.ForkOriginNamespace,
+		ForkOriginName: repo.ForkOriginName,
+		LabelList: labelList,
+	}
+}
+
+type apiAuthorTime struct {
+	Name string `json:"name"`
+	Email string `json:"email"`
+	Time time.Time `json:"time"`
+}
+
+func toAPIAuthorTime(a gitlib.AuthorTime) *apiAuthorTime {
+	return &apiAuthorTime{
+		Name: a.AuthorName,
+		Email: a.AuthorEmail,
+		Time: a.Time,
+	}
+}
+
+type apiCommit struct {
+	Id string `json:"id"`
+	TreeId string `json:"treeId"`
+	ParentIdList []string `json:"parentIdList"`
+	Author *apiAuthorTime `json:"author"`
+	Committer *apiAuthorTime `json:"committer"`
+	CoAuthorList []*apiAuthorTime `json:"coAuthorList"`
+	Message string `json:"message"`
+	Signed bool `json:"signed"`
+}
+
+func toAPICommit(c *gitlib.CommitObject) *apiCommit {
+	coAuthorList := make([]*apiAuthorTime, 0)
+	for _, k := range c.CoAuthorInfo {
+		coAuthorList = append(coAuthorList, toAPIAuthorTime(k))
+	}
+	parentIdList := c.ParentIdList
+	if parentIdList == nil { parentIdList = make([]string, 0) }
+	return &apiCommit{
+		Id: c.Id,
+		TreeId: c.TreeObjId,
+		ParentIdList: parentIdList,
+		Author: toAPIAuthorTime(c.AuthorInfo),
+		Committer: toAPIAuthorTime(c.CommitterInfo),
+		CoAuthorList: coAuthorList,
+		Message: c.CommitMessage,
+		Signed: len(c.Signature) > 0,
+	}
+}
+
+type apiBranch struct {
+	Name string `json:"name"`
+	HeadId string `json:"headId"`
+}
+
+type apiTag struct {
+	Name string `json:"name"`
+	// the id of the tag object if the tag is annotated; the id of the
+	// tagged object (usually a commit) otherwise.
+	HeadId string `json:"headId"`
+	Annotated bool `json:"annotated"`
+	TaggedObjectId string `json:"taggedObjectId"`
+	TaggedObjectType string `json:"taggedObjectType"`
+	Tagger *apiAuthorTime `json:"tagger,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type apiTreeItem struct {
+	Name string `json:"name"`
+	Mode int `json:"mode"`
+	// one of "blob", "tree", "symlink" and "submodule".
+	Type string `json:"type"`
+	Id string `json:"id"`
+}
+
+func toAPITreeItem(item gitlib.TreeObjectItem) *apiTreeItem {
+	t := "blob"
+	switch item.Mode {
+	case gitlib.TREE_TREE_OBJECT: t = "tree"
+	case gitlib.TREE_SYMBOLIC_LINK: t = "symlink"
+	case gitlib.TREE_SUBMODULE: t = "submodule"
+	}
+	return &apiTreeItem{
+		Name: item.Name,
+		Mode: item.Mode,
+		Type: t,
+		Id: item.Hash,
+	}
+}
+
+type apiTree struct {
+	Id string `json:"id"`
+	CommitId string `json:"commitId"`
+	Path string `json:"path"`
+	Items []*apiTreeItem `json:"items"`
+}
+
+type apiBlob struct {
+	Id string `json:"id"`
+	CommitId string `json:"commitId"`
+	Path string `json:"path"`
+	Size int `json:"size"`
+	// base64-encoded.
+	Content []byte `json:"content"`
+}
+
+type apiIssue struct {
+	Id int `json:"id"`
+	Namespace string `json:"namespace"`
+	Name string `json:"name"`
+	Author string `json:"author"`
+	Title string `json:"title"`
+	Content string `json:"content"`
+	Time int64 `json:"time"`
+	// 1 - open, 2 - closed as solved, 3 - closed as discarded.
+	Status int `json:"status"`
+	// >0 means pinned.
+	Priority int `json:"priority"`
+	Label []string `json:"label"`
+	Assignee []string `json:"assignee"`
+	// empty if the issue isn't in any milestone.
+	Milestone string `json:"milestone"`
+	// only set when retrieving a single issue.
+	EventList []*apiIssueEvent `json:"eventList,omitempty"`
+}
+
+func toAPIIssue(i *model.Issue) *apiIssue {
+	return &apiIssue{
+		Id: i.IssueId,
+		Namespace: i.RepoNamespace,
+		Name: i.RepoName,
+		Author: i.IssueAuthor,
+		Title: i.IssueTitle,
+		Content: i.IssueContent,
+		Time: i.IssueTime,
+		Status: i.IssueStatus,
+		Priority: i.IssuePriority,
+		Label: i.IssueLabel,
+		Assignee: i.IssueAssignee,
+		Milestone: i.IssueMilestone,
+	}
+}
+
+type apiIssueEvent struct {
+	// 1 - comment, 2 - closed as solved, 3 - closed as discarded,
+	// 4 - reopened, 5/6 - label added/removed, 7/8 - assignee
+	// added/removed, 9/10 - milestone set/removed, 11/12/13 -
+	// referenced by a commit/issue/pull request. for 5~10 the
+	// content is the label, the username or the milestone title; for
+	// 11~13 it's the json of `model.IssueReferenceSource`.
+	Type int `json:"type"`
+	Time int64 `json:"time"`
+	Author string `json:"author"`
+	Content string `json:"content"`
+}
+
+func toAPIIssueEvent(e *model.IssueEvent) *apiIssueEvent {
+	return &apiIssueEvent{
+		Type: e.EventType,
+		Time: e.EventTimestamp,
+		Author: e.EventAuthor,
+		Content: e.EventContent,
+	}
+}
+
+type apiMergeCheckResult struct {
+	Successful bool `json:"success"`
+	Time int64 `json:"time"`
+	FileInfo []gitlib.MergeCheckConflictedFileInfo `json:"fileInfo"`
+	Message []gitlib.MergeCheckInformationalMessage `json:"msg"`
+}
+
+type apiPullRequest struct {
+	Id int64 `json:"id"`
+	Title string `json:"title"`
+	Author string `json:"author"`
+	Time int64 `json:"time"`
+	ReceiverNamespace string `json:"receiverNamespace"`
+	ReceiverName string `json:"receiverName"`
+	ReceiverBranch string `json:"receiverBranch"`
+	ProviderNamespace string `json:"providerNamespace"`
+	ProviderName string `json:"providerName"`
+	ProviderBranch string `json:"providerBranch"`
+	// 1 - open, 2 - merged, 3 - closed as not merged.
+	Status int `json:"status"`
+	// nil if the merge check is never performed.
+	MergeCheckResult *apiMergeCheckResult `json:"mergeCheckResult"`
+}
+
+func toAPIPullRequest(pr *model.PullRequest) *apiPullRequest {
+	var mcr *apiMergeCheckResult = nil
+	if pr.MergeCheckResult != nil && pr.MergeCheckTimestamp > 0 {
+		mcr = &apiMergeCheckResult{
+			Successful: pr.MergeCheckResult.Successful,
+			Time: pr.MergeCheckTimestamp,
+			FileInfo: pr.MergeCheckResult.FileInfo,
+			Message: pr.MergeCheckResult.Message,
+		}
+	}
+	return &apiPullRequest{
+		Id: pr.PRId,
+		Title: pr.Title,
+		Author: pr.Author,
+		Time: pr.Timestamp,
+		ReceiverNamespace: pr.ReceiverNamespace,
+		ReceiverName: pr.ReceiverName,
+		ReceiverBranch: pr.ReceiverBranch,
+		ProviderNamespace: pr.ProviderNamespace,
+		ProviderName: pr.ProviderName,
+		ProviderBranch: pr.ProviderBranch,
+		Status: pr.Status,
+		MergeCheckResult: mcr,
+	}
+}
+
+type apiPullRequestEvent struct {
+	// see `model.PullRequestEvent`.
+	Type int `json:"type"`
+	Time int64 `json:"time"`
+	Author string `json:"author"`
+	Content string `json:"content"`
+}
+
+func toAPIPullRequestEvent(e *model.PullRequestEvent) *apiPullRequestEvent {
+	return &apiPullRequestEvent{
+		Type: e.EventType,
+		Time: e.EventTimestamp,
+		Author: e.EventAuthor,
+		Content: e.EventContent,
+	}
+}
+
+
+type apiPullRequestReview struct {
+	Reviewer string `json:"reviewer"`
+	// see `model.PullRequestReview`.
+	State int `json:"state"`
+	Summary string `json:"summary"`
+	CommitId string `json:"commitId"`
+	Time int64 `json:"time"`
+	Dismissed bool `json:"dismissed"`
+}
+
+func toAPIPullRequestReview(r *model.PullRequestReview) *apiPullRequestReview {
+	return &apiPullRequestReview{
+		Reviewer: r.Reviewer,
+		State: r.State,
+		Summary: r.Summary,
+		CommitId: r.CommitId,
+		Time: r.Timestamp,
+		Dismissed: r.Dismissed,
+	}
+}
+
+type apiCommitStatus struct {
+	Context string `json:"context"`
+	// see `model.COMMIT_STATUS_*`.
+	State string `json:"state"`
+	TargetURL string `json:"targetUrl"`
+	Description string `json:"description"`
+	Creator string `json:"creator"`
+	CreateTime int64 `json:"createTime"`
+	UpdateTime int64 `json:"updateTime"`
+}
+
+func toAPICommitStatus(s *model.CommitStatus) *apiCommitStatus {
+	return &apiCommitStatus{
+		Context: s.Context,
+		State: s.State,
+		TargetURL: s.TargetURL,
+		Description: s.Description,
+		Creator: s.Creator,
+		CreateTime: s.CreateTime,
+		UpdateTime: s.UpdateTime,
+	}
+}
+
+type apiCombinedCommitStatus struct {
+	CommitId string `json:"commitId"`
+	// see `model.CombineCommitStatus`; empty if there's no status.
+	State string `json:"state"`
+	Statuses []*apiCommitStatus `json:"statuses"`
+}
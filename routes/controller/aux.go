@@ -3,6 +3,7 @@ package controller
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
 	"fmt"
 	"html"
 	"math/rand/v2"
@@ -16,6 +17,7 @@ import (
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
 	"github.com/GitusCodeForge/Gitus/routes"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -246,6 +248,17 @@ func newConfirmCode() string {
 	return string(res)
 }
 
+// the relying party id & the origin used for webauthn; both come
+// from the http host name in the config since the browser checks them
+// against the address of the page.
+func webAuthnRelyingParty(cfg *gitus.GitusConfig) (string, string, error) {
+	u, err := url.Parse(cfg.ProperHTTPHostName())
+	if err != nil || len(u.Hostname()) <= 0 {
+		return "", "", errors.New("HTTP host name is not configured")
+	}
+	return u.Hostname(), u.Scheme + "://" + u.Host, nil
+}
+
 func getQueryPath(s string) (string, error) {
 	u, err := url.Parse(s)
 	if err != nil { return "", err }
@@ -108,6 +108,7 @@ func bindBranchController(ctx *RouterContext) {
 				RootPath: fmt.Sprintf("/repo/%s", rfn),
 				Commit: cobj,
 				EmailUserMapping: m,
+				StatusList: GetCommitStatusList(rc, repo, cobj.Id),
 			}
 			gobj, err = rr.ReadObject(cobj.TreeObjId)
 			if err != nil { rc.ReportInternalError(err.Error(), w, r) }
@@ -436,6 +437,25 @@ func bindBranchController(ctx *RouterContext) {
 					"Your account doesn't have enough privilege to perform this action.",
 					w, r,
 				)
+				return
+			}
+			// edits made here are pushes as well, so branch
+			// protection rules apply. see docs/protected-branch.org.
+			ruleList, err := rc.DatabaseInterface.GetAllBranchProtectionRule(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve branch protection rules: %s", err), w, r)
+				return
+			}
+			err = model.CheckBranchUpdate(ruleList, rc.LoginInfo.UserName, r.PathValue("branchName"), model.BRANCH_UPDATE_PUSH)
+			if err != nil {
+				rc.ReportRedirect(
+					fmt.Sprintf("/repo/%s/branch/%s/%s", rfn, r.PathValue("branchName"), r.PathValue("treePath")),
+					5,
+					"Protected Branch",
+					err.Error(),
+					w, r,
+				)
+				return
 			}
 			// we have to handle the upload-file case carefully since the
 			// file could be big and i do not wish to read a big file into
@@ -495,6 +515,12 @@ func bindBranchController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed to update ref: %s; %s", err.Error(), stderrBuf.String()), w, r)
 				return
 			}
+			prList, err := rc.DatabaseInterface.UpdatePullRequestProviderBranch(repo.Namespace, repo.Name, branchName, rc.LoginInfo.UserName, commitId)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update pull requests from this branch: %s", err), w, r)
+				return
+			}
+			TriggerPullRequestUpdatedWebhook(rc, prList, rc.LoginInfo.UserName, commitId)
 			rc.ReportRedirect(fmt.Sprintf("/repo/%s/branch/%s/%s", rfn, branchName, r.PathValue("treePath")), 5, "Updated", "Your edit has been saved to the repository.", w, r)
 		},
 	))
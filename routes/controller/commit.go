@@ -94,6 +94,7 @@ func bindCommitController(ctx *RouterContext) {
 				RootPath: fmt.Sprintf("/repo/%s", rfn),
 				Commit: cobj,
 				EmailUserMapping: m,
+				StatusList: GetCommitStatusList(rc, repo, cobj.Id),
 			}
 			gobj, err = rr.ReadObject(cobj.TreeObjId)
 			if err != nil { rc.ReportInternalError(err.Error(), w, r) }
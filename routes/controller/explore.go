@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// repositories sorted by push activity, stars or creation time. see
+// docs/explore.org.
+func bindExploreController(ctx *RouterContext) {
+	http.HandleFunc("GET /explore", UseMiddleware(
+		[]Middleware{Logged, UseLoginInfo, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			sortBy := r.URL.Query().Get("sort")
+			if !model.ValidRepositoryExploreSort(sortBy) {
+				sortBy = model.REPO_EXPLORE_SORT_ACTIVITY
+			}
+			// admins see the same list as everyone else here; the
+			// full list is at /all/repo.
+			repolCount, err := rc.DatabaseInterface.CountAllVisibleRepositories(rc.LoginInfo.UserName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			pageInfo, err := GeneratePageInfo(r, repolCount)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			repol, err := rc.DatabaseInterface.GetAllVisibleRepositoryExplorePaginated(rc.LoginInfo.UserName, sortBy, pageInfo.PageNum-1, pageInfo.PageSize)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("all/explore").Execute(w, &templates.ExploreTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				RepositoryList: repol,
+				PageInfo: pageInfo,
+				Sort: sortBy,
+			}))
+		},
+	))
+}
@@ -10,10 +10,12 @@ import (
 	"path"
 
 	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
 	"github.com/GitusCodeForge/Gitus/routes"
 	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
 )
 
 func printGitError(w io.Writer, s string) {
@@ -26,93 +28,198 @@ func printGitError(w io.Writer, s string) {
 // HEAD
 // objects/
 
-// NOTE THAT this route handles public http read-only clone, thus it
-// will report 404 for all private repositories.
+// NOTE THAT this route handles http read-only clone. guests can only
+// clone public & archived repositories; private repositories (and
+// instances in global private mode) require the client to
+// authenticate w/ http basic auth, in which case the same visibility
+// rules as the web ui apply. pushing thru http is handled in
+// http-push.go.
+
+// authenticates the visitor (if credentials are provided) & checks
+// global visibility. returns nil if any of the checks fails, in which
+// case the response is already written.
+func resolveHTTPCloneLoginInfo(ctx *RouterContext, w http.ResponseWriter, r *http.Request) *templates.LoginInfoModel {
+	loginInfo := &templates.LoginInfoModel{ LoggedIn: false }
+	isNormalMode := ctx.Config.OperationMode == gitus.OP_MODE_NORMAL
+	_, _, hasCredential := r.BasicAuth()
+	if isNormalMode && hasCredential {
+		user, err := resolveHTTPBasicAuthUser(ctx, r, model.TOKEN_SCOPE_REPO_READ)
+		if err == errHTTPAccessTokenRequired {
+			requireHTTPAccessToken(ctx, w)
+			return nil
+		}
+		if err != nil {
+			w.WriteHeader(500)
+			fmt.Fprintf(w, "Failed while authenticating: %s", err)
+			return nil
+		}
+		if user == nil {
+			requireHTTPBasicAuth(ctx, w)
+			return nil
+		}
+		loginInfo = &templates.LoginInfoModel{
+			LoggedIn: true,
+			UserName: user.Name,
+			UserFullName: user.Title,
+			UserEmail: user.Email,
+			IsAdmin: (user.Status == model.ADMIN || user.Status == model.SUPER_ADMIN) && !AdminSecondFactorRequired(ctx, user),
+			IsSuperAdmin: user.Status == model.SUPER_ADMIN && !AdminSecondFactorRequired(ctx, user),
+		}
+	}
+	loggedIn := loginInfo.LoggedIn
+	if !CheckGlobalVisibleToUser(ctx, loginInfo) {
+		if isNormalMode && !loggedIn && ctx.Config.GlobalVisibility == gitus.GLOBAL_VISIBILITY_PRIVATE {
+			requireHTTPBasicAuth(ctx, w)
+			return nil
+		}
+		w.WriteHeader(403)
+		fmt.Fprint(w, "Service not available right now.")
+		return nil
+	}
+	return loginInfo
+}
+
+// resolves & checks the repository for cloning. returns nil if any
+// of the checks fails, in which case the response is already written.
+func resolveHTTPCloneTarget(ctx *RouterContext, w http.ResponseWriter, r *http.Request) *model.Repository {
+	loginInfo := resolveHTTPCloneLoginInfo(ctx, w, r)
+	if loginInfo == nil { return nil }
+	isNormalMode := ctx.Config.OperationMode == gitus.OP_MODE_NORMAL
+	loggedIn := loginInfo.LoggedIn
+	rfn := r.PathValue("repoName")
+	if !model.ValidRepositoryName(rfn) {
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Repository not found.")
+		return nil
+	}
+	_, _, ns, repo, err := ctx.ResolveRepositoryFullName(rfn)
+	if err == routes.ErrNotFound || err == db.ErrEntityNotFound {
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Repository not found.")
+		return nil
+	}
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "Failed to resolve repository: %s", err)
+		return nil
+	}
+	if repo.Type != model.REPO_TYPE_GIT {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "Repository not Git.")
+		return nil
+	}
+	// in plain mode everything that's not ignored is public.
+	if !ctx.Config.IsInPlainMode() && !CheckRepositoryVisibleToUser(loginInfo, ns, repo) {
+		// ask for credential if the visitor is a guest so that git
+		// would prompt for username & password; we report 404
+		// otherwise since we don't want to leak the existence of
+		// repositories the user can't see.
+		if isNormalMode && !loggedIn {
+			requireHTTPBasicAuth(ctx, w)
+			return nil
+		}
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Repository not found.")
+		return nil
+	}
+	return repo
+}
+
+// serves `{p}/info/{...}` of the git directory `gitDir`. both v2
+// (`?service=git-upload-pack`) & v1-dumb are handled here.
+func serveHTTPCloneInfo(ctx *RouterContext, w http.ResponseWriter, r *http.Request, gitDir string) {
+	allowV2 := ctx.Config.GitConfig.HTTPCloneProtocol.V2
+	allowV1Dumb := ctx.Config.GitConfig.HTTPCloneProtocol.V1Dumb
+	// see docs/http-clone.org.
+	if (r.URL.Query().Has("service") && allowV2) {
+		switch r.URL.Query().Get("service") {
+		case "git-upload-pack":
+			cmd := exec.Command("git", "upload-pack", gitDir, "--http-backend-info-refs")
+			cmd.Dir = gitDir
+			protocol := r.Header.Get("Git-Protocol")
+			if protocol == "" { protocol = "version=2" }
+			cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_PROTOCOL=%s", protocol))
+			stdout := new(bytes.Buffer)
+			cmd.Stdout = stdout
+			err := cmd.Run()
+			if err != nil {
+				w.WriteHeader(500)
+				printGitError(w, err.Error())
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+			w.WriteHeader(200)
+			fmt.Fprint(w, "001e# service=git-upload-pack\n")
+			fmt.Fprint(w, "0000")
+			w.Write(stdout.Bytes())
+		default:
+			w.WriteHeader(403)
+			printGitError(w, "Not supported service.")
+		}
+		return
+	}
+	// v1-dumb
+	if !allowV1Dumb {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "v1-dumb protocl not supported on this instance.")
+		return
+	}
+	p := path.Join(gitDir, "info", r.PathValue("p"))
+	s, err := os.ReadFile(p)
+	if err != nil {
+		ctx.ReportInternalError("Fail to read info/refs", w, r)
+		return
+	}
+	w.Write(s)
+}
+
+// serves `{p}/git-upload-pack` of the git directory `gitDir`.
+func serveHTTPCloneUploadPack(ctx *RouterContext, w http.ResponseWriter, r *http.Request, gitDir string) {
+	w.Header().Set("Content-Type", "application/x-git-upload-pack-response")
+	w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+	w.WriteHeader(200)
+	cmd := exec.Command("git", "upload-pack", gitDir, "--stateless-rpc")
+	cmd.Stdin = r.Body
+	protocol := r.Header.Get("Git-Protocol")
+	if protocol == "" { protocol = "version=2" }
+	cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_PROTOCOL=%s", protocol))
+	buf := new(bytes.Buffer)
+	cmd.Stdout = buf
+	cmd.Run()
+	io.Copy(w, buf)
+}
+
+// serves a plain file (`HEAD` or things under `objects/`) for v1-dumb.
+func serveHTTPCloneFile(ctx *RouterContext, w http.ResponseWriter, r *http.Request, p string) {
+	s, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		ctx.ReportNotFound(r.URL.Path, "object", ctx.Config.DepotName, w, r)
+		return
+	}
+	if err != nil {
+		ctx.ReportInternalError("Fail to read info/refs", w, r)
+		return
+	}
+	w.Write(s)
+}
 
 func bindHttpCloneController(ctx *RouterContext) {
 	http.HandleFunc("GET /repo/{repoName}/info/{p...}", UseMiddleware(
 		[]Middleware{ Logged }, ctx,
 		func(ctx *routes.RouterContext, w http.ResponseWriter, r *http.Request) {
-			allowV2 := ctx.Config.GitConfig.HTTPCloneProtocol.V2
-			allowV1Dumb := ctx.Config.GitConfig.HTTPCloneProtocol.V1Dumb
-			if !allowV1Dumb && !allowV2 {
-				w.WriteHeader(403)
-				fmt.Fprint(w, "HTTP clone not supported on this instance")
-				return
-			}
-			if ctx.Config.GlobalVisibility != gitus.GLOBAL_VISIBILITY_PUBLIC {
-				ctx.ReportForbidden("", w, r)
-				return
-			}
-			rfn := r.PathValue("repoName")
-			if !model.ValidRepositoryName(rfn) {
-				ctx.ReportNotFound(rfn, "Repository", "Depot", w, r)
+			if r.URL.Query().Get("service") == "git-receive-pack" {
+				handleHTTPReceivePackInfoRefs(ctx, w, r)
 				return
 			}
-			_, _, ns, repo, err := ctx.ResolveRepositoryFullName(rfn)
-			if err == routes.ErrNotFound {
-				ctx.ReportNotFound(rfn, "Repository", "Depot", w, r)
-				return
-			}
-			if err != nil {
-				ctx.ReportInternalError(err.Error(), w, r)
-				return
-			}
-			if repo.Type != model.REPO_TYPE_GIT {
-				ctx.ReportNormalError("The repository you have requested isn't a Git repository.", w, r)
-				return
-			}
-			isNamespacePublic := ns.Status == model.NAMESPACE_NORMAL_PUBLIC
-			isRepoPublic := repo.Status == model.REPO_NORMAL_PUBLIC
-			isRepoArchived := repo.Status == model.REPO_ARCHIVED
-			if !isNamespacePublic || !(isRepoPublic || isRepoArchived) {
-				w.WriteHeader(404)
-				fmt.Fprint(w, "404 Not Found")
-				return
-			}
-			// see docs/http-clone.org.
-			if (r.URL.Query().Has("service") && allowV2) {
-				switch r.URL.Query().Get("service") {
-				case "git-upload-pack":
-					cmd := exec.Command("git", "upload-pack", repo.LocalPath, "--http-backend-info-refs")
-					cmd.Dir = repo.LocalPath
-					protocol := r.Header.Get("Git-Protocol")
-					if protocol == "" { protocol = "version=2" }
-					cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_PROTOCOL=%s", protocol))
-					stdout := new(bytes.Buffer)
-					cmd.Stdout = stdout
-					err := cmd.Run()
-					if err != nil {
-						w.WriteHeader(500)
-						printGitError(w, err.Error())
-						return
-					}
-					w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
-					w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
-					w.WriteHeader(200)
-					fmt.Fprint(w, "001e# service=git-upload-pack\n")
-					fmt.Fprint(w, "0000")
-					w.Write(stdout.Bytes())
-				default:
-					w.WriteHeader(403)
-					printGitError(w, "Not supported service.")
-				}
-				return
-			}
-			// v1-dumb
-			if !allowV1Dumb {
+			if !ctx.Config.GitConfig.HTTPCloneProtocol.V1Dumb && !ctx.Config.GitConfig.HTTPCloneProtocol.V2 {
 				w.WriteHeader(403)
-				fmt.Fprint(w, "v1-dumb protocl not supported on this instance.")
-				return
-			}
-			rr := repo.Repository.(*gitlib.LocalGitRepository)
-			p := path.Join(rr.GitDirectoryPath, "info", r.PathValue("p"))
-			s, err := os.ReadFile(p)
-			if err != nil {
-				ctx.ReportInternalError("Fail to read info/refs", w, r)
+				fmt.Fprint(w, "HTTP clone not supported on this instance")
 				return
 			}
-			w.Write(s)
+			repo := resolveHTTPCloneTarget(ctx, w, r)
+			if repo == nil { return }
+			serveHTTPCloneInfo(ctx, w, r, repo.LocalPath)
 		}))
 	http.HandleFunc("POST /repo/{repoName}/git-upload-pack", UseMiddleware(
 		[]Middleware{ Logged }, ctx,
@@ -122,140 +229,24 @@ func bindHttpCloneController(ctx *RouterContext) {
 				fmt.Fprint(w, "v2 protocl not supported on this instance.")
 				return
 			}
-			if ctx.Config.GlobalVisibility != gitus.GLOBAL_VISIBILITY_PUBLIC {
-				w.WriteHeader(403)
-				w.Write([]byte("Service not available right now."))
-				return
-			}
-			rfn := r.PathValue("repoName")
-			if !model.ValidRepositoryName(rfn) {
-				w.WriteHeader(404)
-				w.Write([]byte("Repository not found."))
-				return
-			}
-			_, _, ns, repo, err := ctx.ResolveRepositoryFullName(rfn)
-			if err == routes.ErrNotFound {
-				w.WriteHeader(404)
-				w.Write([]byte("Repository not found."))
-				return
-			}
-			if err != nil {
-				ctx.ReportInternalError(err.Error(), w, r)
-				return
-			}
-			if repo.Type != model.REPO_TYPE_GIT {
-				w.WriteHeader(403)
-				w.Write([]byte("Repository not Git."))
-				return
-			}
-			isNamespacePublic := ns.Status == model.NAMESPACE_NORMAL_PUBLIC
-			isRepoPublic := repo.Status == model.REPO_NORMAL_PUBLIC
-			isRepoArchived := repo.Status == model.REPO_ARCHIVED
-			if !isNamespacePublic || !(isRepoPublic || isRepoArchived) {
-				w.WriteHeader(404)
-				w.Write([]byte("404 Not Found"))
-				return
-			}
-			w.Header().Set("Content-Type", "application/x-git-upload-pack-response")
-			w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
-			w.WriteHeader(200)
-			cmd := exec.Command("git", "upload-pack", repo.LocalPath, "--stateless-rpc")
-			cmd.Stdin = r.Body
-			protocol := r.Header.Get("Git-Protocol")
-			if protocol == "" { protocol = "version=2" }
-			cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_PROTOCOL=%s", protocol))
-			buf := new(bytes.Buffer)
-			cmd.Stdout = buf
-			cmd.Run()
-			io.Copy(w, buf)
+			repo := resolveHTTPCloneTarget(ctx, w, r)
+			if repo == nil { return }
+			serveHTTPCloneUploadPack(ctx, w, r, repo.LocalPath)
 		}))
 	http.HandleFunc("GET /repo/{repoName}/HEAD", UseMiddleware(
 		[]Middleware{ Logged }, ctx,
 		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
-			if ctx.Config.GlobalVisibility != gitus.GLOBAL_VISIBILITY_PUBLIC {
-				ctx.ReportForbidden("", w, r)
-				return
-			}
-			rfn := r.PathValue("repoName")
-			if !model.ValidRepositoryName(rfn) {
-				ctx.ReportNotFound(rfn, "Repository", "Depot", w, r)
-				return
-			}
-			_, _, ns, repo, err := ctx.ResolveRepositoryFullName(rfn)
-			if err == routes.ErrNotFound {
-				ctx.ReportNotFound(rfn, "Repository", "Depot", w, r)
-				return
-			}
-			if err != nil {
-				ctx.ReportInternalError(err.Error(), w, r)
-				return
-			}
-			if repo.Type != model.REPO_TYPE_GIT {
-				ctx.ReportNormalError("The repository you have requested isn't a Git repository.", w, r)
-				return
-			}
-			isNamespacePublic := ns.Status == model.NAMESPACE_NORMAL_PUBLIC
-			isRepoPublic := repo.Status == model.REPO_NORMAL_PUBLIC
-			isRepoArchived := repo.Status == model.REPO_ARCHIVED
-			if !isNamespacePublic || !(isRepoPublic || isRepoArchived) {
-				w.WriteHeader(404)
-				w.Write([]byte("404 Not Found"))
-				return
-			}
+			repo := resolveHTTPCloneTarget(ctx, w, r)
+			if repo == nil { return }
 			rr := repo.Repository.(*gitlib.LocalGitRepository)
-			p := path.Join(rr.GitDirectoryPath, "HEAD")
-			s, err := os.ReadFile(p)
-			if err != nil {
-				ctx.ReportInternalError("Fail to read info/refs", w, r)
-				return
-			}
-			w.Write(s)
+			serveHTTPCloneFile(ctx, w, r, path.Join(rr.GitDirectoryPath, "HEAD"))
 		}))
 	http.HandleFunc("GET /repo/{repoName}/objects/{obj...}", UseMiddleware(
 		[]Middleware{ Logged }, ctx,
 		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
-			if ctx.Config.GlobalVisibility != gitus.GLOBAL_VISIBILITY_PUBLIC {
-				ctx.ReportForbidden("", w, r)
-				return
-			}
-			rfn := r.PathValue("repoName")
-			if !model.ValidRepositoryName(rfn) {
-				ctx.ReportNotFound(rfn, "Repository", "Depot", w, r)
-				return
-			}
-			_, _, ns, repo, err := ctx.ResolveRepositoryFullName(rfn)
-			if err == routes.ErrNotFound {
-				ctx.ReportNotFound(rfn, "Repository", "Depot", w, r)
-				return
-			}
-			if err != nil {
-				ctx.ReportInternalError(err.Error(), w, r)
-				return
-			}
-			if repo.Type != model.REPO_TYPE_GIT {
-				ctx.ReportNormalError("The repository you have requested isn't a Git repository.", w, r)
-				return
-			}
-			isNamespacePublic := ns.Status == model.NAMESPACE_NORMAL_PUBLIC
-			isRepoPublic := repo.Status == model.REPO_NORMAL_PUBLIC
-			isRepoArchived := repo.Status == model.REPO_ARCHIVED
-			if !isNamespacePublic || !(isRepoPublic || isRepoArchived) {
-				w.WriteHeader(404)
-				w.Write([]byte("404 Not Found"))
-				return
-			}
-			obj := r.PathValue("obj")
+			repo := resolveHTTPCloneTarget(ctx, w, r)
+			if repo == nil { return }
 			rr := repo.Repository.(*gitlib.LocalGitRepository)
-			p := path.Join(rr.GitDirectoryPath, "objects", obj)
-			s, err := os.ReadFile(p)
-			if os.IsNotExist(err) {
-				ctx.ReportNotFound(rfn, "object", ctx.Config.DepotName, w, r)
-				return
-			}
-			if err != nil {
-				ctx.ReportInternalError("Fail to read info/refs", w, r)
-				return
-			}
-			w.Write(s)
+			serveHTTPCloneFile(ctx, w, r, path.Join(rr.GitDirectoryPath, "objects", r.PathValue("obj")))
 		}))
 }
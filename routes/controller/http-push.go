@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/routes"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// push thru http (git-receive-pack). see docs/http-clone.org.
+//
+// unlike http clone, pushing always requires the client to
+// authenticate itself with http basic auth. the permission checks are
+// the same as the ones performed in `HandleSSHLogin` (in
+// `cmd/gitus/ssh.go`).
+
+func requireHTTPBasicAuth(ctx *RouterContext, w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\", charset=\"UTF-8\"", basicStringEscape(ctx.Config.DepotName)))
+	w.WriteHeader(401)
+	fmt.Fprint(w, "Authentication required.")
+}
+
+// returned by `resolveHTTPBasicAuthUser` when the password of a user
+// w/ a second factor is used.
+var errHTTPAccessTokenRequired = errors.New("access token required")
+
+func requireHTTPAccessToken(ctx *RouterContext, w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\", charset=\"UTF-8\"", basicStringEscape(ctx.Config.DepotName)))
+	w.WriteHeader(401)
+	fmt.Fprint(w, "This account has two-factor authentication enabled; please use a personal access token instead of the password.")
+}
+
+// resolve the user from the http basic auth header. returns nil
+// (with nil error) when the client didn't provide any credential or
+// the credential is invalid, in which case the caller should ask the
+// client to authenticate (again).
+//
+// a personal access token can be used in place of the password, in
+// which case the token must be owned by the user and must have the
+// scope `scope`. see docs/access-token.org. users w/ a second factor
+// can only use access tokens, since the password alone would skip
+// the second factor; `errHTTPAccessTokenRequired` is returned if
+// their password is used.
+func resolveHTTPBasicAuthUser(ctx *RouterContext, r *http.Request, scope string) (*model.GitusUser, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok { return nil, nil }
+	if !model.ValidUserName(username) { return nil, nil }
+	if strings.HasPrefix(password, model.ACCESS_TOKEN_PREFIX) {
+		t, u, err := ResolveAccessToken(ctx, password)
+		if err != nil { return nil, err }
+		if t != nil && u != nil && u.Name == username && t.HasScope(scope) {
+			return u, nil
+		}
+		// fall thru - the password itself may just happen to start
+		// with the prefix.
+	}
+	u, err := ctx.DatabaseInterface.GetUserByName(username)
+	if err == db.ErrEntityNotFound { return nil, nil }
+	if err != nil { return nil, err }
+	switch u.Status {
+	case model.BANNED: fallthrough
+	case model.NORMAL_USER_APPROVAL_NEEDED: fallthrough
+	case model.NORMAL_USER_CONFIRM_NEEDED:
+		return nil, nil
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword { return nil, nil }
+	if err != nil { return nil, err }
+	if u.HasSecondFactorConfigured() { return nil, errHTTPAccessTokenRequired }
+	return u, nil
+}
+
+// resolves & checks the repository for pushing. returns nil if any of
+// the checks fails, in which case the response is already written.
+func resolveHTTPPushTarget(ctx *RouterContext, w http.ResponseWriter, r *http.Request) (*model.GitusUser, *model.Repository) {
+	if !ctx.Config.GitConfig.HTTPPush || !ctx.Config.GitConfig.HTTPCloneProtocol.V2 {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "HTTP push not supported on this instance.")
+		return nil, nil
+	}
+	if ctx.Config.OperationMode != gitus.OP_MODE_NORMAL {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "HTTP push not supported on this instance.")
+		return nil, nil
+	}
+	if ctx.Config.GlobalVisibility != gitus.GLOBAL_VISIBILITY_PUBLIC &&
+		ctx.Config.GlobalVisibility != gitus.GLOBAL_VISIBILITY_PRIVATE {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "Service not available right now.")
+		return nil, nil
+	}
+	user, err := resolveHTTPBasicAuthUser(ctx, r, model.TOKEN_SCOPE_REPO_WRITE)
+	if err == errHTTPAccessTokenRequired {
+		requireHTTPAccessToken(ctx, w)
+		return nil, nil
+	}
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "Failed while authenticating: %s", err)
+		return nil, nil
+	}
+	if user == nil {
+		requireHTTPBasicAuth(ctx, w)
+		return nil, nil
+	}
+	rfn := r.PathValue("repoName")
+	if !model.ValidRepositoryName(rfn) {
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Repository not found.")
+		return nil, nil
+	}
+	_, _, ns, repo, err := ctx.ResolveRepositoryFullName(rfn)
+	if err == routes.ErrNotFound || err == db.ErrEntityNotFound {
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Repository not found.")
+		return nil, nil
+	}
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "Failed to resolve repository: %s", err)
+		return nil, nil
+	}
+	if repo.Type != model.REPO_TYPE_GIT {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "Repository not Git.")
+		return nil, nil
+	}
+	if !CheckUserPushPermission(user.Name, ns, repo) {
+		// we don't want to leak the existence of repositories the
+		// user can't see, so this is reported as not found.
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Repository not found.")
+		return nil, nil
+	}
+	if repo.Status == model.REPO_ARCHIVED {
+		w.WriteHeader(403)
+		fmt.Fprintf(w, "The repository %s is ARCHIVED; no push to remote is allowed.", repo.FullName())
+		return nil, nil
+	}
+	return user, repo
+}
+
+func handleHTTPReceivePackInfoRefs(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
+	user, repo := resolveHTTPPushTarget(ctx, w, r)
+	if user == nil || repo == nil { return }
+	cmd := exec.Command("git", "receive-pack", "--http-backend-info-refs", repo.LocalPath)
+	cmd.Dir = repo.LocalPath
+	stdout := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	err := cmd.Run()
+	if err != nil {
+		w.WriteHeader(500)
+		printGitError(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-git-receive-pack-advertisement")
+	w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+	w.WriteHeader(200)
+	fmt.Fprint(w, "001f# service=git-receive-pack\n")
+	fmt.Fprint(w, "0000")
+	w.Write(stdout.Bytes())
+}
+
+func bindHttpPushController(ctx *RouterContext) {
+	http.HandleFunc("POST /repo/{repoName}/git-receive-pack", UseMiddleware(
+		[]Middleware{ Logged }, ctx,
+		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
+			user, repo := resolveHTTPPushTarget(ctx, w, r)
+			if user == nil || repo == nil { return }
+			var body io.Reader = r.Body
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				gr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					w.WriteHeader(400)
+					fmt.Fprintf(w, "Failed to decompress request body: %s", err)
+					return
+				}
+				defer gr.Close()
+				body = gr
+			}
+			// the gitus hook dispatchers (see docs/hooks.org) need to
+			// know who is pushing.
+			env, err := PreparePushEnvironment(ctx, user.Name, repo)
+			if err != nil {
+				w.WriteHeader(500)
+				printGitError(w, fmt.Sprintf("Failed while preparing push: %s", err))
+				return
+			}
+			cmd := exec.Command("git", "receive-pack", "--stateless-rpc", repo.LocalPath)
+			cmd.Dir = repo.LocalPath
+			cmd.Stdin = body
+			// the hooks spawned by receive-pack need the environment
+			// (e.g. PATH) to function.
+			cmd.Env = append(os.Environ(), env...)
+			buf := new(bytes.Buffer)
+			cmd.Stdout = buf
+			// errors are reported to the client thru the output of
+			// receive-pack itself; we only log it here.
+			LogIfError(cmd.Run())
+			w.Header().Set("Content-Type", "application/x-git-receive-pack-result")
+			w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+			w.WriteHeader(200)
+			io.Copy(w, buf)
+		}))
+}
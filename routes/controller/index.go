@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitus"
 	. "github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/templates"
 	"github.com/gomarkdown/markdown"
@@ -47,6 +48,13 @@ func bindIndexController(ctx *RouterContext) {
 				FoundAt(w, "/all/namespace")
 			} else if rc.Config.FrontPage.Type == "all/repository" {
 				FoundAt(w, "/all/repo")
+			} else if rc.Config.FrontPage.Type == "explore" {
+				// the explore page needs the database.
+				if rc.Config.OperationMode == gitus.OP_MODE_NORMAL {
+					FoundAt(w, "/explore")
+				} else {
+					FoundAt(w, "/all/repo")
+				}
 			} else if rc.Config.FrontPage.Type == "namespace" {
 				if !rc.Config.UseNamespace {
 					frontPageHtml := "<p>Misconfiguration: a namespace is used for the front page, but the depot itself is configured to not support namespaces. Please contact the site owner about this issue.</p>"
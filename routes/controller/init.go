@@ -4,6 +4,7 @@ import (
 	"github.com/GitusCodeForge/Gitus/pkg/gitus"
 	"github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/routes/controller/admin"
+	"github.com/GitusCodeForge/Gitus/routes/controller/api"
 )
 
 func InitializeRoute(context *routes.RouterContext) {
@@ -20,6 +21,7 @@ func InitializeRoute(context *routes.RouterContext) {
 	bindTreeHandler(context)
 	bindAllController(context)
 	bindHttpCloneController(context)
+	bindHttpPushController(context)
 	bindShutdownNoticeController(context)
 	bindMaintenanceNoticeController(context)
 	bindPrivateNoticeController(context)
@@ -29,11 +31,14 @@ func InitializeRoute(context *routes.RouterContext) {
 		bindNamespaceController(context)
 		if context.Config.OperationMode == gitus.OP_MODE_NORMAL {
 			bindNamespaceSettingController(context)
+			bindNamespaceSettingWebhookController(context)
+			bindNamespaceTransferController(context)
 		}
 	}
 
 	if context.Config.OperationMode == gitus.OP_MODE_NORMAL {
 		bindUserController(context)
+		bindExploreController(context)
 		bindLoginController(context)
 		bindLogoutController(context)
 		bindSettingController(context)
@@ -41,7 +46,14 @@ func InitializeRoute(context *routes.RouterContext) {
 		bindSettingGPGController(context)
 		bindSettingEmailController(context)
 		bindSettingPrivacyController(context)
+		bindSettingAccessTokenController(context)
+		bindSettingNotificationController(context)
+		bindNotificationController(context)
 		bindRepositorySettingController(context)
+		bindRepositorySettingBranchProtectionController(context)
+		bindRepositorySettingPullRequestController(context)
+		bindRepositorySettingWebhookController(context)
+		bindRepositorySettingWebhookDeliveryController(context)
 		bindNewNamespaceController(context)
 		bindNewRepositoryController(context)
 		bindNewSnippetController(context)
@@ -54,12 +66,18 @@ func InitializeRoute(context *routes.RouterContext) {
 		// bind admin controller
 		admin.BindAllAdminControllers(context)
 
+		// bind json api
+		api.BindAllAPIControllers(context)
+
 		bindResetPasswordController(context)
 
 		bindIssueController(context)
+		bindIssueLabelController(context)
 		bindLabelController(context)
 
 		bindSnippetController(context)
+		bindSnippetCloneController(context)
+		bindSnippetSocialController(context)
 	}
 }
 
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// issue labels & milestones. see docs/issue.org.
+//
+// anyone who can see the repository can see the labels & milestones;
+// only "triagers" (the owner of the repository or the namespace,
+// members in their acl, and admins) can edit them or change the
+// labels, assignees & milestone of an issue.
+
+func resolveIssueRepository(rc *RouterContext, w http.ResponseWriter, r *http.Request) (*model.Namespace, *model.Repository) {
+	rfn := r.PathValue("repoName")
+	_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+	if err == ErrNotFound {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil, nil
+	}
+	if err != nil {
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil, nil
+	}
+	if rc.Config.IsInPlainMode() {
+		FoundAt(w, fmt.Sprintf("/repo/%s", rfn))
+		return nil, nil
+	}
+	rc.LoginInfo.IsOwner = ns.Owner == rc.LoginInfo.UserName || repo.Owner == rc.LoginInfo.UserName
+	rc.LoginInfo.IsStrictOwner = repo.Owner == rc.LoginInfo.UserName
+	if (repo.Status == model.REPO_NORMAL_PRIVATE) && !rc.LoginInfo.IsAdmin && !rc.LoginInfo.IsOwner && !isIssueRepositoryMember(rc, ns, repo) {
+		rc.ReportNotFound(repo.Name, "Repository", "", w, r)
+		return nil, nil
+	}
+	return ns, repo
+}
+
+func isIssueRepositoryMember(rc *RouterContext, ns *model.Namespace, repo *model.Repository) bool {
+	if !rc.LoginInfo.LoggedIn { return false }
+	nsPriv := ns.ACL.GetUserPrivilege(rc.LoginInfo.UserName)
+	repoPriv := repo.AccessControlList.GetUserPrivilege(rc.LoginInfo.UserName)
+	return nsPriv != nil || repoPriv != nil
+}
+
+func canTriageIssue(rc *RouterContext, ns *model.Namespace, repo *model.Repository) bool {
+	if !rc.LoginInfo.LoggedIn { return false }
+	if rc.LoginInfo.IsAdmin { return true }
+	if ns.Owner == rc.LoginInfo.UserName || repo.Owner == rc.LoginInfo.UserName { return true }
+	return isIssueRepositoryMember(rc, ns, repo)
+}
+
+// parses the value of an `<input type="date">`. the due date is the
+// end of that day (in utc).
+func parseMilestoneDueDate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) <= 0 { return 0, nil }
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil { return 0, err }
+	return t.Add(24 * time.Hour - time.Second).Unix(), nil
+}
+
+func bindIssueLabelController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/issue/label", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveIssueRepository(rc, w, r)
+			if repo == nil { return }
+			labelList, err := rc.DatabaseInterface.GetAllRepositoryIssueLabel(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve issue labels: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("issue/issue-label").Execute(w, &templates.RepositoryIssueLabelTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				LoginInfo: rc.LoginInfo,
+				ErrorMsg: "",
+				LabelList: labelList,
+				CanTriage: canTriageIssue(rc, ns, repo),
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/issue/label", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			ValidPOSTRequestRequired, LoginRequired,
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveIssueRepository(rc, w, r)
+			if repo == nil { return }
+			labelPath := fmt.Sprintf("/repo/%s/issue/label", repo.FullName())
+			if !canTriageIssue(rc, ns, repo) {
+				rc.ReportRedirect(labelPath, 0, "Not enough privilege", "Your user account seems to not have enough privilege for this action.", w, r)
+				return
+			}
+			name := strings.TrimSpace(r.Form.Get("name"))
+			if len(name) <= 0 {
+				rc.ReportRedirect(labelPath, 5, "Invalid Request", "Label name cannot be empty.", w, r)
+				return
+			}
+			switch r.Form.Get("type") {
+			case "set":
+				color := strings.ToLower(strings.TrimSpace(r.Form.Get("color")))
+				if !model.ValidIssueLabelColor(color) {
+					rc.ReportRedirect(labelPath, 5, "Invalid Request", "Label color must be in the form of #rrggbb.", w, r)
+					return
+				}
+				err := rc.DatabaseInterface.SetRepositoryIssueLabel(repo.Namespace, repo.Name, &model.IssueLabel{
+					Name: name,
+					Color: color,
+					Description: strings.TrimSpace(r.Form.Get("description")),
+				})
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to save issue label: %s", err), w, r)
+					return
+				}
+				rc.ReportRedirect(labelPath, 3, "Updated", fmt.Sprintf("Label \"%s\" has been saved.", name), w, r)
+			case "delete":
+				err := rc.DatabaseInterface.DeleteRepositoryIssueLabel(repo.Namespace, repo.Name, name)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to delete issue label: %s", err), w, r)
+					return
+				}
+				rc.ReportRedirect(labelPath, 3, "Deleted", fmt.Sprintf("Label \"%s\" has been deleted.", name), w, r)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+			}
+		},
+	))
+
+	http.HandleFunc("GET /repo/{repoName}/issue/milestone", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveIssueRepository(rc, w, r)
+			if repo == nil { return }
+			milestoneList, err := rc.DatabaseInterface.GetAllRepositoryIssueMilestone(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve milestones: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("issue/issue-milestone").Execute(w, &templates.RepositoryIssueMilestoneTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				LoginInfo: rc.LoginInfo,
+				ErrorMsg: "",
+				MilestoneList: milestoneList,
+				CanTriage: canTriageIssue(rc, ns, repo),
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/issue/milestone", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			ValidPOSTRequestRequired, LoginRequired,
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveIssueRepository(rc, w, r)
+			if repo == nil { return }
+			milestonePath := fmt.Sprintf("/repo/%s/issue/milestone", repo.FullName())
+			if !canTriageIssue(rc, ns, repo) {
+				rc.ReportRedirect(milestonePath, 0, "Not enough privilege", "Your user account seems to not have enough privilege for this action.", w, r)
+				return
+			}
+			title := strings.TrimSpace(r.Form.Get("title"))
+			if len(title) <= 0 {
+				rc.ReportRedirect(milestonePath, 5, "Invalid Request", "Milestone title cannot be empty.", w, r)
+				return
+			}
+			switch r.Form.Get("type") {
+			case "set":
+				due, err := parseMilestoneDueDate(r.Form.Get("due"))
+				if err != nil {
+					rc.ReportRedirect(milestonePath, 5, "Invalid Request", "Invalid due date.", w, r)
+					return
+				}
+				err = rc.DatabaseInterface.SetRepositoryIssueMilestone(repo.Namespace, repo.Name, &model.IssueMilestone{
+					Title: title,
+					Description: strings.TrimSpace(r.Form.Get("description")),
+					DueTime: due,
+					Closed: len(r.Form.Get("closed")) > 0,
+				})
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to save milestone: %s", err), w, r)
+					return
+				}
+				rc.ReportRedirect(milestonePath, 3, "Updated", fmt.Sprintf("Milestone \"%s\" has been saved.", title), w, r)
+			case "delete":
+				err := rc.DatabaseInterface.DeleteRepositoryIssueMilestone(repo.Namespace, repo.Name, title)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to delete milestone: %s", err), w, r)
+					return
+				}
+				rc.ReportRedirect(milestonePath, 3, "Deleted", fmt.Sprintf("Milestone \"%s\" has been deleted.", title), w, r)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+			}
+		},
+	))
+}
@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	. "github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/templates"
@@ -32,7 +34,8 @@ func bindIssueController(ctx *RouterContext) {
 			if err != nil { s = 30 }
 			f, err := strconv.ParseInt(fStr, 10, 32)
 			if err != nil { f = 0 }
-			count, err := rc.DatabaseInterface.CountIssue(q, nsName, repoName, int(f))
+			filter := ParseIssueFilter(r)
+			count, err := rc.DatabaseInterface.CountIssue(q, nsName, repoName, int(f), filter)
 			if err != nil {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
@@ -47,7 +50,17 @@ func bindIssueController(ctx *RouterContext) {
 				PageSize: s,
 				TotalPage: pageCount,
 			}
-			issueList, err := rc.DatabaseInterface.SearchIssuePaginated(q, nsName, repoName, int(f), p-1, s)
+			issueList, err := rc.DatabaseInterface.SearchIssuePaginated(q, nsName, repoName, int(f), filter, p-1, s)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			labelList, err := rc.DatabaseInterface.GetAllRepositoryIssueLabel(nsName, repoName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			milestoneList, err := rc.DatabaseInterface.GetAllRepositoryIssueMilestone(nsName, repoName)
 			if err != nil {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
@@ -62,6 +75,10 @@ func bindIssueController(ctx *RouterContext) {
 				PageInfo: pageInfo,
 				FilterType: int(f),
 				Query: q,
+				Filter: filter,
+				LabelList: labelList,
+				LabelMap: toIssueLabelMap(labelList),
+				MilestoneList: milestoneList,
 			}))
 
 		},
@@ -117,6 +134,16 @@ func bindIssueController(ctx *RouterContext) {
 			title := r.Form.Get("title")
 			content := r.Form.Get("content")
 			iid, err := rc.DatabaseInterface.NewRepositoryIssue(nsName, repoName, rc.LoginInfo.UserName, title, content)
+			if err == nil {
+				LogIfError(ProcessIssueReference(rc, repo, &model.IssueReferenceSource{
+					RepoNamespace: nsName,
+					RepoName: repoName,
+					IssueId: iid,
+					Title: title,
+				}, content, rc.LoginInfo.UserName, false))
+				NotifyNewIssue(rc, repo, iid, rc.LoginInfo.UserName)
+				TriggerNewIssueWebhook(rc, repo, iid, rc.LoginInfo.UserName)
+			}
 			FoundAt(w, fmt.Sprintf("/repo/%s/issue/%d", rfn, iid))
 		},
 	))
@@ -153,6 +180,16 @@ func bindIssueController(ctx *RouterContext) {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
 			}
+			labelList, err := rc.DatabaseInterface.GetAllRepositoryIssueLabel(nsName, repoName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			milestoneList, err := rc.DatabaseInterface.GetAllRepositoryIssueMilestone(nsName, repoName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
 			LogTemplateError(rc.LoadTemplate("issue/single-issue").Execute(w, &templates.RepositorySingleIssueTemplateModel{
 				Config: rc.Config,
 				Repository: repo,
@@ -161,6 +198,10 @@ func bindIssueController(ctx *RouterContext) {
 				ErrorMsg: "",
 				Issue: issue,
 				IssueEventList: eventList,
+				LabelList: labelList,
+				LabelMap: toIssueLabelMap(labelList),
+				MilestoneList: milestoneList,
+				CanTriage: canTriageIssue(rc, ns, repo),
 			}))
 			
 		},
@@ -199,7 +240,21 @@ func bindIssueController(ctx *RouterContext) {
 				return
 			}
 			formType := strings.TrimSpace(r.Form.Get("type"))
-			if formType == "unpin" || formType == "pin" {
+			if strings.HasSuffix(formType, "-label") || strings.HasSuffix(formType, "-assignee") || formType == "set-milestone" {
+				if !canTriageIssue(rc, ns, repo) {
+					rc.ReportRedirect(fmt.Sprintf("/repo/%s/issue/%d", rfn, iid), 0, "Not enough privilege", "Your user account seems to not have enough privilege for this action.", w, r)
+					return
+				}
+				value := strings.TrimSpace(r.Form.Get("value"))
+				err = updateIssueMetadata(rc, nsName, repoName, iid, formType, value)
+				if err == errInvalidIssueMetadata {
+					rc.ReportRedirect(fmt.Sprintf("/repo/%s/issue/%d", rfn, iid), 5, "Invalid Request", "The label, user or milestone you've specified does not exist.", w, r)
+					return
+				}
+				if err == nil && formType == "add-assignee" {
+					NotifyIssueEvent(rc, repo, iid, model.EVENT_ASSIGNEE_ADDED, rc.LoginInfo.UserName, value)
+				}
+			} else if formType == "unpin" || formType == "pin" {
 				switch formType {
 				case "unpin":
 					err = rc.DatabaseInterface.SetIssuePriority(nsName, repoName, iid, 0)
@@ -221,6 +276,17 @@ func bindIssueController(ctx *RouterContext) {
 					eType = model.EVENT_REOPENED
 				}
 				err = rc.DatabaseInterface.NewRepositoryIssueEvent(nsName, repoName, iid, eType, author, content)
+				if err == nil && eType == model.EVENT_COMMENT {
+					issue, err := rc.DatabaseInterface.GetRepositoryIssue(nsName, repoName, int(iid))
+					if err == nil {
+						err = ProcessIssueReference(rc, repo, IssueReferenceSourceOfIssue(issue), content, author, false)
+					}
+					LogIfError(err)
+				}
+				if err == nil {
+					NotifyIssueEvent(rc, repo, iid, eType, author, content)
+					TriggerIssueWebhook(rc, repo, iid, eType, author, content)
+				}
 			}
 			if err != nil {
 				rc.ReportInternalError(err.Error(), w, r)
@@ -231,3 +297,49 @@ func bindIssueController(ctx *RouterContext) {
 	))
 }
 
+func toIssueLabelMap(labelList []*model.IssueLabel) map[string]*model.IssueLabel {
+	res := make(map[string]*model.IssueLabel, len(labelList))
+	for _, k := range labelList { res[k.Name] = k }
+	return res
+}
+
+var errInvalidIssueMetadata = errors.New("Invalid label, user or milestone")
+
+// handles the "add-label", "remove-label", "add-assignee",
+// "remove-assignee" & "set-milestone" forms on the issue page. the
+// value is checked against the labels & milestones of the repository
+// when adding; removing things that no longer exist is allowed.
+func updateIssueMetadata(rc *RouterContext, ns string, name string, iid int64, formType string, value string) error {
+	issue, err := rc.DatabaseInterface.GetRepositoryIssue(ns, name, int(iid))
+	if err != nil { return err }
+	author := rc.LoginInfo.UserName
+	switch formType {
+	case "add-label":
+		labelList, err := rc.DatabaseInterface.GetAllRepositoryIssueLabel(ns, name)
+		if err != nil { return err }
+		if _, ok := toIssueLabelMap(labelList)[value]; !ok { return errInvalidIssueMetadata }
+		return rc.DatabaseInterface.AddIssueLabel(issue.IssueAbsId, value, author)
+	case "remove-label":
+		return rc.DatabaseInterface.RemoveIssueLabel(issue.IssueAbsId, value, author)
+	case "add-assignee":
+		if !model.ValidUserName(value) { return errInvalidIssueMetadata }
+		_, err := rc.DatabaseInterface.GetUserByName(value)
+		if err == db.ErrEntityNotFound { return errInvalidIssueMetadata }
+		if err != nil { return err }
+		return rc.DatabaseInterface.AddIssueAssignee(issue.IssueAbsId, value, author)
+	case "remove-assignee":
+		return rc.DatabaseInterface.RemoveIssueAssignee(issue.IssueAbsId, value, author)
+	case "set-milestone":
+		if len(value) > 0 {
+			milestoneList, err := rc.DatabaseInterface.GetAllRepositoryIssueMilestone(ns, name)
+			if err != nil { return err }
+			found := false
+			for _, k := range milestoneList {
+				if k.Title == value { found = true; break }
+			}
+			if !found { return errInvalidIssueMetadata }
+		}
+		return rc.DatabaseInterface.SetIssueMilestone(issue.IssueAbsId, value, author)
+	}
+	return errInvalidIssueMetadata
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/GitusCodeForge/Gitus/pkg/gitus"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/session"
+	"github.com/GitusCodeForge/Gitus/pkg/webauthn"
 	. "github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/templates"
 	"golang.org/x/crypto/bcrypt"
@@ -89,6 +91,23 @@ func bindLoginController(ctx *RouterContext) {
 				return
 			}
 
+			// totp & security keys take precedence over email when
+			// both are enabled.
+			if usesAppSecondFactor(u) {
+				if rc.ConfirmCodeManager == nil {
+					rc.ReportInternalError("Confirm code manager not initialized. Please contact site owner to fix this problem... ", w, r)
+					return
+				}
+				// there's no code to bind the temp key to here, so we
+				// use a random key that's only valid for this login
+				// attempt.
+				tempKey := session.NewSessionString()
+				rc.ConfirmCodeManager.Register(secondFactorTempKeyName(u.Name), tempKey, 10 * time.Minute)
+				setLoginConfirmCookie(w, u.Name, tempKey)
+				FoundAt(w, "/login/confirm")
+				return
+			}
+
 			if u.TFAConfig.Email.Enable {
 				confirmCode := newConfirmCode()
 				tempKey, err := bcrypt.GenerateFromPassword([]byte(u.PasswordHash+confirmCode), bcrypt.DefaultCost)
@@ -117,24 +136,7 @@ If this isn't you, we advise you to change your password on %s and other platfor
 					rc.ReportInternalError(fmt.Sprintf("Failed to send confirmation code email: %s.", err), w, r)
 					return
 				}
-				w.Header().Add("Set-Cookie", (&http.Cookie{
-					Name: COOKIE_KEY_USERNAME,
-					Value: u.Name,
-					Path: "/",
-					MaxAge: 600,
-					HttpOnly: true,
-					Secure: true,
-					SameSite: http.SameSiteDefaultMode,
-				}).String())
-				w.Header().Add("Set-Cookie", (&http.Cookie{
-					Name: COOKIE_KEY_TEMP_KEY,
-					Value: string(tempKey),
-					Path: "/",
-					MaxAge: 600,
-					HttpOnly: true,
-					Secure: true,
-					SameSite: http.SameSiteDefaultMode,
-				}).String())
+				setLoginConfirmCookie(w, u.Name, string(tempKey))
 				FoundAt(w, "/login/confirm")
 				return
 			}
@@ -180,15 +182,20 @@ If this isn't you, we advise you to change your password on %s and other platfor
 				return
 			}
 			username, err := r.Cookie(COOKIE_KEY_USERNAME)
-			if err != nil && err != http.ErrNoCookie{
+			if err == http.ErrNoCookie {
+				FoundAt(w, "/login")
+				return
+			}
+			if err != nil {
 				rc.ReportNormalError("Invalid request", w, r)
 				return
 			}
-			LogTemplateError(rc.LoadTemplate("login-confirm").Execute(w, &templates.LoginConfirmTemplateModel{
-				Config: rc.Config,
-				LoginInfo: rc.LoginInfo,
-				Username: username.Value,
-			}))
+			user, err := rc.DatabaseInterface.GetUserByName(username.Value)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve user: %s.", err), w, r)
+				return
+			}
+			renderLoginConfirm(rc, user, "", w)
 		},
 	))
 
@@ -212,7 +219,36 @@ If this isn't you, we advise you to change your password on %s and other platfor
 				return
 			}
 			code := r.Form.Get("confirmation-code")
-			err = bcrypt.CompareHashAndPassword([]byte(key.Value), []byte(user.PasswordHash+code))
+			if usesAppSecondFactor(user) {
+				if rc.ConfirmCodeManager == nil {
+					rc.ReportInternalError("Confirm code manager not initialized. Please contact site owner to fix this problem... ", w, r)
+					return
+				}
+				k, ok := rc.ConfirmCodeManager.Get(secondFactorTempKeyName(user.Name))
+				if !ok || len(k) <= 0 || subtle.ConstantTimeCompare([]byte(k), []byte(key.Value)) != 1 {
+					rc.ReportRedirect("/login", 5, "Login Expired", "Your login attempt has expired. Please log in again.", w, r)
+					return
+				}
+				if r.Form.Get("method") == "webauthn" {
+					errMsg := verifyLoginWebAuthnAssertion(rc, user, r)
+					if len(errMsg) > 0 {
+						renderLoginConfirm(rc, user, errMsg, w)
+						return
+					}
+				} else if !user.TFAConfig.UseTOTPCode(code) && !user.TFAConfig.UseRecoveryCode(code) {
+					renderLoginConfirm(rc, user, "Invalid authentication code or recovery code.", w)
+					return
+				}
+				// saves the used step/recovery code/signature counter.
+				err = rc.DatabaseInterface.UpdateUserInfo(user.Name, user)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to update user: %s.", err), w, r)
+					return
+				}
+				rc.ConfirmCodeManager.Register(secondFactorTempKeyName(user.Name), "", time.Minute)
+			} else {
+				err = bcrypt.CompareHashAndPassword([]byte(key.Value), []byte(user.PasswordHash+code))
+			}
 			if err == bcrypt.ErrMismatchedHashAndPassword {
 				LogTemplateError(rc.LoadTemplate("login-confirm").Execute(w, templates.LoginConfirmTemplateModel{
 					Config: rc.Config,
@@ -260,3 +296,84 @@ If this isn't you, we advise you to change your password on %s and other platfor
 }
 
 
+
+// whether the user logs in w/ totp or security keys (in which case
+// recovery codes can be used as well) instead of email.
+func usesAppSecondFactor(u *model.GitusUser) bool {
+	return u.TFAConfig.TOTP.Enable || u.WebAuthnUsable()
+}
+
+func secondFactorTempKeyName(username string) string {
+	return "2fa:" + username
+}
+
+func webAuthnLoginChallengeKeyName(username string) string {
+	return "webauthn-login:" + username
+}
+
+func renderLoginConfirm(rc *RouterContext, user *model.GitusUser, errorMsg string, w http.ResponseWriter) {
+	m := &templates.LoginConfirmTemplateModel{
+		Config: rc.Config,
+		LoginInfo: rc.LoginInfo,
+		ErrorMsg: errorMsg,
+		Username: user.Name,
+		TOTP: usesAppSecondFactor(user) && (user.TFAConfig.TOTP.Enable || len(user.TFAConfig.RecoveryCode) > 0),
+	}
+	if user.WebAuthnUsable() && rc.ConfirmCodeManager != nil {
+		rpId, _, err := webAuthnRelyingParty(rc.Config)
+		challenge, err2 := webauthn.NewChallenge()
+		if err == nil && err2 == nil {
+			rc.ConfirmCodeManager.Register(webAuthnLoginChallengeKeyName(user.Name), challenge, 10 * time.Minute)
+			m.WebAuthnRPID = rpId
+			m.WebAuthnChallenge = challenge
+			for _, k := range user.TFAConfig.WebAuthn {
+				m.WebAuthnCredentialIdList = append(m.WebAuthnCredentialIdList, webauthn.EncodeBase64(k.Id))
+			}
+		}
+	}
+	LogTemplateError(rc.LoadTemplate("login-confirm").Execute(w, m))
+}
+
+// returns an error message if the assertion is invalid. the signature
+// counter of the credential is updated on success.
+func verifyLoginWebAuthnAssertion(rc *RouterContext, user *model.GitusUser, r *http.Request) string {
+	if !user.WebAuthnUsable() { return "Security keys are not available for this account." }
+	challenge, ok := rc.ConfirmCodeManager.Get(webAuthnLoginChallengeKeyName(user.Name))
+	if !ok || len(challenge) <= 0 { return "The request has expired. Please try again." }
+	// a challenge is only good for one attempt.
+	rc.ConfirmCodeManager.Register(webAuthnLoginChallengeKeyName(user.Name), "", time.Minute)
+	rpId, origin, err := webAuthnRelyingParty(rc.Config)
+	if err != nil { return fmt.Sprintf("Security keys are not available: %s.", err) }
+	credId, err1 := webauthn.DecodeBase64(r.Form.Get("credential-id"))
+	clientData, err2 := webauthn.DecodeBase64(r.Form.Get("client-data"))
+	authData, err3 := webauthn.DecodeBase64(r.Form.Get("authenticator-data"))
+	sig, err4 := webauthn.DecodeBase64(r.Form.Get("signature"))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil { return "Invalid request." }
+	cred := user.TFAConfig.GetWebAuthnCredentialById(credId)
+	if cred == nil { return "Unknown security key." }
+	signCount, err := webauthn.VerifyAssertion(rpId, origin, challenge, cred.PublicKey, cred.SignCount, clientData, authData, sig)
+	if err != nil { return fmt.Sprintf("Failed to verify security key: %s.", err) }
+	cred.SignCount = signCount
+	return ""
+}
+
+func setLoginConfirmCookie(w http.ResponseWriter, username string, tempKey string) {
+	w.Header().Add("Set-Cookie", (&http.Cookie{
+		Name: COOKIE_KEY_USERNAME,
+		Value: username,
+		Path: "/",
+		MaxAge: 600,
+		HttpOnly: true,
+		Secure: true,
+		SameSite: http.SameSiteDefaultMode,
+	}).String())
+	w.Header().Add("Set-Cookie", (&http.Cookie{
+		Name: COOKIE_KEY_TEMP_KEY,
+		Value: tempKey,
+		Path: "/",
+		MaxAge: 600,
+		HttpOnly: true,
+		Secure: true,
+		SameSite: http.SameSiteDefaultMode,
+	}).String())
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the webhooks of a namespace, which receive the events of all the
+// repositories in it. see docs/webhooks.org.
+
+func resolveNamespaceWebhookSettingTarget(rc *RouterContext, w http.ResponseWriter, r *http.Request) *model.Namespace {
+	namespaceName := r.PathValue("namespace")
+	if !model.ValidNamespaceName(namespaceName) {
+		rc.ReportNotFound(namespaceName, "Namespace", "Depot", w, r)
+		return nil
+	}
+	namespacePath := fmt.Sprintf("/s/%s", namespaceName)
+	if rc.Config.IsInPlainMode() { FoundAt(w, namespacePath); return nil }
+	ns, err := rc.DatabaseInterface.GetNamespaceByName(namespaceName)
+	if err == db.ErrEntityNotFound {
+		rc.ReportNotFound(namespaceName, "Namespace", "Depot", w, r)
+		return nil
+	}
+	if err != nil {
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil
+	}
+	isOwner := ns.Owner == rc.LoginInfo.UserName
+	priv := ns.ACL.GetUserPrivilege(rc.LoginInfo.UserName)
+	canEditWebhook := priv != nil && priv.EditWebHooks
+	if !rc.LoginInfo.IsAdmin && !isOwner && !canEditWebhook {
+		rc.ReportRedirect(namespacePath, 0,
+			"Not enough privilege",
+			"Your user account seems to not have enough privilege for this action.",
+			w, r,
+		)
+		return nil
+	}
+	rc.LoginInfo.IsOwner = isOwner
+	rc.LoginInfo.IsSettingMember = true
+	return ns
+}
+
+func bindNamespaceSettingWebhookController(ctx *RouterContext) {
+	http.HandleFunc("GET /s/{namespace}/setting/webhook", UseMiddleware(
+		[]Middleware{Logged, UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveNamespaceWebhookSettingTarget(rc, w, r)
+			if ns == nil { return }
+			hookList, err := rc.DatabaseInterface.GetAllWebhook(ns.Name, "")
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve webhooks: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("namespace-setting/webhook-list").Execute(w, &templates.NamespaceSettingWebhookListTemplateModel{
+				Config: rc.Config,
+				Namespace: ns,
+				LoginInfo: rc.LoginInfo,
+				WebhookList: hookList,
+				Webhook: newWebhookFormDefault(),
+				WebhookEventList: model.WebhookEventList,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /s/{namespace}/setting/webhook/new", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveNamespaceWebhookSettingTarget(rc, w, r)
+			if ns == nil { return }
+			settingPath := fmt.Sprintf("/s/%s/setting/webhook", ns.Name)
+			hook := newWebhookFormDefault()
+			hook.Namespace = ns.Name
+			err := parseWebhookForm(r, hook)
+			if err != nil {
+				rc.ReportRedirect(settingPath, 5, "Invalid Webhook", err.Error(), w, r)
+				return
+			}
+			_, err = rc.DatabaseInterface.NewWebhook(hook)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to add webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Added", "The webhook has been added.", w, r)
+		},
+	))
+
+	http.HandleFunc("GET /s/{namespace}/setting/webhook/{id}", UseMiddleware(
+		[]Middleware{Logged, UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveNamespaceWebhookSettingTarget(rc, w, r)
+			if ns == nil { return }
+			hook := resolveWebhook(rc, w, r, ns.Name, "", fmt.Sprintf("/s/%s/setting/webhook", ns.Name))
+			if hook == nil { return }
+			LogTemplateError(rc.LoadTemplate("namespace-setting/webhook").Execute(w, &templates.NamespaceSettingWebhookTemplateModel{
+				Config: rc.Config,
+				Namespace: ns,
+				LoginInfo: rc.LoginInfo,
+				Webhook: hook,
+				WebhookEventList: model.WebhookEventList,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /s/{namespace}/setting/webhook/{id}", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveNamespaceWebhookSettingTarget(rc, w, r)
+			if ns == nil { return }
+			hook := resolveWebhook(rc, w, r, ns.Name, "", fmt.Sprintf("/s/%s/setting/webhook", ns.Name))
+			if hook == nil { return }
+			hookPath := fmt.Sprintf("/s/%s/setting/webhook/%d", ns.Name, hook.Id)
+			err := parseWebhookForm(r, hook)
+			if err != nil {
+				rc.ReportRedirect(hookPath, 5, "Invalid Webhook", err.Error(), w, r)
+				return
+			}
+			err = rc.DatabaseInterface.UpdateWebhook(hook)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(hookPath, 3, "Updated", "The webhook has been updated.", w, r)
+		},
+	))
+
+	http.HandleFunc("POST /s/{namespace}/setting/webhook/{id}/delete", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns := resolveNamespaceWebhookSettingTarget(rc, w, r)
+			if ns == nil { return }
+			settingPath := fmt.Sprintf("/s/%s/setting/webhook", ns.Name)
+			hook := resolveWebhook(rc, w, r, ns.Name, "", settingPath)
+			if hook == nil { return }
+			err := rc.DatabaseInterface.DeleteWebhook(hook.Id)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to delete webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Deleted", "The webhook has been deleted.", w, r)
+		},
+	))
+}
@@ -87,18 +87,8 @@ func bindNamespaceSettingController(ctx *RouterContext) {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
 			}
-			newOwner := strings.TrimSpace(r.Form.Get("owner"))
-			if ns.Owner != newOwner {
-				if !isOwner && !rc.LoginInfo.IsAdmin {
-					rc.ReportRedirect(fmt.Sprintf("/s/%s/setting", namespaceName), 0,
-						"Not enough privilege",
-						"You are neither owner nor an admin, thus cannot change this namespace's ownership.",
-						w, r,
-					)
-					return
-				}
-				ns.Owner = newOwner
-			}
+			// the owner is changed thru the transfer, which the new
+			// owner has to accept. see docs/namespace-transfer.org.
 			ns.Title = r.Form.Get("title")
 			ns.Description = r.Form.Get("description")
 			ns.Email = r.Form.Get("email")
@@ -279,6 +269,7 @@ func bindNamespaceSettingController(ctx *RouterContext) {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
 			}
+			NotifyMembership(rc, username, rc.LoginInfo.UserName, fmt.Sprintf("%s added you to the namespace %s", rc.LoginInfo.UserName, namespaceName), fmt.Sprintf("/s/%s", namespaceName))
 			rc.ReportRedirect(fmt.Sprintf("/s/%s/member", ns.Name), 3,
 				"Updated",
 				"Member list updated.",
@@ -331,6 +322,7 @@ func bindNamespaceSettingController(ctx *RouterContext) {
 				}))
 				return
 			}
+			NotifyMembership(rc, targetUsername, rc.LoginInfo.UserName, fmt.Sprintf("%s removed you from the namespace %s", rc.LoginInfo.UserName, namespaceName), "")
 			FoundAt(w, fmt.Sprintf("/s/%s/member", namespaceName))
 		},
 	))
@@ -429,6 +421,7 @@ func bindNamespaceSettingController(ctx *RouterContext) {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
 			}
+			NotifyMembership(rc, targetUsername, rc.LoginInfo.UserName, fmt.Sprintf("%s changed your privilege in the namespace %s", rc.LoginInfo.UserName, namespaceName), fmt.Sprintf("/s/%s", namespaceName))
 			FoundAt(w, fmt.Sprintf("/s/%s/member", namespaceName))
 		},
 	))
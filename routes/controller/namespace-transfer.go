@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/receipt"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// namespace ownership transfer. the new owner has to accept it thru
+// the receipt sent to them. see docs/namespace-transfer.org.
+
+// returns the receipt & the namespace if the receipt is a valid
+// transfer for the current user; reports the error & returns nil
+// otherwise.
+func resolveNamespaceTransfer(rc *RouterContext, w http.ResponseWriter, r *http.Request, rid string) (*receipt.Receipt, *model.Namespace) {
+	if len(rid) <= 0 { FoundAt(w, "/"); return nil, nil }
+	re, err := rc.ReceiptSystem.RetrieveReceipt(rid)
+	if err != nil {
+		rc.ReportRedirect("/", 5, "Invalid Receipt", "The transfer you've specified does not exist or has been cancelled.", w, r)
+		return nil, nil
+	}
+	if re.Expired() {
+		rc.ReceiptSystem.CancelReceipt(rid)
+		rc.ReportRedirect("/", 5, "Receipt Expired", "The transfer has passed its validity time limit. Please ask the owner to transfer the namespace again.", w, r)
+		return nil, nil
+	}
+	if len(re.Command) != 4 || re.Command[0] != receipt.TRANSFER_NAMESPACE {
+		rc.ReportRedirect("/", 5, "Invalid Receipt", "The receipt you've provided is invalid.", w, r)
+		return nil, nil
+	}
+	// the receipt id is only sent to the new owner, but we check
+	// anyway in case it's leaked.
+	if re.Command[3] != rc.LoginInfo.UserName {
+		rc.ReportRedirect("/", 5, "Not For You", "This transfer is not for your user account.", w, r)
+		return nil, nil
+	}
+	ns, err := rc.DatabaseInterface.GetNamespaceByName(re.Command[1])
+	if err == db.ErrEntityNotFound {
+		rc.ReceiptSystem.CancelReceipt(rid)
+		rc.ReportRedirect("/", 5, "Namespace Not Found", "The namespace of this transfer no longer exists.", w, r)
+		return nil, nil
+	}
+	if err != nil {
+		rc.ReportInternalError(fmt.Sprintf("Failed to retrieve namespace: %s", err), w, r)
+		return nil, nil
+	}
+	// the owner has changed since the transfer is issued.
+	if ns.Owner != re.Command[2] {
+		rc.ReceiptSystem.CancelReceipt(rid)
+		rc.ReportRedirect(fmt.Sprintf("/s/%s", ns.Name), 5, "Transfer Outdated", "The owner of this namespace has changed since the transfer was issued.", w, r)
+		return nil, nil
+	}
+	return re, ns
+}
+
+func bindNamespaceTransferController(ctx *RouterContext) {
+	http.HandleFunc("POST /s/{namespace}/setting/transfer", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			namespaceName := r.PathValue("namespace")
+			if !model.ValidNamespaceName(namespaceName) {
+				rc.ReportNotFound(namespaceName, "Namespace", "Depot", w, r)
+				return
+			}
+			namespacePath := fmt.Sprintf("/s/%s", namespaceName)
+			if rc.Config.IsInPlainMode() { FoundAt(w, namespacePath); return }
+			ns, err := rc.DatabaseInterface.GetNamespaceByName(namespaceName)
+			if err == db.ErrEntityNotFound {
+				rc.ReportNotFound(namespaceName, "Namespace", "Depot", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			settingPath := fmt.Sprintf("/s/%s/setting", ns.Name)
+			if !rc.LoginInfo.IsAdmin && ns.Owner != rc.LoginInfo.UserName {
+				rc.ReportRedirect(settingPath, 0,
+					"Not enough privilege",
+					"You are neither owner nor an admin, thus cannot change this namespace's ownership.",
+					w, r,
+				)
+				return
+			}
+			newOwner := strings.TrimSpace(r.Form.Get("new-owner"))
+			if newOwner == ns.Owner {
+				rc.ReportRedirect(settingPath, 3, "Nothing Changed", fmt.Sprintf("%s is already the owner of this namespace.", newOwner), w, r)
+				return
+			}
+			_, err = rc.DatabaseInterface.GetUserByName(newOwner)
+			if err == db.ErrEntityNotFound {
+				rc.ReportRedirect(settingPath, 5, "User Not Found", fmt.Sprintf("The user %s does not exist.", newOwner), w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve user: %s", err), w, r)
+				return
+			}
+			// valid for a week.
+			rid, err := rc.ReceiptSystem.IssueReceipt(7*24*60, []string{
+				receipt.TRANSFER_NAMESPACE, ns.Name, ns.Owner, newOwner,
+			})
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to issue receipt: %s", err), w, r)
+				return
+			}
+			NotifyMembership(rc, newOwner, rc.LoginInfo.UserName,
+				fmt.Sprintf("%s wants to transfer the ownership of the namespace %s to you", rc.LoginInfo.UserName, ns.Name),
+				fmt.Sprintf("/receipt?id=%s", rid),
+			)
+			rc.ReportRedirect(settingPath, 5, "Transfer Requested", fmt.Sprintf("%s has been asked to accept the transfer; the namespace will be theirs once they accept it.", newOwner), w, r)
+		},
+	))
+
+	//     /namespace-transfer?id={receipt id}
+	http.HandleFunc("GET /namespace-transfer", UseMiddleware(
+		[]Middleware{Logged, UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rid := strings.TrimSpace(r.URL.Query().Get("id"))
+			re, ns := resolveNamespaceTransfer(rc, w, r, rid)
+			if re == nil { return }
+			LogTemplateError(rc.LoadTemplate("namespace-transfer").Execute(w, &templates.NamespaceTransferTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				Namespace: ns,
+				CurrentOwner: re.Command[2],
+				ReceiptId: rid,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /namespace-transfer", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rid := strings.TrimSpace(r.Form.Get("id"))
+			re, ns := resolveNamespaceTransfer(rc, w, r, rid)
+			if re == nil { return }
+			namespacePath := fmt.Sprintf("/s/%s", ns.Name)
+			oldOwner := re.Command[2]
+			if r.Form.Get("action") != "accept" {
+				rc.ReceiptSystem.CancelReceipt(rid)
+				NotifyMembership(rc, oldOwner, rc.LoginInfo.UserName,
+					fmt.Sprintf("%s declined the transfer of the namespace %s", rc.LoginInfo.UserName, ns.Name),
+					namespacePath,
+				)
+				rc.ReportRedirect("/", 3, "Transfer Declined", "You've declined the transfer.", w, r)
+				return
+			}
+			err := rc.DatabaseInterface.UpdateNamespaceOwner(ns.Name, rc.LoginInfo.UserName)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to transfer namespace: %s", err), w, r)
+				return
+			}
+			rc.ReceiptSystem.CancelReceipt(rid)
+			NotifyMembership(rc, oldOwner, rc.LoginInfo.UserName,
+				fmt.Sprintf("%s accepted the transfer of the namespace %s", rc.LoginInfo.UserName, ns.Name),
+				namespacePath,
+			)
+			rc.ReportRedirect(namespacePath, 3, "Transfer Accepted", fmt.Sprintf("You are now the owner of the namespace %s.", ns.Name), w, r)
+		},
+	))
+}
@@ -156,6 +156,7 @@ func bindNamespaceController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed to create repository: %s", err), w, r)
 				return
 			}
+			AutoWatchRepository(rc, repo, rc.LoginInfo.UserName)
 			rc.ReportRedirect(fmt.Sprintf("/repo/%s", repo.FullName()), 5, "Repository Created", fmt.Sprintf("A new repository named %s has been created under namespace %s.", name, nsName), w, r)
 		},
 	))
@@ -72,6 +72,7 @@ func bindNewRepositoryController(ctx *RouterContext) {
 			repo.Description = newRepoDescription
 			// NOTE: we ignore this error since we have the repository already.
 			rc.DatabaseInterface.UpdateRepositoryInfo(newRepoNS, newRepoName, repo)
+			AutoWatchRepository(rc, repo, userName)
 			FoundAt(w, fmt.Sprintf("/repo/%s:%s", newRepoNS, newRepoName))
 		},
 	))
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	. "github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/templates"
 )
@@ -33,6 +34,10 @@ func bindNewSnippetController(ctx *RouterContext) {
 			username := rc.LoginInfo.UserName
 			name := r.Form.Get("name")
 			filename := r.Form.Get("filename")
+			if !model.ValidSnippetFileName(filename) {
+				rc.ReportNormalError("Invalid file name", w, r)
+				return
+			}
 			statusStr := r.Form.Get("status")
 			status, err := strconv.ParseInt(statusStr, 10, 8)
 			if err != nil {
@@ -49,7 +54,7 @@ func bindNewSnippetController(ctx *RouterContext) {
 				sn.FileList = make(map[string]string, 0)
 			}
 			sn.FileList[filename] = content
-			err = sn.SyncFile(rc.Config.SnippetRoot, filename)
+			err = sn.SyncFile(rc.Config.SnippetRoot, filename, rc.LoginInfo.UserFullName, rc.LoginInfo.UserEmail, fmt.Sprintf("Create %s", filename))
 			if err != nil {
 				rc.ReportInternalError(fmt.Sprintf("Failed to create new snippet: %s", err), w, r)
 				return
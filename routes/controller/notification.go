@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// /notification?unread={1}&p={pagenum}&s={pagesize}
+func bindNotificationController(ctx *RouterContext) {
+	http.HandleFunc("GET /notification", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			unreadOnly := r.URL.Query().Get("unread") == "1"
+			count, err := rc.DatabaseInterface.CountNotification(rc.LoginInfo.UserName, unreadOnly)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			pageInfo, err := GeneratePageInfo(r, count)
+			if err != nil {
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			l, err := rc.DatabaseInterface.GetNotificationPaginated(rc.LoginInfo.UserName, unreadOnly, pageInfo.PageNum-1, pageInfo.PageSize)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("notification").Execute(w, &templates.NotificationTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				NotificationList: l,
+				PageInfo: pageInfo,
+				UnreadOnly: unreadOnly,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /notification", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			LoginRequired, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			var err error
+			un := rc.LoginInfo.UserName
+			switch r.Form.Get("type") {
+			case "read":
+				var id int64
+				id, err = strconv.ParseInt(r.Form.Get("id"), 10, 64)
+				if err != nil {
+					rc.ReportNormalError("Invalid request", w, r)
+					return
+				}
+				err = rc.DatabaseInterface.MarkNotificationRead(un, id)
+			case "read-all":
+				err = rc.DatabaseInterface.MarkAllNotificationRead(un)
+			case "clear-read":
+				err = rc.DatabaseInterface.DeleteAllReadNotification(un)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update notification: %s", err), w, r)
+				return
+			}
+			FoundAt(w, "/notification")
+		},
+	))
+}
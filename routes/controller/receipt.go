@@ -46,6 +46,9 @@ func bindReceiptController(ctx *RouterContext) {
 			case receipt.VERIFY_EMAIL:
 				FoundAt(w, fmt.Sprintf("/verify-email?id=%s", rid))
 				return
+			case receipt.TRANSFER_NAMESPACE:
+				FoundAt(w, fmt.Sprintf("/namespace-transfer?id=%s", rid))
+				return
 			}
 			rc.ReportNormalError("Invalid receipt", w, r)
 		},
@@ -99,6 +99,7 @@ func bindRegisterController(ctx *RouterContext) {
 					rc.ReportInternalError(fmt.Sprintf("Failed to submit registration request: %s. Please contact the site owner.", err.Error()), w, r)
 					return
 				} else {
+					NotifyNewRegistrationRequest(rc, userName, strings.TrimSpace(r.Form.Get("reason")))
 					msg := "Your registration request has been submitted. "
 					if rc.Config.EmailConfirmationRequired {
 						msg += " You will receive the confirmation email after the administrators approved your request."
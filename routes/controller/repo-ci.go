@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the runs of the built-in ci runner. see docs/ci-runner.org.
+
+func resolveCIRepository(rc *RouterContext, w http.ResponseWriter, r *http.Request) (*model.Namespace, *model.Repository) {
+	rfn := r.PathValue("repoName")
+	_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+	if err == ErrNotFound || (err == nil && !CheckRepositoryVisibleToUser(rc.LoginInfo, ns, repo)) {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil, nil
+	}
+	if err != nil {
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil, nil
+	}
+	if rc.LoginInfo.LoggedIn {
+		rc.LoginInfo.IsOwner = repo.Owner == rc.LoginInfo.UserName || ns.Owner == rc.LoginInfo.UserName
+	}
+	return ns, repo
+}
+
+// returns nil if the run doesn't belong to `repo`.
+func resolveCIRun(rc *RouterContext, w http.ResponseWriter, r *http.Request, repo *model.Repository) *model.CIRun {
+	listPath := fmt.Sprintf("/repo/%s/ci", repo.FullName())
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		rc.ReportRedirect(listPath, 5, "Not Found", "The run you've specified does not exist in this repository.", w, r)
+		return nil
+	}
+	run, err := rc.DatabaseInterface.GetCIRun(id)
+	if err == db.ErrEntityNotFound || (err == nil && (run.RepoNamespace != repo.Namespace || run.RepoName != repo.Name)) {
+		rc.ReportRedirect(listPath, 5, "Not Found", "The run you've specified does not exist in this repository.", w, r)
+		return nil
+	}
+	if err != nil {
+		rc.ReportInternalError(fmt.Sprintf("Failed to retrieve run: %s", err), w, r)
+		return nil
+	}
+	return run
+}
+
+func bindRepositoryCIController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/ci", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			_, repo := resolveCIRepository(rc, w, r)
+			if repo == nil { return }
+			p, err := strconv.ParseInt(r.URL.Query().Get("p"), 10, 64)
+			if err != nil { p = 1 }
+			count, err := rc.DatabaseInterface.CountCIRun(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to count runs: %s", err), w, r)
+				return
+			}
+			var pageSize int64 = 30
+			pageCount := count / pageSize
+			if (count % pageSize) > 0 { pageCount += 1 }
+			if pageCount <= 0 { pageCount = 1 }
+			if p > pageCount { p = pageCount }
+			if p < 1 { p = 1 }
+			runList, err := rc.DatabaseInterface.GetCIRunPaginated(repo.Namespace, repo.Name, p-1, pageSize)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve runs: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("repo-ci/ci-run-list").Execute(w, &templates.RepositoryCIRunListTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				RunList: runList,
+				PageInfo: &templates.PageInfoModel{
+					PageNum: p,
+					PageSize: pageSize,
+					TotalPage: pageCount,
+				},
+			}))
+		},
+	))
+
+	http.HandleFunc("GET /repo/{repoName}/ci/{id}", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveCIRepository(rc, w, r)
+			if repo == nil { return }
+			run := resolveCIRun(rc, w, r, repo)
+			if run == nil { return }
+			LogTemplateError(rc.LoadTemplate("repo-ci/ci-run").Execute(w, &templates.RepositoryCIRunTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				Run: run,
+				CanRerun: rc.LoginInfo.LoggedIn && (rc.LoginInfo.IsAdmin || CheckUserPushPermission(rc.LoginInfo.UserName, ns, repo)),
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/ci/{id}/rerun", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			ns, repo := resolveCIRepository(rc, w, r)
+			if repo == nil { return }
+			run := resolveCIRun(rc, w, r, repo)
+			if run == nil { return }
+			runPath := fmt.Sprintf("/repo/%s/ci/%d", repo.FullName(), run.Id)
+			if !rc.LoginInfo.IsAdmin && !CheckUserPushPermission(rc.LoginInfo.UserName, ns, repo) {
+				rc.ReportRedirect(runPath, 0,
+					"Not enough privilege",
+					"Your user account seems to not have enough privilege for this action.",
+					w, r,
+				)
+				return
+			}
+			if !rc.Config.Runner.Enable {
+				rc.ReportRedirect(runPath, 5, "Runner Disabled", "The CI runner is not enabled on this site.", w, r)
+				return
+			}
+			if !run.Finished() {
+				rc.ReportRedirect(runPath, 5, "Still Running", "This run hasn't finished yet.", w, r)
+				return
+			}
+			nr, err := RerunCIRun(rc, repo, run, rc.LoginInfo.UserName)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to queue run: %s", err), w, r)
+				return
+			}
+			FoundAt(w, fmt.Sprintf("/repo/%s/ci/%d", repo.FullName(), nr.Id))
+		},
+	))
+}
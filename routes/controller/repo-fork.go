@@ -70,7 +70,7 @@ func bindRepositoryForkController(ctx *RouterContext) {
 		}, ctx,
 		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
 			rfn := r.PathValue("repoName")
-			originNs, originName, _, _, err := rc.ResolveRepositoryFullName(rfn)
+			originNs, originName, _, origin, err := rc.ResolveRepositoryFullName(rfn)
 			if err == ErrNotFound {
 				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
 				return
@@ -94,6 +94,8 @@ func bindRepositoryForkController(ctx *RouterContext) {
 				rc.ReportInternalError(err.Error(), w, r)
 				return
 			}
+			AutoWatchRepository(rc, rp, rc.LoginInfo.UserName)
+			TriggerForkWebhook(rc, origin, rp, rc.LoginInfo.UserName)
 			FoundAt(w, fmt.Sprintf("/repo/%s", rp.FullName()))
 		},
 	))
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the "files changed" & "commits" tabs of a pull request. both are
+// computed from the range of the pull request (see
+// `GetPullRequestRange` & docs/pull-request.org).
+
+func resolvePullRequestPageTarget(rc *RouterContext, w http.ResponseWriter, r *http.Request) (*model.Repository, *model.PullRequest) {
+	rfn := r.PathValue("repoName")
+	if rc.Config.IsInPlainMode() {
+		FoundAt(w, fmt.Sprintf("/repo/%s", rfn))
+		return nil, nil
+	}
+	_, _, _, s, err := rc.ResolveRepositoryFullName(rfn)
+	if err == ErrNotFound {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil, nil
+	}
+	if err != nil {
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil, nil
+	}
+	if s.Type != model.REPO_TYPE_GIT {
+		rc.ReportNormalError("The repository you have requested isn't a Git repository.", w, r)
+		return nil, nil
+	}
+	pridStr := r.PathValue("prid")
+	prid, err := strconv.ParseInt(pridStr, 10, 64)
+	if err != nil {
+		rc.ReportNotFound(pridStr, "Pull request", rfn, w, r)
+		return nil, nil
+	}
+	pr, err := rc.DatabaseInterface.GetPullRequest(s.Namespace, s.Name, prid)
+	if err != nil {
+		if err == db.ErrEntityNotFound {
+			rc.ReportRedirect(fmt.Sprintf("/repo/%s/pull-request", rfn), 5, "Not Found", "The pull request you've specified does not exist in this repository.", w, r)
+			return nil, nil
+		}
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil, nil
+	}
+	return s, pr
+}
+
+func bindRepositoryPullRequestDiffController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/pull-request/{prid}/diff", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			s, pr := resolvePullRequestPageTarget(rc, w, r)
+			if pr == nil { return }
+			baseId, headId, err := GetPullRequestRange(rc, s, pr)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to resolve the range of the pull request: %s", err), w, r)
+				return
+			}
+			lgr := s.Repository.(*gitlib.LocalGitRepository)
+			diff, err := lgr.GetRangeDiff(baseId, headId)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to read diff: %s", err), w, r)
+				return
+			}
+			commentList, err := GetAllPullRequestCommentOnCode(rc, pr)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			// comments made on an older range (i.e. before the
+			// provider branch or the receiver branch is updated) are
+			// only shown in the timeline.
+			commentMap := make(map[int][]*model.PullRequestCommentOnCode, 0)
+			for i, item := range diff.ItemList {
+				for _, c := range commentList {
+					if (c.CommitId == headId && len(item.NewPath()) > 0 && c.Path == item.NewPath()) || (c.CommitId == baseId && len(item.OldPath()) > 0 && c.Path == item.OldPath()) {
+						commentMap[i] = append(commentMap[i], c)
+					}
+				}
+			}
+			LogTemplateError(rc.LoadTemplate("pull-request/pull-request-diff").Execute(w, &templates.RepositoryPullRequestDiffTemplateModel{
+				Config: rc.Config,
+				Repository: s,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				LoginInfo: rc.LoginInfo,
+				PullRequest: pr,
+				BaseId: baseId,
+				HeadId: headId,
+				Diff: diff,
+				CommentMap: commentMap,
+			}))
+		},
+	))
+
+	http.HandleFunc("GET /repo/{repoName}/pull-request/{prid}/commit", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			s, pr := resolvePullRequestPageTarget(rc, w, r)
+			if pr == nil { return }
+			baseId, headId, err := GetPullRequestRange(rc, s, pr)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to resolve the range of the pull request: %s", err), w, r)
+				return
+			}
+			lgr := s.Repository.(*gitlib.LocalGitRepository)
+			commitList, err := lgr.GetCommitRange(baseId, headId)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to read commits: %s", err), w, r)
+				return
+			}
+			m := make(map[string]string, 0)
+			for _, k := range commitList {
+				m[k.AuthorInfo.AuthorEmail] = ""
+				m[k.CommitterInfo.AuthorEmail] = ""
+			}
+			rc.DatabaseInterface.ResolveMultipleEmailToUsername(m)
+			LogTemplateError(rc.LoadTemplate("pull-request/pull-request-commit").Execute(w, &templates.RepositoryPullRequestCommitTemplateModel{
+				Config: rc.Config,
+				Repository: s,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				LoginInfo: rc.LoginInfo,
+				PullRequest: pr,
+				BaseId: baseId,
+				HeadId: headId,
+				CommitList: commitList,
+				EmailUserMapping: m,
+			}))
+		},
+	))
+}
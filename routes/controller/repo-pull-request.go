@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -87,7 +88,7 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 				FoundAt(w, fmt.Sprintf("/repo/%s", rfn))
 				return
 			}
-			_, _, _, s, err := rc.ResolveRepositoryFullName(rfn)
+			_, _, ns, s, err := rc.ResolveRepositoryFullName(rfn)
 			if err == ErrNotFound {
 				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
 				return
@@ -115,6 +116,31 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 			pn, err := strconv.ParseInt(pnstr, 10, 64)
 			if err != nil { pn = 0 }
 			preList, err := rc.DatabaseInterface.GetAllPullRequestEventPaginated(pr.PRAbsId, pn, 30)
+			pullRequestSetting, err := rc.DatabaseInterface.GetPullRequestSetting(s.Namespace, s.Name)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			reviewList, err := rc.DatabaseInterface.GetAllPullRequestReview(pr.PRAbsId)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			reviewCheckMessage := ""
+			err = model.CheckPullRequestReview(pullRequestSetting, reviewList)
+			if err != nil { reviewCheckMessage = err.Error() }
+			canReview := rc.LoginInfo.LoggedIn && CheckUserReviewPermission(rc.LoginInfo.UserName, rc.LoginInfo.IsAdmin, ns, s, pr)
+			// only open pull requests have their statuses shown since
+			// the provider branch may be gone after they're closed.
+			var statusList []*model.CommitStatus = nil
+			var providerStatusList []*model.CommitStatus = nil
+			statusCheckMessage := ""
+			if pr.Status == model.PULL_REQUEST_OPEN {
+				statusList, providerStatusList, err = db.GetPullRequestCommitStatus(rc.DatabaseInterface, pr)
+				if err != nil { LogIfError(err); statusList = nil; providerStatusList = nil }
+				err = model.CheckRequiredCommitStatus(pullRequestSetting, statusList)
+				if err != nil { statusCheckMessage = err.Error() }
+			}
 			LogTemplateError(rc.LoadTemplate("pull-request/single-pull-request").Execute(w, &templates.RepositorySinglePullRequestTemplateModel{
 				Config: rc.Config,
 				Repository: s,
@@ -125,6 +151,13 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 				PullRequest: pr,
 				PullRequestEventList: preList,
 				PageNum: pn,
+				PullRequestSetting: pullRequestSetting,
+				ReviewStateMap: model.LatestPullRequestReviewState(reviewList),
+				ReviewCheckMessage: reviewCheckMessage,
+				CanReview: canReview,
+				StatusList: statusList,
+				ProviderStatusList: providerStatusList,
+				StatusCheckMessage: statusCheckMessage,
 			}))
 		},
 	))
@@ -140,7 +173,7 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 				FoundAt(w, fmt.Sprintf("/repo/%s", rfn))
 				return
 			}
-			_, _, _, s, err := rc.ResolveRepositoryFullName(rfn)
+			_, _, ns, s, err := rc.ResolveRepositoryFullName(rfn)
 			if err == ErrNotFound {
 				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
 				return
@@ -182,21 +215,151 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 					rc.ReportInternalError(err.Error(), w, r)
 					return
 				}
+				LogIfError(ProcessIssueReference(rc, s, IssueReferenceSourceOfPullRequest(pr), r.Form.Get("content"), rc.LoginInfo.UserName, false))
+				NotifyPullRequestEvent(rc, s, pr, model.PULL_REQUEST_EVENT_COMMENT, rc.LoginInfo.UserName, r.Form.Get("content"))
 				FoundAt(w, returnPath)
-			case "merge-check":
-				e, err := rc.DatabaseInterface.CheckPullRequestMergeConflict(pr.PRAbsId)
-				fmt.Println("ee", e)
+			case "comment-on-code":
+				// see docs/pull-request.org.
+				diffPath := fmt.Sprintf("/repo/%s/pull-request/%d/diff", rfn, prid)
+				baseId, headId, err := GetPullRequestRange(rc, s, pr)
+				if err != nil {
+					rc.ReportInternalError(err.Error(), w, r)
+					return
+				}
+				if r.Form.Get("base") != baseId || r.Form.Get("head") != headId {
+					rc.ReportRedirect(diffPath, 5, "Pull Request Updated", "The pull request has been updated since you've loaded the page. Please try again.", w, r)
+					return
+				}
+				var commitId, p string
+				switch r.Form.Get("side") {
+				case "new":
+					commitId = headId
+					p = r.Form.Get("new-path")
+				case "old":
+					commitId = baseId
+					p = r.Form.Get("old-path")
+				}
+				content := strings.TrimSpace(r.Form.Get("content"))
+				if len(commitId) <= 0 || len(p) <= 0 || len(content) <= 0 {
+					rc.ReportRedirect(diffPath, 5, "Invalid Request", "Please specify the side, the lines & the content of the comment.", w, r)
+					return
+				}
+				lineStart, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("line-start")))
+				if err != nil { lineStart = 0 }
+				lineEnd, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("line-end")))
+				if err != nil { lineEnd = lineStart }
+				lines, err := s.Repository.(*gitlib.LocalGitRepository).ReadFileLineAtCommit(commitId, p)
+				if err != nil {
+					rc.ReportInternalError(err.Error(), w, r)
+					return
+				}
+				if lineStart < 1 || lineEnd < lineStart || lineEnd > len(lines) {
+					rc.ReportRedirect(diffPath, 5, "Invalid Request", fmt.Sprintf("Invalid line range; the file has %d line(s) on this side.", len(lines)), w, r)
+					return
+				}
+				c := &model.PullRequestCommentOnCode{
+					RepoNamespace: s.Namespace,
+					RepoName: s.Name,
+					CommitId: commitId,
+					Path: p,
+					LineRangeStart: lineStart-1,
+					LineRangeEnd: lineEnd,
+					Username: rc.LoginInfo.UserName,
+					Content: content,
+					Code: lines[lineStart-1:lineEnd],
+				}
+				_, err = rc.DatabaseInterface.CommentOnPullRequestCode(pr.PRAbsId, c)
+				if err != nil {
+					rc.ReportInternalError(err.Error(), w, r)
+					return
+				}
+				NotifyPullRequestCommentOnCode(rc, s, pr, c)
+				FoundAt(w, diffPath)
+			case "review":
+				state, err := strconv.Atoi(r.Form.Get("state"))
+				if err != nil || !model.ValidPullRequestReviewState(state) {
+					rc.ReportNormalError("Invalid Request", w, r)
+					return
+				}
+				if state != model.PULL_REQUEST_REVIEW_COMMENT && !CheckUserReviewPermission(rc.LoginInfo.UserName, rc.LoginInfo.IsAdmin, ns, s, pr) {
+					rc.ReportRedirect(returnPath, 5, "Not Allowed", "You can only leave review comments on this pull request.", w, r)
+					return
+				}
+				commitId, err := GetPullRequestProviderHead(rc, pr)
+				if err != nil {
+					rc.ReportInternalError(err.Error(), w, r)
+					return
+				}
+				review := &model.PullRequestReview{
+					PRAbsId: pr.PRAbsId,
+					Reviewer: rc.LoginInfo.UserName,
+					State: state,
+					Summary: r.Form.Get("summary"),
+					CommitId: commitId,
+				}
+				_, err = rc.DatabaseInterface.ReviewPullRequest(review)
 				if err != nil {
 					rc.ReportInternalError(err.Error(), w, r)
 					return
 				}
+				NotifyPullRequestReview(rc, s, pr, review)
 				FoundAt(w, returnPath)
+			case "merge-check":
+				// the check fetches from the provider repository,
+				// which could take a while. see docs/job-queue.org.
+				err = rc.JobQueue.Enqueue(model.JOB_TYPE_MERGE_CHECK, &model.MergeCheckJobPayload{PRAbsId: pr.PRAbsId})
+				if err != nil {
+					rc.ReportInternalError(err.Error(), w, r)
+					return
+				}
+				rc.ReportRedirect(returnPath, 3, "Merge Check Queued", "The merge check will be done in the background. Reload the page later to see the result.", w, r)
 			case "close-as-merged":
-				err = rc.DatabaseInterface.CheckAndMergePullRequest(pr.PRAbsId, rc.LoginInfo.UserName)
+				strategy, err := strconv.Atoi(r.Form.Get("merge-strategy"))
+				if err != nil || !gitlib.ValidMergeStrategy(strategy) {
+					rc.ReportNormalError("Invalid Request", w, r)
+					return
+				}
+				// the commits are needed for closing the issues they
+				// refer to, and they might be rewritten by the merge.
+				commitList, err := GetPullRequestCommitList(rc, s, pr)
+				LogIfError(err)
+				err = rc.DatabaseInterface.CheckAndMergePullRequest(pr.PRAbsId, rc.LoginInfo.UserName, strategy, r.Form.Get("merge-message"))
+				if errors.Is(err, model.ErrMergeStrategyNotAllowed) {
+					rc.ReportRedirect(returnPath, 5, "Not Allowed", err.Error(), w, r)
+					return
+				}
+				if errors.Is(err, gitlib.ErrCannotFastForward) || errors.Is(err, gitlib.ErrCannotRebaseMergeCommit) {
+					rc.ReportRedirect(returnPath, 5, "Cannot Merge", err.Error(), w, r)
+					return
+				}
+				var bpe *model.BranchProtectionError
+				if errors.As(err, &bpe) {
+					rc.ReportRedirect(returnPath, 5, "Protected Branch", err.Error(), w, r)
+					return
+				}
+				var pre *model.PullRequestReviewError
+				if errors.As(err, &pre) {
+					rc.ReportRedirect(returnPath, 5, "Review Required", err.Error(), w, r)
+					return
+				}
+				var cse *model.CommitStatusError
+				if errors.As(err, &cse) {
+					rc.ReportRedirect(returnPath, 5, "Status Checks Required", err.Error(), w, r)
+					return
+				}
 				if err != nil {
 					rc.ReportInternalError(err.Error(), w, r)
 					return
 				}
+				// `CheckAndMergePullRequest` silently does nothing when
+				// the merge check fails.
+				pr, err = rc.DatabaseInterface.GetPullRequestByAbsId(pr.PRAbsId)
+				if err == nil && pr.Status == model.PULL_REQUEST_CLOSED_AS_MERGED {
+					NotifyPullRequestEvent(rc, s, pr, model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED, rc.LoginInfo.UserName, "")
+					TriggerPullRequestWebhook(rc, s, pr, model.WEBHOOK_EVENT_PULL_REQUEST_MERGED, rc.LoginInfo.UserName, "")
+					err = ProcessPullRequestMergeReference(rc, s, pr, commitList, rc.LoginInfo.UserName)
+				}
+				LogIfError(err)
 				FoundAt(w, returnPath)
 			case "close-as-not-merged":
 				err = rc.DatabaseInterface.ClosePullRequestAsNotMerged(pr.PRAbsId, rc.LoginInfo.UserName)
@@ -204,6 +367,7 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 					rc.ReportInternalError(err.Error(), w, r)
 					return
 				}
+				NotifyPullRequestEvent(rc, s, pr, model.PULL_REQUEST_EVENT_CLOSE_AS_NOT_MERGED, rc.LoginInfo.UserName, "")
 				FoundAt(w, returnPath)
 			case "reopen":
 				err = rc.DatabaseInterface.ReopenPullRequest(pr.PRAbsId, rc.LoginInfo.UserName)
@@ -211,6 +375,7 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 					rc.ReportInternalError(err.Error(), w, r)
 					return
 				}
+				NotifyPullRequestEvent(rc, s, pr, model.PULL_REQUEST_EVENT_REOPEN, rc.LoginInfo.UserName, "")
 				FoundAt(w, returnPath)
 			default:
 				rc.ReportNormalError("Invalid Request", w, r)
@@ -338,6 +503,15 @@ func bindRepositoryPullRequestController(ctx *RouterContext) {
 				rc.ReportRedirect(fmt.Sprintf("/repo/%s/pull-request/new", rfn), 0, "Internal Error", fmt.Sprintf("Failed to create pull request: %s", err.Error()), w, r)
 				return
 			}
+			NotifyNewPullRequest(rc, s, resId, rc.LoginInfo.UserName)
+			TriggerNewPullRequestWebhook(rc, s, resId, rc.LoginInfo.UserName)
+			EnqueuePullRequestMergeCheck(rc, s, resId)
+			pr, err := rc.DatabaseInterface.GetPullRequest(s.Namespace, s.Name, resId)
+			if err != nil {
+				LogIfError(err)
+			} else {
+				EnqueuePullRequestCIRun(rc, []int64{pr.PRAbsId}, rc.LoginInfo.UserName)
+			}
 			FoundAt(w, fmt.Sprintf("/repo/%s/pull-request/%d", rfn, resId))
 		},
 	))
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// branch protection rules. see docs/protected-branch.org.
+//
+// only the owner of the repository, the owner of the namespace and
+// admins can edit the rules; having setting privileges thru acl is
+// not enough, since the rules are meant to restrict exactly those
+// people. the same goes for pull request settings (see
+// repo-setting-pull-request.go).
+
+func resolveOwnerOnlySettingTarget(rc *RouterContext, w http.ResponseWriter, r *http.Request, feature string) *model.Repository {
+	rfn := r.PathValue("repoName")
+	if !model.ValidRepositoryName(rfn) {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil
+	}
+	_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+	if err == ErrNotFound {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil
+	}
+	if err != nil {
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil
+	}
+	repoPath := fmt.Sprintf("/repo/%s", repo.FullName())
+	if rc.Config.IsInPlainMode() { FoundAt(w, repoPath); return nil }
+	if repo.Type != model.REPO_TYPE_GIT {
+		rc.ReportRedirect(fmt.Sprintf("/repo/%s/setting", rfn), 5, "Unsupported", fmt.Sprintf("%s only supports Git repositories.", feature), w, r)
+		return nil
+	}
+	isRepoOwner := repo.Owner == rc.LoginInfo.UserName
+	isNsOwner := ns.Owner == rc.LoginInfo.UserName
+	rc.LoginInfo.IsOwner = isRepoOwner || isNsOwner
+	rc.LoginInfo.IsStrictOwner = isRepoOwner
+	if !rc.LoginInfo.IsAdmin && !isRepoOwner && !isNsOwner {
+		rc.ReportRedirect(repoPath, 0,
+			"Not enough privilege",
+			"Your user account seems to not have enough privilege for this action.",
+			w, r,
+		)
+		return nil
+	}
+	rc.LoginInfo.IsSettingMember = true
+	return repo
+}
+
+func bindRepositorySettingBranchProtectionController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/setting/branch-protection", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveOwnerOnlySettingTarget(rc, w, r, "Branch protection")
+			if repo == nil { return }
+			ruleList, err := rc.DatabaseInterface.GetAllBranchProtectionRule(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve branch protection rules: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("repo-setting/branch-protection").Execute(w, &templates.RepositorySettingBranchProtectionTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				RuleList: ruleList,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/setting/branch-protection", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveOwnerOnlySettingTarget(rc, w, r, "Branch protection")
+			if repo == nil { return }
+			settingPath := fmt.Sprintf("/repo/%s/setting/branch-protection", repo.FullName())
+			err := r.ParseForm()
+			if err != nil {
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			pattern := strings.TrimSpace(r.Form.Get("pattern"))
+			if !model.ValidBranchProtectionPattern(pattern) {
+				rc.ReportRedirect(settingPath, 5, "Invalid Request", "Branch name pattern is invalid.", w, r)
+				return
+			}
+			allowedPusher := model.ParseUsernameList(r.Form.Get("allowed-pusher"))
+			for _, k := range allowedPusher {
+				if !model.ValidUserName(k) {
+					rc.ReportRedirect(settingPath, 5, "Invalid Request", fmt.Sprintf("Invalid user name: %s", k), w, r)
+					return
+				}
+			}
+			err = rc.DatabaseInterface.SetBranchProtectionRule(repo.Namespace, repo.Name, &model.BranchProtectionRule{
+				Pattern: pattern,
+				DisallowForcePush: len(r.Form.Get("disallow-force-push")) > 0,
+				DisallowDeletion: len(r.Form.Get("disallow-deletion")) > 0,
+				AllowedPusher: allowedPusher,
+				RequirePullRequest: len(r.Form.Get("require-pull-request")) > 0,
+			})
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to save branch protection rule: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Updated", "The branch protection rule has been saved.", w, r)
+		},
+	))
+
+	http.HandleFunc("GET /repo/{repoName}/setting/branch-protection/delete", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveOwnerOnlySettingTarget(rc, w, r, "Branch protection")
+			if repo == nil { return }
+			settingPath := fmt.Sprintf("/repo/%s/setting/branch-protection", repo.FullName())
+			// patterns can contain slashes, so it's passed as a query
+			// parameter instead.
+			pattern := r.URL.Query().Get("pattern")
+			err := rc.DatabaseInterface.DeleteBranchProtectionRule(repo.Namespace, repo.Name, pattern)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to delete branch protection rule: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Deleted", fmt.Sprintf("The branch protection rule for \"%s\" has been deleted.", pattern), w, r)
+		},
+	))
+}
+
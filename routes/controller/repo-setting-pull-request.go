@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// pull request settings, i.e. what a pull request needs before it
+// can be merged & how it can be merged. see
+// docs/pull-request-review.org, docs/commit-status.org &
+// docs/pull-request.org.
+
+func bindRepositorySettingPullRequestController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/setting/pull-request", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveOwnerOnlySettingTarget(rc, w, r, "Pull request")
+			if repo == nil { return }
+			setting, err := rc.DatabaseInterface.GetPullRequestSetting(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve pull request setting: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("repo-setting/pull-request").Execute(w, &templates.RepositorySettingPullRequestTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				Setting: setting,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/setting/pull-request", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveOwnerOnlySettingTarget(rc, w, r, "Pull request")
+			if repo == nil { return }
+			settingPath := fmt.Sprintf("/repo/%s/setting/pull-request", repo.FullName())
+			err := r.ParseForm()
+			if err != nil {
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			requiredApprovalCount, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("required-approval-count")))
+			if err != nil || requiredApprovalCount < 0 {
+				rc.ReportRedirect(settingPath, 5, "Invalid Request", "Required approval count must be a non-negative integer.", w, r)
+				return
+			}
+			requiredReviewer := model.ParseUsernameList(r.Form.Get("required-reviewer"))
+			for _, k := range requiredReviewer {
+				if !model.ValidUserName(k) {
+					rc.ReportRedirect(settingPath, 5, "Invalid Request", fmt.Sprintf("Invalid user name: %s", k), w, r)
+					return
+				}
+			}
+			allowedMergeStrategy := model.ParseMergeStrategyList(strings.Join(r.Form["allowed-merge-strategy"], ","))
+			for _, k := range strings.Split(r.Form.Get("required-status-context"), ",") {
+				k = strings.TrimSpace(k)
+				if len(k) > 0 && !model.ValidCommitStatusContext(k) {
+					rc.ReportRedirect(settingPath, 5, "Invalid Request", fmt.Sprintf("Invalid status context: %s", k), w, r)
+					return
+				}
+			}
+			requiredStatusContext := model.ParseCommitStatusContextList(r.Form.Get("required-status-context"))
+			err = rc.DatabaseInterface.SetPullRequestSetting(repo.Namespace, repo.Name, &model.PullRequestSetting{
+				RequiredApprovalCount: requiredApprovalCount,
+				RequiredReviewer: requiredReviewer,
+				AllowedMergeStrategy: allowedMergeStrategy,
+				RequiredStatusContext: requiredStatusContext,
+			})
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to save pull request setting: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Updated", "The pull request setting has been saved.", w, r)
+		},
+	))
+}
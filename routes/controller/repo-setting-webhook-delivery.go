@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the list of webhook deliveries & redelivery. see docs/webhooks.org.
+
+// like `resolveOwnerOnlySettingTarget` but members w/ the privilege
+// to edit webhooks are allowed as well.
+func resolveWebhookSettingTarget(rc *RouterContext, w http.ResponseWriter, r *http.Request) *model.Repository {
+	rfn := r.PathValue("repoName")
+	if !model.ValidRepositoryName(rfn) {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil
+	}
+	_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+	if err == ErrNotFound {
+		rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+		return nil
+	}
+	if err != nil {
+		rc.ReportInternalError(err.Error(), w, r)
+		return nil
+	}
+	repoPath := fmt.Sprintf("/repo/%s", repo.FullName())
+	if rc.Config.IsInPlainMode() { FoundAt(w, repoPath); return nil }
+	if repo.Type != model.REPO_TYPE_GIT {
+		rc.ReportRedirect(fmt.Sprintf("/repo/%s/setting", rfn), 5, "Unsupported", "Webhooks only supports Git repositories.", w, r)
+		return nil
+	}
+	isRepoOwner := repo.Owner == rc.LoginInfo.UserName
+	isNsOwner := ns.Owner == rc.LoginInfo.UserName
+	rc.LoginInfo.IsOwner = isRepoOwner || isNsOwner
+	repoPriv := repo.AccessControlList.GetUserPrivilege(rc.LoginInfo.UserName)
+	nsPriv := ns.ACL.GetUserPrivilege(rc.LoginInfo.UserName)
+	allowEdit := (repoPriv != nil && repoPriv.EditWebHooks) || (nsPriv != nil && nsPriv.EditWebHooks)
+	if !rc.LoginInfo.IsAdmin && !isRepoOwner && !isNsOwner && !allowEdit {
+		rc.ReportRedirect(repoPath, 0,
+			"Not enough privilege",
+			"Your user account seems to not have enough privilege for this action.",
+			w, r,
+		)
+		return nil
+	}
+	rc.LoginInfo.IsSettingMember = true
+	return repo
+}
+
+// returns nil if the delivery doesn't belong to `repo`.
+func resolveWebhookDelivery(rc *RouterContext, w http.ResponseWriter, r *http.Request, repo *model.Repository) *model.WebhookDelivery {
+	listPath := fmt.Sprintf("/repo/%s/setting/webhook/delivery", repo.FullName())
+	d, err := rc.DatabaseInterface.GetWebhookDelivery(r.PathValue("uuid"))
+	if err == db.ErrEntityNotFound || (err == nil && (d.RepoNamespace != repo.Namespace || d.RepoName != repo.Name)) {
+		rc.ReportRedirect(listPath, 5, "Not Found", "The webhook delivery you've specified does not exist in this repository.", w, r)
+		return nil
+	}
+	if err != nil {
+		rc.ReportInternalError(fmt.Sprintf("Failed to retrieve webhook delivery: %s", err), w, r)
+		return nil
+	}
+	return d
+}
+
+func bindRepositorySettingWebhookDeliveryController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/setting/webhook/delivery", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			p, err := strconv.ParseInt(r.URL.Query().Get("p"), 10, 64)
+			if err != nil { p = 1 }
+			count, err := rc.DatabaseInterface.CountWebhookDelivery(repo.Namespace, repo.Name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to count webhook deliveries: %s", err), w, r)
+				return
+			}
+			var pageSize int64 = 30
+			pageCount := count / pageSize
+			if (count % pageSize) > 0 { pageCount += 1 }
+			if pageCount <= 0 { pageCount = 1 }
+			if p > pageCount { p = pageCount }
+			if p < 1 { p = 1 }
+			deliveryList, err := rc.DatabaseInterface.GetWebhookDeliveryPaginated(repo.Namespace, repo.Name, p-1, pageSize)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve webhook deliveries: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("repo-setting/webhook-delivery-list").Execute(w, &templates.RepositorySettingWebhookDeliveryListTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				DeliveryList: deliveryList,
+				PageInfo: &templates.PageInfoModel{
+					PageNum: p,
+					PageSize: pageSize,
+					TotalPage: pageCount,
+				},
+			}))
+		},
+	))
+
+	http.HandleFunc("GET /repo/{repoName}/setting/webhook/delivery/{uuid}", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			d := resolveWebhookDelivery(rc, w, r, repo)
+			if d == nil { return }
+			LogTemplateError(rc.LoadTemplate("repo-setting/webhook-delivery").Execute(w, &templates.RepositorySettingWebhookDeliveryTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				Delivery: d,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/setting/webhook/delivery/{uuid}/redeliver", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			d := resolveWebhookDelivery(rc, w, r, repo)
+			if d == nil { return }
+			deliveryPath := fmt.Sprintf("/repo/%s/setting/webhook/delivery/%s", repo.FullName(), d.UUID)
+			hook, err := GetWebhookOfDelivery(rc, repo, d)
+			if err == db.ErrEntityNotFound {
+				rc.ReportRedirect(deliveryPath, 5, "Webhook Deleted", "The webhook of this delivery has been deleted.", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			if !hook.Enable {
+				rc.ReportRedirect(deliveryPath, 5, "Webhook Disabled", "The webhook of this delivery is disabled; enable it before redelivering.", w, r)
+				return
+			}
+			nd, err := RedeliverWebhook(rc, repo, hook, d)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to redeliver webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(fmt.Sprintf("/repo/%s/setting/webhook/delivery/%s", repo.FullName(), nd.UUID), 3, "Redelivery Queued", "The webhook will be redelivered in the background.", w, r)
+		},
+	))
+}
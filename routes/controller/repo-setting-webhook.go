@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// the webhooks of a repository other than the default one (which
+// is edited at /repo/{repoName}/setting/webhook). see
+// docs/webhooks.org.
+
+// fills `hook` w/ the submitted webhook form. see the
+// `_webhook-form` template.
+func parseWebhookForm(r *http.Request, hook *model.Webhook) error {
+	targetUrl := strings.TrimSpace(r.Form.Get("target-url"))
+	u, err := url.Parse(targetUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) <= 0 {
+		return errors.New("The target URL must be a valid HTTP or HTTPS URL.")
+	}
+	eventList := make([]string, 0)
+	for _, k := range r.Form["event"] {
+		if !model.ValidWebhookEvent(k) {
+			return fmt.Errorf("Unknown event: %s", k)
+		}
+		eventList = append(eventList, k)
+	}
+	if len(eventList) <= 0 {
+		return errors.New("The webhook must subscribe to at least one event.")
+	}
+	branchFilter := model.ParseWebhookBranchFilter(r.Form.Get("branch-filter"))
+	for _, k := range branchFilter {
+		_, err := path.Match(k, "")
+		if err != nil { return fmt.Errorf("Invalid branch pattern: %s", k) }
+	}
+	hook.TargetURL = targetUrl
+	hook.Secret = strings.TrimSpace(r.Form.Get("secret"))
+	hook.PayloadType = "json"
+	hook.EventList = eventList
+	hook.BranchFilter = branchFilter
+	hook.Enable = len(r.Form.Get("enable")) > 0
+	return nil
+}
+
+// the webhook of the submitted form w/ the defaults filled.
+func newWebhookFormDefault() *model.Webhook {
+	return &model.Webhook{
+		PayloadType: "json",
+		EventList: []string{model.WEBHOOK_EVENT_PUSH},
+		Enable: true,
+	}
+}
+
+// returns nil if the webhook doesn't belong to the repository (or
+// the namespace if `repoName` is empty).
+func resolveWebhook(rc *RouterContext, w http.ResponseWriter, r *http.Request, namespace string, repoName string, listPath string) *model.Webhook {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		rc.ReportRedirect(listPath, 5, "Not Found", "The webhook you've specified does not exist.", w, r)
+		return nil
+	}
+	hook, err := rc.DatabaseInterface.GetWebhook(id)
+	if err == db.ErrEntityNotFound || (err == nil && (hook.Namespace != namespace || hook.RepoName != repoName)) {
+		rc.ReportRedirect(listPath, 5, "Not Found", "The webhook you've specified does not exist.", w, r)
+		return nil
+	}
+	if err != nil {
+		rc.ReportInternalError(fmt.Sprintf("Failed to retrieve webhook: %s", err), w, r)
+		return nil
+	}
+	return hook
+}
+
+func bindRepositorySettingWebhookController(ctx *RouterContext) {
+	http.HandleFunc("POST /repo/{repoName}/setting/webhook/new", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			settingPath := fmt.Sprintf("/repo/%s/setting/webhook", repo.FullName())
+			hook := newWebhookFormDefault()
+			hook.Namespace = repo.Namespace
+			hook.RepoName = repo.Name
+			err := parseWebhookForm(r, hook)
+			if err != nil {
+				rc.ReportRedirect(settingPath, 5, "Invalid Webhook", err.Error(), w, r)
+				return
+			}
+			_, err = rc.DatabaseInterface.NewWebhook(hook)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to add webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Added", "The webhook has been added.", w, r)
+		},
+	))
+
+	http.HandleFunc("GET /repo/{repoName}/setting/webhook/{id}", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			hook := resolveWebhook(rc, w, r, repo.Namespace, repo.Name, fmt.Sprintf("/repo/%s/setting/webhook", repo.FullName()))
+			if hook == nil { return }
+			LogTemplateError(rc.LoadTemplate("repo-setting/webhook").Execute(w, &templates.RepositorySettingWebhookTemplateModel{
+				Config: rc.Config,
+				Repository: repo,
+				RepoHeaderInfo: GenerateRepoHeader("", ""),
+				RepoFullName: repo.FullName(),
+				LoginInfo: rc.LoginInfo,
+				Webhook: hook,
+				WebhookEventList: model.WebhookEventList,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/setting/webhook/{id}", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			hook := resolveWebhook(rc, w, r, repo.Namespace, repo.Name, fmt.Sprintf("/repo/%s/setting/webhook", repo.FullName()))
+			if hook == nil { return }
+			hookPath := fmt.Sprintf("/repo/%s/setting/webhook/%d", repo.FullName(), hook.Id)
+			err := parseWebhookForm(r, hook)
+			if err != nil {
+				rc.ReportRedirect(hookPath, 5, "Invalid Webhook", err.Error(), w, r)
+				return
+			}
+			err = rc.DatabaseInterface.UpdateWebhook(hook)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(hookPath, 3, "Updated", "The webhook has been updated.", w, r)
+		},
+	))
+
+	http.HandleFunc("POST /repo/{repoName}/setting/webhook/{id}/delete", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			repo := resolveWebhookSettingTarget(rc, w, r)
+			if repo == nil { return }
+			settingPath := fmt.Sprintf("/repo/%s/setting/webhook", repo.FullName())
+			hook := resolveWebhook(rc, w, r, repo.Namespace, repo.Name, settingPath)
+			if hook == nil { return }
+			err := rc.DatabaseInterface.DeleteWebhook(hook.Id)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to delete webhook: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(settingPath, 3, "Deleted", "The webhook has been deleted.", w, r)
+		},
+	))
+}
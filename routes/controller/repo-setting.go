@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
 	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 	"github.com/GitusCodeForge/Gitus/pkg/auxfuncs"
 	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
@@ -144,6 +145,89 @@ func bindRepositorySettingController(ctx *RouterContext) {
 		},
 	))
 
+	// see docs/repo-move.org.
+	http.HandleFunc("POST /repo/{repoName}/setting/move", UseMiddleware(
+		[]Middleware{
+			Logged, ValidPOSTRequestRequired, LoginRequired, GlobalVisibility, ErrorGuard,
+			ValidRepositoryNameRequired("repoName"),
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rfn := r.PathValue("repoName")
+			_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+			if err == db.ErrEntityNotFound {
+				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			settingPath := fmt.Sprintf("/repo/%s/setting", repo.FullName())
+			isRepoOwner := repo.Owner == rc.LoginInfo.UserName
+			isNsOwner := ns.Owner == rc.LoginInfo.UserName
+			repoPriv := repo.AccessControlList.GetUserPrivilege(rc.LoginInfo.UserName)
+			nsPriv := ns.ACL.GetUserPrivilege(rc.LoginInfo.UserName)
+			// moving a repository away is about as destructive as
+			// deleting it.
+			canDeleteRepo := (repoPriv != nil && repoPriv.DeleteRepository) || (nsPriv != nil && nsPriv.DeleteRepository)
+			if !rc.LoginInfo.IsAdmin && !isRepoOwner && !isNsOwner && !canDeleteRepo {
+				rc.ReportRedirect(settingPath, 0,
+					"Not enough privilege",
+					"Your user account seems to not have enough privilege for this action.",
+					w, r,
+				)
+				return
+			}
+			newNsName := repo.Namespace
+			if rc.Config.UseNamespace {
+				newNsName = strings.TrimSpace(r.Form.Get("namespace"))
+				if !model.ValidNamespaceName(newNsName) {
+					rc.ReportRedirect(settingPath, 5, "Invalid Namespace Name", "Namespace name must consists of only upper & lowercase letters (a-z, A-Z), 0-9, underscore and hyphen.", w, r)
+					return
+				}
+			}
+			newName := strings.TrimSpace(r.Form.Get("name"))
+			if len(newName) <= 0 || !model.ValidStrictRepositoryName(newName) {
+				rc.ReportRedirect(settingPath, 5, "Invalid Repository Name", "Repository name must consists of only upper & lowercase letters (a-z, A-Z), 0-9, underscore and hyphen.", w, r)
+				return
+			}
+			if newNsName == repo.Namespace && newName == repo.Name {
+				rc.ReportRedirect(settingPath, 3, "Nothing Changed", "The new name is the same as the current one.", w, r)
+				return
+			}
+			if newNsName != repo.Namespace {
+				newNs, err := rc.DatabaseInterface.GetNamespaceByName(newNsName)
+				if err == db.ErrEntityNotFound {
+					rc.ReportRedirect(settingPath, 5, "Namespace Not Found", fmt.Sprintf("The namespace %s does not exist.", newNsName), w, r)
+					return
+				}
+				if err != nil {
+					rc.ReportInternalError(err.Error(), w, r)
+					return
+				}
+				// same as creating a new repository in the target
+				// namespace.
+				priv := newNs.ACL.GetUserPrivilege(rc.LoginInfo.UserName)
+				if !rc.LoginInfo.IsAdmin && newNs.Owner != rc.LoginInfo.UserName && (priv == nil || !priv.AddRepository) {
+					rc.ReportRedirect(settingPath, 5, "Not enough privilege", fmt.Sprintf("Your user account is not allowed to add repositories to the namespace %s.", newNsName), w, r)
+					return
+				}
+			}
+			err = rc.DatabaseInterface.MoveRepository(repo.Namespace, repo.Name, newNsName, newName)
+			if err == db.ErrEntityAlreadyExists {
+				rc.ReportRedirect(settingPath, 5, "Name Taken", "A repository with the new name already exists.", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to move repository: %s", err), w, r)
+				return
+			}
+			repo.Namespace = newNsName
+			repo.Name = newName
+			rc.ReportRedirect(fmt.Sprintf("/repo/%s/setting", repo.FullName()), 3, "Moved", "The repository has been moved; the old name will redirect to the new one.", w, r)
+		},
+	))
+
 	http.HandleFunc("GET /repo/{repoName}/delete", UseMiddleware(
 		[]Middleware{
 			Logged, LoginRequired, GlobalVisibility, ErrorGuard,
@@ -338,6 +422,7 @@ func bindRepositorySettingController(ctx *RouterContext) {
 				ctx.ReportInternalError(err.Error(), w, r)
 				return
 			}
+			NotifyMembership(rc, username, rc.LoginInfo.UserName, fmt.Sprintf("%s added you to the repository %s", rc.LoginInfo.UserName, rfn), fmt.Sprintf("/repo/%s", rfn))
 			FoundAt(w, fmt.Sprintf("/repo/%s/setting/member", rfn))
 
 		},
@@ -452,6 +537,7 @@ func bindRepositorySettingController(ctx *RouterContext) {
 				)
 				return
 			}
+			NotifyMembership(rc, targetUsername, rc.LoginInfo.UserName, fmt.Sprintf("%s changed your privilege in the repository %s", rc.LoginInfo.UserName, rfn), fmt.Sprintf("/repo/%s", rfn))
 			FoundAt(w, fmt.Sprintf("/repo/%s/setting/member", rfn))
 		},
 	))
@@ -487,6 +573,7 @@ func bindRepositorySettingController(ctx *RouterContext) {
 				ctx.ReportInternalError(fmt.Sprintf("Failed to delete member: %s.", err), w, r)
 				return
 			}
+			NotifyMembership(rc, targetUsername, rc.LoginInfo.UserName, fmt.Sprintf("%s removed you from the repository %s", rc.LoginInfo.UserName, rfn), "")
 			FoundAt(w, fmt.Sprintf("/repo/%s/setting/member", rfn))
 		},
 	))
@@ -673,11 +760,19 @@ func bindRepositorySettingController(ctx *RouterContext) {
 					return
 				}
 				rc.LoginInfo.IsSettingMember = true
+				hookList, err := rc.DatabaseInterface.GetAllWebhook(repo.Namespace, repo.Name)
+				if err != nil {
+					ctx.ReportInternalError(fmt.Sprintf("Failed to retrieve webhooks: %s", err), w, r)
+					return
+				}
 				LogTemplateError(ctx.LoadTemplate("repo-setting/edit-webhook").Execute(w, templates.RepositorySettingEditWebHookTemplateModel{
 					Config: ctx.Config,
 					Repository: repo,
 					RepoFullName: rfn,
 					LoginInfo: rc.LoginInfo,
+					WebhookList: hookList,
+					Webhook: newWebhookFormDefault(),
+					WebhookEventList: model.WebhookEventList,
 				}))
 
 			},
@@ -743,18 +838,12 @@ func bindRepositorySettingController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed to update repository info: %s", err), w, r)
 				return
 			}
-			if enableWebhook {
-				err = lgr.EnableWebHook(repo.FullName())
-				if err != nil {
-					rc.ReportInternalError(fmt.Sprintf("Failed to setup webhook: %s", err), w, r)
-					return
-				}
-			} else {
-				err = lgr.DisableWebHook()
-				if err != nil {
-					rc.ReportInternalError(fmt.Sprintf("Failed to disable webhook: %s", err), w, r)
-					return
-				}
+			// webhooks are sent by the post-receive hook dispatcher,
+			// which checks if it's enabled by itself.
+			err = lgr.InstallGitusHooks()
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to setup webhook: %s", err), w, r)
+				return
 			}
 			rc.ReportRedirect(fmt.Sprintf("/repo/%s/setting/webhook", repo.FullName()), 5, "Updated", "Your configuration of webhooks has been saved.", w, r)
 		},
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// starring a repository. see docs/explore.org.
+func bindRepositoryStarController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/star", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rfn := r.PathValue("repoName")
+			_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+			if err == ErrNotFound || (err == nil && !CheckRepositoryVisibleToUser(rc.LoginInfo, ns, repo)) {
+				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			starring, err := rc.DatabaseInterface.IsStarringRepository(repo.Namespace, repo.Name, rc.LoginInfo.UserName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("star").Execute(w, &templates.StarTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				Repository: repo,
+				Starring: starring,
+			}))
+		},
+	))
+	
+	http.HandleFunc("POST /repo/{repoName}/star", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rfn := r.PathValue("repoName")
+			_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+			if err == ErrNotFound || (err == nil && !CheckRepositoryVisibleToUser(rc.LoginInfo, ns, repo)) {
+				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			switch r.Form.Get("type") {
+			case "star":
+				err = rc.DatabaseInterface.StarRepository(repo.Namespace, repo.Name, rc.LoginInfo.UserName)
+			case "unstar":
+				err = rc.DatabaseInterface.UnstarRepository(repo.Namespace, repo.Name, rc.LoginInfo.UserName)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update starring status: %s", err), w, r)
+				return
+			}
+			FoundAt(w, fmt.Sprintf("/repo/%s/star", rfn))
+		},
+	))
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// watching a repository. see docs/notification.org.
+func bindRepositoryWatchController(ctx *RouterContext) {
+	http.HandleFunc("GET /repo/{repoName}/watch", UseMiddleware(
+		[]Middleware{Logged, ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rfn := r.PathValue("repoName")
+			_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+			if err == ErrNotFound || (err == nil && !CheckRepositoryVisibleToUser(rc.LoginInfo, ns, repo)) {
+				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			watching, err := rc.DatabaseInterface.IsWatchingRepository(repo.Namespace, repo.Name, rc.LoginInfo.UserName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("watch").Execute(w, &templates.WatchTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				Repository: repo,
+				Watching: watching,
+			}))
+		},
+	))
+	
+	http.HandleFunc("POST /repo/{repoName}/watch", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			ValidRepositoryNameRequired("repoName"),
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			rfn := r.PathValue("repoName")
+			_, _, ns, repo, err := rc.ResolveRepositoryFullName(rfn)
+			if err == ErrNotFound || (err == nil && !CheckRepositoryVisibleToUser(rc.LoginInfo, ns, repo)) {
+				rc.ReportNotFound(rfn, "Repository", "Depot", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			switch r.Form.Get("type") {
+			case "watch":
+				err = rc.DatabaseInterface.WatchRepository(repo.Namespace, repo.Name, rc.LoginInfo.UserName)
+			case "unwatch":
+				err = rc.DatabaseInterface.UnwatchRepository(repo.Namespace, repo.Name, rc.LoginInfo.UserName)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update watching status: %s", err), w, r)
+				return
+			}
+			FoundAt(w, fmt.Sprintf("/repo/%s/watch", rfn))
+		},
+	))
+}
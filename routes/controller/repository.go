@@ -132,6 +132,7 @@ func bindRepositoryController(ctx *RouterContext) {
 				RootPath: fmt.Sprintf("/repo/%s", s.FullName()),
 				Commit: cobj,
 				EmailUserMapping: emailUserMap,
+				StatusList: GetCommitStatusList(rc, s, cobj.Id),
 			}
 			permaLink = fmt.Sprintf("/repo/%s/commit/%s/%s", rfn, cobj.Id, "")
 			obj, err = rr.ReadObject(cobj.TreeObjId)
@@ -257,7 +258,11 @@ func bindRepositoryController(ctx *RouterContext) {
 
 	if ctx.Config.OperationMode == gitus.OP_MODE_NORMAL {
 		bindRepositoryForkController(ctx)
+		bindRepositoryWatchController(ctx)
+		bindRepositoryStarController(ctx)
 		bindRepositoryPullRequestController(ctx)
+		bindRepositoryPullRequestDiffController(ctx)
+		bindRepositoryCIController(ctx)
 	}
 }
 
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+func bindSettingNotificationController(ctx *RouterContext) {
+	http.HandleFunc("GET /setting/notification", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			setting, err := rc.DatabaseInterface.GetNotificationSetting(rc.LoginInfo.UserName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("setting/notification").Execute(w, &templates.SettingNotificationTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				Setting: setting,
+				MailerAvailable: rc.Mailer != nil,
+			}))
+		},
+	))
+	
+	http.HandleFunc("POST /setting/notification", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			LoginRequired, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			setting := &model.NotificationSetting{
+				EmailIssue: len(r.Form.Get("email-issue")) > 0,
+				EmailPullRequest: len(r.Form.Get("email-pull-request")) > 0,
+				EmailMembership: len(r.Form.Get("email-membership")) > 0,
+				EmailRegistration: len(r.Form.Get("email-registration")) > 0,
+				AutoWatch: len(r.Form.Get("auto-watch")) > 0,
+			}
+			err := rc.DatabaseInterface.SetNotificationSetting(rc.LoginInfo.UserName, setting)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			rc.ReportRedirect("/setting/notification", 3, "Setting Updated", "Your notification settings have been updated.", w, r)
+		},
+	))
+}
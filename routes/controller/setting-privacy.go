@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/pkg/totp"
+	"github.com/GitusCodeForge/Gitus/pkg/webauthn"
 	. "github.com/GitusCodeForge/Gitus/routes"
 	"github.com/GitusCodeForge/Gitus/templates"
 )
@@ -19,11 +23,7 @@ func bindSettingPrivacyController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed while retrieving user: %s\n", err), w, r)
 				return
 			}
-			LogTemplateError(rc.LoadTemplate("setting/privacy").Execute(w, &templates.SettingPrivacyTemplateModel{
-				Config: rc.Config,
-				User: user,
-				LoginInfo: rc.LoginInfo,
-			}))
+			renderSettingPrivacy(rc, user, "", nil, w)
 		},
 	))
 	
@@ -50,6 +50,174 @@ func bindSettingPrivacyController(ctx *RouterContext) {
 					}
 					rc.ReportRedirect("/setting/privacy", 5, "Setting Updated", "Your configuration about two-factor authentication has been updated.", w, r)
 					return
+				case "totp-begin":
+					if user.TFAConfig.TOTP.Enable {
+						renderSettingPrivacy(rc, user, "TOTP is already enabled. Disable it first if you want to set it up again.", nil, w)
+						return
+					}
+					secret, err := totp.NewSecret()
+					if err != nil {
+						rc.ReportInternalError(fmt.Sprintf("Failed to generate secret: %s", err), w, r)
+						return
+					}
+					user.TFAConfig.TOTP.PendingSecret = secret
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					FoundAt(w, "/setting/privacy")
+					return
+				case "totp-cancel":
+					user.TFAConfig.TOTP.PendingSecret = ""
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					FoundAt(w, "/setting/privacy")
+					return
+				case "totp-confirm":
+					if len(user.TFAConfig.TOTP.PendingSecret) <= 0 {
+						FoundAt(w, "/setting/privacy")
+						return
+					}
+					step := totp.Validate(user.TFAConfig.TOTP.PendingSecret, r.Form.Get("code"), time.Now())
+					if step < 0 {
+						renderSettingPrivacy(rc, user, "Invalid code. Please check if the time on your device is correct.", nil, w)
+						return
+					}
+					user.TFAConfig.TOTP.Enable = true
+					user.TFAConfig.TOTP.Secret = user.TFAConfig.TOTP.PendingSecret
+					user.TFAConfig.TOTP.PendingSecret = ""
+					user.TFAConfig.TOTP.LastStep = step
+					codeList, err := user.TFAConfig.NewRecoveryCodeList()
+					if err != nil {
+						rc.ReportInternalError(fmt.Sprintf("Failed to generate recovery codes: %s", err), w, r)
+						return
+					}
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					renderSettingPrivacy(rc, user, "", codeList, w)
+					return
+				case "totp-disable":
+					if !user.TFAConfig.UseTOTPCode(r.Form.Get("code")) && !user.TFAConfig.UseRecoveryCode(r.Form.Get("code")) {
+						renderSettingPrivacy(rc, user, "Invalid authentication code or recovery code.", nil, w)
+						return
+					}
+					user.TFAConfig.TOTP = model.GitusUserTOTPConfig{}
+					user.TFAConfig.RecoveryCode = nil
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					rc.ReportRedirect("/setting/privacy", 5, "Setting Updated", "TOTP two-factor authentication has been disabled.", w, r)
+					return
+				case "recovery-code":
+					if !user.TFAConfig.UseTOTPCode(r.Form.Get("code")) {
+						renderSettingPrivacy(rc, user, "Invalid authentication code.", nil, w)
+						return
+					}
+					codeList, err := user.TFAConfig.NewRecoveryCodeList()
+					if err != nil {
+						rc.ReportInternalError(fmt.Sprintf("Failed to generate recovery codes: %s", err), w, r)
+						return
+					}
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					renderSettingPrivacy(rc, user, "", codeList, w)
+					return
+				case "webauthn-register":
+					name := strings.TrimSpace(r.Form.Get("name"))
+					if len(name) <= 0 {
+						renderSettingPrivacyWebAuthnError(rc, user, "Please give the security key a name.", w)
+						return
+					}
+					for _, k := range user.TFAConfig.WebAuthn {
+						if k.Name == name {
+							renderSettingPrivacyWebAuthnError(rc, user, "A security key with the same name already exists.", w)
+							return
+						}
+					}
+					if rc.ConfirmCodeManager == nil {
+						rc.ReportInternalError("Confirm code manager not initialized. Please contact site owner to fix this problem... ", w, r)
+						return
+					}
+					challenge, ok := rc.ConfirmCodeManager.Get(webAuthnRegisterChallengeKeyName(user.Name))
+					if !ok || len(challenge) <= 0 {
+						renderSettingPrivacyWebAuthnError(rc, user, "The request has expired. Please try again.", w)
+						return
+					}
+					rc.ConfirmCodeManager.Register(webAuthnRegisterChallengeKeyName(user.Name), "", time.Minute)
+					rpId, origin, err := webAuthnRelyingParty(rc.Config)
+					if err != nil {
+						renderSettingPrivacyWebAuthnError(rc, user, fmt.Sprintf("Security keys are not available: %s.", err), w)
+						return
+					}
+					clientData, err1 := webauthn.DecodeBase64(r.Form.Get("client-data"))
+					attestationObject, err2 := webauthn.DecodeBase64(r.Form.Get("attestation-object"))
+					if err1 != nil || err2 != nil {
+						rc.ReportNormalError("Invalid request", w, r)
+						return
+					}
+					cred, err := webauthn.VerifyRegistration(rpId, origin, challenge, clientData, attestationObject)
+					if err != nil {
+						renderSettingPrivacyWebAuthnError(rc, user, fmt.Sprintf("Failed to verify security key: %s.", err), w)
+						return
+					}
+					if user.TFAConfig.GetWebAuthnCredentialById(cred.Id) != nil {
+						renderSettingPrivacyWebAuthnError(rc, user, "This security key is already registered.", w)
+						return
+					}
+					user.TFAConfig.WebAuthn = append(user.TFAConfig.WebAuthn, model.GitusUserWebAuthnCredential{
+						Name: name,
+						Id: cred.Id,
+						PublicKey: cred.PublicKey,
+						SignCount: cred.SignCount,
+						RegisterTime: time.Now().Unix(),
+					})
+					// users who only use security keys need recovery
+					// codes as well.
+					var codeList []string = nil
+					if len(user.TFAConfig.RecoveryCode) <= 0 {
+						codeList, err = user.TFAConfig.NewRecoveryCodeList()
+						if err != nil {
+							rc.ReportInternalError(fmt.Sprintf("Failed to generate recovery codes: %s", err), w, r)
+							return
+						}
+					}
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					if codeList != nil {
+						renderSettingPrivacy(rc, user, "", codeList, w)
+						return
+					}
+					rc.ReportRedirect("/setting/privacy", 3, "Setting Updated", "Your security key has been registered.", w, r)
+					return
+				case "webauthn-remove":
+					name := r.Form.Get("name")
+					res := make([]model.GitusUserWebAuthnCredential, 0)
+					for _, k := range user.TFAConfig.WebAuthn {
+						if k.Name != name { res = append(res, k) }
+					}
+					user.TFAConfig.WebAuthn = res
+					err = rc.DatabaseInterface.UpdateUserInfo(rc.LoginInfo.UserName, user)
+					if err != nil {
+						rc.ReportInternalError(err.Error(), w, r)
+						return
+					}
+					rc.ReportRedirect("/setting/privacy", 3, "Setting Updated", "The security key has been removed.", w, r)
+					return
 				default:
 					rc.ReportNormalError("Invalid request", w, r)
 					return
@@ -62,3 +230,57 @@ func bindSettingPrivacyController(ctx *RouterContext) {
 	))
 }
 
+
+// recovery codes are only shown right after they're generated since
+// we only store their hashes.
+func renderSettingPrivacy(rc *RouterContext, user *model.GitusUser, totpErrorMsg string, recoveryCodeList []string, w http.ResponseWriter) {
+	m := &templates.SettingPrivacyTemplateModel{
+		Config: rc.Config,
+		User: user,
+		LoginInfo: rc.LoginInfo,
+		RecoveryCodeList: recoveryCodeList,
+	}
+	if len(user.TFAConfig.TOTP.PendingSecret) > 0 {
+		m.TOTPURI = totp.URI(rc.Config.DepotName, user.Name, user.TFAConfig.TOTP.PendingSecret)
+	}
+	if len(totpErrorMsg) > 0 {
+		m.ErrorMsg.Type = "totp"
+		m.ErrorMsg.Message = totpErrorMsg
+	}
+	setupSettingPrivacyWebAuthn(rc, user, m)
+	LogTemplateError(rc.LoadTemplate("setting/privacy").Execute(w, m))
+}
+
+func renderSettingPrivacyWebAuthnError(rc *RouterContext, user *model.GitusUser, errorMsg string, w http.ResponseWriter) {
+	m := &templates.SettingPrivacyTemplateModel{
+		Config: rc.Config,
+		User: user,
+		LoginInfo: rc.LoginInfo,
+	}
+	m.ErrorMsg.Type = "webauthn"
+	m.ErrorMsg.Message = errorMsg
+	setupSettingPrivacyWebAuthn(rc, user, m)
+	LogTemplateError(rc.LoadTemplate("setting/privacy").Execute(w, m))
+}
+
+func webAuthnRegisterChallengeKeyName(username string) string {
+	return "webauthn-reg:" + username
+}
+
+// issues a new registration challenge. security keys are not
+// offered at all if the user hasn't opted into javascript or the site
+// doesn't have a http host name.
+func setupSettingPrivacyWebAuthn(rc *RouterContext, user *model.GitusUser, m *templates.SettingPrivacyTemplateModel) {
+	if !user.WebsitePreference.UseJavascript || rc.ConfirmCodeManager == nil { return }
+	rpId, _, err := webAuthnRelyingParty(rc.Config)
+	if err != nil { return }
+	challenge, err := webauthn.NewChallenge()
+	if err != nil { return }
+	rc.ConfirmCodeManager.Register(webAuthnRegisterChallengeKeyName(user.Name), challenge, 10 * time.Minute)
+	m.WebAuthnRPID = rpId
+	m.WebAuthnChallenge = challenge
+	m.WebAuthnUserId = webauthn.EncodeBase64([]byte(user.Name))
+	for _, k := range user.TFAConfig.WebAuthn {
+		m.WebAuthnCredentialIdList = append(m.WebAuthnCredentialIdList, webauthn.EncodeBase64(k.Id))
+	}
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// personal access tokens. see docs/access-token.org.
+
+func bindSettingAccessTokenController(ctx *RouterContext) {
+	http.HandleFunc("GET /setting/token", UseMiddleware(
+		[]Middleware{Logged, LoginRequired, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			un := rc.LoginInfo.UserName
+			if !model.ValidUserName(un) {
+				rc.ReportNotFound(un, "User", "Depot", w, r)
+				return
+			}
+			s, err := rc.DatabaseInterface.GetAllAccessTokenByUsername(un)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve access token: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("setting/access-token").Execute(w, &templates.SettingAccessTokenTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				TokenList: s,
+				ScopeList: model.AccessTokenScopeList,
+			}))
+		},
+	))
+
+	http.HandleFunc("POST /setting/token", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			UseLoginInfo, LoginRequired,
+			GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			un := rc.LoginInfo.UserName
+			if !model.ValidUserName(un) {
+				rc.ReportNotFound(un, "User", "Depot", w, r)
+				return
+			}
+			reportError := func(msg string) {
+				tokenList, err := rc.DatabaseInterface.GetAllAccessTokenByUsername(un)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to retrieve access token: %s", err), w, r)
+					return
+				}
+				LogTemplateError(rc.LoadTemplate("setting/access-token").Execute(w, &templates.SettingAccessTokenTemplateModel{
+					Config: rc.Config,
+					LoginInfo: rc.LoginInfo,
+					TokenList: tokenList,
+					ScopeList: model.AccessTokenScopeList,
+					ErrorMsg: msg,
+				}))
+			}
+			chkres, err := checkUserPassword(rc, un, strings.TrimSpace(r.Form.Get("password")))
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			if !chkres {
+				reportError("Invalid confirmation password")
+				return
+			}
+			tokenName := strings.TrimSpace(r.Form.Get("token-name"))
+			if !model.ValidAccessTokenName(tokenName) {
+				reportError("Invalid token name. Token names can only contain 0-9, a-z, A-Z, underscore, hyphen and dot, and must not be longer than 64 characters.")
+				return
+			}
+			scope := make([]string, 0)
+			for _, k := range r.Form["scope"] {
+				if !model.ValidAccessTokenScope(k) {
+					reportError(fmt.Sprintf("Invalid scope: %s", k))
+					return
+				}
+				scope = append(scope, k)
+			}
+			if len(scope) <= 0 {
+				reportError("A token must have at least one scope.")
+				return
+			}
+			// the admin scope is meaningless for normal users.
+			if !rc.LoginInfo.IsAdmin {
+				for _, k := range scope {
+					if k == model.TOKEN_SCOPE_ADMIN {
+						reportError("Only admins can create tokens with the admin scope.")
+						return
+					}
+				}
+			}
+			var expireTime int64 = 0
+			expireStr := strings.TrimSpace(r.Form.Get("expire"))
+			if len(expireStr) > 0 {
+				days, err := strconv.ParseInt(expireStr, 10, 64)
+				if err != nil || days < 0 {
+					reportError("Invalid expiration time.")
+					return
+				}
+				if days > 0 {
+					expireTime = time.Now().Add(time.Duration(days) * 24 * time.Hour).Unix()
+				}
+			}
+			tokenText, err := model.NewAccessTokenString()
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to generate access token: %s", err), w, r)
+				return
+			}
+			err = rc.DatabaseInterface.RegisterAccessToken(un, tokenName, model.HashAccessToken(tokenText), scope, expireTime)
+			if err == db.ErrEntityAlreadyExists {
+				reportError(fmt.Sprintf("Access token %s already exists.", tokenName))
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to register access token: %s", err), w, r)
+				return
+			}
+			tokenList, err := rc.DatabaseInterface.GetAllAccessTokenByUsername(un)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to retrieve access token: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("setting/access-token").Execute(w, &templates.SettingAccessTokenTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				TokenList: tokenList,
+				ScopeList: model.AccessTokenScopeList,
+				NewTokenName: tokenName,
+				NewTokenText: tokenText,
+			}))
+		},
+	))
+
+	http.HandleFunc("GET /setting/token/{tokenName}/revoke", UseMiddleware(
+		[]Middleware{Logged, UseLoginInfo, LoginRequired,
+			GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			un := rc.LoginInfo.UserName
+			if !model.ValidUserName(un) {
+				rc.ReportNotFound(un, "User", "Depot", w, r)
+				return
+			}
+			tokenName := r.PathValue("tokenName")
+			if !model.ValidAccessTokenName(tokenName) {
+				rc.ReportNotFound(tokenName, "Access Token", un, w, r)
+				return
+			}
+			err := rc.DatabaseInterface.RevokeAccessToken(un, tokenName)
+			if err != nil {
+				rc.ReportInternalError(err.Error(), w, r)
+				return
+			}
+			FoundAt(w, "/setting/token")
+		},
+	))
+}
+
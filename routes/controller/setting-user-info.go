@@ -96,10 +96,23 @@ func bindSettingController(ctx *RouterContext) {
 					return
 				}
 			case "website-preference":
+				useJavascript := r.Form.Has("use-javascript") && len(r.Form.Get("use-javascript")) > 0
+				if !useJavascript && user.WebAuthnIsOnlySecondFactor() {
+					LogTemplateError(ctx.LoadTemplate("setting/user-info").Execute(w, templates.SettingUserInfoTemplateModel{
+						User: user,
+						Config: ctx.Config,
+						LoginInfo: rc.LoginInfo,
+						ErrorMsg: struct{Type string; Message string}{
+							Type: r.Form.Get("type"),
+							Message: "Security keys are your only second factor & they need Javascript; please set up another second factor before turning Javascript off.",
+						},
+					}))
+					return
+				}
 				user.WebsitePreference.ForegroundColor = strings.TrimSpace(r.Form.Get("foreground-color"))
 				user.WebsitePreference.BackgroundColor = strings.TrimSpace(r.Form.Get("background-color"))
 				user.WebsitePreference.UseSiteWideThemeConfig = r.Form.Has("use-sitewide-theme-config") && len(r.Form.Get("use-sitewide-theme-config")) > 0
-				user.WebsitePreference.UseJavascript = r.Form.Has("use-javascript") && len(r.Form.Get("use-javascript")) > 0
+				user.WebsitePreference.UseJavascript = useJavascript
 				err = rc.DatabaseInterface.UpdateUserInfo(user.Name, user)
 				if err != nil {
 					ctx.ReportInternalError(err.Error(), w, r)
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+// NOTE THAT snippets can only be cloned, never pushed to; edits are
+// done thru the web ui. visibility rules are the same as the web ui
+// (see docs/snippets.org): guests are asked to authenticate w/ http
+// basic auth if the snippet isn't visible to them.
+
+// resolves & checks the snippet for cloning. returns the path of the
+// git directory, or an empty string if any of the checks fails, in
+// which case the response is already written.
+func resolveHTTPSnippetCloneTarget(ctx *RouterContext, w http.ResponseWriter, r *http.Request) string {
+	loginInfo := resolveHTTPCloneLoginInfo(ctx, w, r)
+	if loginInfo == nil { return "" }
+	username := r.PathValue("username")
+	name := r.PathValue("name")
+	sn, err := ctx.DatabaseInterface.GetSnippet(username, name)
+	if err != nil {
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Snippet not found.")
+		return ""
+	}
+	if !CheckSnippetVisibleToUser(loginInfo, sn) {
+		if !loginInfo.LoggedIn {
+			requireHTTPBasicAuth(ctx, w)
+			return ""
+		}
+		w.WriteHeader(404)
+		fmt.Fprint(w, "Snippet not found.")
+		return ""
+	}
+	lgr, err := sn.OpenRepository(ctx.Config.SnippetRoot)
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "Failed to open snippet: %s", err)
+		return ""
+	}
+	return lgr.GitDirectoryPath
+}
+
+func bindSnippetCloneController(ctx *RouterContext) {
+	http.HandleFunc("GET /snippet/{username}/{name}/info/{p...}", UseMiddleware(
+		[]Middleware{ Logged }, ctx,
+		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("service") == "git-receive-pack" {
+				w.WriteHeader(403)
+				printGitError(w, "Snippets cannot be pushed to.")
+				return
+			}
+			if !ctx.Config.GitConfig.HTTPCloneProtocol.V1Dumb && !ctx.Config.GitConfig.HTTPCloneProtocol.V2 {
+				w.WriteHeader(403)
+				fmt.Fprint(w, "HTTP clone not supported on this instance")
+				return
+			}
+			p := resolveHTTPSnippetCloneTarget(ctx, w, r)
+			if p == "" { return }
+			serveHTTPCloneInfo(ctx, w, r, p)
+		}))
+	http.HandleFunc("POST /snippet/{username}/{name}/git-upload-pack", UseMiddleware(
+		[]Middleware{ Logged }, ctx,
+		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
+			if !ctx.Config.GitConfig.HTTPCloneProtocol.V2 {
+				w.WriteHeader(403)
+				fmt.Fprint(w, "v2 protocl not supported on this instance.")
+				return
+			}
+			p := resolveHTTPSnippetCloneTarget(ctx, w, r)
+			if p == "" { return }
+			serveHTTPCloneUploadPack(ctx, w, r, p)
+		}))
+	http.HandleFunc("GET /snippet/{username}/{name}/HEAD", UseMiddleware(
+		[]Middleware{ Logged }, ctx,
+		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
+			p := resolveHTTPSnippetCloneTarget(ctx, w, r)
+			if p == "" { return }
+			serveHTTPCloneFile(ctx, w, r, path.Join(p, "HEAD"))
+		}))
+	http.HandleFunc("GET /snippet/{username}/{name}/objects/{obj...}", UseMiddleware(
+		[]Middleware{ Logged }, ctx,
+		func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
+			p := resolveHTTPSnippetCloneTarget(ctx, w, r)
+			if p == "" { return }
+			serveHTTPCloneFile(ctx, w, r, path.Join(p, "objects", r.PathValue("obj")))
+		}))
+}
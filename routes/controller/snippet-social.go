@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	. "github.com/GitusCodeForge/Gitus/routes"
+)
+
+// comments, stars & forks of snippets. see docs/snippets.org.
+
+// resolves the snippet in the path & checks if it's visible to the
+// current user. returns nil if not, in which case the response is
+// already written.
+func resolveVisibleSnippet(rc *RouterContext, w http.ResponseWriter, r *http.Request) *model.Snippet {
+	username := r.PathValue("username")
+	name := r.PathValue("name")
+	sn, err := rc.DatabaseInterface.GetSnippet(username, name)
+	if err != nil {
+		rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
+		return nil
+	}
+	if !CheckSnippetVisibleToUser(rc.LoginInfo, sn) {
+		rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
+		return nil
+	}
+	return sn
+}
+
+func bindSnippetSocialController(ctx *RouterContext) {
+	http.HandleFunc("POST /snippet/{username}/{name}/comment", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			sn := resolveVisibleSnippet(rc, w, r)
+			if sn == nil { return }
+			snippetPath := fmt.Sprintf("/snippet/%s/%s", sn.BelongingUser, sn.Name)
+			var err error
+			switch r.Form.Get("type") {
+			case "comment":
+				content := r.Form.Get("content")
+				if len(strings.TrimSpace(content)) <= 0 {
+					rc.ReportRedirect(snippetPath, 5, "Empty Comment", "The comment cannot be empty.", w, r)
+					return
+				}
+				err = rc.DatabaseInterface.NewSnippetEvent(sn.BelongingUser, sn.Name, model.SNIPPET_EVENT_COMMENT, rc.LoginInfo.UserName, content)
+			case "delete":
+				id, err := strconv.ParseInt(r.Form.Get("id"), 10, 64)
+				if err != nil {
+					rc.ReportNormalError("Invalid request", w, r)
+					return
+				}
+				eventList, err := rc.DatabaseInterface.GetAllSnippetEvent(sn.BelongingUser, sn.Name)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to get snippet comments: %s", err), w, r)
+					return
+				}
+				var target *model.SnippetEvent = nil
+				for _, k := range eventList {
+					if k.EventAbsId == id { target = k; break }
+				}
+				if target == nil {
+					rc.ReportNotFound(fmt.Sprintf("%d", id), "Comment", fmt.Sprintf("Snippet %s", sn.FullName()), w, r)
+					return
+				}
+				// the author of the comment, the owner of the snippet
+				// & admins can delete comments.
+				if target.EventAuthor != rc.LoginInfo.UserName && sn.BelongingUser != rc.LoginInfo.UserName && !rc.LoginInfo.IsAdmin {
+					rc.ReportRedirect(snippetPath, 5, "Not Enough Privilege", "You don't have the permission required to perform this action.", w, r)
+					return
+				}
+				err = rc.DatabaseInterface.HardDeleteSnippetEvent(sn.BelongingUser, sn.Name, id)
+				if err != nil {
+					rc.ReportInternalError(fmt.Sprintf("Failed to delete comment: %s", err), w, r)
+					return
+				}
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to post comment: %s", err), w, r)
+				return
+			}
+			FoundAt(w, snippetPath + "#comment")
+		},
+	))
+
+	http.HandleFunc("POST /snippet/{username}/{name}/star", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			sn := resolveVisibleSnippet(rc, w, r)
+			if sn == nil { return }
+			var err error
+			switch r.Form.Get("type") {
+			case "star":
+				err = rc.DatabaseInterface.StarSnippet(sn.BelongingUser, sn.Name, rc.LoginInfo.UserName)
+			case "unstar":
+				err = rc.DatabaseInterface.UnstarSnippet(sn.BelongingUser, sn.Name, rc.LoginInfo.UserName)
+			default:
+				rc.ReportNormalError("Invalid request", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to update starring status: %s", err), w, r)
+				return
+			}
+			FoundAt(w, fmt.Sprintf("/snippet/%s/%s", sn.BelongingUser, sn.Name))
+		},
+	))
+
+	http.HandleFunc("POST /snippet/{username}/{name}/fork", UseMiddleware(
+		[]Middleware{Logged, ValidPOSTRequestRequired,
+			UseLoginInfo, LoginRequired, GlobalVisibility, ErrorGuard,
+		}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			sn := resolveVisibleSnippet(rc, w, r)
+			if sn == nil { return }
+			snippetPath := fmt.Sprintf("/snippet/%s/%s", sn.BelongingUser, sn.Name)
+			newName := strings.TrimSpace(r.Form.Get("name"))
+			if len(newName) <= 0 { newName = sn.Name }
+			if !model.ValidStrictRepositoryName(newName) {
+				rc.ReportRedirect(snippetPath, 5, "Invalid Name", "Snippet names can only contain letters, digits, underscores & hyphens.", w, r)
+				return
+			}
+			username := rc.LoginInfo.UserName
+			if username == sn.BelongingUser && newName == sn.Name {
+				rc.ReportRedirect(snippetPath, 5, "Name Taken", "You already have a snippet with this name.", w, r)
+				return
+			}
+			_, err := rc.DatabaseInterface.ForkSnippet(sn.BelongingUser, sn.Name, username, newName, model.SnippetForkStatus(sn.Status))
+			if err == db.ErrEntityAlreadyExists {
+				rc.ReportRedirect(snippetPath, 5, "Name Taken", "You already have a snippet with this name.", w, r)
+				return
+			}
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to fork snippet: %s", err), w, r)
+				return
+			}
+			rc.ReportRedirect(fmt.Sprintf("/snippet/%s/%s", username, newName), 5, "Snippet Forked", "The snippet has been forked.", w, r)
+		},
+	))
+}
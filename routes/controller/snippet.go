@@ -23,66 +23,105 @@ func bindSnippetController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
 				return
 			}
-			if rc.LoginInfo == nil || !rc.LoginInfo.IsAdmin {
-				switch sn.Status {
-				case model.SNIPPET_PUBLIC:
-					// intentionally left blank
-				case model.SNIPPET_INTERNAL:
-					if rc.LoginInfo == nil || !rc.LoginInfo.LoggedIn {
-						rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
-						return
-					}
-				case model.SNIPPET_SHARED_LINK:
-					// intentionally left blank
-				case model.SNIPPET_SHARED_USER:
-					if rc.LoginInfo == nil || !rc.LoginInfo.LoggedIn {
-						rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
-						return
-					}
-					_, ok := sn.SharedUser[rc.LoginInfo.UserName]
-					if !ok {
-						rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
-						return
-					}
-				case model.SNIPPET_PRIVATE:
-					if rc.LoginInfo == nil || !rc.LoginInfo.LoggedIn || sn.BelongingUser != rc.LoginInfo.UserName {
-						rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
-						return
-					}
-				case model.SNIPPET_SHARED_LINK_INTERNAL:
-					if rc.LoginInfo == nil || !rc.LoginInfo.LoggedIn {
-						rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
-						return
-					}
-				}
+			if !CheckSnippetVisibleToUser(rc.LoginInfo, sn) {
+				rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
+				return
 			}
 			err = sn.RetrieveAllFile(rc.Config.SnippetRoot)
 			if err != nil {
 				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
 				return
 			}
-			disp := make(map[string]string, 0)
-			for k, v := range sn.FileList {
-				filename := path.Base(k)
-				coloredStr, err := colorSyntax(filename, v)
+			revisionList, err := sn.GetRevisionList(rc.Config.SnippetRoot, 0)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet revisions: %s", err), w, r)
+				return
+			}
+			eventList, err := rc.DatabaseInterface.GetAllSnippetEvent(username, name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet comments: %s", err), w, r)
+				return
+			}
+			starCount, err := rc.DatabaseInterface.CountSnippetStar(username, name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet stars: %s", err), w, r)
+				return
+			}
+			forkCount, err := rc.DatabaseInterface.CountSnippetFork(username, name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet forks: %s", err), w, r)
+				return
+			}
+			starring := false
+			if rc.LoginInfo.LoggedIn {
+				starring, err = rc.DatabaseInterface.IsStarringSnippet(username, name, rc.LoginInfo.UserName)
 				if err != nil {
-					disp[k] = v
-				} else {
-					disp[k] = coloredStr
+					rc.ReportInternalError(fmt.Sprintf("Failed to get snippet stars: %s", err), w, r)
+					return
+				}
+			}
+			// only show the origin if the viewer can see it.
+			var forkOrigin *model.Snippet = nil
+			originUser, originName, err := rc.DatabaseInterface.GetSnippetForkOrigin(username, name)
+			if err == nil {
+				origin, err := rc.DatabaseInterface.GetSnippet(originUser, originName)
+				if err == nil && CheckSnippetVisibleToUser(rc.LoginInfo, origin) {
+					forkOrigin = origin
 				}
 			}
 			LogTemplateError(rc.LoadTemplate("snippet/all-file").Execute(w, &templates.SnippetAllFileTemplateModel{
 				Config: rc.Config,
 				LoginInfo: rc.LoginInfo,
 				Snippet: sn,
-				DisplayingFileList: disp,
+				DisplayingFileList: colorSnippetFileList(sn.FileList),
+				RevisionList: revisionList,
+				EventList: eventList,
+				StarCount: starCount,
+				Starring: starring,
+				ForkCount: forkCount,
+				ForkOrigin: forkOrigin,
+			}))
+		},
+	))
+
+	http.HandleFunc("GET /snippet/{username}/{name}/revision/{commitId}", UseMiddleware(
+		[]Middleware{Logged, UseLoginInfo, GlobalVisibility, ErrorGuard}, ctx,
+		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
+			username := r.PathValue("username")
+			name := r.PathValue("name")
+			commitId := r.PathValue("commitId")
+			sn, err := rc.DatabaseInterface.GetSnippet(username, name)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
+				return
+			}
+			if !CheckSnippetVisibleToUser(rc.LoginInfo, sn) {
+				rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
+				return
+			}
+			commit, diff, err := sn.GetRevision(rc.Config.SnippetRoot, commitId)
+			if err != nil {
+				rc.ReportNotFound(commitId, "Revision", fmt.Sprintf("Snippet %s:%s", username, name), w, r)
+				return
+			}
+			err = sn.RetrieveAllFileAt(rc.Config.SnippetRoot, commit.Id)
+			if err != nil {
+				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
+				return
+			}
+			LogTemplateError(rc.LoadTemplate("snippet/revision").Execute(w, &templates.SnippetRevisionTemplateModel{
+				Config: rc.Config,
+				LoginInfo: rc.LoginInfo,
+				Snippet: sn,
+				Commit: commit,
+				Diff: diff,
+				DisplayingFileList: colorSnippetFileList(sn.FileList),
 			}))
-			
 		},
 	))
 	
 	http.HandleFunc("GET /snippet/{username}/{name}/raw/{path}", UseMiddleware(
-		[]Middleware{Logged, GlobalVisibility, ErrorGuard}, ctx,
+		[]Middleware{Logged, UseLoginInfo, GlobalVisibility, ErrorGuard}, ctx,
 		func(rc *RouterContext, w http.ResponseWriter, r *http.Request) {
 			username := r.PathValue("username")
 			name := r.PathValue("name")
@@ -92,6 +131,10 @@ func bindSnippetController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
 				return
 			}
+			if !CheckSnippetVisibleToUser(rc.LoginInfo, sn) {
+				rc.ReportNotFound(fmt.Sprintf("%s:%s", username, name), "Snippet", "Depot", w, r)
+				return
+			}
 			err = sn.RetrieveAllFile(rc.Config.SnippetRoot)
 			if err != nil {
 				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
@@ -204,6 +247,10 @@ func bindSnippetController(ctx *RouterContext) {
 				return
 			}
 			filename := r.Form.Get("filename")
+			if !model.ValidSnippetFileName(filename) {
+				rc.ReportNormalError("Invalid file name", w, r)
+				return
+			}
 			content := r.Form.Get("content")
 			sn, err := rc.DatabaseInterface.GetSnippet(username, name)
 			if err != nil {
@@ -211,7 +258,7 @@ func bindSnippetController(ctx *RouterContext) {
 				return
 			}
 			sn.SetFile(filename, content)
-			err = sn.SyncFile(rc.Config.SnippetRoot, filename)
+			err = sn.SyncFile(rc.Config.SnippetRoot, filename, rc.LoginInfo.UserFullName, rc.LoginInfo.UserEmail, fmt.Sprintf("Create %s", filename))
 			if err != nil {
 				rc.ReportInternalError(fmt.Sprintf("Failed to save snippet file: %s", err), w, r)
 				return
@@ -275,7 +322,7 @@ func bindSnippetController(ctx *RouterContext) {
 			}
 			content := r.Form.Get("content")
 			sn.SetFile(filePath, content)
-			err = sn.SyncFile(rc.Config.SnippetRoot, filePath)
+			err = sn.SyncFile(rc.Config.SnippetRoot, filePath, rc.LoginInfo.UserFullName, rc.LoginInfo.UserEmail, fmt.Sprintf("Update %s", filePath))
 			if err != nil {
 				rc.ReportInternalError(fmt.Sprintf("Failed to save file to snippet: %s", err), w, r)
 				return
@@ -300,7 +347,7 @@ func bindSnippetController(ctx *RouterContext) {
 				rc.ReportInternalError(fmt.Sprintf("Failed to get snippet: %s", err), w, r)
 				return
 			}
-			err = sn.DeleteFile(rc.Config.SnippetRoot, filePath)
+			err = sn.DeleteFile(rc.Config.SnippetRoot, filePath, rc.LoginInfo.UserFullName, rc.LoginInfo.UserEmail, fmt.Sprintf("Delete %s", filePath))
 			if err != nil {
 				rc.ReportInternalError(fmt.Sprintf("Failed to delete file: %s", err), w, r)
 				return
@@ -310,3 +357,17 @@ func bindSnippetController(ctx *RouterContext) {
 	))
 }
 
+func colorSnippetFileList(fileList map[string]string) map[string]string {
+	disp := make(map[string]string, 0)
+	for k, v := range fileList {
+		filename := path.Base(k)
+		coloredStr, err := colorSyntax(filename, v)
+		if err != nil {
+			disp[k] = v
+		} else {
+			disp[k] = coloredStr
+		}
+	}
+	return disp
+}
+
@@ -53,6 +53,24 @@ func bindWebhookResultReportController(ctx *RouterContext) {
 				fmt.Fprintf(w, "Failed to find corresponding entry: %s", err)
 				return
 			}
+			// signed w/ the secret of the webhook the delivery is for.
+			d, err := rc.DatabaseInterface.GetWebhookDelivery(body.UUID)
+			if err != nil {
+				w.WriteHeader(500)
+				fmt.Fprintf(w, "Failed to find corresponding entry: %s", err)
+				return
+			}
+			hook, err := GetWebhookOfDelivery(rc, repo, d)
+			if errors.Is(err, db.ErrEntityNotFound) {
+				w.WriteHeader(404)
+				fmt.Fprintf(w, "Not found: %s", err)
+				return
+			}
+			if err != nil {
+				w.WriteHeader(500)
+				fmt.Fprintf(w, "Failed to find corresponding entry: %s", err)
+				return
+			}
 			bearer := r.Header.Get("Authorization")
 			p := REGEX_BEARER.FindStringSubmatch(bearer)
 			if len(p) <= 0 {
@@ -61,7 +79,7 @@ func bindWebhookResultReportController(ctx *RouterContext) {
 				return
 			}
 			token, err := jwt.Parse(p[0], func(token *jwt.Token) (any, error) {
-				return repo.WebHookConfig.Secret, nil
+				return []byte(hook.Secret), nil
 			}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 			if err != nil {
 				w.WriteHeader(500)
@@ -79,6 +97,20 @@ func bindWebhookResultReportController(ctx *RouterContext) {
 						fmt.Fprintf(w, "Failed to update: %s", err)
 						return
 					}
+					// the result also shows up as a commit status. see
+					// docs/commit-status.org.
+					if len(d.CommitId) > 0 {
+						LogIfError(rc.DatabaseInterface.SetCommitStatus(&model.CommitStatus{
+							RepoNamespace: repo.Namespace,
+							RepoName: repo.Name,
+							CommitId: d.CommitId,
+							Context: model.WebhookCommitStatusContext(d.WebhookId),
+							State: model.WebhookResultCommitStatusState(body.Status),
+							TargetURL: "",
+							Description: body.Message,
+							Creator: "",
+						}))
+					}
 				} else {
 					w.WriteHeader(500)
 					fmt.Fprintf(w, "Failed to validate JWT: %s", err)
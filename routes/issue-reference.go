@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitlib"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+)
+
+// cross-references between commits, issues & pull requests. see
+// `model.ParseIssueReference` & docs/issue.org.
+//
+// references to other repositories are only recorded if the
+// repository where the reference is made can be seen by guests;
+// otherwise the title of a private commit/issue would show up in
+// the timeline of a public issue. closing references only work
+// within the same repository.
+
+func CommitReferenceSource(repo *model.Repository, cobj *gitlib.CommitObject) *model.IssueReferenceSource {
+	title, _, _ := strings.Cut(strings.TrimSpace(cobj.CommitMessage), "\n")
+	return &model.IssueReferenceSource{
+		RepoNamespace: repo.Namespace,
+		RepoName: repo.Name,
+		CommitId: cobj.Id,
+		Title: title,
+	}
+}
+
+func IssueReferenceSourceOfIssue(issue *model.Issue) *model.IssueReferenceSource {
+	return &model.IssueReferenceSource{
+		RepoNamespace: issue.RepoNamespace,
+		RepoName: issue.RepoName,
+		IssueId: int64(issue.IssueId),
+		Title: issue.IssueTitle,
+	}
+}
+
+func IssueReferenceSourceOfPullRequest(pr *model.PullRequest) *model.IssueReferenceSource {
+	return &model.IssueReferenceSource{
+		RepoNamespace: pr.ReceiverNamespace,
+		RepoName: pr.ReceiverName,
+		PullRequestId: pr.PRId,
+		Title: pr.Title,
+	}
+}
+
+func isRepositoryVisibleToGuest(ctx *RouterContext, repo *model.Repository) bool {
+	ns, err := ctx.DatabaseInterface.GetNamespaceByName(repo.Namespace)
+	if err != nil { return false }
+	return CheckRepositoryVisibleToUser(nil, ns, repo)
+}
+
+// records the references in `text`, which is made from `source` (in
+// `repo`), as events on the referenced issues & pull requests.
+// referenced issues in `repo` are closed as solved if `allowClose`
+// is true and a closing keyword is used. references to things that
+// don't exist are ignored; other errors are returned after every
+// reference is processed.
+func ProcessIssueReference(ctx *RouterContext, repo *model.Repository, source *model.IssueReferenceSource, text string, author string, allowClose bool) error {
+	refList := model.ParseIssueReference(text)
+	if len(refList) <= 0 { return nil }
+	var eType int
+	switch {
+	case len(source.CommitId) > 0: eType = model.EVENT_REFERENCED_BY_COMMIT
+	case source.PullRequestId > 0: eType = model.EVENT_REFERENCED_BY_PULL_REQUEST
+	default: eType = model.EVENT_REFERENCED_BY_ISSUE
+	}
+	contentBytes, err := json.Marshal(source)
+	if err != nil { return err }
+	content := string(contentBytes)
+	// only checked when needed since it takes a query.
+	checkedVisibility := false
+	visibleToGuest := false
+	var lastErr error = nil
+	for _, ref := range refList {
+		ns, name := ref.RepoNamespace, ref.RepoName
+		sameRepo := len(name) <= 0 || (ns == repo.Namespace && name == repo.Name)
+		if sameRepo {
+			ns, name = repo.Namespace, repo.Name
+		} else {
+			if !checkedVisibility {
+				visibleToGuest = isRepositoryVisibleToGuest(ctx, repo)
+				checkedVisibility = true
+			}
+			if !visibleToGuest { continue }
+		}
+		if ref.IsPullRequest {
+			if sameRepo && eType == model.EVENT_REFERENCED_BY_PULL_REQUEST && ref.Id == source.PullRequestId { continue }
+			pr, err := ctx.DatabaseInterface.GetPullRequest(ns, name, ref.Id)
+			if err == db.ErrEntityNotFound { continue }
+			if err != nil { lastErr = err; continue }
+			err = ctx.DatabaseInterface.AddPullRequestReferenceEvent(pr.PRAbsId, author, content)
+			if err != nil { lastErr = err }
+			continue
+		}
+		if sameRepo && eType == model.EVENT_REFERENCED_BY_ISSUE && ref.Id == source.IssueId { continue }
+		issue, err := ctx.DatabaseInterface.GetRepositoryIssue(ns, name, int(ref.Id))
+		if err == db.ErrEntityNotFound { continue }
+		if err != nil { lastErr = err; continue }
+		err = ctx.DatabaseInterface.AddIssueReferenceEvent(issue.IssueAbsId, eType, author, content)
+		if err != nil { lastErr = err; continue }
+		if allowClose && ref.Closing && sameRepo && issue.IssueStatus == model.ISSUE_OPENED {
+			err = ctx.DatabaseInterface.NewRepositoryIssueEvent(ns, name, int64(issue.IssueId), model.EVENT_CLOSED_AS_SOLVED, author, "")
+			if err != nil { lastErr = err; continue }
+			NotifyIssueEvent(ctx, repo, int64(issue.IssueId), model.EVENT_CLOSED_AS_SOLVED, author, "")
+			TriggerIssueWebhook(ctx, repo, int64(issue.IssueId), model.EVENT_CLOSED_AS_SOLVED, author, "")
+		}
+	}
+	return lastErr
+}
+
+// called when `pr` is merged. closing references in the title of the
+// pull request and in the merged commits close the issues of the
+// receiving repository, no matter which branch it's merged into.
+// `commitList` should be the commits in the pull request before it's
+// merged, since the rebase & squash strategies rewrite them.
+func ProcessPullRequestMergeReference(ctx *RouterContext, repo *model.Repository, pr *model.PullRequest, commitList []gitlib.CommitObject, author string) error {
+	var lastErr error = nil
+	err := ProcessIssueReference(ctx, repo, IssueReferenceSourceOfPullRequest(pr), pr.Title, author, true)
+	if err != nil { lastErr = err }
+	for i := range commitList {
+		err = ProcessIssueReference(ctx, repo, CommitReferenceSource(repo, &commitList[i]), commitList[i].CommitMessage, author, true)
+		if err != nil { lastErr = err }
+	}
+	return lastErr
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+)
+
+// background jobs. see docs/job-queue.org.
+
+// queues a merge check for the pull request `prId` of `repo`. like
+// notifications, failing to queue one never fails the action that
+// caused it; the check can always be started again from the pull
+// request page.
+func EnqueuePullRequestMergeCheck(ctx *RouterContext, repo *model.Repository, prId int64) {
+	if ctx.JobQueue == nil { return }
+	pr, err := ctx.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, prId)
+	if err != nil { LogIfError(err); return }
+	LogIfError(ctx.JobQueue.Enqueue(model.JOB_TYPE_MERGE_CHECK, &model.MergeCheckJobPayload{PRAbsId: pr.PRAbsId}))
+}
@@ -95,6 +95,10 @@ var AdminRequired Middleware = func(f HandlerFunc) HandlerFunc {
 					return
 				}
 			}
+			if ctx.LoginInfo.SecondFactorRequired {
+				ctx.ReportRedirect("/setting/privacy", 5, "Second Factor Required", "This site requires administrators to enroll a second factor. Please set up two-factor authentication before performing this action.", w, r)
+				return
+			}
 			if !ctx.LoginInfo.IsAdmin {
 				ctx.ReportRedirect("/", 0, "Permission Denied", "You need administrator prividege to perform this action.", w, r)
 				return
@@ -143,6 +147,90 @@ func CheckGlobalVisibleToUser(ctx *RouterContext, loginInfo *templates.LoginInfo
 	}
 }
 
+// check if a repository is visible to a user according to the rules
+// described in docs/member.org. `loginInfo` being nil or not logged in
+// means the visitor is a guest. this only concerns the status of the
+// repository & its namespace; global visibility should be checked
+// separately with `CheckGlobalVisibleToUser`.
+func CheckRepositoryVisibleToUser(loginInfo *templates.LoginInfoModel, ns *model.Namespace, repo *model.Repository) bool {
+	loggedIn := loginInfo != nil && loginInfo.LoggedIn
+	username := ""
+	if loggedIn { username = loginInfo.UserName }
+	if loggedIn && loginInfo.IsAdmin { return true }
+	isRepoMember := loggedIn && (repo.Owner == username || repo.AccessControlList.GetUserPrivilege(username) != nil)
+	if isRepoMember { return true }
+	isNamespaceMember := loggedIn && (ns.Owner == username || ns.ACL.GetUserPrivilege(username) != nil)
+	switch ns.Status {
+	case model.NAMESPACE_NORMAL_PRIVATE:
+		if !isNamespaceMember { return false }
+	case model.NAMESPACE_INTERNAL:
+		if !loggedIn { return false }
+	}
+	switch repo.Status {
+	case model.REPO_NORMAL_PUBLIC: return true
+	case model.REPO_ARCHIVED: return true
+	case model.REPO_INTERNAL: return loggedIn
+	case model.REPO_LIMITED: return isNamespaceMember
+	default: return false
+	}
+}
+
+// check if a snippet is visible to a user according to the rules
+// described in docs/snippets.org. like `CheckRepositoryVisibleToUser`,
+// `loginInfo` being nil or not logged in means the visitor is a guest.
+func CheckSnippetVisibleToUser(loginInfo *templates.LoginInfoModel, sn *model.Snippet) bool {
+	loggedIn := loginInfo != nil && loginInfo.LoggedIn
+	if loggedIn && (loginInfo.IsAdmin || loginInfo.UserName == sn.BelongingUser) { return true }
+	switch sn.Status {
+	case model.SNIPPET_INTERNAL: return loggedIn
+	case model.SNIPPET_SHARED_USER:
+		if !loggedIn { return false }
+		_, ok := sn.SharedUser[loginInfo.UserName]
+		return ok
+	case model.SNIPPET_PRIVATE: return false
+	case model.SNIPPET_SHARED_LINK_INTERNAL: return loggedIn
+	default: return true
+	}
+}
+
+// checks if `username` is allowed to push to `repo`. the rules are
+// the same as the ones in `HandleSSHLogin` (in `cmd/gitus/ssh.go`).
+func CheckUserPushPermission(username string, ns *model.Namespace, repo *model.Repository) bool {
+	if repo.Owner == username || ns.Owner == username { return true }
+	aclt := repo.AccessControlList.GetUserPrivilege(username)
+	if aclt == nil { aclt = ns.ACL.GetUserPrivilege(username) }
+	if aclt == nil { return false }
+	return aclt.PushToRepository
+}
+
+// checks if `username` can approve or request changes on `pr` (which
+// is a pull request to `repo`). everyone can leave review comments
+// but only the ones who can push to the receiving repository (and
+// site admins) have a say in whether it can be merged; the author of
+// the pull request can't review their own.
+func CheckUserReviewPermission(username string, isAdmin bool, ns *model.Namespace, repo *model.Repository, pr *model.PullRequest) bool {
+	if pr.Author == username { return false }
+	return isAdmin || CheckUserPushPermission(username, ns, repo)
+}
+
+// check if a namespace is visible to a user. the rules are the same
+// as the ones used by the namespace page (`/s/{namespace}`): private
+// namespaces are only visible to admins, the owner, and members w/
+// setting privileges.
+func CheckNamespaceVisibleToUser(loginInfo *templates.LoginInfoModel, ns *model.Namespace) bool {
+	loggedIn := loginInfo != nil && loginInfo.LoggedIn
+	switch ns.Status {
+	case model.NAMESPACE_INTERNAL:
+		return loggedIn
+	case model.NAMESPACE_NORMAL_PRIVATE:
+		if !loggedIn { return false }
+		if loginInfo.IsAdmin || ns.Owner == loginInfo.UserName { return true }
+		return ns.ACL.GetUserPrivilege(loginInfo.UserName).HasSettingPrivilege()
+	default:
+		return true
+	}
+}
+
 var GlobalVisibility Middleware = func(f HandlerFunc) HandlerFunc {
 	return func(ctx *RouterContext, w http.ResponseWriter, r *http.Request) {
 		if !CheckGlobalVisibleToUser(ctx, ctx.LoginInfo) {
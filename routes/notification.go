@@ -0,0 +1,221 @@
+package routes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/GitusCodeForge/Gitus/templates"
+)
+
+// notifications. see docs/notification.org.
+//
+// notifications are best-effort: failing to deliver one never fails
+// the action that caused it, so errors here are only logged.
+
+// stores `n` in the inbox of every user in `recipientList` (except
+// `n.Actor`) and mails it to the ones who want it by email.
+func SendNotification(ctx *RouterContext, recipientList []string, n model.Notification) {
+	seen := make(map[string]bool, 0)
+	for _, username := range recipientList {
+		if len(username) <= 0 || username == n.Actor || seen[username] { continue }
+		seen[username] = true
+		k := n
+		k.Username = username
+		err := ctx.DatabaseInterface.NewNotification(&k)
+		if err != nil { LogIfError(err); continue }
+		LogIfError(mailNotification(ctx, &k))
+	}
+}
+
+func mailNotification(ctx *RouterContext, n *model.Notification) error {
+	if ctx.Mailer == nil { return nil }
+	setting, err := ctx.DatabaseInterface.GetNotificationSetting(n.Username)
+	if err != nil { return err }
+	if !setting.ShouldSendEmail(n.Type) { return nil }
+	u, err := ctx.DatabaseInterface.GetUserByName(n.Username)
+	if err != nil { return err }
+	if len(u.Email) <= 0 { return nil }
+	hostName := ctx.Config.ProperHTTPHostName()
+	body := new(strings.Builder)
+	fmt.Fprintf(body, "%s\n", n.Title)
+	if len(n.Content) > 0 { fmt.Fprintf(body, "\n%s\n", n.Content) }
+	if len(n.Link) > 0 { fmt.Fprintf(body, "\n    %s%s\n", hostName, n.Link) }
+	fmt.Fprintf(body, `
+--
+You are receiving this because of your notification settings on %s.
+You can change them at %s/setting/notification
+`, ctx.Config.DepotName, hostName)
+	return ctx.Mailer.SendPlainTextMail(u.Email, fmt.Sprintf("[%s] %s", ctx.Config.DepotName, n.Title), body.String())
+}
+
+// the users in `usernameList` who can see `repo`. admins who aren't
+// members are not included.
+func filterRepositoryVisibleUser(ctx *RouterContext, repo *model.Repository, usernameList []string) []string {
+	ns, err := ctx.DatabaseInterface.GetNamespaceByName(repo.Namespace)
+	if err != nil { LogIfError(err); return nil }
+	res := make([]string, 0)
+	for _, k := range usernameList {
+		if CheckRepositoryVisibleToUser(&templates.LoginInfoModel{LoggedIn: true, UserName: k}, ns, repo) {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+func getRepositoryWatcher(ctx *RouterContext, repo *model.Repository) []string {
+	res, err := ctx.DatabaseInterface.GetAllRepositoryWatcher(repo.Namespace, repo.Name)
+	if err != nil { LogIfError(err); return make([]string, 0) }
+	return res
+}
+
+// watches `repo` for `username` if they want to watch the
+// repositories they create.
+func AutoWatchRepository(ctx *RouterContext, repo *model.Repository, username string) {
+	setting, err := ctx.DatabaseInterface.GetNotificationSetting(username)
+	if err != nil { LogIfError(err); return }
+	if !setting.AutoWatch { return }
+	LogIfError(ctx.DatabaseInterface.WatchRepository(repo.Namespace, repo.Name, username))
+}
+
+// an issue notification goes to the watchers of the repository and
+// the participants of the issue (the author, the assignees & everyone
+// who's commented on it).
+func notifyIssue(ctx *RouterContext, repo *model.Repository, issue *model.Issue, actor string, content string) {
+	recipientList := getRepositoryWatcher(ctx, repo)
+	recipientList = append(recipientList, issue.IssueAuthor)
+	recipientList = append(recipientList, issue.IssueAssignee...)
+	eventList, err := ctx.DatabaseInterface.GetAllIssueEvent(repo.Namespace, repo.Name, issue.IssueId)
+	if err != nil { LogIfError(err); return }
+	for _, k := range eventList {
+		if k.EventType == model.EVENT_COMMENT { recipientList = append(recipientList, k.EventAuthor) }
+	}
+	SendNotification(ctx, filterRepositoryVisibleUser(ctx, repo, recipientList), model.Notification{
+		Type: model.NOTIFICATION_ISSUE,
+		Actor: actor,
+		Title: fmt.Sprintf("%s#%d: %s", repo.FullName(), issue.IssueId, issue.IssueTitle),
+		Content: content,
+		Link: fmt.Sprintf("/repo/%s/issue/%d", repo.FullName(), issue.IssueId),
+	})
+}
+
+func NotifyNewIssue(ctx *RouterContext, repo *model.Repository, issueId int64, actor string) {
+	issue, err := ctx.DatabaseInterface.GetRepositoryIssue(repo.Namespace, repo.Name, int(issueId))
+	if err != nil { LogIfError(err); return }
+	notifyIssue(ctx, repo, issue, actor, fmt.Sprintf("%s opened this issue:\n\n%s", actor, issue.IssueContent))
+}
+
+// only comments, closing, reopening & assignment are notified.
+func NotifyIssueEvent(ctx *RouterContext, repo *model.Repository, issueId int64, eType int, actor string, content string) {
+	var s string
+	switch eType {
+	case model.EVENT_COMMENT: s = fmt.Sprintf("%s commented:\n\n%s", actor, content)
+	case model.EVENT_CLOSED_AS_SOLVED: s = fmt.Sprintf("%s closed this issue as solved.", actor)
+	case model.EVENT_CLOSED_AS_DISCARDED: s = fmt.Sprintf("%s closed this issue.", actor)
+	case model.EVENT_REOPENED: s = fmt.Sprintf("%s reopened this issue.", actor)
+	case model.EVENT_ASSIGNEE_ADDED: s = fmt.Sprintf("%s assigned %s.", actor, content)
+	default: return
+	}
+	issue, err := ctx.DatabaseInterface.GetRepositoryIssue(repo.Namespace, repo.Name, int(issueId))
+	if err != nil { LogIfError(err); return }
+	notifyIssue(ctx, repo, issue, actor, s)
+}
+
+// a pull request notification goes to the watchers of the receiving
+// repository and the participants of the pull request (the author &
+// everyone who's commented on or reviewed it).
+func notifyPullRequest(ctx *RouterContext, repo *model.Repository, pr *model.PullRequest, actor string, content string) {
+	recipientList := getRepositoryWatcher(ctx, repo)
+	recipientList = append(recipientList, pr.Author)
+	var pageNum int64 = 0
+	var pageSize int64 = 100
+	for {
+		l, err := ctx.DatabaseInterface.GetAllPullRequestEventPaginated(pr.PRAbsId, pageNum, pageSize)
+		if err != nil { LogIfError(err); return }
+		for _, k := range l {
+			switch k.EventType {
+			case model.PULL_REQUEST_EVENT_COMMENT: fallthrough
+			case model.PULL_REQUEST_EVENT_COMMENT_ON_CODE: fallthrough
+			case model.PULL_REQUEST_EVENT_REVIEW:
+				recipientList = append(recipientList, k.EventAuthor)
+			}
+		}
+		if int64(len(l)) < pageSize { break }
+		pageNum += 1
+	}
+	SendNotification(ctx, filterRepositoryVisibleUser(ctx, repo, recipientList), model.Notification{
+		Type: model.NOTIFICATION_PULL_REQUEST,
+		Actor: actor,
+		Title: fmt.Sprintf("%s!%d: %s", repo.FullName(), pr.PRId, pr.Title),
+		Content: content,
+		Link: fmt.Sprintf("/repo/%s/pull-request/%d", repo.FullName(), pr.PRId),
+	})
+}
+
+func NotifyNewPullRequest(ctx *RouterContext, repo *model.Repository, prId int64, actor string) {
+	pr, err := ctx.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, prId)
+	if err != nil { LogIfError(err); return }
+	notifyPullRequest(ctx, repo, pr, actor, fmt.Sprintf("%s wants to merge %s into %s.", actor, pr.ProviderBranch, pr.ReceiverBranch))
+}
+
+// only comments, merging, closing & reopening; see
+// `NotifyPullRequestCommentOnCode` & `NotifyPullRequestReview` for the
+// others.
+func NotifyPullRequestEvent(ctx *RouterContext, repo *model.Repository, pr *model.PullRequest, eType int, actor string, content string) {
+	var s string
+	switch eType {
+	case model.PULL_REQUEST_EVENT_COMMENT: s = fmt.Sprintf("%s commented:\n\n%s", actor, content)
+	case model.PULL_REQUEST_EVENT_CLOSE_AS_MERGED: s = fmt.Sprintf("%s merged this pull request.", actor)
+	case model.PULL_REQUEST_EVENT_CLOSE_AS_NOT_MERGED: s = fmt.Sprintf("%s closed this pull request.", actor)
+	case model.PULL_REQUEST_EVENT_REOPEN: s = fmt.Sprintf("%s reopened this pull request.", actor)
+	default: return
+	}
+	notifyPullRequest(ctx, repo, pr, actor, s)
+}
+
+func NotifyPullRequestCommentOnCode(ctx *RouterContext, repo *model.Repository, pr *model.PullRequest, c *model.PullRequestCommentOnCode) {
+	notifyPullRequest(ctx, repo, pr, c.Username, fmt.Sprintf("%s commented on %s:\n\n%s", c.Username, c.Path, c.Content))
+}
+
+func NotifyPullRequestReview(ctx *RouterContext, repo *model.Repository, pr *model.PullRequest, review *model.PullRequestReview) {
+	var s string
+	switch review.State {
+	case model.PULL_REQUEST_REVIEW_APPROVE: s = fmt.Sprintf("%s approved these changes.", review.Reviewer)
+	case model.PULL_REQUEST_REVIEW_REQUEST_CHANGES: s = fmt.Sprintf("%s requested changes.", review.Reviewer)
+	default: s = fmt.Sprintf("%s reviewed this pull request.", review.Reviewer)
+	}
+	if len(review.Summary) > 0 { s += "\n\n" + review.Summary }
+	notifyPullRequest(ctx, repo, pr, review.Reviewer, s)
+}
+
+// `title` is what happened, e.g. "alice added you to the repository
+// ns:repo".
+func NotifyMembership(ctx *RouterContext, username string, actor string, title string, link string) {
+	SendNotification(ctx, []string{username}, model.Notification{
+		Type: model.NOTIFICATION_MEMBERSHIP,
+		Actor: actor,
+		Title: title,
+		Link: link,
+	})
+}
+
+// tells the admins that there's a new registration request.
+func NotifyNewRegistrationRequest(ctx *RouterContext, username string, reason string) {
+	adminList, err := ctx.DatabaseInterface.GetAllAdminUsername()
+	if err != nil { LogIfError(err); return }
+	SendNotification(ctx, adminList, model.Notification{
+		Type: model.NOTIFICATION_REGISTRATION,
+		Title: fmt.Sprintf("New registration request from %s", username),
+		Content: reason,
+		Link: "/admin/reg-request",
+	})
+}
+
+func NotifyRegistrationApproved(ctx *RouterContext, username string, actor string) {
+	SendNotification(ctx, []string{username}, model.Notification{
+		Type: model.NOTIFICATION_REGISTRATION,
+		Actor: actor,
+		Title: fmt.Sprintf("Your registration on %s has been approved", ctx.Config.DepotName),
+		Link: fmt.Sprintf("/u/%s", username),
+	})
+}
@@ -0,0 +1,304 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/google/uuid"
+)
+
+// webhook events. see docs/webhooks.org.
+//
+// like notifications, webhooks are best-effort: failing to queue
+// one never fails the action that caused it.
+
+type WebHookRepositoryOwnerInfo struct{
+	Id int64 `json:"id"`
+	Login string `json:"login"`
+	FullName string `json:"full_name"`
+	Email string `json:"email"`
+	UserName string `json:"username"`
+}
+
+type WebHookRepositoryInfo struct {
+	Id int64 `json:"id"`
+	Owner WebHookRepositoryOwnerInfo `json:"owner"`
+	Name string `json:"name"`
+	Namespace string `json:"namespace"`
+	FullName string `json:"full_name"`
+	Description string `json:"description"`
+	Fork bool `json:"fork"`
+	HTMLURL string `json:"html_url"`
+	SSHURL string `json:"ssh_url"`
+	CloneURL string `json:"clone_url"`
+}
+
+type WebHookIssueInfo struct {
+	Id int `json:"id"`
+	Title string `json:"title"`
+	Author string `json:"author"`
+	// see model.ISSUE_*.
+	Status int `json:"status"`
+	HTMLURL string `json:"html_url"`
+}
+
+type WebHookPullRequestInfo struct {
+	Id int64 `json:"id"`
+	Title string `json:"title"`
+	Author string `json:"author"`
+	// see model.PULL_REQUEST_*.
+	Status int `json:"status"`
+	// the receiving branch.
+	Base string `json:"base"`
+	HeadNamespace string `json:"head_namespace"`
+	HeadName string `json:"head_name"`
+	HeadBranch string `json:"head_branch"`
+	HTMLURL string `json:"html_url"`
+}
+
+// the payload of the events other than ref updates.
+type WebHookEventPayload struct {
+	Id string `json:"id"`
+	ResultReport string `json:"result_report"`
+	ResultReportId string `json:"result_report_id"`
+	Event string `json:"event"`
+	Actor string `json:"actor"`
+	Repository *WebHookRepositoryInfo `json:"repository"`
+	Issue *WebHookIssueInfo `json:"issue,omitempty"`
+	PullRequest *WebHookPullRequestInfo `json:"pull_request,omitempty"`
+	// the commit the pull request is updated to.
+	After string `json:"after,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Fork *WebHookRepositoryInfo `json:"fork,omitempty"`
+}
+
+func NewWebHookRepositoryInfo(ctx *RouterContext, repo *model.Repository) (*WebHookRepositoryInfo, error) {
+	owner, err := ctx.DatabaseInterface.GetUserByName(repo.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get user: %s", err)
+	}
+	httpHostName := ctx.Config.ProperHTTPHostName()
+	return &WebHookRepositoryInfo{
+		Id: repo.AbsId,
+		Owner: WebHookRepositoryOwnerInfo{
+			Id: 0,
+			Login: owner.Name,
+			FullName: owner.Title,
+			Email: owner.Email,
+			UserName: owner.Name,
+		},
+		Description: repo.Description,
+		Name: repo.Name,
+		Namespace: repo.Namespace,
+		FullName: repo.FullName(),
+		Fork: repo.ForkOriginName != "" || repo.ForkOriginNamespace != "",
+		HTMLURL: fmt.Sprintf("%s/repo/%s", httpHostName, repo.FullName()),
+		SSHURL: fmt.Sprintf("%s%s/%s", ctx.Config.GitSSHHostName(), repo.Namespace, repo.Name),
+		CloneURL: fmt.Sprintf("%s/repo/%s", httpHostName, repo.FullName()),
+	}, nil
+}
+
+// the webhooks that want `event` (on `branch`, if it's a branch
+// event) of `repo`: the default webhook, the webhooks of the
+// repository & the webhooks of its namespace.
+func GetMatchingWebhook(ctx *RouterContext, repo *model.Repository, event string, branch string) ([]*model.Webhook, error) {
+	res := make([]*model.Webhook, 0)
+	if w := model.DefaultWebhookOf(repo); w != nil && w.Wants(event, branch) {
+		res = append(res, w)
+	}
+	l, err := ctx.DatabaseInterface.GetAllWebhook(repo.Namespace, repo.Name)
+	if err != nil { return nil, err }
+	if ctx.Config.UseNamespace {
+		l2, err := ctx.DatabaseInterface.GetAllWebhook(repo.Namespace, "")
+		if err != nil { return nil, err }
+		l = append(l, l2...)
+	}
+	for _, w := range l {
+		if w.Wants(event, branch) { res = append(res, w) }
+	}
+	return res, nil
+}
+
+// sets the id fields of the json `payload` for a delivery.
+func setWebhookPayloadId(ctx *RouterContext, payload []byte, reqUuid string, reportUuid string) ([]byte, error) {
+	var m map[string]any
+	err := json.Unmarshal(payload, &m)
+	if err != nil { return nil, err }
+	m["id"] = reqUuid
+	m["result_report"] = fmt.Sprintf("%s/%s", ctx.Config.ProperHTTPHostName(), "webhook-result-report")
+	m["result_report_id"] = reportUuid
+	return json.Marshal(m)
+}
+
+// stores a delivery of `payload` for each webhook in `hookList`;
+// the id fields of `payload` are filled for each of them. the
+// deliveries are not sent; see `DeliverWebhook` &
+// `EnqueueWebhookDelivery`.
+func NewWebhookDeliveryList(ctx *RouterContext, repo *model.Repository, hookList []*model.Webhook, event string, refName string, commitId string, payload any) ([]*model.WebhookDelivery, error) {
+	res := make([]*model.WebhookDelivery, 0)
+	if len(hookList) <= 0 { return res, nil }
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize webhook to json: %s", err)
+	}
+	for _, w := range hookList {
+		if w.PayloadType != "json" {
+			return nil, fmt.Errorf("Unsupported webhook payload type: %s", w.PayloadType)
+		}
+		reqUuid := uuid.New().String()
+		reportUuid := uuid.New().String()
+		p, err := setWebhookPayloadId(ctx, payloadJson, reqUuid, reportUuid)
+		if err != nil { return nil, err }
+		d := &model.WebhookDelivery{
+			UUID: reqUuid,
+			ReportUUID: reportUuid,
+			RepoNamespace: repo.Namespace,
+			RepoName: repo.Name,
+			WebhookId: w.Id,
+			Event: event,
+			CommitId: commitId,
+			RefName: refName,
+			TargetURL: w.TargetURL,
+			RequestPayload: string(p),
+		}
+		err = ctx.DatabaseInterface.NewWebhookDelivery(d)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to register webhook in database: %s", err)
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// queues the deliveries of `event` to the webhooks of `repo` that
+// want it. `makePayload` is only called if there's any.
+func triggerWebhook(ctx *RouterContext, repo *model.Repository, event string, branch string, refName string, commitId string, makePayload func(*WebHookRepositoryInfo) any) error {
+	hookList, err := GetMatchingWebhook(ctx, repo, event, branch)
+	if err != nil { return err }
+	if len(hookList) <= 0 { return nil }
+	repoInfo, err := NewWebHookRepositoryInfo(ctx, repo)
+	if err != nil { return err }
+	dList, err := NewWebhookDeliveryList(ctx, repo, hookList, event, refName, commitId, makePayload(repoInfo))
+	if err != nil { return err }
+	var lastErr error = nil
+	for _, d := range dList {
+		err = EnqueueWebhookDelivery(ctx, d)
+		if err != nil { lastErr = err }
+	}
+	return lastErr
+}
+
+// queues a job that sends the webhooks of the update of
+// `refFullName` from `oldRev` to `newRev`, if there's any webhook
+// that wants it. the payload is built by the job since it needs to
+// go thru the pushed commits. see docs/job-queue.org.
+func EnqueueRefUpdateWebhook(ctx *RouterContext, repo *model.Repository, refFullName string, oldRev string, newRev string) error {
+	event, branch := model.WebhookRefEvent(refFullName, newRev)
+	if len(event) <= 0 { return nil }
+	hookList, err := GetMatchingWebhook(ctx, repo, event, branch)
+	if err != nil { return err }
+	if len(hookList) <= 0 { return nil }
+	return ctx.JobQueue.Enqueue(model.JOB_TYPE_WEBHOOK, &model.WebhookJobPayload{
+		RepoNamespace: repo.Namespace,
+		RepoName: repo.Name,
+		RefName: refFullName,
+		OldRev: oldRev,
+		NewRev: newRev,
+	})
+}
+
+// `eType` is one of model.EVENT_*; only new comments & closing are
+// sent. see `TriggerNewIssueWebhook` for new issues.
+func TriggerIssueWebhook(ctx *RouterContext, repo *model.Repository, issueId int64, eType int, actor string, content string) {
+	var event string
+	switch eType {
+	case model.EVENT_COMMENT: event = model.WEBHOOK_EVENT_ISSUE_COMMENTED
+	case model.EVENT_CLOSED_AS_SOLVED: fallthrough
+	case model.EVENT_CLOSED_AS_DISCARDED: event = model.WEBHOOK_EVENT_ISSUE_CLOSED
+	default: return
+	}
+	if eType != model.EVENT_COMMENT { content = "" }
+	triggerIssueWebhook(ctx, repo, issueId, event, actor, content)
+}
+
+func TriggerNewIssueWebhook(ctx *RouterContext, repo *model.Repository, issueId int64, actor string) {
+	triggerIssueWebhook(ctx, repo, issueId, model.WEBHOOK_EVENT_ISSUE_OPENED, actor, "")
+}
+
+func triggerIssueWebhook(ctx *RouterContext, repo *model.Repository, issueId int64, event string, actor string, content string) {
+	issue, err := ctx.DatabaseInterface.GetRepositoryIssue(repo.Namespace, repo.Name, int(issueId))
+	if err != nil { LogIfError(err); return }
+	LogIfError(triggerWebhook(ctx, repo, event, "", "", "", func(repoInfo *WebHookRepositoryInfo) any {
+		return &WebHookEventPayload{
+			Event: event,
+			Actor: actor,
+			Repository: repoInfo,
+			Issue: &WebHookIssueInfo{
+				Id: issue.IssueId,
+				Title: issue.IssueTitle,
+				Author: issue.IssueAuthor,
+				Status: issue.IssueStatus,
+				HTMLURL: fmt.Sprintf("%s/repo/%s/issue/%d", ctx.Config.ProperHTTPHostName(), repo.FullName(), issue.IssueId),
+			},
+			Comment: content,
+		}
+	}))
+}
+
+// `event` is one of the pull request events in model.WEBHOOK_EVENT_*.
+// `commitId` is the commit the pull request is updated to; only
+// used for model.WEBHOOK_EVENT_PULL_REQUEST_UPDATED.
+func TriggerPullRequestWebhook(ctx *RouterContext, repo *model.Repository, pr *model.PullRequest, event string, actor string, commitId string) {
+	LogIfError(triggerWebhook(ctx, repo, event, pr.ReceiverBranch, "refs/heads/" + pr.ReceiverBranch, commitId, func(repoInfo *WebHookRepositoryInfo) any {
+		return &WebHookEventPayload{
+			Event: event,
+			Actor: actor,
+			Repository: repoInfo,
+			PullRequest: &WebHookPullRequestInfo{
+				Id: pr.PRId,
+				Title: pr.Title,
+				Author: pr.Author,
+				Status: pr.Status,
+				Base: pr.ReceiverBranch,
+				HeadNamespace: pr.ProviderNamespace,
+				HeadName: pr.ProviderName,
+				HeadBranch: pr.ProviderBranch,
+				HTMLURL: fmt.Sprintf("%s/repo/%s/pull-request/%d", ctx.Config.ProperHTTPHostName(), repo.FullName(), pr.PRId),
+			},
+			After: commitId,
+		}
+	}))
+}
+
+// sent to the receiving repositories of the pull requests (w/ the
+// absolute ids in `absIdList`) that's updated to `commitId`.
+func TriggerPullRequestUpdatedWebhook(ctx *RouterContext, absIdList []int64, actor string, commitId string) {
+	for _, k := range absIdList {
+		pr, err := ctx.DatabaseInterface.GetPullRequestByAbsId(k)
+		if err != nil { LogIfError(err); continue }
+		repo, err := ctx.DatabaseInterface.GetRepositoryByName(pr.ReceiverNamespace, pr.ReceiverName)
+		if err != nil { LogIfError(err); continue }
+		TriggerPullRequestWebhook(ctx, repo, pr, model.WEBHOOK_EVENT_PULL_REQUEST_UPDATED, actor, commitId)
+	}
+}
+
+func TriggerNewPullRequestWebhook(ctx *RouterContext, repo *model.Repository, prId int64, actor string) {
+	pr, err := ctx.DatabaseInterface.GetPullRequest(repo.Namespace, repo.Name, prId)
+	if err != nil { LogIfError(err); return }
+	TriggerPullRequestWebhook(ctx, repo, pr, model.WEBHOOK_EVENT_PULL_REQUEST_OPENED, actor, "")
+}
+
+// sent to the webhooks of the repository that's forked.
+func TriggerForkWebhook(ctx *RouterContext, origin *model.Repository, fork *model.Repository, actor string) {
+	forkInfo, err := NewWebHookRepositoryInfo(ctx, fork)
+	if err != nil { LogIfError(err); return }
+	LogIfError(triggerWebhook(ctx, origin, model.WEBHOOK_EVENT_FORK, "", "", "", func(repoInfo *WebHookRepositoryInfo) any {
+		return &WebHookEventPayload{
+			Event: model.WEBHOOK_EVENT_FORK,
+			Actor: actor,
+			Repository: repoInfo,
+			Fork: forkInfo,
+		}
+	}))
+}
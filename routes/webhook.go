@@ -0,0 +1,197 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/db"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/jobqueue"
+	"github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// webhook deliveries. see docs/webhooks.org.
+//
+// the payload of a delivery is built (by the post-receive hook
+// dispatcher or `gitus web-hooks send`) and stored once; every
+// attempt sends the stored payload w/ a freshly signed token.
+
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+func formatWebhookRequestHeader(h http.Header) string {
+	keyList := make([]string, 0, len(h))
+	for k := range h { keyList = append(keyList, k) }
+	sort.Strings(keyList)
+	res := new(strings.Builder)
+	for _, k := range keyList {
+		for _, v := range h[k] { fmt.Fprintf(res, "%s: %s\n", k, v) }
+	}
+	return res.String()
+}
+
+// the hex-encoded HMAC-SHA256 of `payload` w/ `secret`, which is
+// sent in the `X-Gitus-Signature` header.
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// the webhook `d` is for; the default webhook is returned even if
+// it's disabled. returns db.ErrEntityNotFound if the webhook has
+// been deleted.
+func GetWebhookOfDelivery(ctx *RouterContext, repo *model.Repository, d *model.WebhookDelivery) (*model.Webhook, error) {
+	if d.WebhookId == 0 {
+		res := model.DefaultWebhookOf(repo)
+		if res == nil {
+			res = &model.Webhook{
+				Namespace: repo.Namespace,
+				RepoName: repo.Name,
+				TargetURL: repo.WebHookConfig.TargetURL,
+				Secret: repo.WebHookConfig.Secret,
+				PayloadType: repo.WebHookConfig.PayloadType,
+			}
+		}
+		return res, nil
+	}
+	return ctx.DatabaseInterface.GetWebhook(d.WebhookId)
+}
+
+func failWebhookDelivery(ctx *RouterContext, d *model.WebhookDelivery, err error) error {
+	d.Error = err.Error()
+	d.Status = model.WEBHOOK_DELIVERY_FAILED
+	LogIfError(ctx.DatabaseInterface.UpdateWebhookDelivery(d))
+	return &jobqueue.PermanentError{Err: err}
+}
+
+// 4xx responses (other than "request timeout" & "too many requests")
+// mean the receiving end doesn't want it, which sending it again
+// wouldn't fix.
+func isPermanentWebhookFailure(statusCode int) bool {
+	if statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests { return false }
+	return statusCode >= 400 && statusCode < 500
+}
+
+// sends `d` once & records the result. `lastAttempt` tells whether
+// the delivery should be marked as failed (instead of pending) if
+// this attempt fails. the returned error is a
+// `*jobqueue.PermanentError` if trying again wouldn't help.
+func DeliverWebhook(ctx *RouterContext, d *model.WebhookDelivery, lastAttempt bool) error {
+	repo, err := ctx.DatabaseInterface.GetRepositoryByName(d.RepoNamespace, d.RepoName)
+	if err == db.ErrEntityNotFound {
+		return failWebhookDelivery(ctx, d, fmt.Errorf("Repository %s:%s no longer exists", d.RepoNamespace, d.RepoName))
+	}
+	if err != nil { return err }
+	hook, err := GetWebhookOfDelivery(ctx, repo, d)
+	if err == db.ErrEntityNotFound {
+		return failWebhookDelivery(ctx, d, fmt.Errorf("The webhook no longer exists"))
+	}
+	if err != nil { return err }
+	nonce, err := rand.Int(rand.Reader, big.NewInt(1<<31))
+	if err != nil { return err }
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+		"iss": time.Now().Unix(),
+		"nonce": nonce.Int64(),
+	})
+	tokenStr, err := token.SignedString([]byte(hook.Secret))
+	if err != nil { return fmt.Errorf("Failed to sign webhook token: %s", err) }
+	req, err := http.NewRequest("POST", d.TargetURL, bytes.NewReader([]byte(d.RequestPayload)))
+	if err != nil {
+		// the url is broken.
+		err = fmt.Errorf("Failed to create HTTP request: %s", err)
+		d.Error = err.Error()
+		d.Status = model.WEBHOOK_DELIVERY_FAILED
+		d.Attempt += 1
+		d.DeliveryTime = time.Now().Unix()
+		LogIfError(ctx.DatabaseInterface.UpdateWebhookDelivery(d))
+		return &jobqueue.PermanentError{Err: err}
+	}
+	req.Header.Add("Authentication", fmt.Sprintf("Bearer webhook-jwt-%s", tokenStr))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Gitus-Delivery", d.UUID)
+	req.Header.Add("X-Gitus-Event", d.Event)
+	req.Header.Add("X-Gitus-Signature", "sha256=" + SignWebhookPayload(hook.Secret, []byte(d.RequestPayload)))
+	startTime := time.Now()
+	resp, err := webhookClient.Do(req)
+	d.Attempt += 1
+	d.DeliveryTime = startTime.Unix()
+	d.RequestHeader = formatWebhookRequestHeader(req.Header)
+	d.ResponseStatus = 0
+	d.ResponseBody = ""
+	d.Error = ""
+	permanent := false
+	if err != nil {
+		d.Duration = time.Since(startTime).Milliseconds()
+		err = fmt.Errorf("Failed while sending HTTP POST request: %s", err)
+	} else {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, model.WEBHOOK_RESPONSE_BODY_LIMIT))
+		resp.Body.Close()
+		d.Duration = time.Since(startTime).Milliseconds()
+		d.ResponseStatus = resp.StatusCode
+		d.ResponseBody = string(body)
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err = fmt.Errorf("Errorneous HTTP response: %s", resp.Status)
+			permanent = isPermanentWebhookFailure(resp.StatusCode)
+		}
+	}
+	if err == nil {
+		d.Status = model.WEBHOOK_DELIVERY_SUCCESS
+	} else {
+		d.Error = err.Error()
+		if permanent || lastAttempt {
+			d.Status = model.WEBHOOK_DELIVERY_FAILED
+		} else {
+			d.Status = model.WEBHOOK_DELIVERY_PENDING
+		}
+	}
+	LogIfError(ctx.DatabaseInterface.UpdateWebhookDelivery(d))
+	if permanent { return &jobqueue.PermanentError{Err: err} }
+	return err
+}
+
+// queues `d` to be delivered by the job workers, which retries
+// failed attempts. see docs/job-queue.org.
+func EnqueueWebhookDelivery(ctx *RouterContext, d *model.WebhookDelivery) error {
+	if ctx.JobQueue == nil { return errors.New("Job queue not available") }
+	return ctx.JobQueue.Enqueue(model.JOB_TYPE_WEBHOOK_DELIVERY, &model.WebhookDeliveryJobPayload{UUID: d.UUID})
+}
+
+// sends the payload of `d` again as a new delivery to the current
+// target url of its webhook. the new delivery comes w/ new ids so
+// that the receiving end can report its result again.
+func RedeliverWebhook(ctx *RouterContext, repo *model.Repository, hook *model.Webhook, d *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	reqUuid := uuid.New().String()
+	reportUuid := uuid.New().String()
+	payloadJson, err := setWebhookPayloadId(ctx, []byte(d.RequestPayload), reqUuid, reportUuid)
+	if err != nil { return nil, fmt.Errorf("Failed to parse the original payload: %s", err) }
+	res := &model.WebhookDelivery{
+		UUID: reqUuid,
+		ReportUUID: reportUuid,
+		RepoNamespace: repo.Namespace,
+		RepoName: repo.Name,
+		WebhookId: d.WebhookId,
+		Event: d.Event,
+		CommitId: d.CommitId,
+		RefName: d.RefName,
+		TargetURL: hook.TargetURL,
+		RequestPayload: string(payloadJson),
+		RedeliveryOf: d.UUID,
+	}
+	err = ctx.DatabaseInterface.NewWebhookDelivery(res)
+	if err != nil { return nil, err }
+	err = EnqueueWebhookDelivery(ctx, res)
+	if err != nil { return nil, err }
+	return res, nil
+}
@@ -3,10 +3,13 @@
 package templates
 
 import "github.com/GitusCodeForge/Gitus/pkg/gitlib"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 
 type CommitInfoTemplateModel struct {
 	RootPath string
 	Commit *gitlib.CommitObject
 	EmailUserMapping map[string]string
+	// nil if not shown. see docs/commit-status.org.
+	StatusList []*model.CommitStatus
 }
 
@@ -12,5 +12,24 @@ type LoginInfoModel struct {
 	IsSettingMember bool
 	IsAdmin bool
 	IsSuperAdmin bool
+	// the user is an admin but doesn't have admin privileges until a
+	// second factor is enrolled. see `AdminSecondFactorRequired`.
+	SecondFactorRequired bool
+	// counts the unread notifications of the user. only called (at
+	// most once) when the header is rendered, so that the requests
+	// that don't render it (e.g. assets & the api) don't query for
+	// it. nil when not logged in.
+	CountUnreadNotification func() int64
+	unreadNotificationCount int64
+	unreadNotificationCounted bool
+}
+
+func (m *LoginInfoModel) UnreadNotificationCount() int64 {
+	if m.CountUnreadNotification == nil { return 0 }
+	if !m.unreadNotificationCounted {
+		m.unreadNotificationCount = m.CountUnreadNotification()
+		m.unreadNotificationCounted = true
+	}
+	return m.unreadNotificationCount
 }
 
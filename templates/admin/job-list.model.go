@@ -0,0 +1,15 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type AdminJobListTemplateModel struct {
+	Config *gitus.GitusConfig
+	LoginInfo *LoginInfoModel
+	JobList []*model.Job
+	PageInfo *PageInfoModel
+	// 0 for all jobs.
+	Status int
+}
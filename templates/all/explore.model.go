@@ -0,0 +1,15 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type ExploreTemplateModel struct {
+	Config *gitus.GitusConfig
+	LoginInfo *LoginInfoModel
+	RepositoryList []*model.Repository
+	PageInfo *PageInfoModel
+	Sort string
+}
+
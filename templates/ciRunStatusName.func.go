@@ -0,0 +1,8 @@
+//go:build ignore
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+func(status int) string {
+	return model.CIRunStatusName(status)
+}
@@ -0,0 +1,8 @@
+//go:build ignore
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+func(l []*model.CommitStatus) string {
+	return model.CombineCommitStatus(l)
+}
@@ -0,0 +1,13 @@
+//go:build ignore
+package templates
+
+import "fmt"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+
+func(cfg gitus.GitusConfig, sn model.Snippet) string {
+	gitSshHostName := cfg.GitSSHHostName()
+	return fmt.Sprintf("%ssnippet/%s/%s", gitSshHostName, sn.BelongingUser, sn.Name)
+}
+
@@ -0,0 +1,13 @@
+//go:build ignore
+package templates
+
+import "fmt"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+
+func(cfg gitus.GitusConfig, sn model.Snippet) string {
+	httpHostName := cfg.ProperHTTPHostName()
+	return fmt.Sprintf("%s/snippet/%s/%s", httpHostName, sn.BelongingUser, sn.Name)
+}
+
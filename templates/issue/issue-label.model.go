@@ -0,0 +1,17 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type RepositoryIssueLabelTemplateModel struct {
+	Config *gitus.GitusConfig
+	Repository *model.Repository
+	RepoHeaderInfo *RepoHeaderTemplateModel
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	LabelList []*model.IssueLabel
+	CanTriage bool
+}
+
@@ -15,5 +15,9 @@ type RepositoryIssueListTemplateModel struct {
 	PageInfo *PageInfoModel
 	Query string
 	FilterType int
+	Filter *model.IssueFilter
+	LabelList []*model.IssueLabel
+	LabelMap map[string]*model.IssueLabel
+	MilestoneList []*model.IssueMilestone
 }
 
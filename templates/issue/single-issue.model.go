@@ -13,5 +13,9 @@ type RepositorySingleIssueTemplateModel struct {
 	ErrorMsg string
 	Issue *model.Issue
 	IssueEventList []*model.IssueEvent
+	LabelList []*model.IssueLabel
+	LabelMap map[string]*model.IssueLabel
+	MilestoneList []*model.IssueMilestone
+	CanTriage bool
 }
 
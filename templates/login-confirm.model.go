@@ -9,5 +9,14 @@ type LoginConfirmTemplateModel struct {
 	ErrorMsg string
 	LoginInfo *LoginInfoModel
 	Username string
+	// whether the user is asked for a totp code (or a recovery
+	// code) instead of a code sent by email.
+	TOTP bool
+	// the following are only set when the user can use security
+	// keys (see `GitusUser.WebAuthnUsable`).
+	WebAuthnRPID string
+	WebAuthnChallenge string
+	// base64url-encoded.
+	WebAuthnCredentialIdList []string
 }
 
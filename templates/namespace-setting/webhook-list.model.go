@@ -0,0 +1,17 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type NamespaceSettingWebhookListTemplateModel struct {
+	Config *gitus.GitusConfig
+	Namespace *model.Namespace
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	WebhookList []*model.Webhook
+	// the default of the form for adding a new webhook.
+	Webhook *model.Webhook
+	WebhookEventList []string
+}
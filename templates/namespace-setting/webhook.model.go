@@ -0,0 +1,15 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type NamespaceSettingWebhookTemplateModel struct {
+	Config *gitus.GitusConfig
+	Namespace *model.Namespace
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	Webhook *model.Webhook
+	WebhookEventList []string
+}
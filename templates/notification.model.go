@@ -0,0 +1,15 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type NotificationTemplateModel struct {
+	Config *gitus.GitusConfig
+	LoginInfo *LoginInfoModel
+	NotificationList []*model.Notification
+	PageInfo *PageInfoModel
+	UnreadOnly bool
+}
+
@@ -0,0 +1,11 @@
+//go:build ignore
+package templates
+
+import "encoding/json"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+func(s string) *model.IssueReferenceSource {
+	var r *model.IssueReferenceSource
+	json.Unmarshal([]byte(s), &r)
+	return r
+}
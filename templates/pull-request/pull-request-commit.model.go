@@ -0,0 +1,20 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+import "github.com/GitusCodeForge/Gitus/pkg/gitlib"
+
+type RepositoryPullRequestCommitTemplateModel struct {
+	Config *gitus.GitusConfig
+	Repository *model.Repository
+	RepoHeaderInfo *RepoHeaderTemplateModel
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	PullRequest *model.PullRequest
+	BaseId string
+	HeadId string
+	CommitList []gitlib.CommitObject
+	EmailUserMapping map[string]string
+}
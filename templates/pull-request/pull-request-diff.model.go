@@ -0,0 +1,22 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+import "github.com/GitusCodeForge/Gitus/pkg/gitlib"
+
+type RepositoryPullRequestDiffTemplateModel struct {
+	Config *gitus.GitusConfig
+	Repository *model.Repository
+	RepoHeaderInfo *RepoHeaderTemplateModel
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	PullRequest *model.PullRequest
+	// the merge base & the head of the provider branch.
+	BaseId string
+	HeadId string
+	Diff *gitlib.Diff
+	// index of `Diff.ItemList` -> comments on that file.
+	CommentMap map[int][]*model.PullRequestCommentOnCode
+}
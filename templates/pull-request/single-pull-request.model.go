@@ -14,5 +14,20 @@ type RepositorySinglePullRequestTemplateModel struct {
 	PullRequest *model.PullRequest
 	PullRequestEventList []*model.PullRequestEvent
 	PageNum int64
+	PullRequestSetting *model.PullRequestSetting
+	// reviewer -> latest review state. see
+	// `model.LatestPullRequestReviewState`.
+	ReviewStateMap map[string]int
+	// empty if the review requirements are met.
+	ReviewCheckMessage string
+	// whether the current user can approve/request changes.
+	CanReview bool
+	// the statuses of the head of the provider branch. see
+	// docs/commit-status.org.
+	StatusList []*model.CommitStatus
+	// reported to the provider repository; informational only.
+	ProviderStatusList []*model.CommitStatus
+	// empty if the required status checks are passed.
+	StatusCheckMessage string
 }
 
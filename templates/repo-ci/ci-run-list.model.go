@@ -0,0 +1,17 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type RepositoryCIRunListTemplateModel struct {
+	Config *gitus.GitusConfig
+	Repository *model.Repository
+	RepoHeaderInfo *RepoHeaderTemplateModel
+	RepoFullName string
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	RunList []*model.CIRun
+	PageInfo *PageInfoModel
+}
@@ -0,0 +1,17 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type RepositoryCIRunTemplateModel struct {
+	Config *gitus.GitusConfig
+	Repository *model.Repository
+	RepoHeaderInfo *RepoHeaderTemplateModel
+	RepoFullName string
+	LoginInfo *LoginInfoModel
+	ErrorMsg string
+	Run *model.CIRun
+	CanRerun bool
+}
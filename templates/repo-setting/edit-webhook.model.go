@@ -12,5 +12,9 @@ type RepositorySettingEditWebHookTemplateModel struct {
 	RepoFullName string
 	LoginInfo *LoginInfoModel
 	ErrorMsg string
+	WebhookList []*model.Webhook
+	// the default of the form for adding a new webhook.
+	Webhook *model.Webhook
+	WebhookEventList []string
 }
 
@@ -9,25 +9,19 @@ import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
 
 func(cfg *gitus.GitusConfig, s string) *model.PullRequestCommentOnCode {
 	var r *model.PullRequestCommentOnCode
-	json.Unmarshal([]byte(s), &r)
+	err := json.Unmarshal([]byte(s), &r)
+	if err != nil || r == nil { return &model.PullRequestCommentOnCode{} }
+	// comments made after range diffs were introduced carry the code
+	// themselves; older ones are read from the repository.
+	if len(r.Code) > 0 { return r }
 	p := path.Join(cfg.GitRoot, r.RepoNamespace, r.RepoName)
 	localRepo := gitlib.NewLocalGitRepository(p)
-	gobj, err := localRepo.ReadObject(r.CommitId)
-	if err != nil { log.Fatal(err.Error()) }
-	if gobj.Type() != gitlib.COMMIT {
-		log.Fatalf("Error: %s isn't a commit", r.CommitId)
+	lines, err := localRepo.ReadFileLineAtCommit(r.CommitId, r.Path)
+	if err != nil {
+		log.Printf("Failed to read code of comment: %s\n", err)
+		return r
 	}
-	cobj := gobj.(*gitlib.CommitObject)
-	gobj, err = localRepo.ReadObject(cobj.TreeObjId)
-	if err != nil { log.Fatal(err.Error()) }
-	if gobj.Type() != gitlib.COMMIT {
-		log.Fatalf("Error: %s isn't a commit", r.CommitId)
-	}
-	tobj := gobj.(*gitlib.TreeObject)
-	gobj, err = localRepo.ResolveTreePath(tobj, r.Path)
-	bobj := gobj.(*gitlib.BlobObject)
-	lines := strings.Split(string(bobj.Data), "\n")
+	if r.LineRangeStart < 0 || r.LineRangeEnd > len(lines) || r.LineRangeStart >= r.LineRangeEnd { return r }
 	r.Code = lines[r.LineRangeStart:r.LineRangeEnd]
 	return r
 }
-
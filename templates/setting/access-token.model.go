@@ -0,0 +1,19 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+
+type SettingAccessTokenTemplateModel struct {
+	Config *gitus.GitusConfig
+	LoginInfo *LoginInfoModel
+	TokenList []*model.GitusAccessToken
+	ScopeList []string
+	// only set right after a token is created; the token text is
+	// never retrievable afterwards.
+	NewTokenName string
+	NewTokenText string
+	ErrorMsg string
+}
+
@@ -13,6 +13,16 @@ type SettingPrivacyTemplateModel struct {
 		Type string
 		Message string
 	}
-		
+	// the otpauth uri of the pending totp secret.
+	TOTPURI string
+	// newly generated recovery codes.
+	RecoveryCodeList []string
+	// the following are only set when the user has opted into
+	// javascript, which is required by security keys.
+	WebAuthnRPID string
+	WebAuthnChallenge string
+	// base64url-encoded.
+	WebAuthnUserId string
+	WebAuthnCredentialIdList []string
 }
 
@@ -11,5 +11,11 @@ type SnippetAllFileTemplateModel struct {
 	LoginInfo *LoginInfoModel
 	Snippet *model.Snippet
 	DisplayingFileList map[string]string
+	RevisionList []gitlib.CommitObject
+	EventList []*model.SnippetEvent
+	StarCount int64
+	Starring bool
+	ForkCount int64
+	ForkOrigin *model.Snippet
 }
 
@@ -0,0 +1,17 @@
+//go:build ignore
+
+package templates
+
+import "github.com/GitusCodeForge/Gitus/pkg/gitus"
+import "github.com/GitusCodeForge/Gitus/pkg/gitus/model"
+import "github.com/GitusCodeForge/Gitus/pkg/gitlib"
+
+type SnippetRevisionTemplateModel struct {
+	Config *gitus.GitusConfig
+	LoginInfo *LoginInfoModel
+	Snippet *model.Snippet
+	Commit *gitlib.CommitObject
+	Diff *gitlib.Diff
+	DisplayingFileList map[string]string
+}
+
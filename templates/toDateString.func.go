@@ -0,0 +1,9 @@
+//go:build ignore
+package templates
+
+import "time"
+
+func(timestamp int64) string {
+	if timestamp == 0 { return "" }
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+}